@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/services/apps"
+	"github.com/KVSH-user/vizapSSO/internal/storage/postgres"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Manages client apps, e.g.
+//
+//	apps -actor ivanov create -name "web" -redirect-uris https://vizap.ru/callback
+//	apps -actor ivanov add-secret -app 3 -ttl 720h
+//	apps -actor ivanov revoke-secret -app 3 -secret 7
+//	apps -actor ivanov set-scopes -app 3 -scopes orders:read,orders:write
+//	apps -actor ivanov grant-role -app 3 -uid 42 -role manager
+//
+// Client secrets are printed once, when they are created. Every change is
+// written to the audit log under the -actor name.
+func main() {
+	actor := flag.String("actor", "", "staff member making the change, recorded in the audit log")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	if cmd != "list" && cmd != "roles" && *actor == "" {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.MustLoad()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	storage, err := postgres.New(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port), cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+	if err != nil {
+		panic(err)
+	}
+
+	service := apps.New(log, storage, storage, storage)
+	ctx := context.Background()
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	appID := fs.Int("app", 0, "id of the app")
+
+	switch cmd {
+	case "list":
+		fs.Parse(args)
+
+		list, err := service.Apps(ctx)
+		if err != nil {
+			log.Error("failed to list apps", sl.Err(err))
+			os.Exit(1)
+		}
+
+		for _, app := range list {
+			fmt.Printf("%d\t%s\tdisabled=%t\tgrants=%s\tscopes=%s\n",
+				app.ID, app.Name, app.Disabled, strings.Join(app.GrantTypes, ","), strings.Join(app.Scopes, ","))
+		}
+
+	case "create":
+		name := fs.String("name", "", "name of the app")
+		grants := fs.String("grants", "", "comma-separated grant types, password and refresh_token if not set")
+		redirectURIs := fs.String("redirect-uris", "", "comma-separated redirect uris")
+		accessTTL := fs.Duration("access-ttl", 0, "access token ttl, the global default if not set")
+		refreshTTL := fs.Duration("refresh-ttl", 0, "refresh token ttl, the global default if not set")
+		lifetime := fs.Duration("session-lifetime", 0, "absolute session lifetime, the global default if not set")
+		idleTimeout := fs.Duration("idle-timeout", 0, "session idle timeout, the global default if not set")
+		sliding := fs.String("sliding-refresh", "", "true or false, the global default if not set")
+		fs.Parse(args)
+
+		slidingRefresh, err := parseSliding(*sliding)
+		if err != nil {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		app, clientSecret, err := service.CreateApp(ctx, *actor, entity.App{
+			Name:            *name,
+			GrantTypes:      splitList(*grants),
+			RedirectURIs:    splitList(*redirectURIs),
+			AccessTokenTTL:  *accessTTL,
+			RefreshTokenTTL: *refreshTTL,
+			SessionLifetime: *lifetime,
+			IdleTimeout:     *idleTimeout,
+			SlidingRefresh:  slidingRefresh,
+		})
+		if err != nil {
+			log.Error("failed to create app", sl.Err(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("app_id: %d\nclient_secret: %s\n", app.ID, clientSecret)
+
+	case "update":
+		name := fs.String("name", "", "new name of the app")
+		grants := fs.String("grants", "", "comma-separated grant types")
+		redirectURIs := fs.String("redirect-uris", "", "comma-separated redirect uris")
+		accessTTL := fs.Duration("access-ttl", -1, "access token ttl, 0 for the global default")
+		refreshTTL := fs.Duration("refresh-ttl", -1, "refresh token ttl, 0 for the global default")
+		lifetime := fs.Duration("session-lifetime", -1, "absolute session lifetime, 0 for the global default")
+		idleTimeout := fs.Duration("idle-timeout", -1, "session idle timeout, 0 for the global default")
+		sliding := fs.String("sliding-refresh", "", "true, false or default")
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		app, _, err := service.App(ctx, int32(*appID))
+		if err != nil {
+			log.Error("failed to get app", sl.Err(err))
+			os.Exit(1)
+		}
+
+		// Only the flags that were passed change the app.
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "name":
+				app.Name = *name
+			case "grants":
+				app.GrantTypes = splitList(*grants)
+			case "redirect-uris":
+				app.RedirectURIs = splitList(*redirectURIs)
+			case "access-ttl":
+				app.AccessTokenTTL = *accessTTL
+			case "refresh-ttl":
+				app.RefreshTokenTTL = *refreshTTL
+			case "session-lifetime":
+				app.SessionLifetime = *lifetime
+			case "idle-timeout":
+				app.IdleTimeout = *idleTimeout
+			case "sliding-refresh":
+				if *sliding == "default" {
+					app.SlidingRefresh = nil
+					return
+				}
+				if app.SlidingRefresh, err = parseSliding(*sliding); err != nil {
+					fs.Usage()
+					os.Exit(2)
+				}
+			}
+		})
+
+		if _, err := service.UpdateApp(ctx, *actor, app); err != nil {
+			log.Error("failed to update app", sl.Err(err))
+			os.Exit(1)
+		}
+
+	case "disable", "enable":
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		if err := service.SetAppDisabled(ctx, *actor, int32(*appID), cmd == "disable"); err != nil {
+			log.Error("failed to change app", sl.Err(err))
+			os.Exit(1)
+		}
+
+	case "delete":
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		if err := service.DeleteApp(ctx, *actor, int32(*appID)); err != nil {
+			log.Error("failed to delete app", sl.Err(err))
+			os.Exit(1)
+		}
+
+	case "add-secret":
+		ttl := fs.Duration("ttl", 0, "how long the secret is valid, forever if not set")
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		var expiresAt time.Time
+		if *ttl > 0 {
+			expiresAt = time.Now().Add(*ttl)
+		}
+
+		clientSecret, secret, err := service.CreateSecret(ctx, *actor, int32(*appID), expiresAt)
+		if err != nil {
+			log.Error("failed to create secret", sl.Err(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("secret_id: %d\nclient_secret: %s\n", secret.ID, clientSecret)
+
+	case "revoke-secret":
+		secretID := fs.Int64("secret", 0, "id of the secret")
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		if err := service.RevokeSecret(ctx, *actor, int32(*appID), *secretID); err != nil {
+			log.Error("failed to revoke secret", sl.Err(err))
+			os.Exit(1)
+		}
+
+	case "set-scopes":
+		scopes := fs.String("scopes", "", "comma-separated scopes issued in the app's tokens, none if empty")
+		fs.Parse(args)
+		requireApp(fs, *appID)
+
+		if err := service.SetAppScopes(ctx, *actor, int32(*appID), splitList(*scopes)); err != nil {
+			log.Error("failed to set scopes", sl.Err(err))
+			os.Exit(1)
+		}
+
+	case "roles", "grant-role", "revoke-role":
+		uid := fs.Int64("uid", 0, "id of the user")
+		role := fs.String("role", "", "role of the user in the app")
+		fs.Parse(args)
+		requireApp(fs, *appID)
+		if *uid == 0 || (cmd != "roles" && *role == "") {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		switch cmd {
+		case "roles":
+			roles, err := service.UserRoles(ctx, *uid, int32(*appID))
+			if err != nil {
+				log.Error("failed to list roles", sl.Err(err))
+				os.Exit(1)
+			}
+			for _, role := range roles {
+				fmt.Println(role)
+			}
+		case "grant-role":
+			err = service.GrantRole(ctx, *actor, *uid, int32(*appID), *role)
+		case "revoke-role":
+			err = service.RevokeRole(ctx, *actor, *uid, int32(*appID), *role)
+		}
+		if err != nil {
+			log.Error("failed to change role", sl.Err(err))
+			os.Exit(1)
+		}
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(),
+		"Usage: %s -actor NAME list|create|update|disable|enable|delete|add-secret|revoke-secret|"+
+			"set-scopes|roles|grant-role|revoke-role [flags]\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func requireApp(fs *flag.FlagSet, appID int) {
+	if appID == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// parseSliding returns nil for an empty value, so the app uses the global
+// setting.
+func parseSliding(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	sliding, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sliding, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/auditchain"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/services/audit"
+	"github.com/KVSH-user/vizapSSO/internal/storage/postgres"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+var (
+	// errUsage means the command line is wrong; usage has been printed.
+	errUsage = errors.New("invalid usage")
+	// errChainBroken means verify found problems; they have been printed.
+	errChainBroken = errors.New("audit log was modified")
+)
+
+// Checks and exports the audit log, e.g.
+//
+//	audit verify
+//	audit verify -from 1000 -to 2000
+//	audit verify -head 3f9a... -last 2000
+//	audit export -from 1000 -to 2000 -out audit-1000-2000.jsonl
+//
+// verify exits with status 1 if any record was modified or removed.
+// Exports are JSON lines signed with audit.signing_key_file.
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(log, flag.Args()); err != nil {
+		switch {
+		case errors.Is(err, errUsage):
+			os.Exit(2)
+		case !errors.Is(err, errChainBroken):
+			log.Error("audit failed", sl.Err(err))
+		}
+		os.Exit(1)
+	}
+}
+
+func run(log *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		usage()
+		return errUsage
+	}
+
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fromID := fs.Int64("from", 0, "first record id, the start of the log if not set")
+	toID := fs.Int64("to", 0, "last record id, the end of the log if not set")
+
+	var head, out *string
+	var lastID *int64
+
+	switch cmd {
+	case "verify":
+		head = fs.String("head", "", "hash of the -last record, e.g. from an earlier export")
+		lastID = fs.Int64("last", 0, "id of the record expected to have the -head hash")
+	case "export":
+		out = fs.String("out", "-", "output file, \"-\" for stdout")
+	default:
+		usage()
+		return errUsage
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if head != nil && *head != "" && *lastID == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg := config.MustLoad()
+
+	storage, err := postgres.New(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port), cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	ctx := context.Background()
+
+	if cmd == "export" {
+		key, err := signingKey(cfg.Audit.SigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
+
+		service := audit.New(log, storage, key)
+
+		count, err := exportTo(*out, func(w io.Writer) (int, error) {
+			return service.Export(ctx, w, *fromID, *toID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export audit log: %w", err)
+		}
+
+		log.Info("audit log exported", slog.Int("records", count))
+
+		return nil
+	}
+
+	report, err := audit.New(log, storage, nil).Verify(ctx, *fromID, *toID)
+	if err != nil {
+		return fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Printf("record %d: %s\n", problem.RecordID, problem.Reason)
+	}
+
+	ok := report.OK()
+
+	// Records removed from the end leave no broken link behind, so the
+	// chain is also checked against a head recorded earlier.
+	if *head != "" {
+		records, err := storage.AuditRecords(*lastID-1, 1)
+		if err != nil {
+			return fmt.Errorf("failed to get head record: %w", err)
+		}
+
+		if !hasHead(records, *lastID, *head) {
+			fmt.Printf("record %d: doesn't have the expected head hash %s\n", *lastID, *head)
+			ok = false
+		}
+	}
+
+	fmt.Printf("checked %d records (%d written before chaining), last id %d, head %s\n",
+		report.Checked, report.Legacy, report.LastID, report.HeadHash)
+
+	if !ok {
+		return errChainBroken
+	}
+
+	return nil
+}
+
+// exportTo runs export on the file out, or on stdout for "-". A failed
+// export removes the file, so a partial log is never left behind looking
+// like a complete one.
+func exportTo(out string, export func(w io.Writer) (int, error)) (int, error) {
+	if out == "-" {
+		return export(os.Stdout)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := export(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// hasHead tells whether records, the result of looking up lastID, is the
+// record lastID with the hash head.
+func hasHead(records []entity.AuditRecord, lastID int64, head string) bool {
+	return len(records) > 0 && records[0].ID == lastID && records[0].Hash == head
+}
+
+func signingKey(file string) (ed25519.PrivateKey, error) {
+	if file == "" {
+		return nil, audit.ErrNoSigningKey
+	}
+
+	return auditchain.LoadSigningKey(file)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s verify|export [flags]\n", os.Args[0])
+	flag.PrintDefaults()
+}
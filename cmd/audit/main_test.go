@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command"},
+		{name: "unknown command", args: []string{"repair"}},
+		{name: "head without last", args: []string{"verify", "-head", "3f9a"}},
+		{name: "unknown flag", args: []string{"export", "-head", "3f9a"}},
+		{name: "bad number", args: []string{"verify", "-from", "ten"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			if err := run(log, tt.args); !errors.Is(err, errUsage) {
+				t.Fatalf("run() = %v, want %v", err, errUsage)
+			}
+		})
+	}
+}
+
+func TestExportTo(t *testing.T) {
+	errExport := errors.New("connection lost")
+
+	tests := []struct {
+		name     string
+		out      string
+		export   func(w io.Writer) (int, error)
+		wantErr  bool
+		wantFile string
+	}{
+		{
+			name: "complete export",
+			out:  "audit.jsonl",
+			export: func(w io.Writer) (int, error) {
+				_, err := io.WriteString(w, "{}\n{}\n")
+				return 2, err
+			},
+			wantFile: "{}\n{}\n",
+		},
+		{
+			name: "failed export is removed",
+			out:  "audit.jsonl",
+			export: func(w io.Writer) (int, error) {
+				io.WriteString(w, "{}\n")
+				return 1, errExport
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing directory",
+			out:     filepath.Join("missing", "audit.jsonl"),
+			export:  func(io.Writer) (int, error) { return 0, nil },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tt.out)
+
+			_, err := exportTo(out, tt.export)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("exportTo() = %v, want error %v", err, tt.wantErr)
+			}
+
+			data, readErr := os.ReadFile(out)
+			if tt.wantErr {
+				if !os.IsNotExist(readErr) {
+					t.Errorf("file is left behind: %v", readErr)
+				}
+				return
+			}
+
+			if string(data) != tt.wantFile {
+				t.Errorf("file = %q, want %q", data, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestHasHead(t *testing.T) {
+	records := []entity.AuditRecord{{ID: 2000, Hash: "3f9a"}}
+
+	tests := []struct {
+		name    string
+		records []entity.AuditRecord
+		lastID  int64
+		head    string
+		want    bool
+	}{
+		{name: "matches", records: records, lastID: 2000, head: "3f9a", want: true},
+		{name: "other hash", records: records, lastID: 2000, head: "b7c1"},
+		{name: "record removed, next one found", records: records, lastID: 1999, head: "3f9a"},
+		{name: "log truncated", lastID: 2000, head: "3f9a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasHead(tt.records, tt.lastID, tt.head); got != tt.want {
+				t.Errorf("hasHead() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
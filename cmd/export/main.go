@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/services/export"
+	"github.com/KVSH-user/vizapSSO/internal/storage/postgres"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Exports everything the SSO holds about a user, for data access requests
+// that come through support. Every run is written to the audit log under
+// the -actor name.
+func main() {
+	uid := flag.Int64("uid", 0, "id of the user to export")
+	actor := flag.String("actor", "", "staff member doing the export, recorded in the audit log")
+	zipped := flag.Bool("zip", false, "write a zip archive instead of plain JSON")
+	out := flag.String("out", "", "output file, defaults to the export file name; \"-\" for stdout")
+	flag.Parse()
+
+	if *uid == 0 || *actor == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.MustLoad()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	storage, err := postgres.New(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port), cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+	if err != nil {
+		panic(err)
+	}
+
+	// Admin exports don't go through access tokens, so there is no authorizer.
+	exporter := export.New(log, nil, storage, storage, storage, storage, storage)
+
+	format := export.FormatJSON
+	if *zipped {
+		format = export.FormatZip
+	}
+
+	bundle, err := exporter.ExportUserData(context.Background(), *actor, *uid, format)
+	if err != nil {
+		panic(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		name := *out
+		if name == "" {
+			name = bundle.Filename
+		}
+
+		f, err := os.Create(name)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	if _, err := w.Write(bundle.Data); err != nil {
+		panic(err)
+	}
+}
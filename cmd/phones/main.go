@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/phone"
+	"github.com/KVSH-user/vizapSSO/internal/storage/postgres"
+	stlog "log"
+	"strconv"
+)
+
+// Reports users whose phones normalize to the same E.164 number, and
+// phones that can't be parsed at all. With -apply, rewrites the rest to
+// E.164; duplicates are left for manual merging.
+func main() {
+	apply := flag.Bool("apply", false, "rewrite non-conflicting phones to E.164")
+	flag.Parse()
+
+	cfg := config.MustLoad()
+
+	storage, err := postgres.New(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port), cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+	if err != nil {
+		panic(err)
+	}
+
+	// Existing numbers are normalized without the mobile-only rule, so
+	// landlines registered earlier still show up in the report.
+	phones, err := phone.New(cfg.Phone.DefaultRegion, false)
+	if err != nil {
+		panic(err)
+	}
+
+	users, err := storage.UserPhones()
+	if err != nil {
+		panic(err)
+	}
+
+	groups := make(map[string][]entity.User)
+	var order []string
+
+	for _, user := range users {
+		normalized, err := phones.Normalize(user.Phone)
+		if err != nil {
+			stlog.Printf("INVALID   user=%d phone=%q: %s\n", user.ID, user.Phone, err)
+			continue
+		}
+
+		if _, ok := groups[normalized]; !ok {
+			order = append(order, normalized)
+		}
+		groups[normalized] = append(groups[normalized], user)
+	}
+
+	var duplicates, updated int
+
+	for _, normalized := range order {
+		group := groups[normalized]
+
+		if len(group) > 1 {
+			duplicates++
+			stlog.Printf("DUPLICATE %s:\n", normalized)
+			for _, user := range group {
+				stlog.Printf("          user=%d phone=%q\n", user.ID, user.Phone)
+			}
+			continue
+		}
+
+		user := group[0]
+		if user.Phone == normalized {
+			continue
+		}
+
+		stlog.Printf("NORMALIZE user=%d %q -> %q\n", user.ID, user.Phone, normalized)
+
+		if *apply {
+			if err := storage.UpdateUserPhone(user.ID, normalized); err != nil {
+				stlog.Printf("          failed: %s\n", err)
+				continue
+			}
+			updated++
+		}
+	}
+
+	stlog.Printf("users: %d, duplicate groups: %d, updated: %d\n", len(users), duplicates, updated)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"github.com/KVSH-user/vizapSSO/internal/app"
+	"github.com/KVSH-user/vizapSSO/internal/config"
 	"io"
 	stlog "log"
 	"log/slog"
@@ -8,8 +10,6 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
-	"vizapSSO/internal/app"
-	"vizapSSO/internal/config"
 )
 
 const (
@@ -23,9 +23,23 @@ func main() {
 
 	log := setupLogger(cfg.Env)
 
-	application := app.New(log, cfg.GRPC.Port, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cfg.Postgres)
+	application := app.New(log, cfg)
 
 	go application.GRPSServer.MustRun()
+	go application.MetricsServer.MustRun()
+	go application.JWKSServer.MustRun()
+	go application.PurgeJob.Run()
+	go application.CleanupJob.Run()
+	go application.ThrottleJob.Run()
+	go application.RelayJob.Run()
+	go application.WebhookJob.Run()
+	go application.RevocationFeed.Run()
+	if application.SIEMExporter != nil {
+		go application.SIEMExporter.Run()
+	}
+	if application.AdminServer != nil {
+		go application.AdminServer.MustRun()
+	}
 
 	Print(cfg, log)
 
@@ -36,7 +50,21 @@ func main() {
 
 	log.Info("stopping SSO app")
 
+	application.RevocationFeed.Stop()
 	application.GRPSServer.Stop()
+	if application.AdminServer != nil {
+		application.AdminServer.Stop()
+	}
+	application.MetricsServer.Stop()
+	application.JWKSServer.Stop()
+	application.PurgeJob.Stop()
+	application.CleanupJob.Stop()
+	application.ThrottleJob.Stop()
+	application.RelayJob.Stop()
+	application.WebhookJob.Stop()
+	if application.SIEMExporter != nil {
+		application.SIEMExporter.Stop()
+	}
 
 	log.Info("SSO app stopped")
 }
@@ -74,6 +102,11 @@ func Print(cfg *config.Config, log *slog.Logger) {
 
 	stlog.Println("==========SSO APP STARTED===========")
 	stlog.Printf("|gRPC PORT................%d\n", cfg.GRPC.Port)
+	stlog.Printf("|METRICS PORT.............%d\n", cfg.Metrics.Port)
+	stlog.Printf("|JWKS PORT................%d\n", cfg.JWKS.Port)
+	if cfg.Admin.Enabled {
+		stlog.Printf("|ADMIN gRPC PORT..........%d\n", cfg.Admin.Port)
+	}
 	stlog.Printf("|POSTGRESQL HOST..........%s\n", cfg.Postgres.Host)
 	stlog.Printf("|POSTGRESQL PORT..........%d\n", cfg.Postgres.Port)
 	stlog.Printf("|ACCESS TOKEN TTL.........%s\n", cfg.AccessTokenTTL)
@@ -0,0 +1,122 @@
+package adminapp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	admingrpc "github.com/KVSH-user/vizapSSO/internal/grpc/admin"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"log/slog"
+	"net"
+	"os"
+)
+
+type App struct {
+	log        *slog.Logger
+	gRPCServer *grpc.Server
+	port       int
+}
+
+// New builds the admin gRPC server. It returns an error if the TLS files
+// can't be loaded, since the server must never run without mTLS.
+func New(log *slog.Logger,
+	adminService admingrpc.Admin,
+	exportService admingrpc.Export,
+	appsService admingrpc.Apps,
+	securityService admingrpc.SecurityEvents,
+	webhooksService admingrpc.Webhooks,
+	authorizer interceptor.AdminAuthorizer,
+	cfg config.AdminConfig) (*App, error) {
+	const op = "adminapp.New"
+
+	creds, err := serverCredentials(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	gRPCServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(
+			interceptor.UnaryLoggingInterceptor(log),
+			// mTLS ends here, so no proxy in between can be trusted.
+			interceptor.UnaryClientInfoInterceptor(nil),
+			interceptor.UnaryAdminAuthInterceptor(log, authorizer),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.StreamLoggingInterceptor(log),
+			interceptor.StreamClientInfoInterceptor(nil),
+			interceptor.StreamAdminAuthInterceptor(log, authorizer),
+		),
+	)
+
+	admingrpc.Register(gRPCServer, adminService, exportService, appsService, securityService, webhooksService)
+
+	return &App{
+		log:        log,
+		gRPCServer: gRPCServer,
+		port:       cfg.Port,
+	}, nil
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "adminapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Int("port", a.port))
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("starting admin gRPC server", slog.String("address", l.Addr().String()))
+
+	if err := a.gRPCServer.Serve(l); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "adminapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	a.gRPCServer.GracefulStop()
+
+	log.Info("admin gRPC server stopped", slog.Int("port", a.port))
+}
+
+func serverCredentials(cfg config.AdminConfig) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPEM, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in client CA file")
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
@@ -1,30 +1,184 @@
 package app
 
 import (
+	adminapp "github.com/KVSH-user/vizapSSO/internal/app/admin"
+	cleanupapp "github.com/KVSH-user/vizapSSO/internal/app/cleanup"
+	grpcapp "github.com/KVSH-user/vizapSSO/internal/app/grpc"
+	jwksapp "github.com/KVSH-user/vizapSSO/internal/app/jwks"
+	metricsapp "github.com/KVSH-user/vizapSSO/internal/app/metrics"
+	purgeapp "github.com/KVSH-user/vizapSSO/internal/app/purge"
+	relayapp "github.com/KVSH-user/vizapSSO/internal/app/relay"
+	throttleapp "github.com/KVSH-user/vizapSSO/internal/app/throttle"
+	webhookapp "github.com/KVSH-user/vizapSSO/internal/app/webhook"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	admingrpc "github.com/KVSH-user/vizapSSO/internal/grpc/admin"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"github.com/KVSH-user/vizapSSO/internal/lib/email"
+	"github.com/KVSH-user/vizapSSO/internal/lib/geoip"
+	"github.com/KVSH-user/vizapSSO/internal/lib/hasher"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"github.com/KVSH-user/vizapSSO/internal/lib/phone"
+	"github.com/KVSH-user/vizapSSO/internal/lib/publisher"
+	"github.com/KVSH-user/vizapSSO/internal/lib/siem"
+	"github.com/KVSH-user/vizapSSO/internal/lib/sms"
+	"github.com/KVSH-user/vizapSSO/internal/lib/webhook"
+	"github.com/KVSH-user/vizapSSO/internal/services/account"
+	"github.com/KVSH-user/vizapSSO/internal/services/address"
+	"github.com/KVSH-user/vizapSSO/internal/services/admin"
+	"github.com/KVSH-user/vizapSSO/internal/services/apps"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/services/export"
+	"github.com/KVSH-user/vizapSSO/internal/services/profile"
+	"github.com/KVSH-user/vizapSSO/internal/services/revocation"
+	"github.com/KVSH-user/vizapSSO/internal/services/security"
+	"github.com/KVSH-user/vizapSSO/internal/services/webhooks"
+	"github.com/KVSH-user/vizapSSO/internal/storage/postgres"
 	"log/slog"
 	"strconv"
-	"time"
-	grpcapp "vizapSSO/internal/app/grpc"
-	"vizapSSO/internal/config"
-	"vizapSSO/internal/services/auth"
-	"vizapSSO/internal/storage/postgres"
 )
 
 type App struct {
-	GRPSServer *grpcapp.App
+	GRPSServer    *grpcapp.App
+	MetricsServer *metricsapp.App
+	JWKSServer    *jwksapp.App
+	PurgeJob      *purgeapp.App
+	CleanupJob    *cleanupapp.App
+	ThrottleJob   *throttleapp.App
+	RelayJob      *relayapp.App
+	WebhookJob    *webhookapp.App
+	// RevocationFeed must be stopped before GRPSServer: the server waits
+	// for open watch streams.
+	RevocationFeed *revocation.Feed
+	// AdminServer is nil when the admin API is disabled.
+	AdminServer *adminapp.App
+	// SIEMExporter is nil when the SIEM export is disabled.
+	SIEMExporter *siem.Exporter
 }
 
-func New(log *slog.Logger, GRPCPort int, AccessTokenTTL time.Duration, RefreshTokenTTL time.Duration, postgresConfig config.PostgresConfig) *App {
-	storage, err := postgres.New(postgresConfig.Host, strconv.Itoa(postgresConfig.Port), postgresConfig.User, postgresConfig.Password, postgresConfig.DBName)
+func New(log *slog.Logger, cfg *config.Config) *App {
+	storage, err := postgres.New(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port), cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.New(log, storage, storage, storage, storage, storage, AccessTokenTTL, RefreshTokenTTL)
+	tokenKeys, err := jwt.LoadKeys(cfg.Tokens.SigningKeyFile, cfg.Tokens.VerifyKeyFiles)
+	if err != nil {
+		panic("failed to load token keys: " + err.Error())
+	}
+
+	passHasher := hasher.New(cfg.Hasher.Workers, cfg.Hasher.QueueTimeout, cfg.Hasher.Cost)
+
+	smsSender := sms.NewLogSender(log)
+	emailSender := email.NewLogSender(log)
+
+	passPolicy := password.NewPolicy(cfg.Password.MinLength, cfg.Password.MaxLength)
+
+	phones, err := phone.New(cfg.Phone.DefaultRegion, cfg.Phone.MobileOnly)
+	if err != nil {
+		panic(err)
+	}
+
+	locator, err := geoip.New(cfg.GeoIP.File)
+	if err != nil {
+		panic(err)
+	}
+
+	var siemExporter *siem.Exporter
+	if cfg.SIEM.Enabled {
+		siemExporter, err = siem.New(log, cfg.SIEM)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	recorder := siem.NewRecorder(storage, storage, siemExporter)
+
+	codeLimiter := otp.NewLimiter(storage, cfg.OTP.ResendLimit, cfg.OTP.ResendWindow)
+
+	authService := auth.New(log, storage, storage, storage, storage, storage, passHasher, storage, smsSender, codeLimiter, cfg.Registration, phones, storage, passPolicy, cfg.Password.History, storage, cfg.Lockout, storage, cfg.Phone, storage, emailSender, storage, cfg.PasswordReset, storage, cfg.Account, storage, locator, cfg.Session, recorder, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, tokenKeys)
+
+	profileService := profile.New(log, authService, storage, storage, storage, emailSender, codeLimiter, cfg.Email)
+
+	addressService := address.New(log, authService, storage, storage, cfg.Address.MaxPerUser, cfg.Address.DefaultPageSize, cfg.Address.MaxPageSize)
+
+	exportService := export.New(log, authService, storage, storage, storage, storage, recorder)
+
+	securityService := security.New(log, authService, storage, cfg.SecurityEvents.DefaultPageSize, cfg.SecurityEvents.MaxPageSize)
+
+	webhooksService := webhooks.New(log, storage, storage, storage, recorder, cfg.Webhooks)
 
-	grpcApp := grpcapp.New(log, authService, GRPCPort)
+	appsService := apps.New(log, storage, storage, recorder)
+
+	revocationListener, err := storage.ListenRevocations()
+	if err != nil {
+		panic(err)
+	}
+
+	revocationFeed := revocation.New(log, appsService, storage, revocationListener, cfg.Revocations)
+
+	trustedProxies, err := interceptor.ParseTrustedProxies(cfg.GRPC.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+
+	grpcApp := grpcapp.New(log, authService, profileService, addressService, exportService, securityService,
+		revocationFeed, trustedProxies, cfg.GRPC.Port)
+
+	var adminApp *adminapp.App
+	if cfg.Admin.Enabled {
+		accountService := account.New(log, storage, storage, recorder, cfg.Account)
+
+		adminService := admin.New(log, storage, storage, accountService, authService, storage, recorder,
+			admingrpc.MethodRoles, cfg.Admin.Superusers, cfg.Admin.DefaultPageSize, cfg.Admin.MaxPageSize)
+
+		adminApp, err = adminapp.New(log, adminService, exportService, appsService, securityService, webhooksService,
+			adminService, cfg.Admin)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	cleanupJob := cleanupapp.New(log, storage, cfg.SecurityEvents.Retention, cfg.SecurityEvents.CleanupInterval,
+		cfg.SecurityEvents.CleanupBatchSize)
+
+	throttleJob := throttleapp.New(log, storage, cfg.OTP.ResendWindow, cfg.Lockout.Window, cfg.OTP.CleanupInterval)
+
+	publishers := []relayapp.Publisher{newPublisher(log, cfg.Outbox), webhooksService}
+
+	relayJob := relayapp.New(log, storage, publishers, cfg.Outbox.RelayInterval,
+		cfg.Outbox.BatchSize, cfg.Outbox.Lease, cfg.Outbox.Retention, cfg.Outbox.CleanupInterval)
+
+	webhookJob := webhookapp.New(log, storage, webhook.NewSender(cfg.Webhooks.Timeout), cfg.Webhooks)
 
 	return &App{
-		GRPSServer: grpcApp,
+		GRPSServer:     grpcApp,
+		MetricsServer:  metricsapp.New(log, cfg.Metrics.Port),
+		JWKSServer:     jwksapp.New(log, tokenKeys.JWKS(), cfg.JWKS.MaxAge, cfg.JWKS.Port),
+		PurgeJob:       purgeapp.New(log, storage, cfg.Account.PurgeInterval, cfg.Account.PurgeBatchSize),
+		CleanupJob:     cleanupJob,
+		ThrottleJob:    throttleJob,
+		RelayJob:       relayJob,
+		WebhookJob:     webhookJob,
+		RevocationFeed: revocationFeed,
+		AdminServer:    adminApp,
+		SIEMExporter:   siemExporter,
+	}
+}
+
+func newPublisher(log *slog.Logger, cfg config.OutboxConfig) relayapp.Publisher {
+	switch cfg.Publisher {
+	case "log":
+		return publisher.NewLog(log)
+	case "memory":
+		return publisher.NewMemory()
+	case "kafka_rest":
+		if cfg.KafkaREST.URL == "" {
+			panic("outbox.kafka_rest.url is required")
+		}
+		return publisher.NewKafkaREST(cfg.KafkaREST.URL, cfg.KafkaREST.Topic, cfg.KafkaREST.Timeout)
+	default:
+		panic("unknown outbox publisher: " + cfg.Publisher)
 	}
 }
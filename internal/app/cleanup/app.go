@@ -0,0 +1,101 @@
+package cleanupapp
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"time"
+)
+
+type EventCleaner interface {
+	DeleteSecurityEventsBefore(cutoff time.Time, limit int) (int64, error)
+}
+
+// App removes security events older than the retention period.
+type App struct {
+	log       *slog.Logger
+	cleaner   EventCleaner
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func New(log *slog.Logger, cleaner EventCleaner, retention, interval time.Duration, batchSize int) *App {
+	return &App{
+		log:       log,
+		cleaner:   cleaner,
+		retention: retention,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "cleanupapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Duration("interval", a.interval))
+
+	log.Info("starting security events cleanup job")
+
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.cleanup()
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "cleanupapp.Stop"
+
+	close(a.stop)
+	<-a.done
+
+	a.log.Info("security events cleanup job stopped", slog.String("op", op))
+}
+
+// cleanup deletes expired events in batches, so a large backlog doesn't
+// hold a long lock on the table.
+func (a *App) cleanup() {
+	const op = "cleanupapp.cleanup"
+
+	log := a.log.With(slog.String("op", op))
+
+	cutoff := time.Now().Add(-a.retention)
+
+	var total int64
+	for {
+		n, err := a.cleaner.DeleteSecurityEventsBefore(cutoff, a.batchSize)
+		if err != nil {
+			log.Error("failed to delete security events", sl.Err(err))
+			break
+		}
+
+		total += n
+
+		if n < int64(a.batchSize) {
+			break
+		}
+
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		log.Info("deleted expired security events", slog.Int64("count", total))
+	}
+}
@@ -0,0 +1,71 @@
+package cleanupapp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// testCleaner has pending expired events and deletes up to limit of them
+// per call.
+type testCleaner struct {
+	pending int64
+	failure error
+	calls   int
+	cutoffs []time.Time
+}
+
+func (c *testCleaner) DeleteSecurityEventsBefore(cutoff time.Time, limit int) (int64, error) {
+	c.calls++
+	c.cutoffs = append(c.cutoffs, cutoff)
+
+	if c.failure != nil {
+		return 0, c.failure
+	}
+
+	n := min(c.pending, int64(limit))
+	c.pending -= n
+
+	return n, nil
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		pending     int64
+		failure     error
+		wantCalls   int
+		wantPending int64
+	}{
+		{name: "nothing to delete", pending: 0, wantCalls: 1},
+		{name: "less than a batch", pending: 7, wantCalls: 1},
+		{name: "exactly one batch", pending: 10, wantCalls: 2},
+		{name: "several batches", pending: 25, wantCalls: 3},
+		{name: "storage fails", pending: 25, failure: errors.New("boom"), wantCalls: 1, wantPending: 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaner := &testCleaner{pending: tt.pending, failure: tt.failure}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, cleaner, 90*24*time.Hour, time.Hour, 10)
+
+			before := time.Now()
+			a.cleanup()
+
+			if cleaner.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", cleaner.calls, tt.wantCalls)
+			}
+			if cleaner.pending != tt.wantPending {
+				t.Errorf("pending = %d, want %d", cleaner.pending, tt.wantPending)
+			}
+
+			wantCutoff := before.Add(-90 * 24 * time.Hour)
+			if d := cleaner.cutoffs[0].Sub(wantCutoff); d < 0 || d > time.Second {
+				t.Errorf("cutoff = %v, want about %v", cleaner.cutoffs[0], wantCutoff)
+			}
+		})
+	}
+}
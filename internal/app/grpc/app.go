@@ -2,11 +2,17 @@ package grpcapp
 
 import (
 	"fmt"
+	addressgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/address"
+	authgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/auth"
+	exportgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/export"
+	profilegrpc "github.com/KVSH-user/vizapSSO/internal/grpc/profile"
+	revocationgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/revocation"
+	securitygrpc "github.com/KVSH-user/vizapSSO/internal/grpc/security"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
 	"google.golang.org/grpc"
 	"log/slog"
 	"net"
-	authgrpc "vizapSSO/internal/grpc/auth"
-	"vizapSSO/internal/interceptor"
+	"net/netip"
 )
 
 type App struct {
@@ -15,13 +21,32 @@ type App struct {
 	port       int
 }
 
-func New(log *slog.Logger, authService authgrpc.Auth, GRPCPort int) *App {
+func New(log *slog.Logger,
+	authService authgrpc.Auth,
+	profileService profilegrpc.Profile,
+	addressService addressgrpc.Address,
+	exportService exportgrpc.Export,
+	securityService securitygrpc.Security,
+	revocationFeed revocationgrpc.Revocations,
+	trustedProxies []netip.Prefix,
+	GRPCPort int) *App {
 	gRPCServer := grpc.NewServer(
-		grpc.UnaryInterceptor(interceptor.UnaryLoggingInterceptor(log)),
-		grpc.StreamInterceptor(interceptor.StreamLoggingInterceptor(log)),
+		grpc.ChainUnaryInterceptor(
+			interceptor.UnaryLoggingInterceptor(log),
+			interceptor.UnaryClientInfoInterceptor(trustedProxies),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.StreamLoggingInterceptor(log),
+			interceptor.StreamClientInfoInterceptor(trustedProxies),
+		),
 	)
 
 	authgrpc.Register(gRPCServer, authService)
+	profilegrpc.Register(gRPCServer, profileService)
+	addressgrpc.Register(gRPCServer, addressService)
+	exportgrpc.Register(gRPCServer, exportService)
+	securitygrpc.Register(gRPCServer, securityService)
+	revocationgrpc.Register(gRPCServer, revocationFeed)
 
 	return &App{
 		log:        log,
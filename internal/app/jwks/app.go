@@ -0,0 +1,83 @@
+package jwksapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Path is where the key set is served, as OpenID Connect discovery
+// usually points to.
+const Path = "/.well-known/jwks.json"
+
+// App serves the public keys of access tokens, so services verify tokens
+// without sharing a secret with the SSO.
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+}
+
+func New(log *slog.Logger, jwks []byte, maxAge time.Duration, port int) *App {
+	mux := http.NewServeMux()
+	mux.Handle("GET "+Path, Handler(jwks, maxAge))
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		port: port,
+	}
+}
+
+// Handler serves the key set with a Cache-Control of maxAge.
+func Handler(jwks []byte, maxAge time.Duration) http.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write(jwks)
+	})
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "jwksapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Int("port", a.port))
+
+	log.Info("starting JWKS server")
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "jwksapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	if err := a.httpServer.Shutdown(context.Background()); err != nil {
+		log.Error("failed to stop JWKS server", sl.Err(err))
+		return
+	}
+
+	log.Info("JWKS server stopped", slog.Int("port", a.port))
+}
@@ -0,0 +1,40 @@
+package jwksapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	jwks := []byte(`{"keys":[]}`)
+
+	tests := []struct {
+		name             string
+		maxAge           time.Duration
+		wantCacheControl string
+	}{
+		{name: "five minutes", maxAge: 5 * time.Minute, wantCacheControl: "public, max-age=300"},
+		{name: "no caching", wantCacheControl: "public, max-age=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler(jwks, tt.maxAge).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+
+			if rec.Code != http.StatusOK || rec.Body.String() != string(jwks) {
+				t.Errorf("response = %d %s", rec.Code, rec.Body)
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != "application/jwk-set+json" {
+				t.Errorf("Content-Type = %s", got)
+			}
+
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %s, want %s", got, tt.wantCacheControl)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net/http"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+}
+
+func New(log *slog.Logger, port int) *App {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf(":%d", port),
+			Handler: mux,
+		},
+		port: port,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Int("port", a.port))
+
+	log.Info("starting metrics server")
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "metricsapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	if err := a.httpServer.Shutdown(context.Background()); err != nil {
+		log.Error("failed to stop metrics server", sl.Err(err))
+		return
+	}
+
+	log.Info("metrics server stopped", slog.Int("port", a.port))
+}
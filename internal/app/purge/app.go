@@ -0,0 +1,105 @@
+package purgeapp
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	UsersToPurge(now time.Time, limit int) ([]int64, error)
+	PurgeUser(uid int64) error
+}
+
+// App removes personal data of accounts whose deletion grace period is over.
+type App struct {
+	log       *slog.Logger
+	purger    Purger
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func New(log *slog.Logger, purger Purger, interval time.Duration, batchSize int) *App {
+	return &App{
+		log:       log,
+		purger:    purger,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "purgeapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Duration("interval", a.interval))
+
+	log.Info("starting purge job")
+
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.purge()
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "purgeapp.Stop"
+
+	close(a.stop)
+	<-a.done
+
+	a.log.Info("purge job stopped", slog.String("op", op))
+}
+
+// purge works through due accounts in batches until none are left.
+func (a *App) purge() {
+	const op = "purgeapp.purge"
+
+	log := a.log.With(slog.String("op", op))
+
+	for {
+		uids, err := a.purger.UsersToPurge(time.Now(), a.batchSize)
+		if err != nil {
+			log.Error("failed to get users to purge", sl.Err(err))
+			return
+		}
+
+		if len(uids) == 0 {
+			return
+		}
+
+		purged := 0
+		for _, uid := range uids {
+			if err := a.purger.PurgeUser(uid); err != nil {
+				log.Error("failed to purge user", slog.Int64("uid", uid), sl.Err(err))
+				continue
+			}
+			purged++
+		}
+
+		log.Info("purged deleted accounts", slog.Int("count", purged))
+
+		if purged == 0 || len(uids) < a.batchSize {
+			return
+		}
+
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package relayapp
+
+import (
+	"context"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"time"
+)
+
+type Outbox interface {
+	// ClaimOutboxEvents leases up to limit unpublished events, lowest id
+	// first, skipping users whose events are leased by another claim.
+	ClaimOutboxEvents(limit int, lease time.Duration) ([]entity.DomainEvent, error)
+	MarkOutboxPublished(ids []int64) error
+	ReleaseOutboxEvents(ids []int64) error
+	DeletePublishedOutbox(cutoff time.Time, limit int) (int64, error)
+}
+
+// Publisher delivers an event to other services. Returning nil means the
+// broker has accepted it; the event is retried until then, so a publisher
+// must tolerate events it has already accepted.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.DomainEvent) error
+}
+
+// App publishes events from the outbox to every publisher in turn and
+// removes published ones after the retention period.
+//
+// Delivery is at least once: an event is published again when the relay
+// stops before marking it or when one of the publishers fails. Events of
+// a user are published in the order their changes were committed: the
+// outbox numbers them in that order, a claim leases the user's events to
+// one relay, and after an event fails the user's later events wait for
+// it. Consumers must skip ids they have already seen; the rest of a
+// user's events come in order.
+//
+// The lease must outlast publishing a batch. The relay stops publishing a
+// batch when its lease runs out and leaves the rest for the next claim.
+type App struct {
+	log             *slog.Logger
+	outbox          Outbox
+	publishers      []Publisher
+	interval        time.Duration
+	batchSize       int
+	lease           time.Duration
+	retention       time.Duration
+	cleanupInterval time.Duration
+	stop            chan struct{}
+	done            chan struct{}
+}
+
+func New(log *slog.Logger,
+	outbox Outbox,
+	publishers []Publisher,
+	interval time.Duration,
+	batchSize int,
+	lease time.Duration,
+	retention time.Duration,
+	cleanupInterval time.Duration) *App {
+	return &App{
+		log:             log,
+		outbox:          outbox,
+		publishers:      publishers,
+		interval:        interval,
+		batchSize:       batchSize,
+		lease:           lease,
+		retention:       retention,
+		cleanupInterval: cleanupInterval,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "relayapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Duration("interval", a.interval))
+
+	log.Info("starting outbox relay")
+
+	defer close(a.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-a.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+
+	for {
+		a.relay(ctx)
+
+		if time.Since(lastCleanup) >= a.cleanupInterval {
+			a.cleanup()
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "relayapp.Stop"
+
+	close(a.stop)
+	<-a.done
+
+	a.log.Info("outbox relay stopped", slog.String("op", op))
+}
+
+// relay publishes batches until the outbox is drained or publishing fails.
+// Events are claimed and marked in separate statements, so no transaction
+// stays open while the publishers talk to the network.
+func (a *App) relay(ctx context.Context) {
+	const op = "relayapp.relay"
+
+	log := a.log.With(slog.String("op", op))
+
+	for {
+		leaseEnd := time.Now().Add(a.lease)
+
+		events, err := a.outbox.ClaimOutboxEvents(a.batchSize, a.lease)
+		if err != nil {
+			log.Error("failed to claim outbox events", sl.Err(err))
+			return
+		}
+
+		published, held, err := a.publish(ctx, events, leaseEnd)
+
+		if len(published) > 0 {
+			if err := a.outbox.MarkOutboxPublished(published); err != nil {
+				log.Error("failed to mark outbox events as published", sl.Err(err))
+				return
+			}
+		}
+
+		if len(held) > 0 {
+			if err := a.outbox.ReleaseOutboxEvents(held); err != nil {
+				// They are claimed again when the lease ends.
+				log.Error("failed to release outbox events", sl.Err(err))
+			}
+		}
+
+		if err != nil {
+			log.Error("failed to publish outbox events", slog.Int("published", len(published)), sl.Err(err))
+			return
+		}
+
+		if len(held) > 0 {
+			log.Warn("outbox lease ran out", slog.Int("published", len(published)))
+			return
+		}
+
+		if len(events) < a.batchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// publish passes each event to every publisher in id order. Once an event
+// of a user fails, the user's later events are held back so they don't
+// overtake it; once the lease ends, all the rest are. It returns the ids
+// of the events all publishers accepted, the ids of the held ones and the
+// first error.
+func (a *App) publish(ctx context.Context, events []entity.DomainEvent, leaseEnd time.Time,
+) (published, held []int64, err error) {
+	failed := make(map[int64]bool)
+
+	for _, event := range events {
+		if failed[event.UserID] || time.Now().After(leaseEnd) {
+			held = append(held, event.ID)
+			continue
+		}
+
+		if pubErr := a.publishEvent(ctx, event); pubErr != nil {
+			if err == nil {
+				err = pubErr
+			}
+			failed[event.UserID] = true
+			held = append(held, event.ID)
+			continue
+		}
+
+		published = append(published, event.ID)
+	}
+
+	return published, held, err
+}
+
+func (a *App) publishEvent(ctx context.Context, event entity.DomainEvent) error {
+	for _, publisher := range a.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanup removes events published more than retention ago.
+func (a *App) cleanup() {
+	const op = "relayapp.cleanup"
+
+	log := a.log.With(slog.String("op", op))
+
+	cutoff := time.Now().Add(-a.retention)
+
+	for {
+		n, err := a.outbox.DeletePublishedOutbox(cutoff, a.batchSize)
+		if err != nil {
+			log.Error("failed to delete published events", sl.Err(err))
+			return
+		}
+
+		if n < int64(a.batchSize) {
+			return
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package relayapp
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// testOutbox keeps events the way the outbox table does. Leases never
+// end by themselves.
+type testOutbox struct {
+	events    []entity.DomainEvent
+	published map[int64]bool
+	leased    map[int64]bool
+	markErr   error
+}
+
+// newTestOutbox makes an event of each user in turn.
+func newTestOutbox(users ...int64) *testOutbox {
+	o := &testOutbox{published: map[int64]bool{}, leased: map[int64]bool{}}
+	for i, uid := range users {
+		o.events = append(o.events, entity.DomainEvent{ID: int64(i + 1), UserID: uid, Type: entity.UserRegistered})
+	}
+
+	return o
+}
+
+func (o *testOutbox) ClaimOutboxEvents(limit int, _ time.Duration) ([]entity.DomainEvent, error) {
+	busy := map[int64]bool{}
+	for _, event := range o.events {
+		if o.leased[event.ID] && !o.published[event.ID] {
+			busy[event.UserID] = true
+		}
+	}
+
+	var events []entity.DomainEvent
+	for _, event := range o.events {
+		if len(events) == limit {
+			break
+		}
+		if !o.published[event.ID] && !busy[event.UserID] {
+			events = append(events, event)
+		}
+	}
+
+	for _, event := range events {
+		o.leased[event.ID] = true
+	}
+
+	return events, nil
+}
+
+func (o *testOutbox) MarkOutboxPublished(ids []int64) error {
+	if o.markErr != nil {
+		return o.markErr
+	}
+
+	for _, id := range ids {
+		o.published[id] = true
+	}
+
+	return nil
+}
+
+func (o *testOutbox) ReleaseOutboxEvents(ids []int64) error {
+	for _, id := range ids {
+		delete(o.leased, id)
+	}
+
+	return nil
+}
+
+func (o *testOutbox) DeletePublishedOutbox(time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+// testPublisher fails the events in fail and records the rest.
+type testPublisher struct {
+	fail map[int64]bool
+	got  []int64
+}
+
+func (p *testPublisher) Publish(_ context.Context, event entity.DomainEvent) error {
+	if p.fail[event.ID] {
+		return errors.New("broker is down")
+	}
+
+	p.got = append(p.got, event.ID)
+
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		users   []int64
+		fail    map[int64]bool
+		markErr error
+		// wantPublished is the number of events marked as published.
+		wantPublished int
+		// wantSent is the number of events the second publisher got.
+		wantSent int
+	}{
+		{name: "empty outbox"},
+		{name: "less than a batch", users: []int64{1, 2, 3}, wantPublished: 3, wantSent: 3},
+		{
+			name:          "several batches",
+			users:         []int64{1, 2, 3, 1, 2, 3, 1, 2, 3, 1, 2, 3},
+			wantPublished: 12, wantSent: 12,
+		},
+		{
+			name:  "failed event stays unpublished, other users' go out",
+			users: []int64{1, 2, 3, 1}, fail: map[int64]bool{2: true},
+			wantPublished: 3, wantSent: 3,
+		},
+		{
+			name:  "failed event holds back later events of its user",
+			users: []int64{1, 2, 1, 3, 1}, fail: map[int64]bool{1: true},
+			wantPublished: 2, wantSent: 2,
+		},
+		{
+			name:  "marking fails",
+			users: []int64{1, 2, 3}, markErr: errors.New("database is down"),
+			wantSent: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newTestOutbox(tt.users...)
+			outbox.markErr = tt.markErr
+
+			first := &testPublisher{}
+			second := &testPublisher{fail: tt.fail}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, outbox, []Publisher{first, second}, time.Second, 5, time.Hour, time.Hour, time.Hour)
+
+			a.relay(context.Background())
+
+			if len(outbox.published) != tt.wantPublished {
+				t.Errorf("published %d events, want %d", len(outbox.published), tt.wantPublished)
+			}
+
+			if len(second.got) != tt.wantSent {
+				t.Errorf("publisher got %d events, want %d", len(second.got), tt.wantSent)
+			}
+
+			for id := range tt.fail {
+				if outbox.published[id] {
+					t.Errorf("failed event %d is marked as published", id)
+				}
+			}
+		})
+	}
+}
+
+func TestRelayRetriesFailedEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		// fails is how many relay runs fail the event.
+		fails int
+	}{
+		{name: "fails once", fails: 1},
+		{name: "fails twice", fails: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newTestOutbox(1, 1)
+			publisher := &testPublisher{fail: map[int64]bool{1: true}}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, outbox, []Publisher{publisher}, time.Second, 5, time.Hour, time.Hour, time.Hour)
+
+			for i := 0; i < tt.fails; i++ {
+				a.relay(context.Background())
+			}
+
+			if len(publisher.got) != 0 {
+				t.Fatalf("published %v while event 1 fails, want nothing", publisher.got)
+			}
+
+			delete(publisher.fail, 1)
+			a.relay(context.Background())
+
+			// Event 2 waited for event 1 of the same user.
+			want := []int64{1, 2}
+			if len(publisher.got) != len(want) || publisher.got[0] != want[0] || publisher.got[1] != want[1] {
+				t.Errorf("published %v, want %v", publisher.got, want)
+			}
+
+			if !outbox.published[1] || !outbox.published[2] {
+				t.Errorf("published = %v, want both events", outbox.published)
+			}
+		})
+	}
+}
+
+func TestRelaySkipsLeasedUsers(t *testing.T) {
+	outbox := newTestOutbox(1, 2, 1)
+	// Another relay claimed the first event of user 1.
+	outbox.leased[1] = true
+
+	publisher := &testPublisher{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := New(log, outbox, []Publisher{publisher}, time.Second, 5, time.Hour, time.Hour, time.Hour)
+
+	a.relay(context.Background())
+
+	if len(publisher.got) != 1 || publisher.got[0] != 2 {
+		t.Errorf("published %v, want only the event of user 2", publisher.got)
+	}
+}
+
+func TestRelayLeaseRunsOut(t *testing.T) {
+	outbox := newTestOutbox(1, 2, 3)
+	publisher := &testPublisher{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// The lease is over before the first event.
+	a := New(log, outbox, []Publisher{publisher}, time.Second, 5, -time.Second, time.Hour, time.Hour)
+
+	a.relay(context.Background())
+
+	if len(publisher.got) != 0 {
+		t.Errorf("published %v after the lease ran out", publisher.got)
+	}
+	if len(outbox.leased) != 0 {
+		t.Errorf("leased = %v, want every event released", outbox.leased)
+	}
+}
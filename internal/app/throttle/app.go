@@ -0,0 +1,91 @@
+package throttleapp
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"time"
+)
+
+type Cleaner interface {
+	DeleteCodeSendsBefore(cutoff time.Time) (int64, error)
+	DeleteLoginFailuresBefore(cutoff time.Time) (int64, error)
+}
+
+// App removes code sends and login attempts that left their windows.
+// Saving one only prunes the rows of its own phone, email or login, so
+// without the job those that are never used again would be kept forever.
+type App struct {
+	log                *slog.Logger
+	cleaner            Cleaner
+	codeSendWindow     time.Duration
+	loginFailureWindow time.Duration
+	interval           time.Duration
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+func New(log *slog.Logger, cleaner Cleaner, codeSendWindow, loginFailureWindow, interval time.Duration) *App {
+	return &App{
+		log:                log,
+		cleaner:            cleaner,
+		codeSendWindow:     codeSendWindow,
+		loginFailureWindow: loginFailureWindow,
+		interval:           interval,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "throttleapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Duration("interval", a.interval))
+
+	log.Info("starting throttle cleanup job")
+
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.cleanup()
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "throttleapp.Stop"
+
+	close(a.stop)
+	<-a.done
+
+	a.log.Info("throttle cleanup job stopped", slog.String("op", op))
+}
+
+func (a *App) cleanup() {
+	const op = "throttleapp.cleanup"
+
+	log := a.log.With(slog.String("op", op))
+
+	now := time.Now()
+
+	n, err := a.cleaner.DeleteCodeSendsBefore(now.Add(-a.codeSendWindow))
+	if err != nil {
+		log.Error("failed to delete code sends", sl.Err(err))
+	} else if n > 0 {
+		log.Info("deleted expired code sends", slog.Int64("count", n))
+	}
+
+	n, err = a.cleaner.DeleteLoginFailuresBefore(now.Add(-a.loginFailureWindow))
+	if err != nil {
+		log.Error("failed to delete login failures", sl.Err(err))
+	} else if n > 0 {
+		log.Info("deleted expired login failures", slog.Int64("count", n))
+	}
+}
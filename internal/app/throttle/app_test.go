@@ -0,0 +1,63 @@
+package throttleapp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type testCleaner struct {
+	deleted int64
+	failure error
+	// cutoffs are the code sends cutoff and the login failures one.
+	cutoffs []time.Time
+}
+
+func (c *testCleaner) DeleteCodeSendsBefore(cutoff time.Time) (int64, error) {
+	c.cutoffs = append(c.cutoffs, cutoff)
+
+	return c.deleted, c.failure
+}
+
+func (c *testCleaner) DeleteLoginFailuresBefore(cutoff time.Time) (int64, error) {
+	c.cutoffs = append(c.cutoffs, cutoff)
+
+	return c.deleted, c.failure
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted int64
+		failure error
+	}{
+		{name: "nothing to delete"},
+		{name: "expired rows", deleted: 7},
+		{name: "storage fails", failure: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaner := &testCleaner{deleted: tt.deleted, failure: tt.failure}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, cleaner, time.Hour, 15*time.Minute, time.Minute)
+
+			before := time.Now()
+			a.cleanup()
+
+			// A failed table doesn't stop the other one.
+			if len(cleaner.cutoffs) != 2 {
+				t.Fatalf("calls = %d, want 2", len(cleaner.cutoffs))
+			}
+
+			for i, window := range []time.Duration{time.Hour, 15 * time.Minute} {
+				wantCutoff := before.Add(-window)
+				if d := cleaner.cutoffs[i].Sub(wantCutoff); d < 0 || d > time.Second {
+					t.Errorf("cutoff %d = %v, want about %v", i, cleaner.cutoffs[i], wantCutoff)
+				}
+			}
+		})
+	}
+}
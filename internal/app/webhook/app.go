@@ -0,0 +1,269 @@
+package webhookapp
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const maxErrorLength = 512
+
+var deliveryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sso",
+	Subsystem: "webhook",
+	Name:      "delivery_attempts_total",
+	Help:      "Webhook delivery attempts by result: delivered, failed or dead.",
+}, []string{"result"})
+
+type Deliveries interface {
+	Webhook(webhookID int64) (entity.Webhook, error)
+	ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]entity.WebhookDelivery, error)
+	WebhookDelivered(deliveryID int64, statusCode int) error
+	WebhookDeliveryFailed(deliveryID int64, statusCode int, lastError string, nextAttemptAt time.Time, dead bool) error
+	DeleteDeliveredWebhookDeliveries(cutoff time.Time, limit int) (int64, error)
+}
+
+type Sender interface {
+	Send(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) (statusCode int, err error)
+}
+
+// App sends queued webhook deliveries, retries failed ones with
+// exponential backoff and removes old delivered ones.
+type App struct {
+	log        *slog.Logger
+	deliveries Deliveries
+	sender     Sender
+	cfg        config.WebhooksConfig
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func New(log *slog.Logger, deliveries Deliveries, sender Sender, cfg config.WebhooksConfig) *App {
+	return &App{
+		log:        log,
+		deliveries: deliveries,
+		sender:     sender,
+		cfg:        cfg,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "webhookapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Duration("interval", a.cfg.Interval))
+
+	log.Info("starting webhook dispatcher")
+
+	defer close(a.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-a.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+
+	for {
+		a.dispatch(ctx)
+
+		if time.Since(lastCleanup) >= a.cfg.CleanupInterval {
+			a.cleanup()
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "webhookapp.Stop"
+
+	close(a.stop)
+	<-a.done
+
+	a.log.Info("webhook dispatcher stopped", slog.String("op", op))
+}
+
+// dispatch sends due deliveries batch by batch until none are left.
+func (a *App) dispatch(ctx context.Context) {
+	const op = "webhookapp.dispatch"
+
+	log := a.log.With(slog.String("op", op))
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		// The lease must outlast the whole batch, not just one request.
+		lease := a.cfg.Timeout * time.Duration(a.cfg.BatchSize/a.cfg.Workers+2)
+
+		batch, err := a.deliveries.ClaimWebhookDeliveries(now, now.Add(lease), a.cfg.BatchSize)
+		if err != nil {
+			log.Error("failed to claim webhook deliveries", sl.Err(err))
+			return
+		}
+
+		a.sendBatch(ctx, batch)
+
+		if len(batch) < a.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (a *App) sendBatch(ctx context.Context, batch []entity.WebhookDelivery) {
+	webhooks := make(map[int64]entity.Webhook)
+	sem := make(chan struct{}, a.cfg.Workers)
+
+	var wg sync.WaitGroup
+
+	for _, delivery := range batch {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			var err error
+			webhook, err = a.deliveries.Webhook(delivery.WebhookID)
+			if err != nil {
+				if !errors.Is(err, storage.ErrWebhookNotFound) {
+					a.log.Error("failed to get webhook", slog.Int64("webhook_id", delivery.WebhookID), sl.Err(err))
+				}
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			a.send(ctx, webhook, delivery)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (a *App) send(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) {
+	const op = "webhookapp.send"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("webhook_id", webhook.ID),
+		slog.Int64("delivery_id", delivery.ID),
+	)
+
+	statusCode, sendErr := a.sender.Send(ctx, webhook, delivery)
+	if sendErr == nil {
+		deliveryAttempts.WithLabelValues("delivered").Inc()
+
+		if err := a.deliveries.WebhookDelivered(delivery.ID, statusCode); err != nil {
+			log.Error("failed to mark webhook delivery as delivered", sl.Err(err))
+		}
+		return
+	}
+
+	// A stop in the middle of a request is not the endpoint's fault; the
+	// lease runs out and the delivery is sent again.
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= a.cfg.MaxAttempts
+
+	result := "failed"
+	if dead {
+		result = "dead"
+	}
+	deliveryAttempts.WithLabelValues(result).Inc()
+
+	lastError := truncate(sendErr.Error(), maxErrorLength)
+
+	nextAttemptAt := time.Now().Add(a.backoff(attempts))
+
+	err := a.deliveries.WebhookDeliveryFailed(delivery.ID, statusCode, lastError, nextAttemptAt, dead)
+	if err != nil {
+		log.Error("failed to save webhook delivery attempt", sl.Err(err))
+		return
+	}
+
+	if dead {
+		log.Warn("webhook delivery moved to dead letters", slog.Int("attempts", attempts), sl.Err(sendErr))
+	}
+}
+
+// backoff is the pause after the given number of failed attempts: it
+// doubles from BackoffBase up to BackoffMax, with up to a fifth added at
+// random so deliveries that failed together don't retry together.
+func (a *App) backoff(attempts int) time.Duration {
+	d := a.cfg.BackoffBase
+	for i := 1; i < attempts && d < a.cfg.BackoffMax; i++ {
+		d *= 2
+	}
+	d = min(d, a.cfg.BackoffMax)
+
+	return d + rand.N(d/5+1)
+}
+
+// cleanup removes deliveries delivered more than the retention ago.
+func (a *App) cleanup() {
+	const op = "webhookapp.cleanup"
+
+	log := a.log.With(slog.String("op", op))
+
+	cutoff := time.Now().Add(-a.cfg.Retention)
+
+	for {
+		n, err := a.deliveries.DeleteDeliveredWebhookDeliveries(cutoff, a.cfg.BatchSize)
+		if err != nil {
+			log.Error("failed to delete delivered webhook deliveries", sl.Err(err))
+			return
+		}
+
+		if n < int64(a.cfg.BatchSize) {
+			return
+		}
+	}
+}
+
+// truncate cuts s to at most n bytes on a rune boundary, so the stored
+// error stays valid UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
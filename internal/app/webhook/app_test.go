@@ -0,0 +1,30 @@
+package webhookapp
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{name: "short", s: "timeout", n: 10, want: "timeout"},
+		{name: "ascii", s: "endpoint answered 500", n: 8, want: "endpoint"},
+		{name: "on a rune boundary", s: "ошибка", n: 4, want: "ош"},
+		{name: "inside a rune", s: "ошибка", n: 5, want: "ош"},
+		{name: "inside the first rune", s: "ошибка", n: 1, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.s, tt.n)
+			if got != tt.want || !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,11 +8,33 @@ import (
 )
 
 type Config struct {
-	Env             string         `yaml:"env" env-default:"local"`
-	Postgres        PostgresConfig `yaml:"postgres"`
-	AccessTokenTTL  time.Duration  `yaml:"access_token_ttl" env-required:"true"`
-	RefreshTokenTTL time.Duration  `yaml:"refresh_token_ttl" env-required:"true"`
-	GRPC            GRPCConfig     `yaml:"grpc"`
+	Env             string               `yaml:"env" env-default:"local"`
+	Postgres        PostgresConfig       `yaml:"postgres"`
+	AccessTokenTTL  time.Duration        `yaml:"access_token_ttl" env-required:"true"`
+	RefreshTokenTTL time.Duration        `yaml:"refresh_token_ttl" env-required:"true"`
+	Tokens          TokensConfig         `yaml:"tokens"`
+	JWKS            JWKSConfig           `yaml:"jwks"`
+	GRPC            GRPCConfig           `yaml:"grpc"`
+	Hasher          HasherConfig         `yaml:"hasher"`
+	Metrics         MetricsConfig        `yaml:"metrics"`
+	Registration    RegistrationConfig   `yaml:"registration"`
+	OTP             OTPConfig            `yaml:"otp"`
+	Phone           PhoneConfig          `yaml:"phone"`
+	Password        PasswordConfig       `yaml:"password"`
+	Lockout         LockoutConfig        `yaml:"lockout"`
+	Email           EmailConfig          `yaml:"email"`
+	PasswordReset   PasswordResetConfig  `yaml:"password_reset"`
+	Address         AddressConfig        `yaml:"address"`
+	Account         AccountConfig        `yaml:"account"`
+	Session         SessionConfig        `yaml:"session"`
+	GeoIP           GeoIPConfig          `yaml:"geoip"`
+	SecurityEvents  SecurityEventsConfig `yaml:"security_events"`
+	Audit           AuditConfig          `yaml:"audit"`
+	SIEM            SIEMConfig           `yaml:"siem"`
+	Outbox          OutboxConfig         `yaml:"outbox"`
+	Webhooks        WebhooksConfig       `yaml:"webhooks"`
+	Revocations     RevocationsConfig    `yaml:"revocations"`
+	Admin           AdminConfig          `yaml:"admin"`
 }
 
 type PostgresConfig struct {
@@ -26,6 +48,222 @@ type PostgresConfig struct {
 type GRPCConfig struct {
 	Port    int           `yaml:"port"`
 	Timeout time.Duration `yaml:"timeout"`
+	// TrustedProxies are the load balancers in front of the SSO, in CIDR
+	// notation. X-Forwarded-For is read only from them; without any the
+	// client IP is the peer address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type HasherConfig struct {
+	Workers      int           `yaml:"workers"`
+	QueueTimeout time.Duration `yaml:"queue_timeout" env-default:"2s"`
+	Cost         int           `yaml:"cost"`
+}
+
+type MetricsConfig struct {
+	Port int `yaml:"port" env-default:"9090"`
+}
+
+// TokensConfig holds the Ed25519 keys of access tokens, private keys in
+// PKCS #8 PEM form. SigningKeyFile signs new tokens. VerifyKeyFiles are
+// earlier signing keys, private or public, whose tokens are still
+// accepted and published until they expire; keep them for one access
+// token TTL after a rotation.
+type TokensConfig struct {
+	SigningKeyFile string   `yaml:"signing_key_file" env-required:"true"`
+	VerifyKeyFiles []string `yaml:"verify_key_files"`
+}
+
+// JWKSConfig is the HTTP server of the public keys of access tokens, at
+// /.well-known/jwks.json. Clients may cache the set for MaxAge.
+type JWKSConfig struct {
+	Port   int           `yaml:"port" env-default:"8080"`
+	MaxAge time.Duration `yaml:"max_age" env-default:"5m"`
+}
+
+type RegistrationConfig struct {
+	EnumerationSafe bool          `yaml:"enumeration_safe"`
+	CodeTTL         time.Duration `yaml:"code_ttl" env-default:"10m"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"5"`
+}
+
+// OTPConfig limits the SMS and emails with codes a phone or email gets,
+// whatever flow they come from. Sends that left the window are deleted
+// every CleanupInterval.
+type OTPConfig struct {
+	ResendLimit     int           `yaml:"resend_limit" env-default:"5"`
+	ResendWindow    time.Duration `yaml:"resend_window" env-default:"1h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"10m"`
+}
+
+type PhoneConfig struct {
+	DefaultRegion     string        `yaml:"default_region" env-default:"RU"`
+	MobileOnly        bool          `yaml:"mobile_only"`
+	ChangeCodeTTL     time.Duration `yaml:"change_code_ttl" env-default:"10m"`
+	ChangeMaxAttempts int           `yaml:"change_max_attempts" env-default:"5"`
+	// ReuseCooldown is how long a released phone can't be taken by another account.
+	ReuseCooldown time.Duration `yaml:"reuse_cooldown" env-default:"720h"`
+	// LegacyLookup also finds users by the raw forms phones were stored in
+	// before normalization. Turn it off once cmd/phones -apply has run.
+	LegacyLookup bool `yaml:"legacy_lookup" env-default:"true"`
+}
+
+type PasswordConfig struct {
+	MinLength int `yaml:"min_length" env-default:"8"`
+	MaxLength int `yaml:"max_length" env-default:"72"`
+	// History is how many last passwords can't be reused, including the current one.
+	History int `yaml:"history" env-default:"5"`
+}
+
+// LockoutConfig throttles password guessing. After MaxFailures wrong
+// passwords for a login from one IP in Window, its password checks from
+// that IP fail until the oldest failures leave the window. Zero
+// MaxFailures turns it off.
+type LockoutConfig struct {
+	MaxFailures int           `yaml:"max_failures" env-default:"10"`
+	Window      time.Duration `yaml:"window" env-default:"15m"`
+}
+
+type EmailConfig struct {
+	CodeTTL     time.Duration `yaml:"code_ttl" env-default:"30m"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// LinkFormat is the reset page URL with %s in place of the token.
+	LinkFormat string `yaml:"link_format" env-default:"https://vizap.ru/reset-password?token=%s"`
+}
+
+type AddressConfig struct {
+	MaxPerUser      int `yaml:"max_per_user" env-default:"20"`
+	DefaultPageSize int `yaml:"default_page_size" env-default:"20"`
+	MaxPageSize     int `yaml:"max_page_size" env-default:"100"`
+}
+
+// SessionConfig is the session policy of apps that don't set their own.
+// Zero AbsoluteLifetime and IdleTimeout mean no limit.
+type SessionConfig struct {
+	AbsoluteLifetime time.Duration `yaml:"absolute_lifetime" env-default:"0"`
+	IdleTimeout      time.Duration `yaml:"idle_timeout" env-default:"0"`
+	SlidingRefresh   bool          `yaml:"sliding_refresh" env-default:"true"`
+}
+
+// GeoIPConfig is the network list used to show approximate locations of
+// sessions. Without a file locations are left empty.
+type GeoIPConfig struct {
+	File string `yaml:"file"`
+}
+
+// SecurityEventsConfig is the feed of logins and credential changes.
+// Events older than Retention are deleted every CleanupInterval.
+type SecurityEventsConfig struct {
+	Retention        time.Duration `yaml:"retention" env-default:"2160h"`
+	CleanupInterval  time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+	CleanupBatchSize int           `yaml:"cleanup_batch_size" env-default:"1000"`
+	DefaultPageSize  int           `yaml:"default_page_size" env-default:"20"`
+	MaxPageSize      int           `yaml:"max_page_size" env-default:"100"`
+}
+
+// AuditConfig is used by the audit command. SigningKeyFile is an Ed25519
+// private key in PKCS #8 PEM form that signs audit log exports.
+type AuditConfig struct {
+	SigningKeyFile string `yaml:"signing_key_file"`
+}
+
+// SIEMConfig streams security events and admin actions of the server to a
+// SIEM. Format is "syslog" (RFC 5424) or "cef"; Output is "udp", "tcp" or
+// "tls" to Address, or "file" to append lines to File. Events are name
+// patterns such as "login.failure", "*.failure" or "app.*"; empty means
+// every event. When the collector is down, up to BufferSize events wait
+// and the rest are dropped.
+type SIEMConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Format     string   `yaml:"format" env-default:"syslog"`
+	Output     string   `yaml:"output" env-default:"udp"`
+	Address    string   `yaml:"address"`
+	CAFile     string   `yaml:"ca_file"`
+	File       string   `yaml:"file"`
+	Events     []string `yaml:"events"`
+	BufferSize int      `yaml:"buffer_size" env-default:"1024"`
+}
+
+// OutboxConfig is the relay of domain events to other services. Publisher
+// is "log" or "memory" for local runs, or "kafka_rest" to send events
+// through a Kafka REST proxy. A relay owns the events it claims for Lease,
+// which must outlast publishing a batch. Published events are kept for
+// Retention.
+type OutboxConfig struct {
+	Publisher       string          `yaml:"publisher" env-default:"log"`
+	RelayInterval   time.Duration   `yaml:"relay_interval" env-default:"1s"`
+	BatchSize       int             `yaml:"batch_size" env-default:"100"`
+	Lease           time.Duration   `yaml:"lease" env-default:"1m"`
+	Retention       time.Duration   `yaml:"retention" env-default:"168h"`
+	CleanupInterval time.Duration   `yaml:"cleanup_interval" env-default:"1h"`
+	KafkaREST       KafkaRESTConfig `yaml:"kafka_rest"`
+}
+
+type KafkaRESTConfig struct {
+	URL     string        `yaml:"url"`
+	Topic   string        `yaml:"topic" env-default:"sso.events"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// WebhooksConfig is the delivery of domain events to the webhooks of apps.
+// A failed delivery is retried after BackoffBase, doubling up to
+// BackoffMax, and goes to the dead-letter list after MaxAttempts.
+// Delivered ones are kept for Retention. AllowHTTP permits plain http
+// endpoints, for local runs.
+type WebhooksConfig struct {
+	AllowHTTP       bool          `yaml:"allow_http"`
+	MaxPerApp       int           `yaml:"max_per_app" env-default:"10"`
+	Interval        time.Duration `yaml:"interval" env-default:"5s"`
+	BatchSize       int           `yaml:"batch_size" env-default:"50"`
+	Workers         int           `yaml:"workers" env-default:"4"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"10"`
+	BackoffBase     time.Duration `yaml:"backoff_base" env-default:"30s"`
+	BackoffMax      time.Duration `yaml:"backoff_max" env-default:"6h"`
+	Retention       time.Duration `yaml:"retention" env-default:"720h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+	DefaultPageSize int           `yaml:"default_page_size" env-default:"50"`
+	MaxPageSize     int           `yaml:"max_page_size" env-default:"500"`
+}
+
+// RevocationsConfig is the feed of revoked sessions and tokens for
+// services that check access tokens offline. Retention must be longer than
+// the longest access token TTL of any app. A watcher that resumes from a
+// cursor older than the retained entries gets an error and has to start
+// over from zero. PollInterval is a fallback for lost notifications.
+type RevocationsConfig struct {
+	Retention       time.Duration `yaml:"retention" env-default:"24h"`
+	PollInterval    time.Duration `yaml:"poll_interval" env-default:"30s"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+	BatchSize       int           `yaml:"batch_size" env-default:"500"`
+}
+
+type AccountConfig struct {
+	// DeletionGracePeriod is how long a deleted account can still be restored.
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" env-default:"720h"`
+	CodeTTL             time.Duration `yaml:"code_ttl" env-default:"10m"`
+	CodeMaxAttempts     int           `yaml:"code_max_attempts" env-default:"5"`
+	PurgeInterval       time.Duration `yaml:"purge_interval" env-default:"1h"`
+	PurgeBatchSize      int           `yaml:"purge_batch_size" env-default:"100"`
+}
+
+// AdminConfig is the admin gRPC server. It only accepts clients with a
+// certificate signed by ClientCAFile; the certificate common name is the
+// staff member's name in roles and the audit log.
+type AdminConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Port         int    `yaml:"port" env-default:"5002"`
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	// Superusers have every role without a grant, to hand out the first roles.
+	Superusers      []string `yaml:"superusers"`
+	DefaultPageSize int      `yaml:"default_page_size" env-default:"50"`
+	MaxPageSize     int      `yaml:"max_page_size" env-default:"500"`
 }
 
 func MustLoad() *Config {
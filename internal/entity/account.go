@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+type AccountStatus string
+
+const (
+	AccountPending   AccountStatus = "pending"
+	AccountActive    AccountStatus = "active"
+	AccountLocked    AccountStatus = "locked"
+	AccountSuspended AccountStatus = "suspended"
+	AccountDeleted   AccountStatus = "deleted"
+)
+
+// AccountState is the account status together with who set it and why.
+type AccountState struct {
+	Status    AccountStatus
+	Reason    string
+	ChangedBy string
+	ChangedAt time.Time
+	// Until is when a locked or suspended account becomes active again.
+	// Zero means until someone changes the status.
+	Until time.Time
+}
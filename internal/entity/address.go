@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+type Address struct {
+	ID          int64
+	UserID      int64
+	FullAddress string
+	// Structured form, optional. Empty when the app only sends FullAddress.
+	City      string
+	Street    string
+	Building  string
+	Apartment string
+	Location  *GeoPoint
+	IsDefault bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
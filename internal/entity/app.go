@@ -1,7 +1,63 @@
 package entity
 
+import "time"
+
+const (
+	GrantPassword     = "password"
+	GrantRefreshToken = "refresh_token"
+)
+
 type App struct {
-	ID     int32
-	Name   string
-	Secret string
+	ID   int32
+	Name string
+	// SigningKey signs the app's refresh tokens; access tokens are signed
+	// with the SSO's Ed25519 key. It never leaves the SSO, unlike client
+	// secrets, which are given to the app and stored only as hashes.
+	SigningKey string
+	// RetiredSigningKey is the key the app had before SigningKeyRotatedAt.
+	// It only verifies refresh tokens, until those it signed expire.
+	RetiredSigningKey   string
+	SigningKeyRotatedAt time.Time
+	Disabled            bool
+	GrantTypes          []string
+	RedirectURIs        []string
+	// Scopes are issued in the scope claim of every access token of the
+	// app, for resource servers to check.
+	Scopes []string
+	// AccessTokenTTL, RefreshTokenTTL, SessionLifetime and IdleTimeout
+	// override the global session settings when set.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	SessionLifetime time.Duration
+	IdleTimeout     time.Duration
+	// SlidingRefresh is nil when the app uses the global setting.
+	SlidingRefresh *bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// SessionPolicy is the session settings of an app after the global
+// defaults are applied. Zero SessionLifetime and IdleTimeout mean no limit.
+type SessionPolicy struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	SessionLifetime time.Duration
+	IdleTimeout     time.Duration
+	// SlidingRefresh extends the refresh token on every refresh. Without it
+	// the refresh token expires RefreshTokenTTL after login.
+	SlidingRefresh bool
+}
+
+// AppSecret is a client secret of an app. An app can have several, so a
+// new one can be rolled out before the old one is revoked.
+type AppSecret struct {
+	ID         int64
+	AppID      int32
+	SecretHash string
+	// Hint is the last characters of the secret, to tell secrets apart.
+	Hint      string
+	CreatedAt time.Time
+	// ExpiresAt is zero for secrets that don't expire.
+	ExpiresAt time.Time
+	RevokedAt time.Time
 }
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+type AuditRecord struct {
+	ID int64
+	// Actor is who did it: "user:<id>" for the account owner, or the
+	// name of the staff member or tool for admin actions.
+	Actor        string
+	Action       string
+	TargetUserID int64
+	// Details is a JSON object with action-specific fields.
+	Details   []byte
+	CreatedAt time.Time
+	// PrevHash is the Hash of the record written before this one, see
+	// auditchain.Hash. Both are empty for records written before the log
+	// was chained.
+	PrevHash string
+	Hash     string
+}
@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+type EmailVerification struct {
+	UserID    int64
+	Email     string
+	CodeHash  string
+	ExpiresAt time.Time
+	Attempts  int
+}
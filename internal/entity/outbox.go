@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Types of domain events other services subscribe to.
+const (
+	UserRegistered  = "user.registered"
+	UserDeleted     = "user.deleted"
+	SessionRevoked  = "session.revoked"
+	PasswordChanged = "password.changed"
+)
+
+// DomainEvent is a change of a user's account, written to the outbox in
+// the same transaction as the change and published afterwards, at least
+// once and in no guaranteed order.
+type DomainEvent struct {
+	ID     int64
+	UserID int64
+	Type   string
+	// Payload is a JSON object with event-specific fields.
+	Payload   []byte
+	CreatedAt time.Time
+}
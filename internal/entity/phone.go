@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+type PhoneChange struct {
+	UserID      int64
+	NewPhone    string
+	NewCodeHash string
+	// OldCodeHash is empty when the user can't receive SMS on the old number.
+	OldCodeHash string
+	ExpiresAt   time.Time
+	Attempts    int
+}
+
+// PhoneRelease is a number the user had before changing it.
+type PhoneRelease struct {
+	Phone      string
+	ReleasedAt time.Time
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+type Profile struct {
+	UserID   int64
+	FullName string
+	Email    string
+	// EmailVerified is true once the user confirmed the email. Only then
+	// can it be used to sign in or recover the account.
+	EmailVerified bool
+	// Version grows on every change, so clients holding cached profile
+	// claims can tell they are stale.
+	Version   int
+	UpdatedAt time.Time
+}
+
+// ProfileUpdate lists the profile fields to change. A nil field keeps its
+// stored value.
+type ProfileUpdate struct {
+	UserID   int64
+	FullName *string
+	Email    *string
+}
@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+type PendingRegistration struct {
+	Phone     string
+	PassHash  []byte
+	CodeHash  string
+	ExpiresAt time.Time
+	Attempts  int
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	// RevokedSession means access tokens with the session's sid are no
+	// longer valid.
+	RevokedSession = "session"
+	// RevokedToken means the access token with the jti is no longer valid.
+	RevokedToken = "token"
+	// RevokedUser means access tokens of the user issued before NotBefore
+	// are no longer valid.
+	RevokedUser = "user"
+)
+
+// Revocation is an entry of the feed that lets services which check access
+// tokens offline learn about logouts. The ID is the cursor of the feed.
+type Revocation struct {
+	ID        int64
+	Kind      string
+	UserID    int64
+	SessionID int64
+	TokenID   string
+	NotBefore time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// Security event types.
+const (
+	EventLogin          = "login"
+	EventRefresh        = "refresh"
+	EventPasswordChange = "password_change"
+	EventPasswordReset  = "password_reset"
+	EventPhoneChange    = "phone_change"
+	EventSessionRevoke  = "session_revoke"
+	EventAccountDelete  = "account_delete"
+	EventAccountRestore = "account_restore"
+	// EventLockout is the wrong password that used up the attempts of a
+	// user or login from an IP.
+	EventLockout = "lockout"
+)
+
+// Authentication methods of security events.
+const (
+	MethodPassword     = "password"
+	MethodRefreshToken = "refresh_token"
+	MethodAccessToken  = "access_token"
+	MethodResetLink    = "reset_link"
+	MethodSMSCode      = "sms_code"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// SecurityEvent is an authentication attempt or a change of credentials,
+// shown to the user in their activity feed.
+type SecurityEvent struct {
+	ID      int64
+	UserID  int64
+	Type    string
+	Outcome string
+	Method  string
+	// AppID is zero when the event is not tied to an app.
+	AppID     int32
+	IP        string
+	UserAgent string
+	// Reason says why a failed attempt failed, e.g. "invalid_credentials".
+	Reason    string
+	CreatedAt time.Time
+}
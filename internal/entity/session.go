@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Session is a signed in device. It lives from Login until it is revoked
+// or its refresh token expires.
+type Session struct {
+	ID     int64
+	UserID int64
+	// AppID is zero for sessions started before sessions were recorded.
+	AppID      int32
+	Device     Device
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  time.Time
+	IsActive   bool
+}
+
+// Device describes where a session was started. Name and Platform are
+// reported by the app, the rest is taken from the request.
+type Device struct {
+	Name      string
+	Platform  string
+	UserAgent string
+	IP        string
+	// Location is an approximate location by IP, empty if unknown.
+	Location string
+}
+
+// RefreshToken is a stored refresh token. StartedAt is the login the token
+// descends from, LastUsedAt the last time the session was used.
+type RefreshToken struct {
+	UserID    int64
+	SessionID int64
+	IsActive  bool
+	// SessionRevoked tells an inactive token of a signed-out session from
+	// one that was already exchanged for a new pair.
+	SessionRevoked bool
+	StartedAt      time.Time
+	LastUsedAt     time.Time
+}
@@ -1,7 +1,44 @@
 package entity
 
+import "time"
+
 type User struct {
-	ID       int64
-	Phone    string
-	PassHash []byte
+	ID        int64
+	Phone     string
+	PassHash  []byte
+	CreatedAt time.Time
+	State     AccountState
+	// PurgeAfter is when a deleted account loses its personal data. Until
+	// then the user can restore it.
+	PurgeAfter time.Time
+	// PasswordResetRequired is set by support. The user can't sign in
+	// until they reset the password.
+	PasswordResetRequired bool
+}
+
+// UserFilter selects users in admin search. Empty fields match everything;
+// Phone and Email match by prefix.
+type UserFilter struct {
+	ID    int64
+	Phone string
+	Email string
+}
+
+// UserDetails is a user with profile fields, as shown to support staff.
+type UserDetails struct {
+	User    User
+	Profile Profile
+}
+
+type PasswordReset struct {
+	UserID    int64
+	ExpiresAt time.Time
+	Used      bool
+}
+
+type DeletionCode struct {
+	UserID    int64
+	CodeHash  string
+	ExpiresAt time.Time
+	Attempts  int
 }
@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that failed every attempt. It stays in the
+	// dead-letter list until it is replayed.
+	DeliveryDead = "dead"
+)
+
+// Webhook is an endpoint of an app that receives domain events. Empty
+// Events means every event.
+type Webhook struct {
+	ID    int64
+	AppID int32
+	URL   string
+	// Secret signs the deliveries. Like the signing key of the app it is
+	// stored as is, because the SSO needs it to sign.
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// WebhookDelivery is one domain event to be sent to one webhook.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	EventID        int64
+	EventType      string
+	Body           []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+}
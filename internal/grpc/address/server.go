@@ -0,0 +1,177 @@
+package address
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/services/address"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Address interface {
+	ListAddresses(ctx context.Context, accessToken string, pageSize int, pageToken string,
+	) (addresses []entity.Address, nextPageToken string, err error)
+	AddAddress(ctx context.Context, accessToken string, addr entity.Address) (entity.Address, error)
+	UpdateAddress(ctx context.Context, accessToken string, addr entity.Address) (entity.Address, error)
+	DeleteAddress(ctx context.Context, accessToken string, addressID int64) error
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedAddressBookServer
+	address Address
+}
+
+func Register(gRPC *grpc.Server, address Address) {
+	ssov1.RegisterAddressBookServer(gRPC, &serverAPI{address: address})
+}
+
+func (s *serverAPI) ListAddresses(ctx context.Context, req *ssov1.ListAddressesRequest,
+) (*ssov1.ListAddressesResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	addresses, nextPageToken, err := s.address.ListAddresses(ctx, req.GetAccessToken(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ssov1.ListAddressesResponse{
+		NextPageToken: nextPageToken,
+	}
+	for _, addr := range addresses {
+		resp.Addresses = append(resp.Addresses, toProto(addr))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) AddAddress(ctx context.Context, req *ssov1.AddAddressRequest,
+) (*ssov1.AddAddressResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetAddress() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Укажите адрес")
+	}
+
+	addr, err := s.address.AddAddress(ctx, req.GetAccessToken(), fromProto(req.GetAddress()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.AddAddressResponse{
+		Address: toProto(addr),
+	}, nil
+}
+
+func (s *serverAPI) UpdateAddress(ctx context.Context, req *ssov1.UpdateAddressRequest,
+) (*ssov1.UpdateAddressResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetAddress().GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "address id is required")
+	}
+
+	addr, err := s.address.UpdateAddress(ctx, req.GetAccessToken(), fromProto(req.GetAddress()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.UpdateAddressResponse{
+		Address: toProto(addr),
+	}, nil
+}
+
+func (s *serverAPI) DeleteAddress(ctx context.Context, req *ssov1.DeleteAddressRequest,
+) (*ssov1.DeleteAddressResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "address id is required")
+	}
+
+	if err := s.address.DeleteAddress(ctx, req.GetAccessToken(), req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.DeleteAddressResponse{
+		Success: true,
+	}, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+	case errors.As(err, new(*accountstate.Error)):
+		return status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+	case errors.Is(err, storage.ErrAddressNotFound):
+		return status.Error(codes.NotFound, "Адрес не найден")
+	case errors.Is(err, address.ErrInvalidAddress):
+		return status.Error(codes.InvalidArgument, "Некорректный адрес")
+	case errors.Is(err, address.ErrInvalidLocation):
+		return status.Error(codes.InvalidArgument, "Некорректные координаты")
+	case errors.Is(err, address.ErrInvalidPageToken):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	case errors.Is(err, address.ErrLimitReached):
+		return status.Error(codes.ResourceExhausted, "Достигнуто максимальное количество адресов")
+	}
+
+	return status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+}
+
+func toProto(addr entity.Address) *ssov1.Address {
+	a := &ssov1.Address{
+		Id:          addr.ID,
+		FullAddress: addr.FullAddress,
+		City:        addr.City,
+		Street:      addr.Street,
+		Building:    addr.Building,
+		Apartment:   addr.Apartment,
+		IsDefault:   addr.IsDefault,
+		CreatedAt:   addr.CreatedAt.Unix(),
+		UpdatedAt:   addr.UpdatedAt.Unix(),
+	}
+
+	if addr.Location != nil {
+		a.Location = &ssov1.GeoPoint{
+			Latitude:  addr.Location.Latitude,
+			Longitude: addr.Location.Longitude,
+		}
+	}
+
+	return a
+}
+
+func fromProto(a *ssov1.Address) entity.Address {
+	addr := entity.Address{
+		ID:          a.GetId(),
+		FullAddress: a.GetFullAddress(),
+		City:        a.GetCity(),
+		Street:      a.GetStreet(),
+		Building:    a.GetBuilding(),
+		Apartment:   a.GetApartment(),
+		IsDefault:   a.GetIsDefault(),
+	}
+
+	if loc := a.GetLocation(); loc != nil {
+		addr.Location = &entity.GeoPoint{
+			Latitude:  loc.GetLatitude(),
+			Longitude: loc.GetLongitude(),
+		}
+	}
+
+	return addr
+}
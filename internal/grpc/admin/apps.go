@@ -0,0 +1,246 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"github.com/KVSH-user/vizapSSO/internal/services/apps"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+type Apps interface {
+	Apps(ctx context.Context) ([]entity.App, error)
+	App(ctx context.Context, appID int32) (entity.App, []entity.AppSecret, error)
+	CreateApp(ctx context.Context, actor string, app entity.App) (entity.App, string, error)
+	UpdateApp(ctx context.Context, actor string, app entity.App) (entity.App, error)
+	SetAppDisabled(ctx context.Context, actor string, appID int32, disabled bool) error
+	DeleteApp(ctx context.Context, actor string, appID int32) error
+	CreateSecret(ctx context.Context, actor string, appID int32, expiresAt time.Time,
+	) (string, entity.AppSecret, error)
+	RevokeSecret(ctx context.Context, actor string, appID int32, secretID int64) error
+}
+
+func (s *serverAPI) CreateApp(ctx context.Context, req *ssov1.CreateAppRequest,
+) (*ssov1.CreateAppResponse, error) {
+	app := entity.App{
+		Name:            req.GetName(),
+		GrantTypes:      req.GetGrantTypes(),
+		RedirectURIs:    req.GetRedirectUris(),
+		AccessTokenTTL:  time.Duration(req.GetAccessTokenTtlSeconds()) * time.Second,
+		RefreshTokenTTL: time.Duration(req.GetRefreshTokenTtlSeconds()) * time.Second,
+		SessionLifetime: time.Duration(req.GetSessionLifetimeSeconds()) * time.Second,
+		IdleTimeout:     time.Duration(req.GetIdleTimeoutSeconds()) * time.Second,
+		SlidingRefresh:  req.SlidingRefresh,
+	}
+
+	created, clientSecret, err := s.apps.CreateApp(ctx, interceptor.AdminSubject(ctx), app)
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.CreateAppResponse{
+		App:          appToProto(created),
+		ClientSecret: clientSecret,
+	}, nil
+}
+
+func (s *serverAPI) GetApp(ctx context.Context, req *ssov1.GetAppRequest,
+) (*ssov1.GetAppResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	app, secrets, err := s.apps.App(ctx, req.GetAppId())
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	resp := &ssov1.GetAppResponse{
+		App: appToProto(app),
+	}
+	for _, secret := range secrets {
+		resp.Secrets = append(resp.Secrets, secretToProto(secret))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) ListApps(ctx context.Context, req *ssov1.ListAppsRequest,
+) (*ssov1.ListAppsResponse, error) {
+	list, err := s.apps.Apps(ctx)
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	resp := &ssov1.ListAppsResponse{}
+	for _, app := range list {
+		resp.Apps = append(resp.Apps, appToProto(app))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) UpdateApp(ctx context.Context, req *ssov1.UpdateAppRequest,
+) (*ssov1.UpdateAppResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	app := entity.App{
+		ID:              req.GetAppId(),
+		Name:            req.GetName(),
+		GrantTypes:      req.GetGrantTypes(),
+		RedirectURIs:    req.GetRedirectUris(),
+		AccessTokenTTL:  time.Duration(req.GetAccessTokenTtlSeconds()) * time.Second,
+		RefreshTokenTTL: time.Duration(req.GetRefreshTokenTtlSeconds()) * time.Second,
+		SessionLifetime: time.Duration(req.GetSessionLifetimeSeconds()) * time.Second,
+		IdleTimeout:     time.Duration(req.GetIdleTimeoutSeconds()) * time.Second,
+		SlidingRefresh:  req.SlidingRefresh,
+	}
+
+	updated, err := s.apps.UpdateApp(ctx, interceptor.AdminSubject(ctx), app)
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.UpdateAppResponse{App: appToProto(updated)}, nil
+}
+
+func (s *serverAPI) DisableApp(ctx context.Context, req *ssov1.DisableAppRequest,
+) (*ssov1.DisableAppResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if err := s.apps.SetAppDisabled(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), true); err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.DisableAppResponse{Success: true}, nil
+}
+
+func (s *serverAPI) EnableApp(ctx context.Context, req *ssov1.EnableAppRequest,
+) (*ssov1.EnableAppResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if err := s.apps.SetAppDisabled(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), false); err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.EnableAppResponse{Success: true}, nil
+}
+
+func (s *serverAPI) DeleteApp(ctx context.Context, req *ssov1.DeleteAppRequest,
+) (*ssov1.DeleteAppResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if err := s.apps.DeleteApp(ctx, interceptor.AdminSubject(ctx), req.GetAppId()); err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.DeleteAppResponse{Success: true}, nil
+}
+
+func (s *serverAPI) CreateAppSecret(ctx context.Context, req *ssov1.CreateAppSecretRequest,
+) (*ssov1.CreateAppSecretResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	if req.GetTtlSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_seconds must not be negative")
+	}
+
+	var expiresAt time.Time
+	if req.GetTtlSeconds() > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.GetTtlSeconds()) * time.Second)
+	}
+
+	clientSecret, secret, err := s.apps.CreateSecret(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), expiresAt)
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.CreateAppSecretResponse{
+		Secret:       secretToProto(secret),
+		ClientSecret: clientSecret,
+	}, nil
+}
+
+func (s *serverAPI) RevokeAppSecret(ctx context.Context, req *ssov1.RevokeAppSecretRequest,
+) (*ssov1.RevokeAppSecretResponse, error) {
+	if req.GetAppId() == 0 || req.GetSecretId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id and secret_id are required")
+	}
+
+	err := s.apps.RevokeSecret(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), req.GetSecretId())
+	if err != nil {
+		return nil, appStatus(err)
+	}
+
+	return &ssov1.RevokeAppSecretResponse{Success: true}, nil
+}
+
+func appStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, storage.ErrAppSecretNotFound):
+		return status.Error(codes.NotFound, "secret not found")
+	case errors.Is(err, storage.ErrAppExists):
+		return status.Error(codes.AlreadyExists, "app with this name already exists")
+	case errors.Is(err, apps.ErrInvalidName):
+		return status.Error(codes.InvalidArgument, "invalid name")
+	case errors.Is(err, apps.ErrInvalidGrantType):
+		return status.Error(codes.InvalidArgument, "unknown grant type")
+	case errors.Is(err, apps.ErrInvalidRedirectURI):
+		return status.Error(codes.InvalidArgument, "invalid redirect uri")
+	case errors.Is(err, apps.ErrInvalidTTL):
+		return status.Error(codes.InvalidArgument, "invalid ttl")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// appToProto never includes the signing key: it stays on the server.
+func appToProto(app entity.App) *ssov1.AdminApp {
+	return &ssov1.AdminApp{
+		Id:                     app.ID,
+		Name:                   app.Name,
+		Disabled:               app.Disabled,
+		GrantTypes:             app.GrantTypes,
+		RedirectUris:           app.RedirectURIs,
+		AccessTokenTtlSeconds:  int64(app.AccessTokenTTL / time.Second),
+		RefreshTokenTtlSeconds: int64(app.RefreshTokenTTL / time.Second),
+		SessionLifetimeSeconds: int64(app.SessionLifetime / time.Second),
+		IdleTimeoutSeconds:     int64(app.IdleTimeout / time.Second),
+		SlidingRefresh:         app.SlidingRefresh,
+		CreatedAt:              app.CreatedAt.Unix(),
+		UpdatedAt:              app.UpdatedAt.Unix(),
+	}
+}
+
+func secretToProto(secret entity.AppSecret) *ssov1.AppSecret {
+	s := &ssov1.AppSecret{
+		Id:        secret.ID,
+		Hint:      secret.Hint,
+		CreatedAt: secret.CreatedAt.Unix(),
+	}
+
+	if !secret.ExpiresAt.IsZero() {
+		s.ExpiresAt = secret.ExpiresAt.Unix()
+	}
+	if !secret.RevokedAt.IsZero() {
+		s.RevokedAt = secret.RevokedAt.Unix()
+	}
+
+	return s
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"context"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	securitygrpc "github.com/KVSH-user/vizapSSO/internal/grpc/security"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type SecurityEvents interface {
+	ListUserEvents(ctx context.Context, uid int64, pageSize int, pageToken string,
+	) (events []entity.SecurityEvent, nextPageToken string, err error)
+}
+
+func (s *serverAPI) ListUserSecurityEvents(ctx context.Context, req *ssov1.ListUserSecurityEventsRequest,
+) (*ssov1.ListUserSecurityEventsResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	events, nextPageToken, err := s.securityEvents.ListUserEvents(ctx, req.GetUserId(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ssov1.ListUserSecurityEventsResponse{
+		NextPageToken: nextPageToken,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, securitygrpc.EventToProto(event))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	exportgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/export"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/services/account"
+	"github.com/KVSH-user/vizapSSO/internal/services/admin"
+	"github.com/KVSH-user/vizapSSO/internal/services/export"
+	"github.com/KVSH-user/vizapSSO/internal/services/security"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// MethodRoles is the role each admin RPC requires.
+var MethodRoles = map[string]string{
+	ssov1.Admin_SearchUsers_FullMethodName:                 admin.RoleViewer,
+	ssov1.Admin_GetUser_FullMethodName:                     admin.RoleViewer,
+	ssov1.Admin_BlockUser_FullMethodName:                   admin.RoleSupport,
+	ssov1.Admin_UnblockUser_FullMethodName:                 admin.RoleSupport,
+	ssov1.Admin_ForcePasswordReset_FullMethodName:          admin.RoleSupport,
+	ssov1.Admin_RevokeSessions_FullMethodName:              admin.RoleSupport,
+	ssov1.Admin_RestoreUser_FullMethodName:                 admin.RoleSupport,
+	ssov1.Admin_ExportUserData_FullMethodName:              admin.RoleSupport,
+	ssov1.Admin_GrantRole_FullMethodName:                   admin.RoleSuperadmin,
+	ssov1.Admin_RevokeRole_FullMethodName:                  admin.RoleSuperadmin,
+	ssov1.Admin_ListApps_FullMethodName:                    admin.RoleViewer,
+	ssov1.Admin_GetApp_FullMethodName:                      admin.RoleViewer,
+	ssov1.Admin_CreateApp_FullMethodName:                   admin.RoleSuperadmin,
+	ssov1.Admin_UpdateApp_FullMethodName:                   admin.RoleSuperadmin,
+	ssov1.Admin_DisableApp_FullMethodName:                  admin.RoleSuperadmin,
+	ssov1.Admin_EnableApp_FullMethodName:                   admin.RoleSuperadmin,
+	ssov1.Admin_DeleteApp_FullMethodName:                   admin.RoleSuperadmin,
+	ssov1.Admin_CreateAppSecret_FullMethodName:             admin.RoleSuperadmin,
+	ssov1.Admin_RevokeAppSecret_FullMethodName:             admin.RoleSuperadmin,
+	ssov1.Admin_ListUserSecurityEvents_FullMethodName:      admin.RoleSupport,
+	ssov1.Admin_ListWebhooks_FullMethodName:                admin.RoleViewer,
+	ssov1.Admin_CreateWebhook_FullMethodName:               admin.RoleSuperadmin,
+	ssov1.Admin_DeleteWebhook_FullMethodName:               admin.RoleSuperadmin,
+	ssov1.Admin_ListWebhookDeliveries_FullMethodName:       admin.RoleViewer,
+	ssov1.Admin_ReplayWebhookDelivery_FullMethodName:       admin.RoleSupport,
+	ssov1.Admin_ReplayDeadWebhookDeliveries_FullMethodName: admin.RoleSupport,
+}
+
+type Admin interface {
+	SearchUsers(ctx context.Context, filter entity.UserFilter, pageSize int, pageToken string,
+	) (users []entity.UserDetails, nextPageToken string, err error)
+	User(ctx context.Context, uid int64) (entity.UserDetails, []entity.Session, error)
+	BlockUser(ctx context.Context, actor string, uid int64, reason string, until time.Time) error
+	UnblockUser(ctx context.Context, actor string, uid int64, reason string) error
+	ForcePasswordReset(ctx context.Context, actor string, uid int64, reason string) error
+	RevokeSessions(ctx context.Context, actor string, uid int64, reason string) error
+	RestoreUser(ctx context.Context, actor string, uid int64, reason string) error
+	GrantRole(ctx context.Context, actor, subject, role string) error
+	RevokeRole(ctx context.Context, actor, subject, role string) error
+}
+
+type Export interface {
+	ExportUserData(ctx context.Context, actor string, uid int64, format export.Format) (export.Bundle, error)
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedAdminServer
+	admin          Admin
+	export         Export
+	apps           Apps
+	securityEvents SecurityEvents
+	webhooks       Webhooks
+}
+
+func Register(gRPC *grpc.Server, admin Admin, export Export, apps Apps, securityEvents SecurityEvents,
+	webhooks Webhooks) {
+	ssov1.RegisterAdminServer(gRPC, &serverAPI{
+		admin:          admin,
+		export:         export,
+		apps:           apps,
+		securityEvents: securityEvents,
+		webhooks:       webhooks,
+	})
+}
+
+func (s *serverAPI) SearchUsers(ctx context.Context, req *ssov1.SearchUsersRequest,
+) (*ssov1.SearchUsersResponse, error) {
+	filter := entity.UserFilter{
+		ID:    req.GetUserId(),
+		Phone: req.GetPhone(),
+		Email: req.GetEmail(),
+	}
+
+	users, nextPageToken, err := s.admin.SearchUsers(ctx, filter, int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ssov1.SearchUsersResponse{
+		NextPageToken: nextPageToken,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, toProto(user))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) GetUser(ctx context.Context, req *ssov1.GetUserRequest,
+) (*ssov1.GetUserResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, sessions, err := s.admin.User(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ssov1.GetUserResponse{
+		User: toProto(user),
+	}
+	for _, session := range sessions {
+		item := &ssov1.AdminSession{
+			Id:         session.ID,
+			CreatedAt:  session.CreatedAt.Unix(),
+			IsActive:   session.IsActive,
+			AppId:      session.AppID,
+			DeviceName: session.Device.Name,
+			Platform:   session.Device.Platform,
+			UserAgent:  session.Device.UserAgent,
+			Ip:         session.Device.IP,
+			Location:   session.Device.Location,
+			LastSeenAt: session.LastSeenAt.Unix(),
+		}
+		if !session.RevokedAt.IsZero() {
+			item.RevokedAt = session.RevokedAt.Unix()
+		}
+		resp.Sessions = append(resp.Sessions, item)
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) BlockUser(ctx context.Context, req *ssov1.BlockUserRequest,
+) (*ssov1.BlockUserResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	var until time.Time
+	if req.GetUntil() != 0 {
+		until = time.Unix(req.GetUntil(), 0)
+	}
+
+	err := s.admin.BlockUser(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), req.GetReason(), until)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.BlockUserResponse{Success: true}, nil
+}
+
+func (s *serverAPI) UnblockUser(ctx context.Context, req *ssov1.UnblockUserRequest,
+) (*ssov1.UnblockUserResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.admin.UnblockUser(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), req.GetReason()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.UnblockUserResponse{Success: true}, nil
+}
+
+func (s *serverAPI) ForcePasswordReset(ctx context.Context, req *ssov1.ForcePasswordResetRequest,
+) (*ssov1.ForcePasswordResetResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.admin.ForcePasswordReset(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), req.GetReason()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.ForcePasswordResetResponse{Success: true}, nil
+}
+
+func (s *serverAPI) RevokeSessions(ctx context.Context, req *ssov1.RevokeSessionsRequest,
+) (*ssov1.RevokeSessionsResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.admin.RevokeSessions(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), req.GetReason()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.RevokeSessionsResponse{Success: true}, nil
+}
+
+func (s *serverAPI) RestoreUser(ctx context.Context, req *ssov1.RestoreUserRequest,
+) (*ssov1.RestoreUserResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.admin.RestoreUser(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), req.GetReason()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.RestoreUserResponse{Success: true}, nil
+}
+
+func (s *serverAPI) GrantRole(ctx context.Context, req *ssov1.GrantRoleRequest,
+) (*ssov1.GrantRoleResponse, error) {
+	if req.GetSubject() == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject is required")
+	}
+
+	if err := s.admin.GrantRole(ctx, interceptor.AdminSubject(ctx), req.GetSubject(), req.GetRole()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.GrantRoleResponse{Success: true}, nil
+}
+
+func (s *serverAPI) RevokeRole(ctx context.Context, req *ssov1.RevokeRoleRequest,
+) (*ssov1.RevokeRoleResponse, error) {
+	if req.GetSubject() == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject is required")
+	}
+
+	if err := s.admin.RevokeRole(ctx, interceptor.AdminSubject(ctx), req.GetSubject(), req.GetRole()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ssov1.RevokeRoleResponse{Success: true}, nil
+}
+
+func (s *serverAPI) ExportUserData(req *ssov1.ExportUserDataRequest, stream ssov1.Admin_ExportUserDataServer) error {
+	if req.GetUserId() == 0 {
+		return status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	format := export.FormatJSON
+	if req.GetZip() {
+		format = export.FormatZip
+	}
+
+	ctx := stream.Context()
+
+	bundle, err := s.export.ExportUserData(ctx, interceptor.AdminSubject(ctx), req.GetUserId(), format)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return exportgrpc.Send(stream, bundle)
+}
+
+// toStatus keeps messages in English: the admin API is used by internal
+// tools, not shown to end users.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, admin.ErrInvalidPageToken), errors.Is(err, security.ErrInvalidPageToken):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	case errors.Is(err, admin.ErrUnknownRole):
+		return status.Error(codes.InvalidArgument, "unknown role")
+	case errors.Is(err, admin.ErrReasonRequired), errors.Is(err, account.ErrReasonRequired):
+		return status.Error(codes.InvalidArgument, "reason is required")
+	case errors.Is(err, account.ErrInvalidUntil):
+		return status.Error(codes.InvalidArgument, "until must be in the future")
+	case errors.Is(err, admin.ErrNotBlocked):
+		return status.Error(codes.FailedPrecondition, "user is not blocked")
+	case errors.Is(err, admin.ErrNotDeleted):
+		return status.Error(codes.FailedPrecondition, "user is not deleted")
+	case errors.Is(err, account.ErrTransitionNotAllowed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrVersionConflict):
+		return status.Error(codes.Aborted, "user was changed by another request, try again")
+	case errors.Is(err, otp.ErrTooManyCodes):
+		return status.Error(codes.ResourceExhausted, "too many reset links sent to the user, try later")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toProto(details entity.UserDetails) *ssov1.AdminUser {
+	user := details.User
+
+	u := &ssov1.AdminUser{
+		Id:                    user.ID,
+		Phone:                 user.Phone,
+		FullName:              details.Profile.FullName,
+		Email:                 details.Profile.Email,
+		EmailVerified:         details.Profile.EmailVerified,
+		Status:                string(user.State.Status),
+		StatusReason:          user.State.Reason,
+		StatusChangedBy:       user.State.ChangedBy,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt.Unix(),
+	}
+
+	if !user.State.ChangedAt.IsZero() {
+		u.StatusChangedAt = user.State.ChangedAt.Unix()
+	}
+	if !user.State.Until.IsZero() {
+		u.StatusUntil = user.State.Until.Unix()
+	}
+	if !user.PurgeAfter.IsZero() {
+		u.PurgeAfter = user.PurgeAfter.Unix()
+	}
+
+	return u
+}
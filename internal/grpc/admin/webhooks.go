@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"github.com/KVSH-user/vizapSSO/internal/services/webhooks"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Webhooks interface {
+	CreateWebhook(ctx context.Context, actor string, appID int32, rawURL string, events []string,
+	) (entity.Webhook, error)
+	Webhooks(ctx context.Context, appID int32) ([]entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, actor string, appID int32, webhookID int64) error
+	Deliveries(ctx context.Context, webhookID int64, status string, pageSize int, pageToken string,
+	) (deliveries []entity.WebhookDelivery, nextPageToken string, err error)
+	ReplayDelivery(ctx context.Context, actor string, deliveryID int64) error
+	ReplayDeadDeliveries(ctx context.Context, actor string, webhookID int64) (int64, error)
+}
+
+func (s *serverAPI) CreateWebhook(ctx context.Context, req *ssov1.CreateWebhookRequest,
+) (*ssov1.CreateWebhookResponse, error) {
+	if req.GetAppId() == 0 || req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_id and url are required")
+	}
+
+	webhook, err := s.webhooks.CreateWebhook(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), req.GetUrl(),
+		req.GetEvents())
+	if err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	return &ssov1.CreateWebhookResponse{
+		Webhook: webhookToProto(webhook),
+		Secret:  webhook.Secret,
+	}, nil
+}
+
+func (s *serverAPI) ListWebhooks(ctx context.Context, req *ssov1.ListWebhooksRequest,
+) (*ssov1.ListWebhooksResponse, error) {
+	if req.GetAppId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	list, err := s.webhooks.Webhooks(ctx, req.GetAppId())
+	if err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	resp := &ssov1.ListWebhooksResponse{}
+	for _, webhook := range list {
+		resp.Webhooks = append(resp.Webhooks, webhookToProto(webhook))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) DeleteWebhook(ctx context.Context, req *ssov1.DeleteWebhookRequest,
+) (*ssov1.DeleteWebhookResponse, error) {
+	if req.GetAppId() == 0 || req.GetWebhookId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id and webhook_id are required")
+	}
+
+	err := s.webhooks.DeleteWebhook(ctx, interceptor.AdminSubject(ctx), req.GetAppId(), req.GetWebhookId())
+	if err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	return &ssov1.DeleteWebhookResponse{Success: true}, nil
+}
+
+func (s *serverAPI) ListWebhookDeliveries(ctx context.Context, req *ssov1.ListWebhookDeliveriesRequest,
+) (*ssov1.ListWebhookDeliveriesResponse, error) {
+	if req.GetWebhookId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "webhook_id is required")
+	}
+
+	deliveries, nextPageToken, err := s.webhooks.Deliveries(ctx, req.GetWebhookId(), req.GetStatus(),
+		int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	resp := &ssov1.ListWebhookDeliveriesResponse{
+		NextPageToken: nextPageToken,
+	}
+	for _, delivery := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, deliveryToProto(delivery))
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) ReplayWebhookDelivery(ctx context.Context, req *ssov1.ReplayWebhookDeliveryRequest,
+) (*ssov1.ReplayWebhookDeliveryResponse, error) {
+	if req.GetDeliveryId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "delivery_id is required")
+	}
+
+	if err := s.webhooks.ReplayDelivery(ctx, interceptor.AdminSubject(ctx), req.GetDeliveryId()); err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	return &ssov1.ReplayWebhookDeliveryResponse{Success: true}, nil
+}
+
+func (s *serverAPI) ReplayDeadWebhookDeliveries(ctx context.Context, req *ssov1.ReplayDeadWebhookDeliveriesRequest,
+) (*ssov1.ReplayDeadWebhookDeliveriesResponse, error) {
+	if req.GetWebhookId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "webhook_id is required")
+	}
+
+	n, err := s.webhooks.ReplayDeadDeliveries(ctx, interceptor.AdminSubject(ctx), req.GetWebhookId())
+	if err != nil {
+		return nil, webhookStatus(err)
+	}
+
+	return &ssov1.ReplayDeadWebhookDeliveriesResponse{Replayed: n}, nil
+}
+
+func webhookStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return status.Error(codes.NotFound, "webhook not found")
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		return status.Error(codes.NotFound, "delivery not found or still pending")
+	case errors.Is(err, webhooks.ErrInvalidURL):
+		return status.Error(codes.InvalidArgument, "invalid url")
+	case errors.Is(err, webhooks.ErrInvalidEvent):
+		return status.Error(codes.InvalidArgument, "unknown event type")
+	case errors.Is(err, webhooks.ErrInvalidStatus):
+		return status.Error(codes.InvalidArgument, "invalid status")
+	case errors.Is(err, webhooks.ErrInvalidPageToken):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	case errors.Is(err, webhooks.ErrTooManyWebhooks):
+		return status.Error(codes.ResourceExhausted, "too many webhooks for the app")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// webhookToProto never includes the secret: it is shown only on creation.
+func webhookToProto(webhook entity.Webhook) *ssov1.Webhook {
+	return &ssov1.Webhook{
+		Id:        webhook.ID,
+		AppId:     webhook.AppID,
+		Url:       webhook.URL,
+		Events:    webhook.Events,
+		CreatedAt: webhook.CreatedAt.Unix(),
+	}
+}
+
+func deliveryToProto(delivery entity.WebhookDelivery) *ssov1.WebhookDelivery {
+	d := &ssov1.WebhookDelivery{
+		Id:             delivery.ID,
+		WebhookId:      delivery.WebhookID,
+		EventId:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		LastStatusCode: int32(delivery.LastStatusCode),
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt.Unix(),
+	}
+
+	if delivery.Status == entity.DeliveryPending {
+		d.NextAttemptAt = delivery.NextAttemptAt.Unix()
+	}
+	if !delivery.DeliveredAt.IsZero() {
+		d.DeliveredAt = delivery.DeliveredAt.Unix()
+	}
+
+	return d
+}
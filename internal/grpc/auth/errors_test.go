@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/hasher"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHasherError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantOK   bool
+		wantCode codes.Code
+	}{
+		{name: "busy", err: fmt.Errorf("auth.Login: %w", hasher.ErrBusy), wantOK: true, wantCode: codes.Unavailable},
+		{name: "canceled", err: fmt.Errorf("auth.Login: %w", context.Canceled), wantOK: true, wantCode: codes.Canceled},
+		{name: "deadline", err: fmt.Errorf("auth.Login: %w", context.DeadlineExceeded), wantOK: true, wantCode: codes.DeadlineExceeded},
+		{name: "other", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err, ok := hasherError(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("hasherError() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && status.Code(err) != tt.wantCode {
+				t.Fatalf("hasherError() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAccountStateError(t *testing.T) {
+	until := time.Date(2026, 10, 20, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantOK    bool
+		wantCode  codes.Code
+		wantUntil bool
+	}{
+		{
+			name:     "pending",
+			err:      &accountstate.Error{State: entity.AccountState{Status: entity.AccountPending}},
+			wantOK:   true,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:      "locked until",
+			err:       fmt.Errorf("auth.Login: %w", &accountstate.Error{State: entity.AccountState{Status: entity.AccountLocked, Until: until}}),
+			wantOK:    true,
+			wantCode:  codes.PermissionDenied,
+			wantUntil: true,
+		},
+		{
+			name:     "suspended",
+			err:      &accountstate.Error{State: entity.AccountState{Status: entity.AccountSuspended}},
+			wantOK:   true,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "deleted",
+			err:      &accountstate.Error{State: entity.AccountState{Status: entity.AccountDeleted}},
+			wantOK:   true,
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "other", err: accountstate.ErrLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err, ok := accountStateError(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("accountStateError() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("accountStateError() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+
+			if gotUntil := strings.Contains(status.Convert(err).Message(), until.Format(untilLayout)); gotUntil != tt.wantUntil {
+				t.Errorf("message %q mentions the end = %v, want %v", status.Convert(err).Message(), gotUntil, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestSessionError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantOK     bool
+		wantReason string
+	}{
+		{name: "expired", err: fmt.Errorf("auth.RefreshSession: %w", auth.ErrSessionExpired), wantOK: true, wantReason: reasonSessionExpired},
+		{name: "idle", err: fmt.Errorf("auth.RefreshSession: %w", auth.ErrSessionIdle), wantOK: true, wantReason: reasonSessionIdle},
+		{name: "other", err: storage.ErrInvalidRefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err, ok := sessionError(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("sessionError() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			st := status.Convert(err)
+			if st.Code() != codes.Unauthenticated {
+				t.Errorf("code = %v, want %v", st.Code(), codes.Unauthenticated)
+			}
+
+			var reason string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					reason = info.Reason
+				}
+			}
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -4,26 +4,54 @@ import (
 	"context"
 	"errors"
 	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/clientinfo"
+	"github.com/KVSH-user/vizapSSO/internal/lib/hasher"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"github.com/KVSH-user/vizapSSO/internal/lib/phone"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"vizapSSO/internal/services/auth"
-	"vizapSSO/internal/storage"
+	"time"
 )
 
 const (
-	emptyValue = 0
+	emptyValue         = 0
+	deletionDateLayout = "02.01.2006"
+	untilLayout        = "02.01.2006 15:04"
+
+	// errorDomain and the reasons below are sent in ErrorInfo, so clients
+	// can tell why a session ended without parsing the message.
+	errorDomain          = "sso.vizap"
+	reasonSessionExpired = "SESSION_EXPIRED"
+	reasonSessionIdle    = "SESSION_IDLE_TIMEOUT"
+	reasonInvalidRefresh = "INVALID_REFRESH_TOKEN"
 )
 
 type Auth interface {
-	Login(ctx context.Context, phone string, password string,
-		appID int32) (accessToken, refreshToken string, err error)
+	Login(ctx context.Context, login string, password string,
+		appID int32, device entity.Device) (accessToken, refreshToken string, err error)
 	RegisterNewUser(ctx context.Context, phone string, password string,
-	) (userID int64, err error)
+	) (userID int64, pending bool, err error)
+	ConfirmRegistration(ctx context.Context, phone, code string) (userID int64, err error)
+	ChangePassword(ctx context.Context, accessToken, oldPassword, newPassword string,
+	) (newAccessToken, newRefreshToken string, err error)
+	RequestPhoneChange(ctx context.Context, accessToken, newPhone string, oldPhoneReachable bool, password string) error
+	ConfirmPhoneChange(ctx context.Context, accessToken, newCode, oldCode string) error
 	ValidateSession(ctx context.Context, accessToken string) (isValid bool, uid int64, err error)
 	RefreshSession(ctx context.Context, accessToken, refreshToken string) (newAccessToken, newRefreshToken string, err error)
-	RequestPasswordReset(ctx context.Context, email string) (response string, err error)
+	RequestPasswordReset(ctx context.Context, login string) (response string, err error)
 	PerformPasswordReset(ctx context.Context, token, newPassword string) (success bool, err error)
+	RequestDeletionCode(ctx context.Context, accessToken string) error
+	DeleteAccount(ctx context.Context, accessToken, password, code string) (purgeAfter time.Time, err error)
+	RestoreAccount(ctx context.Context, login, password string, appID int32, device entity.Device,
+	) (accessToken, refreshToken string, err error)
+	Sessions
 }
 
 type serverAPI struct {
@@ -41,16 +69,51 @@ func (s *serverAPI) Login(ctx context.Context, req *ssov1.LoginRequest,
 		return nil, err
 	}
 
-	accessToken, refreshToken, err := s.auth.Login(ctx, req.GetPhone(), req.GetPassword(), req.GetAppId())
+	login := req.GetPhone()
+	if req.GetEmail() != "" {
+		login = req.GetEmail()
+	}
+
+	accessToken, refreshToken, err := s.auth.Login(ctx, login, req.GetPassword(), req.GetAppId(),
+		clientinfo.Device(ctx, req.GetDeviceName(), req.GetPlatform()))
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			return nil, status.Error(codes.InvalidArgument, "Неверный логин или пароль!")
 		}
 
+		if errors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много неверных паролей. Попробуйте позже.")
+		}
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "Неверный логин или пароль!")
 		}
 
+		var pendingErr *auth.PendingDeletionError
+		if errors.As(err, &pendingErr) {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"Аккаунт будет удалён %s. Восстановите его, чтобы продолжить.", pendingErr.PurgeAfter.Format(deletionDateLayout))
+		}
+
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
+
+		if st, ok := appError(err); ok {
+			return nil, st
+		}
+
+		if errors.Is(err, auth.ErrPasswordResetRequired) {
+			return nil, status.Error(codes.FailedPrecondition, "Необходимо сменить пароль. Воспользуйтесь восстановлением пароля.")
+		}
+
+		if errors.Is(err, phone.ErrInvalid) || errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+
 		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
 	}
 
@@ -66,24 +129,82 @@ func (s *serverAPI) Register(ctx context.Context, req *ssov1.RegisterRequest,
 		return nil, err
 	}
 
-	userID, err := s.auth.RegisterNewUser(ctx, req.GetPhone(), req.GetPassword())
+	userID, pending, err := s.auth.RegisterNewUser(ctx, req.GetPhone(), req.GetPassword())
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			return nil, status.Error(codes.AlreadyExists, "Пользователь с такими данными уже существует!")
 		}
+		if st, ok := passwordPolicyError(err); ok {
+			return nil, st
+		}
+		if errors.Is(err, auth.ErrPhoneCooldown) {
+			return nil, status.Error(codes.FailedPrecondition, "Этот номер недавно был привязан к другому аккаунту. Попробуйте позже.")
+		}
+		if errors.Is(err, otp.ErrTooManyCodes) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много запросов кода. Попробуйте позже.")
+		}
+		if errors.Is(err, phone.ErrInvalid) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+		if errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Укажите номер мобильного телефона")
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
 		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
 	}
 
+	if pending {
+		return &ssov1.RegisterResponse{
+			Message: "Проверьте телефон: мы отправили SMS с дальнейшими инструкциями.",
+		}, nil
+	}
+
 	return &ssov1.RegisterResponse{
 		UserId: userID,
 	}, nil
 }
 
+func (s *serverAPI) ConfirmRegistration(ctx context.Context, req *ssov1.ConfirmRegistrationRequest,
+) (*ssov1.ConfirmRegistrationResponse, error) {
+	if req.GetPhone() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите телефон")
+	}
+
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите код")
+	}
+
+	userID, err := s.auth.ConfirmRegistration(ctx, req.GetPhone(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCode) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный или просроченный код!")
+		}
+		if errors.Is(err, phone.ErrInvalid) || errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+		if errors.Is(err, storage.ErrUserExists) {
+			return nil, status.Error(codes.AlreadyExists, "Пользователь с такими данными уже существует!")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.ConfirmRegistrationResponse{
+		UserId: userID,
+	}, nil
+}
+
 func (s *serverAPI) ValidateSession(ctx context.Context, req *ssov1.ValidateRequest,
 ) (*ssov1.ValidateResponse, error) {
 	isValid, uid, err := s.auth.ValidateSession(ctx, req.GetAccessToken())
 	if err != nil {
-		//TODO: errors...
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return &ssov1.ValidateResponse{IsValid: false}, nil
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
@@ -98,7 +219,16 @@ func (s *serverAPI) RefreshSession(ctx context.Context, req *ssov1.RefreshReques
 	newAccessToken, newRefreshToken, err := s.auth.RefreshSession(ctx, req.AccessToken, req.GetRefreshToken())
 	if err != nil {
 		if errors.Is(err, storage.ErrInvalidRefreshToken) {
-			return nil, status.Error(codes.InvalidArgument, "invalid refresh token")
+			return nil, withReason(codes.InvalidArgument, "invalid refresh token", reasonInvalidRefresh)
+		}
+		if st, ok := sessionError(err); ok {
+			return nil, st
+		}
+		if st, ok := appError(err); ok {
+			return nil, st
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
 		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
@@ -111,10 +241,24 @@ func (s *serverAPI) RefreshSession(ctx context.Context, req *ssov1.RefreshReques
 
 func (s *serverAPI) RequestPasswordReset(ctx context.Context, req *ssov1.PasswordResetRequest,
 ) (*ssov1.PasswordResetResponse, error) {
-	response, err := s.auth.RequestPasswordReset(ctx, req.GetEmail())
+	login := req.GetPhone()
+	if req.GetEmail() != "" {
+		login = req.GetEmail()
+	}
+
+	if login == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите телефон или email")
+	}
+
+	response, err := s.auth.RequestPasswordReset(ctx, login)
 	if err != nil {
-		//TODO: errors...
-		return nil, status.Error(codes.Internal, "internal error")
+		if errors.Is(err, phone.ErrInvalid) || errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+		if errors.Is(err, otp.ErrTooManyCodes) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много запросов. Попробуйте позже.")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
 	}
 
 	return &ssov1.PasswordResetResponse{
@@ -124,10 +268,29 @@ func (s *serverAPI) RequestPasswordReset(ctx context.Context, req *ssov1.Passwor
 
 func (s *serverAPI) PerformPasswordReset(ctx context.Context, req *ssov1.PerformPasswordResetRequest,
 ) (*ssov1.PerformPasswordResetResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if req.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите новый пароль")
+	}
+
 	success, err := s.auth.PerformPasswordReset(ctx, req.GetToken(), req.GetNewPassword())
 	if err != nil {
-		//TODO: errors...
-		return nil, status.Error(codes.Internal, "internal error")
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return nil, status.Error(codes.InvalidArgument, "Ссылка для восстановления недействительна или устарела")
+		}
+		if errors.Is(err, auth.ErrPasswordReused) {
+			return nil, status.Error(codes.InvalidArgument, "Этот пароль уже использовался. Придумайте новый.")
+		}
+		if st, ok := passwordPolicyError(err); ok {
+			return nil, st
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
 	}
 
 	return &ssov1.PerformPasswordResetResponse{
@@ -135,9 +298,328 @@ func (s *serverAPI) PerformPasswordReset(ctx context.Context, req *ssov1.Perform
 	}, nil
 }
 
+func (s *serverAPI) ChangePassword(ctx context.Context, req *ssov1.ChangePasswordRequest,
+) (*ssov1.ChangePasswordResponse, error) {
+	if err := validateChangePassword(req); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.auth.ChangePassword(ctx, req.GetAccessToken(), req.GetOldPassword(), req.GetNewPassword())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много неверных паролей. Попробуйте позже.")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный текущий пароль!")
+		}
+		if errors.Is(err, auth.ErrPasswordReused) {
+			return nil, status.Error(codes.InvalidArgument, "Этот пароль уже использовался. Придумайте новый.")
+		}
+		if st, ok := passwordPolicyError(err); ok {
+			return nil, st
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.ChangePasswordResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *serverAPI) RequestPhoneChange(ctx context.Context, req *ssov1.RequestPhoneChangeRequest,
+) (*ssov1.RequestPhoneChangeResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetNewPhone() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите новый телефон")
+	}
+
+	if !req.GetOldPhoneReachable() && req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите текущий пароль")
+	}
+
+	err := s.auth.RequestPhoneChange(ctx, req.GetAccessToken(), req.GetNewPhone(), req.GetOldPhoneReachable(),
+		req.GetPassword())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много неверных паролей. Попробуйте позже.")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный текущий пароль!")
+		}
+		if errors.Is(err, otp.ErrTooManyCodes) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много запросов кода. Попробуйте позже.")
+		}
+		if errors.Is(err, auth.ErrSamePhone) {
+			return nil, status.Error(codes.InvalidArgument, "Этот номер уже привязан к вашему аккаунту")
+		}
+		if errors.Is(err, auth.ErrPhoneTaken) {
+			return nil, status.Error(codes.AlreadyExists, "Этот номер привязан к другому аккаунту")
+		}
+		if errors.Is(err, auth.ErrPhoneCooldown) {
+			return nil, status.Error(codes.FailedPrecondition, "Этот номер недавно был привязан к другому аккаунту. Попробуйте позже.")
+		}
+		if errors.Is(err, phone.ErrInvalid) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+		if errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Укажите номер мобильного телефона")
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.RequestPhoneChangeResponse{
+		Message: "Мы отправили код подтверждения на новый номер.",
+	}, nil
+}
+
+func (s *serverAPI) ConfirmPhoneChange(ctx context.Context, req *ssov1.ConfirmPhoneChangeRequest,
+) (*ssov1.ConfirmPhoneChangeResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetNewPhoneCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите код")
+	}
+
+	err := s.auth.ConfirmPhoneChange(ctx, req.GetAccessToken(), req.GetNewPhoneCode(), req.GetOldPhoneCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, auth.ErrInvalidCode) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный или просроченный код!")
+		}
+		if errors.Is(err, auth.ErrPhoneTaken) {
+			return nil, status.Error(codes.AlreadyExists, "Этот номер привязан к другому аккаунту")
+		}
+		if errors.Is(err, auth.ErrPhoneCooldown) {
+			return nil, status.Error(codes.FailedPrecondition, "Этот номер недавно был привязан к другому аккаунту. Попробуйте позже.")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.ConfirmPhoneChangeResponse{
+		Success: true,
+	}, nil
+}
+
+func (s *serverAPI) RequestDeletionCode(ctx context.Context, req *ssov1.RequestDeletionCodeRequest,
+) (*ssov1.RequestDeletionCodeResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if err := s.auth.RequestDeletionCode(ctx, req.GetAccessToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, otp.ErrTooManyCodes) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много запросов кода. Попробуйте позже.")
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.RequestDeletionCodeResponse{
+		Message: "Мы отправили код подтверждения на ваш номер.",
+	}, nil
+}
+
+func (s *serverAPI) DeleteAccount(ctx context.Context, req *ssov1.DeleteAccountRequest,
+) (*ssov1.DeleteAccountResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	purgeAfter, err := s.auth.DeleteAccount(ctx, req.GetAccessToken(), req.GetPassword(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
+		if errors.Is(err, auth.ErrConfirmationRequired) {
+			return nil, status.Error(codes.InvalidArgument, "Укажите пароль или код из SMS")
+		}
+		if errors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много неверных паролей. Попробуйте позже.")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный пароль!")
+		}
+		if errors.Is(err, auth.ErrInvalidCode) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный или просроченный код!")
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.DeleteAccountResponse{
+		PurgeAfter: purgeAfter.Unix(),
+	}, nil
+}
+
+func (s *serverAPI) RestoreAccount(ctx context.Context, req *ssov1.RestoreAccountRequest,
+) (*ssov1.RestoreAccountResponse, error) {
+	login := req.GetPhone()
+	if req.GetEmail() != "" {
+		login = req.GetEmail()
+	}
+
+	if login == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите телефон или email")
+	}
+
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите пароль")
+	}
+
+	if req.GetAppId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	accessToken, refreshToken, err := s.auth.RestoreAccount(ctx, login, req.GetPassword(), req.GetAppId(),
+		clientinfo.Device(ctx, req.GetDeviceName(), req.GetPlatform()))
+	if err != nil {
+		if st, ok := appError(err); ok {
+			return nil, st
+		}
+		if errors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много неверных паролей. Попробуйте позже.")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный логин или пароль!")
+		}
+		if errors.Is(err, phone.ErrInvalid) || errors.Is(err, phone.ErrNotMobile) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный номер телефона")
+		}
+		if st, ok := hasherError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.RestoreAccountResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func appError(err error) (error, bool) {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound), errors.Is(err, auth.ErrAppDisabled):
+		return status.Error(codes.InvalidArgument, "Приложение не найдено или отключено"), true
+	case errors.Is(err, auth.ErrGrantNotAllowed):
+		return status.Error(codes.PermissionDenied, "Этот способ входа недоступен для приложения"), true
+	}
+
+	return nil, false
+}
+
+// sessionError tells the user which limit ended the session.
+func sessionError(err error) (error, bool) {
+	switch {
+	case errors.Is(err, auth.ErrSessionExpired):
+		return withReason(codes.Unauthenticated, "Сессия истекла, войдите снова", reasonSessionExpired), true
+	case errors.Is(err, auth.ErrSessionIdle):
+		return withReason(codes.Unauthenticated, "Сессия завершена из-за неактивности, войдите снова",
+			reasonSessionIdle), true
+	}
+
+	return nil, false
+}
+
+func withReason(code codes.Code, msg, reason string) error {
+	st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+
+	return st.Err()
+}
+
+// accountStateError tells the user why the account can't be used and, for
+// temporary locks, when it can be used again.
+func accountStateError(err error) (error, bool) {
+	var stateErr *accountstate.Error
+	if !errors.As(err, &stateErr) {
+		return nil, false
+	}
+
+	until := ""
+	if !stateErr.State.Until.IsZero() {
+		until = " до " + stateErr.State.Until.Format(untilLayout)
+	}
+
+	switch stateErr.State.Status {
+	case entity.AccountPending:
+		return status.Error(codes.FailedPrecondition, "Аккаунт ещё не активирован"), true
+	case entity.AccountLocked:
+		return status.Error(codes.PermissionDenied, "Аккаунт временно заблокирован"+until), true
+	case entity.AccountSuspended:
+		return status.Error(codes.PermissionDenied, "Аккаунт заблокирован"+until+". Обратитесь в поддержку."), true
+	case entity.AccountDeleted:
+		return status.Error(codes.PermissionDenied, "Аккаунт удалён"), true
+	}
+
+	return status.Error(codes.PermissionDenied, "Аккаунт недоступен"), true
+}
+
+// hasherError maps a password hash call that didn't get a hashing slot:
+// the queue was full or the caller gave up while waiting.
+func hasherError(err error) (error, bool) {
+	if errors.Is(err, hasher.ErrBusy) {
+		return status.Error(codes.Unavailable, "Сервис перегружен. Попробуйте позже."), true
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err(), true
+	}
+
+	return nil, false
+}
+
+func passwordPolicyError(err error) (error, bool) {
+	switch {
+	case errors.Is(err, password.ErrTooShort):
+		return status.Error(codes.InvalidArgument, "Пароль слишком короткий"), true
+	case errors.Is(err, password.ErrTooLong):
+		return status.Error(codes.InvalidArgument, "Пароль слишком длинный"), true
+	case errors.Is(err, password.ErrTooWeak):
+		return status.Error(codes.InvalidArgument, "Пароль должен содержать буквы и цифры"), true
+	}
+
+	return nil, false
+}
+
 func validateLogin(req *ssov1.LoginRequest) error {
-	if req.GetPhone() == "" {
-		return status.Error(codes.InvalidArgument, "Укажите телефон")
+	if req.GetPhone() == "" && req.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "Укажите телефон или email")
 	}
 
 	if req.GetPassword() == "" {
@@ -162,3 +644,19 @@ func validateRegister(req *ssov1.RegisterRequest) error {
 
 	return nil
 }
+
+func validateChangePassword(req *ssov1.ChangePasswordRequest) error {
+	if req.GetAccessToken() == "" {
+		return status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetOldPassword() == "" {
+		return status.Error(codes.InvalidArgument, "Укажите текущий пароль")
+	}
+
+	if req.GetNewPassword() == "" {
+		return status.Error(codes.InvalidArgument, "Укажите новый пароль")
+	}
+
+	return nil
+}
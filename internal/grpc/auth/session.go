@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Sessions interface {
+	ListSessions(ctx context.Context, accessToken string) (sessions []entity.Session, currentID int64, err error)
+	RevokeSession(ctx context.Context, accessToken string, sessionID int64) error
+}
+
+func (s *serverAPI) ListSessions(ctx context.Context, req *ssov1.ListSessionsRequest,
+) (*ssov1.ListSessionsResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	sessions, currentID, err := s.auth.ListSessions(ctx, req.GetAccessToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	resp := &ssov1.ListSessionsResponse{}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &ssov1.Session{
+			Id:         session.ID,
+			AppId:      session.AppID,
+			DeviceName: session.Device.Name,
+			Platform:   session.Device.Platform,
+			UserAgent:  session.Device.UserAgent,
+			Ip:         session.Device.IP,
+			Location:   session.Device.Location,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastSeenAt: session.LastSeenAt.Unix(),
+			Current:    session.ID == currentID,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeSession(ctx context.Context, req *ssov1.RevokeSessionRequest,
+) (*ssov1.RevokeSessionResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetSessionId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+
+	if err := s.auth.RevokeSession(ctx, req.GetAccessToken(), req.GetSessionId()); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, "Сессия не найдена")
+		}
+		if st, ok := accountStateError(err); ok {
+			return nil, st
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.RevokeSessionResponse{Success: true}, nil
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/services/export"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkSize keeps every message well below the default 4 MiB gRPC limit.
+const chunkSize = 64 << 10
+
+type Export interface {
+	ExportMyData(ctx context.Context, accessToken string, format export.Format) (export.Bundle, error)
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedDataExportServer
+	export Export
+}
+
+func Register(gRPC *grpc.Server, export Export) {
+	ssov1.RegisterDataExportServer(gRPC, &serverAPI{export: export})
+}
+
+func (s *serverAPI) ExportMyData(req *ssov1.ExportMyDataRequest, stream ssov1.DataExport_ExportMyDataServer) error {
+	if req.GetAccessToken() == "" {
+		return status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	format := export.FormatJSON
+	if req.GetZip() {
+		format = export.FormatZip
+	}
+
+	bundle, err := s.export.ExportMyData(stream.Context(), req.GetAccessToken(), format)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.Is(err, accountstate.ErrDeleted) {
+			return status.Error(codes.PermissionDenied, "Аккаунт удалён")
+		}
+		if errors.As(err, new(*accountstate.Error)) {
+			return status.Error(codes.PermissionDenied, "Аккаунт заблокирован")
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return status.Error(codes.NotFound, "Пользователь не найден")
+		}
+		return status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return Send(stream, bundle)
+}
+
+type ChunkSender interface {
+	Send(*ssov1.ExportChunk) error
+}
+
+// Send streams the bundle in chunks. The first chunk also carries the file
+// name, content type and total size.
+func Send(stream ChunkSender, bundle export.Bundle) error {
+	first := true
+	data := bundle.Data
+
+	for first || len(data) > 0 {
+		n := min(len(data), chunkSize)
+
+		chunk := &ssov1.ExportChunk{Data: data[:n]}
+		if first {
+			chunk.Filename = bundle.Filename
+			chunk.ContentType = bundle.ContentType
+			chunk.Size = int64(len(bundle.Data))
+			first = false
+		}
+
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/services/profile"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Profile interface {
+	GetProfile(ctx context.Context, accessToken string) (entity.Profile, error)
+	UpdateProfile(ctx context.Context, accessToken string, fullName, email *string, expectedVersion int,
+	) (entity.Profile, error)
+	VerifyEmail(ctx context.Context, accessToken, code string) (email string, err error)
+	ResendEmailVerification(ctx context.Context, accessToken string) error
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedProfileServer
+	profile Profile
+}
+
+func Register(gRPC *grpc.Server, profile Profile) {
+	ssov1.RegisterProfileServer(gRPC, &serverAPI{profile: profile})
+}
+
+func (s *serverAPI) GetProfile(ctx context.Context, req *ssov1.GetProfileRequest,
+) (*ssov1.GetProfileResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	p, err := s.profile.GetProfile(ctx, req.GetAccessToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.As(err, new(*accountstate.Error)) {
+			return nil, status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.GetProfileResponse{
+		Profile: toProto(p),
+	}, nil
+}
+
+func (s *serverAPI) UpdateProfile(ctx context.Context, req *ssov1.UpdateProfileRequest,
+) (*ssov1.UpdateProfileResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	// Unset fields keep their values, so a client that doesn't know a field
+	// can't wipe it.
+	p, err := s.profile.UpdateProfile(ctx, req.GetAccessToken(), req.FullName, req.Email, int(req.GetExpectedVersion()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.As(err, new(*accountstate.Error)) {
+			return nil, status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+		}
+		if errors.Is(err, profile.ErrInvalidFullName) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректное имя")
+		}
+		if errors.Is(err, profile.ErrInvalidEmail) {
+			return nil, status.Error(codes.InvalidArgument, "Некорректный email")
+		}
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "Профиль был изменён. Обновите данные и попробуйте снова.")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.UpdateProfileResponse{
+		Profile: toProto(p),
+	}, nil
+}
+
+func (s *serverAPI) VerifyEmail(ctx context.Context, req *ssov1.VerifyEmailRequest,
+) (*ssov1.VerifyEmailResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Укажите код")
+	}
+
+	email, err := s.profile.VerifyEmail(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.As(err, new(*accountstate.Error)) {
+			return nil, status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+		}
+		if errors.Is(err, profile.ErrInvalidCode) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный или просроченный код!")
+		}
+		if errors.Is(err, profile.ErrEmailAlreadyInUse) {
+			return nil, status.Error(codes.AlreadyExists, "Этот email уже подтверждён в другом аккаунте")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.VerifyEmailResponse{
+		Email: email,
+	}, nil
+}
+
+func (s *serverAPI) ResendEmailVerification(ctx context.Context, req *ssov1.ResendEmailVerificationRequest,
+) (*ssov1.ResendEmailVerificationResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	err := s.profile.ResendEmailVerification(ctx, req.GetAccessToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+		}
+		if errors.As(err, new(*accountstate.Error)) {
+			return nil, status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+		}
+		if errors.Is(err, profile.ErrNothingToVerify) {
+			return nil, status.Error(codes.FailedPrecondition, "В профиле нет неподтверждённого email")
+		}
+		if errors.Is(err, otp.ErrTooManyCodes) {
+			return nil, status.Error(codes.ResourceExhausted, "Слишком много запросов кода. Попробуйте позже.")
+		}
+		return nil, status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+	}
+
+	return &ssov1.ResendEmailVerificationResponse{
+		Message: "Мы отправили код подтверждения на ваш email.",
+	}, nil
+}
+
+func toProto(p entity.Profile) *ssov1.UserProfile {
+	return &ssov1.UserProfile{
+		UserId:        p.UserID,
+		FullName:      p.FullName,
+		Email:         p.Email,
+		EmailVerified: p.EmailVerified,
+		Version:       int64(p.Version),
+		UpdatedAt:     p.UpdatedAt.Unix(),
+	}
+}
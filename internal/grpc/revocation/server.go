@@ -0,0 +1,78 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/services/apps"
+	"github.com/KVSH-user/vizapSSO/internal/services/revocation"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Revocations interface {
+	Watch(ctx context.Context, appID int32, clientSecret string, cursor int64,
+		send func(entity.Revocation) error) error
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedRevocationsServer
+	revocations Revocations
+}
+
+func Register(gRPC *grpc.Server, revocations Revocations) {
+	ssov1.RegisterRevocationsServer(gRPC, &serverAPI{revocations: revocations})
+}
+
+// WatchRevocations is for services that check access tokens offline. The
+// stream doesn't end on its own; after a disconnect the service resumes
+// with the cursor of the last entry it got.
+func (s *serverAPI) WatchRevocations(req *ssov1.WatchRevocationsRequest,
+	stream ssov1.Revocations_WatchRevocationsServer) error {
+	if req.GetAppId() == 0 || req.GetClientSecret() == "" {
+		return status.Error(codes.Unauthenticated, "app_id and client_secret are required")
+	}
+	if req.GetCursor() < 0 {
+		return status.Error(codes.InvalidArgument, "cursor must not be negative")
+	}
+
+	err := s.revocations.Watch(stream.Context(), req.GetAppId(), req.GetClientSecret(), req.GetCursor(),
+		func(revocation entity.Revocation) error {
+			return stream.Send(toProto(revocation))
+		})
+
+	switch {
+	case errors.Is(err, apps.ErrInvalidClientSecret), errors.Is(err, storage.ErrAppNotFound):
+		return status.Error(codes.Unauthenticated, "invalid client credentials")
+	case errors.Is(err, revocation.ErrStopped):
+		return status.Error(codes.Unavailable, "server is shutting down, reconnect with the last cursor")
+	case errors.Is(err, storage.ErrCursorExpired):
+		return status.Error(codes.OutOfRange, "cursor has expired, reload revocations and watch from 0")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case err != nil:
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
+
+func toProto(revocation entity.Revocation) *ssov1.Revocation {
+	r := &ssov1.Revocation{
+		Cursor:    revocation.ID,
+		Kind:      revocation.Kind,
+		UserId:    revocation.UserID,
+		SessionId: revocation.SessionID,
+		Jti:       revocation.TokenID,
+		CreatedAt: revocation.CreatedAt.Unix(),
+	}
+
+	if !revocation.NotBefore.IsZero() {
+		r.NotBefore = revocation.NotBefore.Unix()
+	}
+
+	return r
+}
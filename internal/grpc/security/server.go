@@ -0,0 +1,77 @@
+package security
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/services/security"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Security interface {
+	ListMyEvents(ctx context.Context, accessToken string, pageSize int, pageToken string,
+	) (events []entity.SecurityEvent, nextPageToken string, err error)
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedSecurityServer
+	security Security
+}
+
+func Register(gRPC *grpc.Server, security Security) {
+	ssov1.RegisterSecurityServer(gRPC, &serverAPI{security: security})
+}
+
+func (s *serverAPI) ListSecurityEvents(ctx context.Context, req *ssov1.ListSecurityEventsRequest,
+) (*ssov1.ListSecurityEventsResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, "access_token is required")
+	}
+
+	events, nextPageToken, err := s.security.ListMyEvents(ctx, req.GetAccessToken(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ssov1.ListSecurityEventsResponse{
+		NextPageToken: nextPageToken,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, EventToProto(event))
+	}
+
+	return resp, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+	case errors.As(err, new(*accountstate.Error)):
+		return status.Error(codes.PermissionDenied, "Аккаунт заблокирован или удалён")
+	case errors.Is(err, security.ErrInvalidPageToken):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	return status.Error(codes.Internal, "Внутренняя ошибка. Обратитесь в поддержку или попробуйте позже.")
+}
+
+// EventToProto is shared with the admin API, which lists the same events.
+func EventToProto(event entity.SecurityEvent) *ssov1.SecurityEvent {
+	return &ssov1.SecurityEvent{
+		Id:        event.ID,
+		Type:      event.Type,
+		Outcome:   event.Outcome,
+		Method:    event.Method,
+		AppId:     event.AppID,
+		Ip:        event.IP,
+		UserAgent: event.UserAgent,
+		Reason:    event.Reason,
+		CreatedAt: event.CreatedAt.Unix(),
+	}
+}
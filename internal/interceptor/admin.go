@@ -0,0 +1,103 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+type AdminAuthorizer interface {
+	AuthorizeAdmin(ctx context.Context, subject, method string) error
+}
+
+type adminSubjectKey struct{}
+
+// AdminSubject returns the name of the staff member making the request,
+// taken from their client certificate.
+func AdminSubject(ctx context.Context) string {
+	subject, _ := ctx.Value(adminSubjectKey{}).(string)
+	return subject
+}
+
+func UnaryAdminAuthInterceptor(log *slog.Logger, authorizer AdminAuthorizer) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authorizeAdmin(ctx, log, authorizer, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamAdminAuthInterceptor(log *slog.Logger, authorizer AdminAuthorizer) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authorizeAdmin(ss.Context(), log, authorizer, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &adminStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorizeAdmin identifies the caller by the common name of the verified
+// client certificate and checks their role for the method.
+func authorizeAdmin(ctx context.Context, log *slog.Logger, authorizer AdminAuthorizer, method string,
+) (context.Context, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "client certificate is required")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "client certificate is required")
+	}
+
+	subject := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	if subject == "" {
+		return nil, status.Error(codes.Unauthenticated, "client certificate has no common name")
+	}
+
+	if err := authorizer.AuthorizeAdmin(ctx, subject, method); err != nil {
+		log.Warn("admin request denied",
+			slog.String("subject", subject),
+			slog.String("method", method),
+			sl.Err(err),
+		)
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+	}
+
+	return context.WithValue(ctx, adminSubjectKey{}, subject), nil
+}
+
+type adminStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *adminStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,131 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/clientinfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// UnaryClientInfoInterceptor puts the client IP and user agent into the
+// request context, see clientinfo.FromContext. X-Forwarded-For is only
+// read from peers in trustedProxies.
+func UnaryClientInfoInterceptor(trustedProxies []netip.Prefix) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withClientInfo(ctx, trustedProxies), req)
+	}
+}
+
+func StreamClientInfoInterceptor(trustedProxies []netip.Prefix) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := withClientInfo(ss.Context(), trustedProxies)
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ParseTrustedProxies parses proxy addresses in CIDR notation. A plain
+// address stands for itself.
+func ParseTrustedProxies(cidrs []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if addr, err := netip.ParseAddr(cidr); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func withClientInfo(ctx context.Context, trustedProxies []netip.Prefix) context.Context {
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			userAgent = ua[0]
+		}
+	}
+
+	return clientinfo.NewContext(ctx, clientIP(ctx, trustedProxies), userAgent)
+}
+
+// clientIP is the address of the peer. When the peer is a trusted proxy,
+// X-Forwarded-For is read from the right, since every proxy appends the
+// address it got the request from: the client is the rightmost address
+// that is not a trusted proxy. Addresses left of it are whatever the
+// client sent.
+func clientIP(ctx context.Context, trustedProxies []netip.Prefix) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return addr.String()
+	}
+
+	// Several X-Forwarded-For headers make one list.
+	hops := strings.Split(strings.Join(md.Get("x-forwarded-for"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0 && trusted(addr, trustedProxies); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+
+	return addr.String()
+}
+
+func trusted(addr netip.Addr, proxies []netip.Prefix) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		proxies   []netip.Prefix
+		want      string
+	}{
+		{name: "direct", peer: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted peer", peer: "203.0.113.7", forwarded: []string{"198.51.100.1"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "private peer, no proxies", peer: "10.0.0.5", forwarded: []string{"198.51.100.1"}, want: "10.0.0.5"},
+		{name: "trusted proxy", peer: "10.0.0.5", forwarded: []string{"198.51.100.1"}, proxies: proxies, want: "198.51.100.1"},
+		{
+			name:      "spoofed hop left of the client",
+			peer:      "10.0.0.5",
+			forwarded: []string{"1.1.1.1, 198.51.100.1"},
+			proxies:   proxies,
+			want:      "198.51.100.1",
+		},
+		{
+			name:      "chain of proxies",
+			peer:      "10.0.0.5",
+			forwarded: []string{"1.1.1.1, 198.51.100.1, 192.0.2.1, 10.1.1.1"},
+			proxies:   proxies,
+			want:      "198.51.100.1",
+		},
+		{
+			name:      "several headers",
+			peer:      "10.0.0.5",
+			forwarded: []string{"1.1.1.1", "198.51.100.1"},
+			proxies:   proxies,
+			want:      "198.51.100.1",
+		},
+		{
+			name:      "garbage hop",
+			peer:      "10.0.0.5",
+			forwarded: []string{"198.51.100.1, unknown, 10.1.1.1"},
+			proxies:   proxies,
+			want:      "10.1.1.1",
+		},
+		{name: "only proxies", peer: "10.0.0.5", forwarded: []string{"10.1.1.1"}, proxies: proxies, want: "10.1.1.1"},
+		{name: "no header", peer: "10.0.0.5", proxies: proxies, want: "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 5001},
+			})
+			md := metadata.MD{}
+			for _, value := range tt.forwarded {
+				md.Append("x-forwarded-for", value)
+			}
+			ctx = metadata.NewIncomingContext(ctx, md)
+
+			if got := clientIP(ctx, tt.proxies); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name:  "prefix and address",
+			cidrs: []string{"10.1.2.3/8", "192.0.2.1", "::ffff:192.0.2.2"},
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.0.2.1/32"),
+				netip.MustParsePrefix("192.0.2.2/32"),
+			},
+		},
+		{name: "invalid", cidrs: []string{"proxy"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrustedProxies(tt.cidrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseTrustedProxies() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("prefix %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
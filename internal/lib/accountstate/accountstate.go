@@ -0,0 +1,66 @@
+package accountstate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"time"
+)
+
+var (
+	ErrPending   = errors.New("account is not activated")
+	ErrLocked    = errors.New("account is locked")
+	ErrSuspended = errors.New("account is suspended")
+	ErrDeleted   = errors.New("account is deleted")
+)
+
+// Error is returned for accounts that can't be used. It matches one of
+// the sentinel errors above and carries the state, so callers can tell the
+// user when a lock or suspension ends.
+type Error struct {
+	State entity.AccountState
+}
+
+func (e *Error) Error() string {
+	if e.State.Until.IsZero() {
+		return fmt.Sprintf("account is %s", e.State.Status)
+	}
+
+	return fmt.Sprintf("account is %s until %s", e.State.Status, e.State.Until.Format(time.RFC3339))
+}
+
+func (e *Error) Is(target error) bool {
+	switch e.State.Status {
+	case entity.AccountPending:
+		return target == ErrPending
+	case entity.AccountLocked:
+		return target == ErrLocked
+	case entity.AccountSuspended:
+		return target == ErrSuspended
+	case entity.AccountDeleted:
+		return target == ErrDeleted
+	}
+
+	return false
+}
+
+// Effective returns the status that applies at now. A lock or suspension
+// whose term has passed no longer counts.
+func Effective(state entity.AccountState, now time.Time) entity.AccountStatus {
+	if state.Status == entity.AccountLocked || state.Status == entity.AccountSuspended {
+		if !state.Until.IsZero() && !now.Before(state.Until) {
+			return entity.AccountActive
+		}
+	}
+
+	return state.Status
+}
+
+// Check returns nil if the account can sign in and use its sessions.
+func Check(state entity.AccountState, now time.Time) error {
+	if Effective(state, now) == entity.AccountActive {
+		return nil
+	}
+
+	return &Error{State: state}
+}
@@ -0,0 +1,79 @@
+package accountstate
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		state         entity.AccountState
+		wantEffective entity.AccountStatus
+		wantErr       error
+	}{
+		{name: "active", state: entity.AccountState{Status: entity.AccountActive}, wantEffective: entity.AccountActive},
+		{name: "pending", state: entity.AccountState{Status: entity.AccountPending}, wantEffective: entity.AccountPending, wantErr: ErrPending},
+		{name: "locked for good", state: entity.AccountState{Status: entity.AccountLocked}, wantEffective: entity.AccountLocked, wantErr: ErrLocked},
+		{
+			name:          "locked until later",
+			state:         entity.AccountState{Status: entity.AccountLocked, Until: now.Add(time.Hour)},
+			wantEffective: entity.AccountLocked,
+			wantErr:       ErrLocked,
+		},
+		{
+			name:          "lock ends now",
+			state:         entity.AccountState{Status: entity.AccountLocked, Until: now},
+			wantEffective: entity.AccountActive,
+		},
+		{
+			name:          "suspension is over",
+			state:         entity.AccountState{Status: entity.AccountSuspended, Until: now.Add(-time.Hour)},
+			wantEffective: entity.AccountActive,
+		},
+		{
+			name:          "suspended",
+			state:         entity.AccountState{Status: entity.AccountSuspended, Until: now.Add(time.Hour)},
+			wantEffective: entity.AccountSuspended,
+			wantErr:       ErrSuspended,
+		},
+		{
+			name:          "deleted ignores until",
+			state:         entity.AccountState{Status: entity.AccountDeleted, Until: now.Add(-time.Hour)},
+			wantEffective: entity.AccountDeleted,
+			wantErr:       ErrDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Effective(tt.state, now); got != tt.wantEffective {
+				t.Errorf("Effective() = %s, want %s", got, tt.wantEffective)
+			}
+
+			err := Check(tt.state, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil {
+				return
+			}
+
+			var stateErr *Error
+			if !errors.As(err, &stateErr) || stateErr.State != tt.state {
+				t.Errorf("Check() = %#v, want an *Error with the state", err)
+			}
+
+			for _, other := range []error{ErrPending, ErrLocked, ErrSuspended, ErrDeleted} {
+				if other != tt.wantErr && errors.Is(err, other) {
+					t.Errorf("Check() matches %v as well", other)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package auditchain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"os"
+	"time"
+)
+
+// Hash returns the hex SHA-256 of the record chained to prevHash, the hash
+// of the record written before it. The first record of the chain has an
+// empty prevHash.
+//
+// The hash covers the id, actor, action, target user, details and time of
+// the record. Details are hashed in canonical form, since Postgres keeps
+// JSONB in its own key order and spacing.
+func Hash(prevHash string, record entity.AuditRecord) (string, error) {
+	details, err := CanonicalDetails(record.Details)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal([]any{
+		prevHash,
+		record.ID,
+		record.Actor,
+		record.Action,
+		record.TargetUserID,
+		json.RawMessage(details),
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalDetails re-encodes a JSON object with sorted keys and no
+// spaces. Numbers are kept as written. Empty details are "{}".
+func CanonicalDetails(details []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(details)) == 0 {
+		return []byte("{}"), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(details))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// Timestamp rounds t to what a Postgres TIMESTAMP column stores, so the
+// hash of a record written and read back is the same.
+func Timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// LoadSigningKey reads an Ed25519 private key in PKCS #8 PEM form, as
+// written by "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(file string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 key, got %T", key)
+	}
+
+	return privateKey, nil
+}
+
+// KeyID is a short fingerprint of the public key, so exports signed with
+// different keys can be told apart.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+
+	return hex.EncodeToString(sum[:8])
+}
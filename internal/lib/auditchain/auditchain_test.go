@@ -0,0 +1,93 @@
+package auditchain
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestCanonicalDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", details: "", want: "{}"},
+		{name: "spaces only", details: "  ", want: "{}"},
+		{name: "sorted keys", details: `{"role": "viewer", "reason": "x"}`, want: `{"reason":"x","role":"viewer"}`},
+		{name: "nested", details: `{"b": {"d": 1, "c": 2}, "a": []}`, want: `{"a":[],"b":{"c":2,"d":1}}`},
+		{name: "numbers kept as written", details: `{"n": 1.50}`, want: `{"n":1.50}`},
+		{name: "invalid", details: `{"a":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalDetails([]byte(tt.details))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanonicalDetails() error = %v, want error %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalDetails() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	record := entity.AuditRecord{
+		ID:           10,
+		Actor:        "ivanov",
+		Action:       "admin.revoke_sessions",
+		TargetUserID: 7,
+		Details:      []byte(`{"reason":"stolen phone"}`),
+		CreatedAt:    time.Date(2026, 10, 19, 12, 0, 0, 123456000, time.UTC),
+	}
+
+	base, err := Hash("prev", record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		prevHash string
+		change   func(r *entity.AuditRecord)
+		wantSame bool
+	}{
+		{name: "same record", prevHash: "prev", change: func(*entity.AuditRecord) {}, wantSame: true},
+		{
+			name:     "details reformatted",
+			prevHash: "prev",
+			change:   func(r *entity.AuditRecord) { r.Details = []byte(`{ "reason" : "stolen phone" }`) },
+			wantSame: true,
+		},
+		{
+			name:     "time in another zone",
+			prevHash: "prev",
+			change:   func(r *entity.AuditRecord) { r.CreatedAt = r.CreatedAt.In(time.FixedZone("MSK", 3*3600)) },
+			wantSame: true,
+		},
+		{name: "other previous hash", prevHash: "other", change: func(*entity.AuditRecord) {}},
+		{name: "other actor", prevHash: "prev", change: func(r *entity.AuditRecord) { r.Actor = "petrov" }},
+		{name: "other target", prevHash: "prev", change: func(r *entity.AuditRecord) { r.TargetUserID = 8 }},
+		{name: "other details", prevHash: "prev", change: func(r *entity.AuditRecord) { r.Details = []byte(`{"reason":"x"}`) }},
+		{name: "other time", prevHash: "prev", change: func(r *entity.AuditRecord) { r.CreatedAt = r.CreatedAt.Add(time.Microsecond) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := record
+			tt.change(&changed)
+
+			got, err := Hash(tt.prevHash, changed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (got == base) != tt.wantSame {
+				t.Errorf("hash changed = %v, want %v", got != base, !tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package clientinfo
+
+import (
+	"context"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxDeviceNameLength = 128
+	maxPlatformLength   = 32
+	maxUserAgentLength  = 512
+)
+
+type ctxKey struct{}
+
+// NewContext stores the IP and user agent of the request in ctx.
+func NewContext(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, entity.Device{
+		IP:        ip,
+		UserAgent: truncate(userAgent, maxUserAgentLength),
+	})
+}
+
+// FromContext returns the IP and user agent of the request. They are
+// empty outside of a gRPC request.
+func FromContext(ctx context.Context) entity.Device {
+	device, _ := ctx.Value(ctxKey{}).(entity.Device)
+
+	return device
+}
+
+// Device describes the device of the request with the name and platform
+// reported by the app.
+func Device(ctx context.Context, name, platform string) entity.Device {
+	device := FromContext(ctx)
+	device.Name = truncate(strings.TrimSpace(name), maxDeviceNameLength)
+	device.Platform = truncate(strings.TrimSpace(platform), maxPlatformLength)
+
+	return device
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Cut on a rune boundary so the value stays valid UTF-8.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,22 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogSender writes emails to the log instead of sending them.
+// Used for local runs until a real mail provider is wired in.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(ctx context.Context, to, subject, body string) error {
+	s.log.Info("email sent", slog.String("to", to), slog.String("subject", subject), slog.String("body", body))
+
+	return nil
+}
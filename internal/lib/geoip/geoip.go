@@ -0,0 +1,93 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+type network struct {
+	prefix   netip.Prefix
+	location string
+}
+
+// Locator finds the approximate location of an IP address in a list of
+// networks loaded from a CSV file with "network,location" rows, e.g.
+//
+//	5.3.0.0/16,"Москва, Россия"
+//
+// Networks must not overlap. Lines starting with # are skipped.
+type Locator struct {
+	networks []network
+}
+
+// New loads the networks from file. With an empty file name the locator
+// knows no locations.
+func New(file string) (*Locator, error) {
+	const op = "geoip.New"
+
+	if file == "" {
+		return &Locator{}, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+
+	var networks []network
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		networks = append(networks, network{prefix: prefix.Masked(), location: strings.TrimSpace(record[1])})
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].prefix.Addr().Less(networks[j].prefix.Addr())
+	})
+
+	return &Locator{networks: networks}, nil
+}
+
+// Locate returns the location of the network ip belongs to, or an empty
+// string if there is none.
+func (l *Locator) Locate(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// The last network starting at or before addr is the only one that
+	// can contain it.
+	i := sort.Search(len(l.networks), func(i int) bool {
+		return addr.Less(l.networks[i].prefix.Addr())
+	}) - 1
+	if i < 0 || !l.networks[i].prefix.Contains(addr) {
+		return ""
+	}
+
+	return l.networks[i].location
+}
@@ -0,0 +1,143 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/crypto/bcrypt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBusy = errors.New("password hasher is busy")
+)
+
+var (
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "sso",
+		Subsystem: "password_hasher",
+		Name:      "queue_depth",
+		Help:      "Number of requests waiting for a free hashing slot.",
+	})
+	inFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "sso",
+		Subsystem: "password_hasher",
+		Name:      "in_flight",
+		Help:      "Number of bcrypt operations currently running.",
+	})
+	waitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "sso",
+		Subsystem: "password_hasher",
+		Name:      "wait_seconds",
+		Help:      "Time spent waiting for a free hashing slot.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	})
+	rejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "sso",
+		Subsystem: "password_hasher",
+		Name:      "rejected_total",
+		Help:      "Number of requests rejected because the queue timeout expired.",
+	})
+)
+
+// Hasher runs bcrypt on a bounded number of slots so that a burst of
+// logins can't take every core away from the rest of the service.
+type Hasher struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	cost         int
+
+	dummyOnce sync.Once
+	dummy     []byte
+}
+
+func New(workers int, queueTimeout time.Duration, cost int) *Hasher {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &Hasher{
+		slots:        make(chan struct{}, workers),
+		queueTimeout: queueTimeout,
+		cost:         cost,
+	}
+}
+
+func (h *Hasher) Generate(ctx context.Context, password []byte) ([]byte, error) {
+	const op = "hasher.Generate"
+
+	if err := h.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer h.release()
+
+	hash, err := bcrypt.GenerateFromPassword(password, h.cost)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, nil
+}
+
+// Compare returns bcrypt.ErrMismatchedHashAndPassword when the password
+// doesn't match, so callers can tell it apart from ErrBusy.
+func (h *Hasher) Compare(ctx context.Context, hash, password []byte) error {
+	const op = "hasher.Compare"
+
+	if err := h.acquire(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer h.release()
+
+	return bcrypt.CompareHashAndPassword(hash, password)
+}
+
+// Dummy returns a hash of a random password made with the same cost as
+// real hashes. Comparing against it when a user is unknown keeps the
+// response time the same as for a wrong password.
+func (h *Hasher) Dummy() []byte {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = bcrypt.GenerateFromPassword([]byte("vizap-sso-dummy-password"), h.cost)
+	})
+
+	return h.dummy
+}
+
+func (h *Hasher) acquire(ctx context.Context) error {
+	start := time.Now()
+
+	queueDepth.Inc()
+	defer queueDepth.Dec()
+
+	var timeout <-chan time.Time
+	if h.queueTimeout > 0 {
+		timer := time.NewTimer(h.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		waitSeconds.Observe(time.Since(start).Seconds())
+		inFlight.Inc()
+		return nil
+	case <-timeout:
+		rejected.Inc()
+		return ErrBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hasher) release() {
+	inFlight.Dec()
+	<-h.slots
+}
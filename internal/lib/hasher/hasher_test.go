@@ -0,0 +1,82 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func TestHasherCompare(t *testing.T) {
+	h := New(1, time.Second, bcrypt.MinCost)
+
+	hash, err := h.Generate(context.Background(), []byte("secret123"))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "match", password: "secret123"},
+		{name: "mismatch", password: "secret124", wantErr: bcrypt.ErrMismatchedHashAndPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := h.Compare(context.Background(), hash, []byte(tt.password))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Compare() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasherQueue(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueTimeout time.Duration
+		ctxTimeout   time.Duration
+		wantErr      error
+	}{
+		{name: "queue timeout", queueTimeout: 10 * time.Millisecond, ctxTimeout: time.Second, wantErr: ErrBusy},
+		{name: "caller deadline", queueTimeout: time.Second, ctxTimeout: 10 * time.Millisecond, wantErr: context.DeadlineExceeded},
+		{name: "no queue timeout", ctxTimeout: 10 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(1, tt.queueTimeout, bcrypt.MinCost)
+
+			// Take the only slot so the call has to wait.
+			if err := h.acquire(context.Background()); err != nil {
+				t.Fatalf("acquire: %v", err)
+			}
+			defer h.release()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.ctxTimeout)
+			defer cancel()
+
+			_, err := h.Generate(ctx, []byte("secret123"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasherDummy(t *testing.T) {
+	h := New(0, 0, bcrypt.MinCost)
+
+	dummy := h.Dummy()
+	if cost, err := bcrypt.Cost(dummy); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("Dummy() cost = %d, %v, want %d", cost, err, bcrypt.MinCost)
+	}
+
+	if err := h.Compare(context.Background(), dummy, []byte("secret123")); !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		t.Fatalf("Compare(dummy) = %v, want mismatch", err)
+	}
+}
@@ -1,30 +1,54 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
 	"github.com/golang-jwt/jwt/v5"
-	"log"
+	"strings"
 	"time"
-	"vizapSSO/internal/entity"
 )
 
-func NewAccessToken(user entity.User, app entity.App, duration time.Duration) (accessToken string, err error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+const tokenIDBytes = 16
+
+// NewAccessToken issues a token of the session sessionID, signed with the
+// signing key of keys. The sid claim lets the app tell which of the user's
+// sessions is the current one; jti and iat let services that check tokens
+// offline apply the revocation feed. roles are the user's roles in the app
+// and scope the app's scopes, space-separated as in OAuth 2.0; both are
+// left out when empty.
+func NewAccessToken(keys *Keys, user entity.User, app entity.App, sessionID int64, roles []string,
+	duration time.Duration) (accessToken string, err error) {
+	tokenID := make([]byte, tokenIDBytes)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["exp"] = time.Now().Add(duration).Unix()
-	claims["app_id"] = app.ID
+	now := time.Now()
 
-	accessToken, err = token.SignedString([]byte(app.Secret))
-	log.Println(app.Secret)
-	if err != nil {
-		return "", err
+	claims := jwt.MapClaims{
+		"uid":    user.ID,
+		"exp":    now.Add(duration).Unix(),
+		"iat":    now.Unix(),
+		"jti":    hex.EncodeToString(tokenID),
+		"app_id": app.ID,
+		"sid":    sessionID,
+	}
+
+	if len(roles) > 0 {
+		claims["roles"] = roles
+	}
+	if len(app.Scopes) > 0 {
+		claims["scope"] = strings.Join(app.Scopes, " ")
 	}
 
-	return accessToken, nil
+	return keys.sign(claims)
 }
 
+// NewRefreshToken issues the refresh token of a pair. Only the SSO checks
+// refresh tokens, so they stay signed with the secret key of the app.
 func NewRefreshToken(lastChar string, app entity.App, duration time.Duration) (refreshToken string, err error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
@@ -32,7 +56,7 @@ func NewRefreshToken(lastChar string, app entity.App, duration time.Duration) (r
 	claims["exp"] = time.Now().Add(duration).Unix()
 	claims["last_char"] = lastChar
 
-	refreshToken, err = token.SignedString([]byte(app.Secret))
+	refreshToken, err = token.SignedString([]byte(app.SigningKey))
 	if err != nil {
 		return "", err
 	}
@@ -40,43 +64,20 @@ func NewRefreshToken(lastChar string, app entity.App, duration time.Duration) (r
 	return refreshToken, nil
 }
 
-func ValidateToken(accessToken string) (isValid bool, uid int64, err error) {
-	//TODO: исправить данную реализацию
-	mySigningKey := "secret123"
-
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(mySigningKey), nil
-	})
-	if err != nil {
-		return false, 0, err
+// CheckRefreshToken verifies the refresh token with the key of the app,
+// or its retired key if it has one, and checks that it was issued together
+// with the access token.
+func CheckRefreshToken(refreshToken, accessToken string, app entity.App) error {
+	if len(accessToken) < 6 {
+		return fmt.Errorf("invalid token pair")
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		uid, ok := claims["uid"].(float64)
-		if !ok {
-			return false, 0, err
-		}
-		return true, int64(uid), nil
-	} else {
-		return false, 0, err
-	}
-}
-
-func CheckRefreshToken(refreshToken, accessToken string) error {
-	// replace this
-	mySigningKey := "secret123"
-
 	lastChar := accessToken[len(accessToken)-6:]
 
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(mySigningKey), nil
-	})
+	token, err := parseRefreshToken(refreshToken, app.SigningKey)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && app.RetiredSigningKey != "" {
+		token, err = parseRefreshToken(refreshToken, app.RetiredSigningKey)
+	}
 	if err != nil {
 		return err
 	}
@@ -84,7 +85,7 @@ func CheckRefreshToken(refreshToken, accessToken string) error {
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		forCheck, ok := claims["last_char"].(string)
 		if !ok {
-			return err
+			return fmt.Errorf("invalid token pair")
 		}
 		if lastChar != forCheck {
 			return fmt.Errorf("invalid token pair")
@@ -95,6 +96,15 @@ func CheckRefreshToken(refreshToken, accessToken string) error {
 	}
 }
 
+func parseRefreshToken(refreshToken, key string) (*jwt.Token, error) {
+	return jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(key), nil
+	})
+}
+
 func IdFromJWT(accessToken string) (uid int64, err error) {
 	token, _, err := new(jwt.Parser).ParseUnverified(accessToken, jwt.MapClaims{})
 	if err != nil {
@@ -111,3 +121,72 @@ func IdFromJWT(accessToken string) (uid int64, err error) {
 	}
 	return 0, fmt.Errorf("invalid JWT claims, unable to assert to MapClaims")
 }
+
+func AppIDFromJWT(accessToken string) (appID int32, err error) {
+	token, _, err := new(jwt.Parser).ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, fmt.Errorf("invalid JWT claims, unable to assert to MapClaims")
+	}
+
+	floatAppID, ok := claims["app_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("app_id must be a float64, got %T", claims["app_id"])
+	}
+
+	return int32(floatAppID), nil
+}
+
+// TokenIDFromJWT returns the jti of the access token without verifying it.
+// It is empty for tokens issued before jti was added.
+func TokenIDFromJWT(accessToken string) (tokenID string, err error) {
+	token, _, err := new(jwt.Parser).ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", fmt.Errorf("invalid JWT claims, unable to assert to MapClaims")
+	}
+
+	tokenID, _ = claims["jti"].(string)
+
+	return tokenID, nil
+}
+
+// ParseAccessToken verifies the token with the public key its kid names
+// and returns the user, session and app ids. The session id is zero for
+// tokens issued before sessions were recorded.
+func ParseAccessToken(accessToken string, keys *Keys) (uid, sessionID int64, appID int32, err error) {
+	token, err := jwt.Parse(accessToken, keys.verifyKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, 0, 0, fmt.Errorf("invalid token")
+	}
+
+	floatUID, ok := claims["uid"].(float64)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("uid must be a float64, got %T", claims["uid"])
+	}
+
+	floatAppID, ok := claims["app_id"].(float64)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("app_id must be a float64, got %T", claims["app_id"])
+	}
+
+	floatSID, _ := claims["sid"].(float64)
+
+	return int64(floatUID), int64(floatSID), int32(floatAppID), nil
+}
@@ -0,0 +1,217 @@
+package jwt
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/golang-jwt/jwt/v5"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAccessToken(t *testing.T) {
+	signing := testKey(t)
+	previous := testKey(t)
+	other := testKey(t)
+
+	keys := NewKeys(signing, publicOf(previous))
+	app := entity.App{ID: 3, SigningKey: "app-secret"}
+	user := entity.User{ID: 42}
+
+	issue := func(t *testing.T, keys *Keys, ttl time.Duration) string {
+		token, err := NewAccessToken(keys, user, app, 7, nil, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr bool
+	}{
+		{
+			name:  "current key",
+			token: func(t *testing.T) string { return issue(t, keys, time.Minute) },
+		},
+		{
+			name:  "previous key",
+			token: func(t *testing.T) string { return issue(t, NewKeys(previous), time.Minute) },
+		},
+		{
+			name:    "unknown key",
+			token:   func(t *testing.T) string { return issue(t, NewKeys(other), time.Minute) },
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   func(t *testing.T) string { return issue(t, keys, -time.Minute) },
+			wantErr: true,
+		},
+		{
+			name: "hmac with the app key",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"uid": 42, "app_id": 3, "exp": time.Now().Add(time.Minute).Unix(),
+				})
+				token.Header["kid"] = KeyID(publicOf(signing))
+				signed, err := token.SignedString([]byte(app.SigningKey))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			wantErr: true,
+		},
+		{
+			name: "hmac with the public key",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"uid": 42, "app_id": 3, "exp": time.Now().Add(time.Minute).Unix(),
+				})
+				token.Header["kid"] = KeyID(publicOf(signing))
+				signed, err := token.SignedString([]byte(publicOf(signing)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			wantErr: true,
+		},
+		{
+			name: "no exp",
+			token: func(t *testing.T) string {
+				signed, err := keys.sign(jwt.MapClaims{"uid": 42, "app_id": 3})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, sessionID, appID, err := ParseAccessToken(tt.token(t), keys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAccessToken() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && (uid != user.ID || sessionID != 7 || appID != app.ID) {
+				t.Errorf("ParseAccessToken() = %d, %d, %d, want %d, 7, %d", uid, sessionID, appID, user.ID, app.ID)
+			}
+		})
+	}
+}
+
+func TestNewAccessTokenClaims(t *testing.T) {
+	keys := NewKeys(testKey(t))
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		roles  []string
+		scopes []string
+		// wantRoles and wantScope are the claims; nil means no claim.
+		wantRoles any
+		wantScope any
+	}{
+		{name: "short", ttl: time.Minute},
+		{name: "long", ttl: time.Hour},
+		{
+			name:      "roles and scopes",
+			ttl:       time.Minute,
+			roles:     []string{"manager", "viewer"},
+			scopes:    []string{"orders:read", "orders:write"},
+			wantRoles: []any{"manager", "viewer"},
+			wantScope: "orders:read orders:write",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := entity.App{ID: 3, Scopes: tt.scopes}
+
+			accessToken, err := NewAccessToken(keys, entity.User{ID: 42}, app, 7, tt.roles, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := jwt.MapClaims{}
+			token, _, err := jwt.NewParser().ParseUnverified(accessToken, claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if token.Method != jwt.SigningMethodEdDSA || token.Header["kid"] != keys.kid {
+				t.Errorf("header = %v, want EdDSA with kid %s", token.Header, keys.kid)
+			}
+
+			exp, _ := claims.GetExpirationTime()
+			iat, _ := claims.GetIssuedAt()
+			if exp == nil || iat == nil || exp.Sub(iat.Time) != tt.ttl {
+				t.Errorf("claims = %v, want exp - iat = %v", claims, tt.ttl)
+			}
+
+			if jti, _ := claims["jti"].(string); len(jti) != 2*tokenIDBytes {
+				t.Errorf("jti = %q", jti)
+			}
+
+			if !reflect.DeepEqual(claims["roles"], tt.wantRoles) || !reflect.DeepEqual(claims["scope"], tt.wantScope) {
+				t.Errorf("roles = %v, scope = %v, want %v, %v", claims["roles"], claims["scope"], tt.wantRoles, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestCheckRefreshToken(t *testing.T) {
+	keys := NewKeys(testKey(t))
+	app := entity.App{ID: 3, SigningKey: "app-secret"}
+
+	accessToken, err := NewAccessToken(keys, entity.User{ID: 42}, app, 7, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAccess, err := NewAccessToken(keys, entity.User{ID: 42}, app, 7, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, err := NewRefreshToken(accessToken[len(accessToken)-6:], app, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		accessToken string
+		app         entity.App
+		wantErr     bool
+	}{
+		{name: "pair", accessToken: accessToken, app: app},
+		{name: "other access token", accessToken: otherAccess, app: app, wantErr: true},
+		{name: "other app key", accessToken: accessToken, app: entity.App{ID: 3, SigningKey: "other"}, wantErr: true},
+		{
+			name:        "retired app key",
+			accessToken: accessToken,
+			app:         entity.App{ID: 3, SigningKey: "other", RetiredSigningKey: "app-secret"},
+		},
+		{
+			name:        "other retired key",
+			accessToken: accessToken,
+			app:         entity.App{ID: 3, SigningKey: "other", RetiredSigningKey: "older"},
+			wantErr:     true,
+		},
+		{name: "short access token", accessToken: "abc", app: app, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRefreshToken(refreshToken, tt.accessToken, tt.app)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRefreshToken() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
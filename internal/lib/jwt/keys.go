@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+)
+
+// Keys are the Ed25519 keys of access tokens. The signing key signs new
+// tokens. It and the verify keys, earlier signing keys kept during a
+// rotation, check tokens and are published as a JWKS, so services verify
+// tokens with public keys only.
+type Keys struct {
+	signingKey ed25519.PrivateKey
+	kid        string
+	public     map[string]ed25519.PublicKey
+	jwks       []byte
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+func NewKeys(signingKey ed25519.PrivateKey, verifyKeys ...ed25519.PublicKey) *Keys {
+	k := &Keys{
+		signingKey: signingKey,
+		public:     make(map[string]ed25519.PublicKey),
+	}
+
+	publicKeys := append([]ed25519.PublicKey{signingKey.Public().(ed25519.PublicKey)}, verifyKeys...)
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	for _, key := range publicKeys {
+		kid := KeyID(key)
+		if _, ok := k.public[kid]; ok {
+			continue
+		}
+		k.public[kid] = key
+
+		set.Keys = append(set.Keys, jwk{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+
+	k.kid = KeyID(publicKeys[0])
+	// The set can't fail to marshal: it is strings only.
+	k.jwks, _ = json.Marshal(set)
+
+	return k
+}
+
+// LoadKeys reads the signing key and the verify keys from PEM files, as
+// written by "openssl genpkey -algorithm ed25519". A verify key file may
+// hold the private key or only the public one.
+func LoadKeys(signingKeyFile string, verifyKeyFiles []string) (*Keys, error) {
+	data, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+	}
+
+	var verifyKeys []ed25519.PublicKey
+
+	for _, file := range verifyKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		verifyKeys = append(verifyKeys, key)
+	}
+
+	return NewKeys(signingKey.(ed25519.PrivateKey), verifyKeys...), nil
+}
+
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		return key.(ed25519.PrivateKey).Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := jwt.ParseEdPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.New("expected an ed25519 private or public key")
+	}
+
+	return key.(ed25519.PublicKey), nil
+}
+
+// KeyID is the JWK thumbprint of the key (RFC 7638), used as its kid.
+func KeyID(key ed25519.PublicKey) string {
+	// The required members in lexicographic order, without whitespace.
+	members := `{"crv":"Ed25519","kty":"OKP","x":"` + base64.RawURLEncoding.EncodeToString(key) + `"}`
+
+	sum := sha256.Sum256([]byte(members))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKS returns the public keys as a JSON Web Key Set.
+func (k *Keys) JWKS() []byte {
+	return k.jwks
+}
+
+func (k *Keys) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = k.kid
+
+	return token.SignedString(k.signingKey)
+}
+
+// verifyKey is the key func of access tokens: the kid header picks one of
+// the public keys.
+func (k *Keys) verifyKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.public[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
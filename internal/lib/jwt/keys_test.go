@@ -0,0 +1,169 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyID(t *testing.T) {
+	tests := []struct {
+		name string
+		x    string
+		want string
+	}{
+		{
+			// RFC 8037, appendix A.3.
+			name: "rfc 8037 example",
+			x:    "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := base64.RawURLEncoding.DecodeString(tt.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := KeyID(ed25519.PublicKey(x)); got != tt.want {
+				t.Errorf("KeyID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	signing := testKey(t)
+	previous := testKey(t)
+
+	tests := []struct {
+		name       string
+		verifyKeys []ed25519.PublicKey
+		want       []ed25519.PublicKey
+	}{
+		{name: "signing key only", want: []ed25519.PublicKey{publicOf(signing)}},
+		{
+			name:       "during a rotation",
+			verifyKeys: []ed25519.PublicKey{publicOf(previous)},
+			want:       []ed25519.PublicKey{publicOf(signing), publicOf(previous)},
+		},
+		{
+			name:       "signing key listed again",
+			verifyKeys: []ed25519.PublicKey{publicOf(signing)},
+			want:       []ed25519.PublicKey{publicOf(signing)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var set struct {
+				Keys []jwk `json:"keys"`
+			}
+			if err := json.Unmarshal(NewKeys(signing, tt.verifyKeys...).JWKS(), &set); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(set.Keys) != len(tt.want) {
+				t.Fatalf("set has %d keys, want %d", len(set.Keys), len(tt.want))
+			}
+
+			for i, key := range set.Keys {
+				want := jwk{
+					Kty: "OKP",
+					Crv: "Ed25519",
+					X:   base64.RawURLEncoding.EncodeToString(tt.want[i]),
+					Kid: KeyID(tt.want[i]),
+					Use: "sig",
+					Alg: "EdDSA",
+				}
+				if key != want {
+					t.Errorf("key %d = %+v, want %+v", i, key, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	signing := testKey(t)
+	previous := testKey(t)
+
+	signingFile := writePEM(t, dir, "signing.key", "PRIVATE KEY", must(x509.MarshalPKCS8PrivateKey(signing)))
+	privateFile := writePEM(t, dir, "previous.key", "PRIVATE KEY", must(x509.MarshalPKCS8PrivateKey(previous)))
+	publicFile := writePEM(t, dir, "previous.pub", "PUBLIC KEY", must(x509.MarshalPKIXPublicKey(publicOf(previous))))
+	garbageFile := filepath.Join(dir, "garbage")
+	if err := os.WriteFile(garbageFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		signingFile string
+		verifyFiles []string
+		wantKeys    int
+		wantErr     bool
+	}{
+		{name: "signing key", signingFile: signingFile, wantKeys: 1},
+		{name: "previous private key", signingFile: signingFile, verifyFiles: []string{privateFile}, wantKeys: 2},
+		{name: "previous public key", signingFile: signingFile, verifyFiles: []string{publicFile}, wantKeys: 2},
+		{name: "missing signing key", signingFile: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "public signing key", signingFile: publicFile, wantErr: true},
+		{name: "bad verify key", signingFile: signingFile, verifyFiles: []string{garbageFile}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadKeys(tt.signingFile, tt.verifyFiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeys() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if err == nil && len(keys.public) != tt.wantKeys {
+				t.Errorf("loaded %d keys, want %d", len(keys.public), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func publicOf(key ed25519.PrivateKey) ed25519.PublicKey {
+	return key.Public().(ed25519.PublicKey)
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func must(der []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+
+	return der
+}
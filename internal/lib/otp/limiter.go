@@ -0,0 +1,72 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrTooManyCodes = errors.New("too many codes requested")
+
+// Purposes of codes. Each purpose has its own limit per target.
+const (
+	PurposeRegistration      = "registration"
+	PurposePhoneChange       = "phone_change"
+	PurposeEmailVerification = "email_verification"
+	PurposeAccountDeletion   = "account_deletion"
+	PurposePasswordReset     = "password_reset"
+)
+
+type SendStorage interface {
+	CodeSends(purpose, target string, since time.Time) (int, error)
+	// SaveCodeSend records a send and forgets the target's sends made
+	// before expired.
+	SaveCodeSend(purpose, target string, sentAt, expired time.Time) error
+}
+
+// Limiter caps how many messages go to one phone or email in a window, so
+// code requests can't be used to flood someone with SMS or to get a fresh
+// set of guesses over and over.
+type Limiter struct {
+	storage SendStorage
+	limit   int
+	window  time.Duration
+}
+
+// NewLimiter allows limit sends per target in window. A limit <= 0 turns
+// the check off.
+func NewLimiter(storage SendStorage, limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		storage: storage,
+		limit:   limit,
+		window:  window,
+	}
+}
+
+// Allow records a send to the target, or returns ErrTooManyCodes when the
+// target already got limit messages in the window.
+func (l *Limiter) Allow(purpose, target string) error {
+	const op = "otp.Allow"
+
+	if l.limit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	since := now.Add(-l.window)
+
+	sends, err := l.storage.CodeSends(purpose, target, since)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sends >= l.limit {
+		return ErrTooManyCodes
+	}
+
+	if err := l.storage.SaveCodeSend(purpose, target, now, since); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package otp
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, digits := range []int{4, 6, 8} {
+		code, err := Generate(digits)
+		if err != nil {
+			t.Fatalf("Generate(%d): %v", digits, err)
+		}
+		if len(code) != digits {
+			t.Fatalf("Generate(%d) = %q", digits, code)
+		}
+		if !Equal(code, Hash(code)) || Equal(code, Hash(code+"0")) {
+			t.Fatalf("Equal doesn't match the hash of %q", code)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		sends   []string
+		wantErr []error
+	}{
+		{
+			name:    "below the limit",
+			limit:   3,
+			sends:   []string{"+79991234567", "+79991234567", "+79991234567"},
+			wantErr: []error{nil, nil, nil},
+		},
+		{
+			name:    "over the limit",
+			limit:   2,
+			sends:   []string{"+79991234567", "+79991234567", "+79991234567"},
+			wantErr: []error{nil, nil, ErrTooManyCodes},
+		},
+		{
+			name:    "targets are counted apart",
+			limit:   1,
+			sends:   []string{"+79991234567", "a@example.com", "+79991234567"},
+			wantErr: []error{nil, nil, ErrTooManyCodes},
+		},
+		{
+			name:    "no limit",
+			limit:   0,
+			sends:   []string{"+79991234567", "+79991234567"},
+			wantErr: []error{nil, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(memory.New(), tt.limit, time.Hour)
+
+			for i, target := range tt.sends {
+				if err := l.Allow(PurposeRegistration, target); !errors.Is(err, tt.wantErr[i]) {
+					t.Fatalf("Allow #%d = %v, want %v", i+1, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterWindow(t *testing.T) {
+	st := memory.New()
+	l := NewLimiter(st, 1, time.Hour)
+
+	// A send from before the window doesn't count.
+	if err := st.SaveCodeSend(PurposePhoneChange, "+79991234567", time.Now().Add(-2*time.Hour), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Allow(PurposePhoneChange, "+79991234567"); err != nil {
+		t.Fatalf("Allow = %v", err)
+	}
+
+	if err := l.Allow(PurposeRegistration, "+79991234567"); err != nil {
+		t.Fatalf("Allow(other purpose) = %v", err)
+	}
+
+	if err := l.Allow(PurposePhoneChange, "+79991234567"); !errors.Is(err, ErrTooManyCodes) {
+		t.Fatalf("Allow = %v, want %v", err, ErrTooManyCodes)
+	}
+}
@@ -0,0 +1,54 @@
+package password
+
+import (
+	"errors"
+	"unicode"
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooLong  = errors.New("password is too long")
+	ErrTooWeak  = errors.New("password must contain letters and digits")
+)
+
+// bcrypt ignores everything after 72 bytes.
+const maxBcryptLength = 72
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+}
+
+func NewPolicy(minLength, maxLength int) Policy {
+	if maxLength <= 0 || maxLength > maxBcryptLength {
+		maxLength = maxBcryptLength
+	}
+
+	return Policy{MinLength: minLength, MaxLength: maxLength}
+}
+
+func (p Policy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return ErrTooShort
+	}
+
+	if len(password) > p.MaxLength {
+		return ErrTooLong
+	}
+
+	var hasLetter, hasDigit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLetter(c):
+			hasLetter = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		}
+	}
+
+	if !hasLetter || !hasDigit {
+		return ErrTooWeak
+	}
+
+	return nil
+}
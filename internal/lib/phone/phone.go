@@ -0,0 +1,190 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalid       = errors.New("invalid phone number")
+	ErrNotMobile     = errors.New("phone number is not mobile")
+	ErrUnknownRegion = errors.New("unknown phone region")
+	// ErrUnsupportedRegion is an ErrInvalid for numbers of countries we
+	// can't check when only mobile numbers are accepted.
+	ErrUnsupportedRegion = fmt.Errorf("%w: region is not supported", ErrInvalid)
+)
+
+const maxE164Digits = 15
+
+type region struct {
+	countryCode string
+	// trunkPrefix is dialled instead of the country code inside the country,
+	// e.g. 8 900 123-45-67 in Russia.
+	trunkPrefix string
+	// nsnLength is the length of the national significant number.
+	nsnLength int
+	// mobilePrefixes are prefixes of the national number used by mobile
+	// operators.
+	mobilePrefixes []string
+}
+
+var regions = map[string]region{
+	"RU": {countryCode: "7", trunkPrefix: "8", nsnLength: 10, mobilePrefixes: []string{"9"}},
+	"KZ": {countryCode: "7", trunkPrefix: "8", nsnLength: 10, mobilePrefixes: []string{"70", "74", "75", "76", "77"}},
+	"BY": {countryCode: "375", trunkPrefix: "80", nsnLength: 9, mobilePrefixes: []string{"25", "29", "33", "44"}},
+	"UZ": {countryCode: "998", nsnLength: 9, mobilePrefixes: []string{"20", "33", "50", "55", "77", "88", "90", "91", "93", "94", "95", "97", "98", "99"}},
+}
+
+// Normalizer turns user input into an E.164 number ("+79001234567").
+// Numbers without a country code are read in the default region.
+type Normalizer struct {
+	region     region
+	mobileOnly bool
+}
+
+func New(defaultRegion string, mobileOnly bool) (*Normalizer, error) {
+	r, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, defaultRegion)
+	}
+
+	return &Normalizer{region: r, mobileOnly: mobileOnly}, nil
+}
+
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+
+	international := strings.HasPrefix(s, "+")
+	if strings.HasPrefix(s, "00") {
+		international = true
+		s = s[2:]
+	}
+
+	var digits strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == '+' || c == ' ' || c == '-' || c == '(' || c == ')' || c == '.':
+		default:
+			return "", ErrInvalid
+		}
+	}
+
+	number := digits.String()
+	if number == "" {
+		return "", ErrInvalid
+	}
+
+	if !international {
+		number = n.toInternational(number)
+	}
+
+	if len(number) > maxE164Digits || number[0] == '0' {
+		return "", ErrInvalid
+	}
+
+	known := matchingRegions(number)
+
+	switch {
+	case len(known) > 0:
+		if err := n.check(number, known); err != nil {
+			return "", err
+		}
+	case !international:
+		// Neither a national number of the default region nor one with a
+		// known country code: most likely a typo, not a foreign number.
+		return "", ErrInvalid
+	case n.mobileOnly:
+		return "", ErrUnsupportedRegion
+	case len(number) < 8:
+		return "", ErrInvalid
+	}
+
+	return "+" + number, nil
+}
+
+// Variants returns the forms an E.164 number of the default region may
+// have been stored in before phones were normalized: without the plus,
+// with the trunk prefix and without the country code. Numbers stored with
+// spaces or dashes are only found after cmd/phones -apply.
+func (n *Normalizer) Variants(e164 string) []string {
+	number := strings.TrimPrefix(e164, "+")
+	variants := []string{number}
+
+	r := n.region
+	nsn, ok := strings.CutPrefix(number, r.countryCode)
+	if !ok || len(nsn) != r.nsnLength {
+		return variants
+	}
+
+	if r.trunkPrefix != "" {
+		variants = append(variants, r.trunkPrefix+nsn)
+	}
+
+	return append(variants, nsn)
+}
+
+func (n *Normalizer) toInternational(number string) string {
+	r := n.region
+
+	if r.trunkPrefix != "" && strings.HasPrefix(number, r.trunkPrefix) &&
+		len(number) == len(r.trunkPrefix)+r.nsnLength {
+		return r.countryCode + number[len(r.trunkPrefix):]
+	}
+
+	if len(number) == r.nsnLength {
+		return r.countryCode + number
+	}
+
+	// Already has the country code, just without "+".
+	return number
+}
+
+// check validates the number against every region sharing its country
+// code (RU and KZ both use +7).
+func (n *Normalizer) check(number string, known []region) error {
+	valid := false
+
+	for _, r := range known {
+		nsn := number[len(r.countryCode):]
+		if len(nsn) != r.nsnLength {
+			continue
+		}
+
+		valid = true
+
+		if !n.mobileOnly || hasAnyPrefix(nsn, r.mobilePrefixes) {
+			return nil
+		}
+	}
+
+	if !valid {
+		return ErrInvalid
+	}
+
+	return ErrNotMobile
+}
+
+func matchingRegions(number string) []region {
+	var known []region
+
+	for _, r := range regions {
+		if strings.HasPrefix(number, r.countryCode) {
+			known = append(known, r)
+		}
+	}
+
+	return known
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package phone
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		region     string
+		mobileOnly bool
+		raw        string
+		want       string
+		wantErr    error
+	}{
+		{name: "e164", region: "RU", raw: "+79001234567", want: "+79001234567"},
+		{name: "formatted", region: "RU", raw: " +7 (900) 123-45-67 ", want: "+79001234567"},
+		{name: "trunk prefix", region: "RU", raw: "8 900 123 45 67", want: "+79001234567"},
+		{name: "national number", region: "RU", raw: "9001234567", want: "+79001234567"},
+		{name: "country code without plus", region: "RU", raw: "79001234567", want: "+79001234567"},
+		{name: "00 prefix", region: "RU", raw: "0079001234567", want: "+79001234567"},
+		{name: "kazakhstan mobile", region: "RU", mobileOnly: true, raw: "+77011234567", want: "+77011234567"},
+		{name: "belarus trunk prefix", region: "BY", raw: "80291234567", want: "+375291234567"},
+		{name: "uzbekistan national", region: "UZ", raw: "901234567", want: "+998901234567"},
+		{name: "ru landline", region: "RU", raw: "+74951234567", want: "+74951234567"},
+		{name: "ru landline mobile only", region: "RU", mobileOnly: true, raw: "+74951234567", wantErr: ErrNotMobile},
+		{name: "short local typo", region: "RU", raw: "900123456", wantErr: ErrInvalid},
+		{name: "long local typo", region: "RU", raw: "900123456789", wantErr: ErrInvalid},
+		{name: "unknown country mobile only", region: "RU", mobileOnly: true, raw: "+900123456", wantErr: ErrUnsupportedRegion},
+		{name: "unknown country", region: "RU", raw: "+905321234567", want: "+905321234567"},
+		{name: "unknown country too short", region: "RU", raw: "+9001234", wantErr: ErrInvalid},
+		{name: "wrong length for the country", region: "RU", raw: "+7900123456", wantErr: ErrInvalid},
+		{name: "letters", region: "RU", raw: "+7900abc4567", wantErr: ErrInvalid},
+		{name: "empty", region: "RU", raw: " ", wantErr: ErrInvalid},
+		{name: "too long", region: "RU", raw: "+1234567890123456", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := New(tt.region, tt.mobileOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := n.Normalize(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Normalize(%q) error = %v, want %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedRegionIsInvalid(t *testing.T) {
+	if !errors.Is(ErrUnsupportedRegion, ErrInvalid) {
+		t.Fatal("ErrUnsupportedRegion is not ErrInvalid")
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		e164   string
+		want   []string
+	}{
+		{name: "ru", region: "RU", e164: "+79001234567", want: []string{"79001234567", "89001234567", "9001234567"}},
+		{name: "uz without trunk prefix", region: "UZ", e164: "+998901234567", want: []string{"998901234567", "901234567"}},
+		{name: "other region", region: "BY", e164: "+79001234567", want: []string{"79001234567"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := New(tt.region, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := n.Variants(tt.e164); !slices.Equal(got, tt.want) {
+				t.Fatalf("Variants(%q) = %v, want %v", tt.e164, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUnknownRegion(t *testing.T) {
+	if _, err := New("XX", false); !errors.Is(err, ErrUnknownRegion) {
+		t.Fatalf("New(XX) = %v, want %v", err, ErrUnknownRegion)
+	}
+}
@@ -0,0 +1,94 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaREST publishes events to a Kafka topic through a REST proxy, such
+// as the Confluent REST Proxy or the Redpanda HTTP Proxy. The user id is
+// the record key, so a user's events land in one partition. An event
+// counts as published once the proxy acknowledges it.
+type KafkaREST struct {
+	client   *http.Client
+	endpoint string
+}
+
+func NewKafkaREST(baseURL, topic string, timeout time.Duration) *KafkaREST {
+	return &KafkaREST{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: baseURL + "/topics/" + url.PathEscape(topic),
+	}
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string  `json:"key"`
+	Value Message `json:"value"`
+}
+
+// kafkaResponse reports per-record errors the proxy returns with 200 OK.
+type kafkaResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (p *KafkaREST) Publish(ctx context.Context, event entity.DomainEvent) error {
+	const op = "publisher.KafkaREST.Publish"
+
+	body, err := json.Marshal(kafkaRecords{
+		Records: []kafkaRecord{{Key: Key(event), Value: NewMessage(event)}},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: proxy answered %s: %s", op, resp.Status, bytes.TrimSpace(data))
+	}
+
+	var result kafkaResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("%s: record rejected: %d %s", op, *offset.ErrorCode, offset.Error)
+		}
+	}
+
+	return nil
+}
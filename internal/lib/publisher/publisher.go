@@ -0,0 +1,86 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message is the JSON body of a published event. Delivery is at least
+// once and in no particular order: consumers should skip ids they have
+// already seen and use OccurredAt, not arrival, to order a user's events.
+type Message struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	UserID     int64           `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func NewMessage(event entity.DomainEvent) Message {
+	return Message{
+		ID:         event.ID,
+		Type:       event.Type,
+		UserID:     event.UserID,
+		OccurredAt: event.CreatedAt.UTC(),
+		Payload:    event.Payload,
+	}
+}
+
+// Key is the partition key of the event, so all events of a user go to
+// one partition. It doesn't make them ordered: the relay may publish them
+// out of order and more than once.
+func Key(event entity.DomainEvent) string {
+	return strconv.FormatInt(event.UserID, 10)
+}
+
+// Log writes events to the log instead of publishing them, for local runs.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (p *Log) Publish(ctx context.Context, event entity.DomainEvent) error {
+	p.log.Info("domain event",
+		slog.Int64("id", event.ID),
+		slog.String("type", event.Type),
+		slog.Int64("uid", event.UserID),
+		slog.String("payload", string(event.Payload)),
+	)
+
+	return nil
+}
+
+// Memory keeps published events in memory, for local runs and tests.
+type Memory struct {
+	mu     sync.Mutex
+	events []entity.DomainEvent
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (p *Memory) Publish(ctx context.Context, event entity.DomainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+// Events returns the events published so far.
+func (p *Memory) Events() []entity.DomainEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]entity.DomainEvent(nil), p.events...)
+}
@@ -0,0 +1,121 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent() entity.DomainEvent {
+	return entity.DomainEvent{
+		ID:        7,
+		UserID:    42,
+		Type:      entity.UserRegistered,
+		Payload:   []byte(`{"user_id":42}`),
+		CreatedAt: time.Date(2026, 10, 1, 15, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		event entity.DomainEvent
+		want  string
+	}{
+		{
+			name:  "user registered",
+			event: testEvent(),
+			want:  `{"id":7,"type":"user.registered","user_id":42,"occurred_at":"2026-10-01T12:30:00Z","payload":{"user_id":42}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewMessage(tt.event))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("message = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogDoesNotLogPhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		event entity.DomainEvent
+	}{
+		{name: "user registered", event: testEvent()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewLog(slog.New(slog.NewTextHandler(&buf, nil)))
+
+			if err := p.Publish(context.Background(), tt.event); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(buf.String(), "phone") {
+				t.Errorf("log = %q, want no phone", buf.String())
+			}
+		})
+	}
+}
+
+func TestKafkaREST(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusOK, body: `{"offsets":[{"partition":0,"offset":1}]}`},
+		{name: "record rejected", status: http.StatusOK, body: `{"offsets":[{"error_code":50002,"error":"broker unavailable"}]}`, wantErr: true},
+		{name: "proxy error", status: http.StatusInternalServerError, body: `{"error_code":500}`, wantErr: true},
+		{name: "bad response", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got kafkaRecords
+			var path string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			p := NewKafkaREST(server.URL, "sso.events", time.Second)
+
+			err := p.Publish(context.Background(), testEvent())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if path != "/topics/sso.events" {
+				t.Errorf("path = %q", path)
+			}
+
+			if len(got.Records) != 1 || got.Records[0].Key != "42" || got.Records[0].Value.ID != 7 {
+				t.Errorf("records = %+v, want one record of event 7 keyed by the user id", got.Records)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package siem
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"strconv"
+	"time"
+)
+
+// Severity is a syslog severity level.
+type Severity int
+
+const (
+	SeverityWarning Severity = 4
+	SeverityNotice  Severity = 5
+	SeverityInfo    Severity = 6
+)
+
+// Event is one line sent to the SIEM.
+type Event struct {
+	// Name is "<type>.<outcome>" for security events, e.g. "login.failure",
+	// and the audit action for admin actions, e.g. "app.secret_create".
+	Name     string
+	Severity Severity
+	Time     time.Time
+	Outcome  string
+	// UserID is the account the event is about.
+	UserID int64
+	// Actor is who made an admin change.
+	Actor     string
+	AppID     int32
+	IP        string
+	UserAgent string
+	Reason    string
+	// Details is the JSON of an admin action.
+	Details string
+}
+
+// FromSecurityEvent converts an authentication event of a user.
+func FromSecurityEvent(event entity.SecurityEvent) Event {
+	severity := SeverityInfo
+	switch {
+	case event.Reason == "refresh_token_reuse", event.Type == entity.EventLockout:
+		severity = SeverityWarning
+	case event.Outcome == entity.OutcomeFailure:
+		severity = SeverityNotice
+	}
+
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var actor string
+	if event.UserID != 0 {
+		actor = "user:" + strconv.FormatInt(event.UserID, 10)
+	}
+
+	return Event{
+		Name:      event.Type + "." + event.Outcome,
+		Severity:  severity,
+		Time:      createdAt,
+		Outcome:   event.Outcome,
+		UserID:    event.UserID,
+		Actor:     actor,
+		AppID:     event.AppID,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Reason:    event.Reason,
+	}
+}
+
+// FromAuditRecord converts an admin or security-sensitive action.
+func FromAuditRecord(record entity.AuditRecord) Event {
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return Event{
+		Name:     record.Action,
+		Severity: SeverityNotice,
+		Time:     createdAt,
+		Outcome:  entity.OutcomeSuccess,
+		UserID:   record.TargetUserID,
+		Actor:    record.Actor,
+		Details:  string(record.Details),
+	}
+}
@@ -0,0 +1,94 @@
+package siem
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"testing"
+)
+
+func TestFromSecurityEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        entity.SecurityEvent
+		wantName     string
+		wantSeverity Severity
+		wantActor    string
+	}{
+		{
+			name:         "login success",
+			event:        entity.SecurityEvent{UserID: 42, Type: entity.EventLogin, Outcome: entity.OutcomeSuccess, CreatedAt: testTime},
+			wantName:     "login.success",
+			wantSeverity: SeverityInfo,
+			wantActor:    "user:42",
+		},
+		{
+			name:         "login with an unknown phone",
+			event:        entity.SecurityEvent{Type: entity.EventLogin, Outcome: entity.OutcomeFailure, Reason: "invalid_credentials"},
+			wantName:     "login.failure",
+			wantSeverity: SeverityNotice,
+		},
+		{
+			name:         "refresh token reuse",
+			event:        entity.SecurityEvent{UserID: 42, Type: entity.EventRefresh, Outcome: entity.OutcomeFailure, Reason: "refresh_token_reuse"},
+			wantName:     "refresh.failure",
+			wantSeverity: SeverityWarning,
+			wantActor:    "user:42",
+		},
+		{
+			name:         "lockout",
+			event:        entity.SecurityEvent{UserID: 42, Type: entity.EventLockout, Outcome: entity.OutcomeFailure, Reason: "too_many_attempts"},
+			wantName:     "lockout.failure",
+			wantSeverity: SeverityWarning,
+			wantActor:    "user:42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromSecurityEvent(tt.event)
+
+			if got.Name != tt.wantName || got.Severity != tt.wantSeverity || got.Actor != tt.wantActor {
+				t.Errorf("FromSecurityEvent() = %q, %d, %q, want %q, %d, %q",
+					got.Name, got.Severity, got.Actor, tt.wantName, tt.wantSeverity, tt.wantActor)
+			}
+
+			if got.Time.IsZero() {
+				t.Error("event has no time")
+			}
+		})
+	}
+}
+
+func TestFromAuditRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record entity.AuditRecord
+		want   Event
+	}{
+		{
+			name:   "admin action",
+			record: entity.AuditRecord{Actor: "ivanov", Action: "account.block", TargetUserID: 42, Details: []byte(`{"reason":"fraud"}`), CreatedAt: testTime},
+			want: Event{
+				Name:     "account.block",
+				Severity: SeverityNotice,
+				Time:     testTime,
+				Outcome:  entity.OutcomeSuccess,
+				UserID:   42,
+				Actor:    "ivanov",
+				Details:  `{"reason":"fraud"}`,
+			},
+		},
+		{
+			name:   "app action",
+			record: entity.AuditRecord{Actor: "ivanov", Action: "app.secret_create", CreatedAt: testTime},
+			want:   Event{Name: "app.secret_create", Severity: SeverityNotice, Time: testTime, Outcome: entity.OutcomeSuccess, Actor: "ivanov"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromAuditRecord(tt.record); got != tt.want {
+				t.Errorf("FromAuditRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package siem
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+
+func TestFormatSyslog(t *testing.T) {
+	header := "<85>1 2026-10-01T12:30:00Z sso-1 vizap-sso "
+
+	tests := []struct {
+		name     string
+		event    Event
+		hostname string
+		want     string
+	}{
+		{
+			name:     "login failure",
+			event:    Event{Name: "login.failure", Severity: SeverityNotice, Time: testTime, UserID: 42, IP: "10.0.0.1", Outcome: "failure", Reason: "invalid_credentials"},
+			hostname: "sso-1",
+			want: header + strconv.Itoa(pid) + ` login.failure [sso@32473 uid="42" ip="10.0.0.1" outcome="failure" reason="invalid_credentials"]` +
+				" login.failure: invalid_credentials",
+		},
+		{
+			name:     "escaped details",
+			event:    Event{Name: "app.update", Severity: SeverityNotice, Time: testTime, Actor: "ivanov", Details: `{"name":"a]b\c"}`},
+			hostname: "sso-1",
+			want:     header + strconv.Itoa(pid) + ` app.update [sso@32473 actor="ivanov" details="{\"name\":\"a\]b\\c\"}"] app.update`,
+		},
+		{
+			name:     "no hostname",
+			event:    Event{Name: "login.success", Severity: SeverityInfo, Time: testTime},
+			hostname: "",
+			want:     "<86>1 2026-10-01T12:30:00Z - vizap-sso " + strconv.Itoa(pid) + " login.success [sso@32473] login.success",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(formatSyslog(tt.event, tt.hostname, appName)); got != tt.want {
+				t.Errorf("formatSyslog() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "login failure",
+			event: Event{Name: "login.failure", Severity: SeverityNotice, Time: testTime, UserID: 42, AppID: 3, IP: "10.0.0.1", Outcome: "failure", Reason: "invalid_credentials"},
+			want: "CEF:0|Vizap|SSO|1.0|login.failure|login.failure: invalid_credentials|5|" +
+				"rt=1790857800000 duid=42 src=10.0.0.1 outcome=failure reason=invalid_credentials cs1Label=app_id cs1=3",
+		},
+		{
+			name:  "refresh token reuse",
+			event: Event{Name: "refresh.failure", Severity: SeverityWarning, Time: testTime, Reason: "refresh_token_reuse"},
+			want:  "CEF:0|Vizap|SSO|1.0|refresh.failure|refresh.failure: refresh_token_reuse|7|rt=1790857800000 reason=refresh_token_reuse",
+		},
+		{
+			name:  "escaped header and extension",
+			event: Event{Name: "app|update", Severity: SeverityInfo, Time: testTime, UserAgent: "a=b\nc", Details: `x\y`},
+			want:  `CEF:0|Vizap|SSO|1.0|app\|update|app\|update|3|rt=1790857800000 requestClientApplication=a\=b\nc msg=x\\y`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(formatCEF(tt.event)); got != tt.want {
+				t.Errorf("formatCEF() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderField(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{name: "plain", s: "sso-1", max: 255, want: "sso-1"},
+		{name: "empty", s: "", max: 255, want: "-"},
+		{name: "spaces and non-ascii", s: "вход ok", max: 255, want: "_____ok"},
+		{name: "too long", s: strings.Repeat("a", 40), max: 32, want: strings.Repeat("a", 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headerField(tt.s, tt.max); got != tt.want {
+				t.Errorf("headerField(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package siem
+
+import "github.com/KVSH-user/vizapSSO/internal/entity"
+
+type SecurityEventSaver interface {
+	SaveSecurityEvent(event entity.SecurityEvent) error
+}
+
+type AuditSaver interface {
+	SaveAuditRecord(record entity.AuditRecord) error
+}
+
+// Recorder saves security events and audit records and then passes the
+// saved ones to the exporter. With a nil exporter it only saves them.
+//
+// Security events of unknown accounts, such as logins with a wrong phone,
+// are only exported: there is nobody to show them to.
+type Recorder struct {
+	events   SecurityEventSaver
+	audit    AuditSaver
+	exporter *Exporter
+}
+
+func NewRecorder(events SecurityEventSaver, audit AuditSaver, exporter *Exporter) *Recorder {
+	return &Recorder{
+		events:   events,
+		audit:    audit,
+		exporter: exporter,
+	}
+}
+
+func (r *Recorder) SaveSecurityEvent(event entity.SecurityEvent) error {
+	if event.UserID != 0 {
+		if err := r.events.SaveSecurityEvent(event); err != nil {
+			return err
+		}
+	}
+
+	if r.exporter != nil {
+		r.exporter.Publish(FromSecurityEvent(event))
+	}
+
+	return nil
+}
+
+func (r *Recorder) SaveAuditRecord(record entity.AuditRecord) error {
+	if err := r.audit.SaveAuditRecord(record); err != nil {
+		return err
+	}
+
+	if r.exporter != nil {
+		r.exporter.Publish(FromAuditRecord(record))
+	}
+
+	return nil
+}
+
+// ExportAuditRecord passes a record that was saved together with the
+// action it describes to the exporter.
+func (r *Recorder) ExportAuditRecord(record entity.AuditRecord) {
+	if r.exporter != nil {
+		r.exporter.Publish(FromAuditRecord(record))
+	}
+}
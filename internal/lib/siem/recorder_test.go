@@ -0,0 +1,106 @@
+package siem
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"testing"
+)
+
+type testSaver struct {
+	err     error
+	events  []entity.SecurityEvent
+	records []entity.AuditRecord
+}
+
+func (s *testSaver) SaveSecurityEvent(event entity.SecurityEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *testSaver) SaveAuditRecord(record entity.AuditRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestRecorderSecurityEvent(t *testing.T) {
+	errSave := errors.New("storage is down")
+
+	tests := []struct {
+		name         string
+		userID       int64
+		saveErr      error
+		wantSaved    int
+		wantExported int
+	}{
+		{name: "known account", userID: 42, wantSaved: 1, wantExported: 1},
+		{name: "unknown account is only exported", wantExported: 1},
+		{name: "failed save is not exported", userID: 42, saveErr: errSave},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saver := &testSaver{err: tt.saveErr}
+			exporter := &Exporter{events: make(chan Event, 8)}
+			r := NewRecorder(saver, saver, exporter)
+
+			err := r.SaveSecurityEvent(entity.SecurityEvent{UserID: tt.userID, Type: entity.EventLogin, Outcome: entity.OutcomeFailure})
+			if !errors.Is(err, tt.saveErr) {
+				t.Fatalf("SaveSecurityEvent() = %v, want %v", err, tt.saveErr)
+			}
+
+			if len(saver.events) != tt.wantSaved || len(exporter.events) != tt.wantExported {
+				t.Errorf("saved %d, exported %d, want %d, %d", len(saver.events), len(exporter.events), tt.wantSaved, tt.wantExported)
+			}
+		})
+	}
+}
+
+func TestRecorderAuditRecord(t *testing.T) {
+	errSave := errors.New("storage is down")
+
+	tests := []struct {
+		name         string
+		saveErr      error
+		exporter     bool
+		wantSaved    int
+		wantExported int
+	}{
+		{name: "saved and exported", exporter: true, wantSaved: 1, wantExported: 1},
+		{name: "no exporter", wantSaved: 1},
+		{name: "failed save is not exported", saveErr: errSave, exporter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saver := &testSaver{err: tt.saveErr}
+
+			var exporter *Exporter
+			if tt.exporter {
+				exporter = &Exporter{events: make(chan Event, 8)}
+			}
+			r := NewRecorder(saver, saver, exporter)
+
+			err := r.SaveAuditRecord(entity.AuditRecord{Actor: "ivanov", Action: "account.block", TargetUserID: 42})
+			if !errors.Is(err, tt.saveErr) {
+				t.Fatalf("SaveAuditRecord() = %v, want %v", err, tt.saveErr)
+			}
+
+			exported := 0
+			if exporter != nil {
+				exported = len(exporter.events)
+			}
+
+			if len(saver.records) != tt.wantSaved || exported != tt.wantExported {
+				t.Errorf("saved %d, exported %d, want %d, %d", len(saver.records), exported, tt.wantSaved, tt.wantExported)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package siem
+
+import (
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	appName    = "vizap-sso"
+	retryDelay = 5 * time.Second
+)
+
+var pid = os.Getpid()
+
+var (
+	sent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "sso",
+		Subsystem: "siem",
+		Name:      "sent_total",
+		Help:      "Number of events delivered to the SIEM.",
+	})
+	dropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "sso",
+		Subsystem: "siem",
+		Name:      "dropped_total",
+		Help:      "Number of events dropped because the buffer was full.",
+	})
+	failed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "sso",
+		Subsystem: "siem",
+		Name:      "failed_total",
+		Help:      "Number of failed attempts to deliver an event.",
+	})
+)
+
+// Exporter streams events to a SIEM in the background. Publish never
+// blocks: when the collector is down and the buffer fills up, new events
+// are dropped and counted.
+type Exporter struct {
+	log      *slog.Logger
+	sink     sink
+	format   func(Event) []byte
+	patterns []string
+	events   chan Event
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(log *slog.Logger, cfg config.SIEMConfig) (*Exporter, error) {
+	const op = "siem.New"
+
+	for _, pattern := range cfg.Events {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: bad event pattern %q: %w", op, pattern, err)
+		}
+	}
+
+	e := &Exporter{
+		log:      log,
+		patterns: cfg.Events,
+		events:   make(chan Event, cfg.BufferSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	switch cfg.Format {
+	case "syslog":
+		hostname, _ := os.Hostname()
+		e.format = func(event Event) []byte {
+			return formatSyslog(event, hostname, appName)
+		}
+	case "cef":
+		e.format = formatCEF
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q", op, cfg.Format)
+	}
+
+	var err error
+	if cfg.Output == "file" {
+		e.sink, err = newFileSink(cfg.File)
+	} else {
+		e.sink, err = newNetSink(cfg.Output, cfg.Address, cfg.CAFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return e, nil
+}
+
+// Publish queues the event if it passes the filter.
+func (e *Exporter) Publish(event Event) {
+	if !e.match(event.Name) {
+		return
+	}
+
+	select {
+	case e.events <- event:
+	default:
+		dropped.Inc()
+	}
+}
+
+// match reports whether the event name matches one of the patterns, e.g.
+// "login.failure", "*.failure" or "app.*". No patterns match everything.
+func (e *Exporter) match(name string) bool {
+	if len(e.patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range e.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *Exporter) Run() {
+	const op = "siem.Run"
+
+	log := e.log.With(slog.String("op", op))
+
+	log.Info("starting SIEM exporter")
+
+	defer close(e.done)
+
+	for {
+		select {
+		case event := <-e.events:
+			e.deliver(log, event)
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// deliver retries the event until it is sent or the exporter stops.
+// Events published meanwhile wait in the buffer.
+func (e *Exporter) deliver(log *slog.Logger, event Event) {
+	msg := e.format(event)
+
+	for {
+		err := e.sink.Write(msg)
+		if err == nil {
+			sent.Inc()
+			return
+		}
+
+		failed.Inc()
+		log.Warn("failed to send event to SIEM", slog.String("event", event.Name), sl.Err(err))
+
+		select {
+		case <-time.After(retryDelay):
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *Exporter) Stop() {
+	const op = "siem.Stop"
+
+	close(e.stop)
+	<-e.done
+
+	if err := e.sink.Close(); err != nil {
+		e.log.Error("failed to close SIEM output", slog.String("op", op), sl.Err(err))
+	}
+
+	e.log.Info("SIEM exporter stopped", slog.String("op", op), slog.Int("pending", len(e.events)))
+}
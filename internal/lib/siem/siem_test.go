@@ -0,0 +1,141 @@
+package siem
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "siem.log")
+
+	tests := []struct {
+		name    string
+		cfg     config.SIEMConfig
+		wantErr bool
+	}{
+		{name: "cef to file", cfg: config.SIEMConfig{Format: "cef", Output: "file", File: file}},
+		{name: "syslog over udp", cfg: config.SIEMConfig{Format: "syslog", Output: "udp", Address: "127.0.0.1:514"}},
+		{name: "unknown format", cfg: config.SIEMConfig{Format: "leef", Output: "file", File: file}, wantErr: true},
+		{name: "bad pattern", cfg: config.SIEMConfig{Format: "cef", Output: "file", File: file, Events: []string{"login.["}}, wantErr: true},
+		{name: "missing ca file", cfg: config.SIEMConfig{Format: "syslog", Output: "tls", CAFile: "/nonexistent/ca.pem"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(testLogger(), tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if e != nil {
+				e.sink.Close()
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		event    string
+		want     bool
+	}{
+		{name: "no patterns", event: "login.success", want: true},
+		{name: "exact", patterns: []string{"login.failure"}, event: "login.failure", want: true},
+		{name: "any failure", patterns: []string{"*.failure"}, event: "refresh.failure", want: true},
+		{name: "success is not a failure", patterns: []string{"*.failure"}, event: "login.success"},
+		{name: "admin actions on apps", patterns: []string{"login.failure", "app.*"}, event: "app.secret_create", want: true},
+		{name: "other admin action", patterns: []string{"app.*"}, event: "account.block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Exporter{patterns: tt.patterns}
+
+			if got := e.match(tt.event); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		bufferSize int
+		published  []string
+		wantQueued int
+	}{
+		{name: "room left", bufferSize: 3, published: []string{"login.failure", "app.update"}, wantQueued: 2},
+		{name: "full buffer drops", bufferSize: 2, published: []string{"login.failure", "app.update", "app.delete"}, wantQueued: 2},
+		{name: "filtered out", bufferSize: 2, published: []string{"login.success"}, wantQueued: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Exporter{
+				patterns: []string{"*.failure", "app.*"},
+				events:   make(chan Event, tt.bufferSize),
+			}
+
+			for _, name := range tt.published {
+				e.Publish(Event{Name: name})
+			}
+
+			if got := len(e.events); got != tt.wantQueued {
+				t.Errorf("queued %d events, want %d", got, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestExporterWritesFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "cef", format: "cef", want: "CEF:0|Vizap|SSO|1.0|login.failure|"},
+		{name: "syslog", format: "syslog", want: "<85>1 2026-10-01T12:30:00Z "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "siem.log")
+
+			e, err := New(testLogger(), config.SIEMConfig{Format: tt.format, Output: "file", File: file, BufferSize: 8})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			go e.Run()
+
+			e.Publish(Event{Name: "login.failure", Severity: SeverityNotice, Time: testTime, UserID: 42})
+
+			var data []byte
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				if data, err = os.ReadFile(file); err == nil && len(data) > 0 {
+					break
+				}
+			}
+
+			e.Stop()
+
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != 1 || !strings.HasPrefix(lines[0], tt.want) {
+				t.Errorf("file = %q, want one line starting with %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,133 @@
+package siem
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const dialTimeout = 5 * time.Second
+
+// sink delivers formatted messages. It is used from one goroutine.
+type sink interface {
+	Write(msg []byte) error
+	Close() error
+}
+
+// netSink sends syslog messages over UDP, TCP or TLS. The connection is
+// opened on the first write and again after an error.
+type netSink struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	conn      net.Conn
+}
+
+func newNetSink(network, address, caFile string) (*netSink, error) {
+	s := &netSink{network: network, address: address}
+
+	if network == "tls" {
+		s.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if caFile != "" {
+			ca, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("no certificates found in CA file")
+			}
+			s.tlsConfig.RootCAs = pool
+		}
+	}
+
+	return s, nil
+}
+
+func (s *netSink) Write(msg []byte) error {
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return err
+		}
+	}
+
+	// Stream transports need framing; RFC 6587 octet counting works for
+	// both plain TCP and TLS (RFC 5425).
+	if s.network != "udp" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(dialTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+func (s *netSink) dial() error {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	var conn net.Conn
+	var err error
+
+	switch s.network {
+	case "udp", "tcp":
+		conn, err = dialer.Dial(s.network, s.address)
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	default:
+		err = fmt.Errorf("unknown network %q", s.network)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+
+	return nil
+}
+
+func (s *netSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
+
+// fileSink appends messages as lines to a file.
+type fileSink struct {
+	f *os.File
+}
+
+func newFileSink(file string) (*fileSink, error) {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSink{f: f}, nil
+}
+
+func (s *fileSink) Write(msg []byte) error {
+	_, err := s.f.Write(append(msg, '\n'))
+
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
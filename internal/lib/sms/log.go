@@ -0,0 +1,22 @@
+package sms
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogSender writes messages to the log instead of sending them.
+// Used for local runs until a real SMS gateway is wired in.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(ctx context.Context, phone, text string) error {
+	s.log.Info("sms sent", slog.String("phone", phone), slog.String("text", text))
+
+	return nil
+}
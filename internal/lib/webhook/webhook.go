@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a delivery. The signature covers the timestamp and the body,
+// so a receiver that rejects old timestamps is safe from replayed requests.
+const (
+	HeaderEvent     = "X-SSO-Event"
+	HeaderDelivery  = "X-SSO-Delivery"
+	HeaderTimestamp = "X-SSO-Timestamp"
+	HeaderSignature = "X-SSO-Signature"
+
+	signaturePrefix = "v1="
+	maxErrorLength  = 512
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is too old")
+)
+
+// Sign returns the signature header value: v1= and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" with the webhook secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the headers of a delivery as a receiver would. Requests
+// whose timestamp is more than tolerance away from now are rejected.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleTimestamp
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Sender posts deliveries to webhook endpoints. Redirects are not
+// followed: the endpoint must answer 2xx itself.
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send makes one attempt of the delivery. It returns the response status,
+// zero when there was none, and an error unless the status is 2xx.
+func (s *Sender) Send(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery,
+) (statusCode int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vizapSSO-Webhook/1")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The limit can split a rune, and the body needn't be text at all.
+		body := strings.ToValidUTF8(strings.TrimSpace(string(data)), "\uFFFD")
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s: %s", resp.Status, body)
+	}
+
+	return resp.StatusCode, nil
+}
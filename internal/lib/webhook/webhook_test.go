@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSendErrorBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "text", body: "maintenance"},
+		// 2-byte runes with an odd prefix put the limit inside a rune.
+		{name: "cut inside a rune", body: "x" + strings.Repeat("ж", maxErrorLength)},
+		{name: "not text", body: "\xff\xfe\x00binary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			code, err := NewSender(time.Second).Send(context.Background(),
+				entity.Webhook{URL: srv.URL, Secret: "secret"}, entity.WebhookDelivery{ID: 1, Body: []byte(`{}`)})
+			if code != http.StatusServiceUnavailable || err == nil {
+				t.Fatalf("Send() = %d, %v, want 503 and an error", code, err)
+			}
+			if !utf8.ValidString(err.Error()) {
+				t.Errorf("error %q is not valid UTF-8", err)
+			}
+		})
+	}
+}
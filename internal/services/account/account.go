@@ -0,0 +1,162 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	ErrTransitionNotAllowed = errors.New("account status transition is not allowed")
+	ErrReasonRequired       = errors.New("reason is required")
+	ErrActorRequired        = errors.New("actor is required")
+	ErrInvalidUntil         = errors.New("until must be in the future and is only allowed for locks and suspensions")
+)
+
+const actionStatusChange = "account.status_change"
+
+// transitions lists the statuses each status can be changed to. Deleted
+// accounts can only be restored while they are in the grace period.
+var transitions = map[entity.AccountStatus][]entity.AccountStatus{
+	entity.AccountPending:   {entity.AccountActive, entity.AccountDeleted},
+	entity.AccountActive:    {entity.AccountLocked, entity.AccountSuspended, entity.AccountDeleted},
+	entity.AccountLocked:    {entity.AccountActive, entity.AccountSuspended, entity.AccountDeleted},
+	entity.AccountSuspended: {entity.AccountActive, entity.AccountDeleted},
+	entity.AccountDeleted:   {entity.AccountActive},
+}
+
+type Account struct {
+	log           *slog.Logger
+	userProvider  UserProvider
+	stateStorage  StateStorage
+	auditExporter AuditExporter
+	accountCfg    config.AccountConfig
+}
+
+type UserProvider interface {
+	UserByID(uid int64) (entity.User, error)
+}
+
+// StateStorage saves the audit record in the same transaction as the
+// status change.
+type StateStorage interface {
+	SetAccountState(uid int64, from entity.AccountStatus, state entity.AccountState, audit *entity.AuditRecord) error
+	MarkUserDeleted(uid int64, changedBy, reason string, purgeAfter time.Time, audit *entity.AuditRecord) error
+	RestoreUser(uid int64, changedBy, reason string, audit *entity.AuditRecord) error
+}
+
+// AuditExporter passes audit records to the SIEM once the storage has
+// saved them together with the change.
+type AuditExporter interface {
+	ExportAuditRecord(record entity.AuditRecord)
+}
+
+func New(log *slog.Logger,
+	userProvider UserProvider,
+	stateStorage StateStorage,
+	auditExporter AuditExporter,
+	accountCfg config.AccountConfig) *Account {
+	return &Account{
+		log:           log,
+		userProvider:  userProvider,
+		stateStorage:  stateStorage,
+		auditExporter: auditExporter,
+		accountCfg:    accountCfg,
+	}
+}
+
+// ChangeStatus moves the account to another status on behalf of staff.
+// Deleting starts the usual grace period, and activating a deleted account
+// restores it. Every change is written to the audit log.
+func (a *Account) ChangeStatus(ctx context.Context, actor string, uid int64, to entity.AccountStatus,
+	reason string, until time.Time) error {
+	const op = "account.ChangeStatus"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", uid), slog.String("actor", actor))
+
+	if actor == "" {
+		return fmt.Errorf("%s: %w", op, ErrActorRequired)
+	}
+
+	if reason == "" {
+		return fmt.Errorf("%s: %w", op, ErrReasonRequired)
+	}
+
+	now := time.Now()
+
+	if !until.IsZero() && (to != entity.AccountLocked && to != entity.AccountSuspended || !until.After(now)) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidUntil)
+	}
+
+	user, err := a.userProvider.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	from := accountstate.Effective(user.State, now)
+	if !slices.Contains(transitions[from], to) {
+		return fmt.Errorf("%s: %s -> %s: %w", op, from, to, ErrTransitionNotAllowed)
+	}
+
+	record, err := auditRecord(actor, uid, from, to, reason, until)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case to == entity.AccountDeleted:
+		err = a.stateStorage.MarkUserDeleted(uid, actor, reason, now.Add(a.accountCfg.DeletionGracePeriod), &record)
+	case from == entity.AccountDeleted:
+		err = a.stateStorage.RestoreUser(uid, actor, reason, &record)
+	default:
+		err = a.stateStorage.SetAccountState(uid, user.State.Status, entity.AccountState{
+			Status:    to,
+			Reason:    reason,
+			ChangedBy: actor,
+			Until:     until,
+		}, &record)
+	}
+	if err != nil {
+		log.Error("failed to change account status", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.auditExporter.ExportAuditRecord(record)
+
+	log.Info("account status changed", slog.String("from", string(from)), slog.String("to", string(to)))
+
+	return nil
+}
+
+// auditRecord builds the record the storage saves in the same transaction
+// as the status change.
+func auditRecord(actor string, uid int64, from, to entity.AccountStatus, reason string, until time.Time,
+) (entity.AuditRecord, error) {
+	details := map[string]string{
+		"from":   string(from),
+		"to":     string(to),
+		"reason": reason,
+	}
+	if !until.IsZero() {
+		details["until"] = until.UTC().Format(time.RFC3339)
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return entity.AuditRecord{}, err
+	}
+
+	return entity.AuditRecord{
+		Actor:        actor,
+		Action:       actionStatusChange,
+		TargetUserID: uid,
+		Details:      data,
+	}, nil
+}
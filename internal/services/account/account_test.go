@@ -0,0 +1,128 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage/memory"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type testExporter struct {
+	records []entity.AuditRecord
+}
+
+func (e *testExporter) ExportAuditRecord(record entity.AuditRecord) {
+	e.records = append(e.records, record)
+}
+
+func TestChangeStatus(t *testing.T) {
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		from    entity.AccountStatus
+		to      entity.AccountStatus
+		actor   string
+		reason  string
+		until   time.Time
+		wantErr error
+	}{
+		{name: "lock", from: entity.AccountActive, to: entity.AccountLocked, actor: "admin", reason: "fraud"},
+		{name: "lock for an hour", from: entity.AccountActive, to: entity.AccountLocked, actor: "admin", reason: "fraud", until: later},
+		{name: "suspend a locked one", from: entity.AccountLocked, to: entity.AccountSuspended, actor: "admin", reason: "fraud"},
+		{name: "unlock", from: entity.AccountLocked, to: entity.AccountActive, actor: "admin", reason: "checked"},
+		{name: "delete", from: entity.AccountActive, to: entity.AccountDeleted, actor: "admin", reason: "request"},
+		{name: "restore", from: entity.AccountDeleted, to: entity.AccountActive, actor: "admin", reason: "mistake"},
+		{
+			name: "suspended can't be locked", from: entity.AccountSuspended, to: entity.AccountLocked,
+			actor: "admin", reason: "fraud", wantErr: ErrTransitionNotAllowed,
+		},
+		{
+			name: "deleted can't be suspended", from: entity.AccountDeleted, to: entity.AccountSuspended,
+			actor: "admin", reason: "fraud", wantErr: ErrTransitionNotAllowed,
+		},
+		{
+			name: "active to active", from: entity.AccountActive, to: entity.AccountActive,
+			actor: "admin", reason: "noop", wantErr: ErrTransitionNotAllowed,
+		},
+		{name: "no actor", from: entity.AccountActive, to: entity.AccountLocked, reason: "fraud", wantErr: ErrActorRequired},
+		{name: "no reason", from: entity.AccountActive, to: entity.AccountLocked, actor: "admin", wantErr: ErrReasonRequired},
+		{
+			name: "until on delete", from: entity.AccountActive, to: entity.AccountDeleted,
+			actor: "admin", reason: "request", until: later, wantErr: ErrInvalidUntil,
+		},
+		{
+			name: "until in the past", from: entity.AccountActive, to: entity.AccountLocked,
+			actor: "admin", reason: "fraud", until: time.Now().Add(-time.Hour), wantErr: ErrInvalidUntil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := memory.New()
+			exporter := &testExporter{}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, st, st, exporter, config.AccountConfig{DeletionGracePeriod: 720 * time.Hour})
+
+			uid, err := st.SaveUser("+79991234567", []byte("hash"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch tt.from {
+			case entity.AccountActive:
+			case entity.AccountDeleted:
+				err = st.MarkUserDeleted(uid, "user", "test", time.Now().Add(time.Hour), nil)
+			default:
+				err = st.SetAccountState(uid, entity.AccountActive, entity.AccountState{Status: tt.from}, nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = a.ChangeStatus(ctx, tt.actor, uid, tt.to, tt.reason, tt.until)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeStatus() = %v, want %v", err, tt.wantErr)
+			}
+
+			user, err := st.UserByID(uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := tt.to
+			wantAudit := 1
+			if tt.wantErr != nil {
+				want = tt.from
+				wantAudit = 0
+			}
+
+			if user.State.Status != want {
+				t.Errorf("status = %s, want %s", user.State.Status, want)
+			}
+
+			saved, err := st.AuditRecords(0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(saved) != wantAudit {
+				t.Fatalf("saved audit records = %d, want %d", len(saved), wantAudit)
+			}
+
+			if len(exporter.records) != wantAudit {
+				t.Fatalf("exported audit records = %d, want %d", len(exporter.records), wantAudit)
+			}
+
+			if wantAudit == 1 && (saved[0].Actor != tt.actor || saved[0].TargetUserID != uid) {
+				t.Errorf("audit record = %+v", saved[0])
+			}
+		})
+	}
+}
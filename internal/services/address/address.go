@@ -0,0 +1,217 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrInvalidLocation  = errors.New("invalid geo coordinates")
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrLimitReached     = errors.New("address limit reached")
+)
+
+const (
+	maxFullAddressLength = 1000
+	maxPartLength        = 255
+	maxShortPartLength   = 64
+)
+
+type Address struct {
+	log             *slog.Logger
+	authorizer      Authorizer
+	addressProvider AddressProvider
+	addressSaver    AddressSaver
+	maxPerUser      int
+	defaultPageSize int
+	maxPageSize     int
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, accessToken string) (uid int64, err error)
+}
+
+type AddressProvider interface {
+	Addresses(uid, afterID int64, limit int) ([]entity.Address, error)
+	CountAddresses(uid int64) (int, error)
+}
+
+type AddressSaver interface {
+	SaveAddress(addr entity.Address) (entity.Address, error)
+	UpdateAddress(addr entity.Address) (entity.Address, error)
+	DeleteAddress(uid, addressID int64) error
+}
+
+func New(log *slog.Logger,
+	authorizer Authorizer,
+	addressProvider AddressProvider,
+	addressSaver AddressSaver,
+	maxPerUser int,
+	defaultPageSize int,
+	maxPageSize int) *Address {
+	return &Address{
+		log:             log,
+		authorizer:      authorizer,
+		addressProvider: addressProvider,
+		addressSaver:    addressSaver,
+		maxPerUser:      maxPerUser,
+		defaultPageSize: defaultPageSize,
+		maxPageSize:     maxPageSize,
+	}
+}
+
+// ListAddresses returns a page of the user's addresses. An empty
+// nextPageToken means there are no more pages.
+func (a *Address) ListAddresses(ctx context.Context, accessToken string, pageSize int, pageToken string,
+) (addresses []entity.Address, nextPageToken string, err error) {
+	const op = "address.ListAddresses"
+
+	uid, err := a.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var afterID int64
+	if pageToken != "" {
+		afterID, err = strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || afterID < 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	if pageSize <= 0 {
+		pageSize = a.defaultPageSize
+	}
+	if pageSize > a.maxPageSize {
+		pageSize = a.maxPageSize
+	}
+
+	// Ask for one more to know whether there is a next page.
+	addresses, err = a.addressProvider.Addresses(uid, afterID, pageSize+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(addresses) > pageSize {
+		addresses = addresses[:pageSize]
+		nextPageToken = strconv.FormatInt(addresses[pageSize-1].ID, 10)
+	}
+
+	return addresses, nextPageToken, nil
+}
+
+func (a *Address) AddAddress(ctx context.Context, accessToken string, addr entity.Address) (entity.Address, error) {
+	const op = "address.AddAddress"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, err := a.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := normalize(&addr); err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	count, err := a.addressProvider.CountAddresses(uid)
+	if err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if count >= a.maxPerUser {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, ErrLimitReached)
+	}
+
+	addr.UserID = uid
+
+	saved, err := a.addressSaver.SaveAddress(addr)
+	if err != nil {
+		log.Error("failed to save address", sl.Err(err))
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+func (a *Address) UpdateAddress(ctx context.Context, accessToken string, addr entity.Address) (entity.Address, error) {
+	const op = "address.UpdateAddress"
+
+	uid, err := a.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := normalize(&addr); err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	addr.UserID = uid
+
+	saved, err := a.addressSaver.UpdateAddress(addr)
+	if err != nil {
+		return entity.Address{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+func (a *Address) DeleteAddress(ctx context.Context, accessToken string, addressID int64) error {
+	const op = "address.DeleteAddress"
+
+	uid, err := a.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.addressSaver.DeleteAddress(uid, addressID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// normalize trims the fields and builds FullAddress from the structured
+// form when the app didn't send it.
+func normalize(addr *entity.Address) error {
+	addr.FullAddress = strings.TrimSpace(addr.FullAddress)
+	addr.City = strings.TrimSpace(addr.City)
+	addr.Street = strings.TrimSpace(addr.Street)
+	addr.Building = strings.TrimSpace(addr.Building)
+	addr.Apartment = strings.TrimSpace(addr.Apartment)
+
+	if addr.FullAddress == "" {
+		var parts []string
+		for _, p := range []string{addr.City, addr.Street, addr.Building, addr.Apartment} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		addr.FullAddress = strings.Join(parts, ", ")
+	}
+
+	if addr.FullAddress == "" || utf8.RuneCountInString(addr.FullAddress) > maxFullAddressLength {
+		return ErrInvalidAddress
+	}
+
+	if utf8.RuneCountInString(addr.City) > maxPartLength || utf8.RuneCountInString(addr.Street) > maxPartLength ||
+		utf8.RuneCountInString(addr.Building) > maxShortPartLength || utf8.RuneCountInString(addr.Apartment) > maxShortPartLength {
+		return ErrInvalidAddress
+	}
+
+	if p := addr.Location; p != nil {
+		if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+			return ErrInvalidLocation
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const testToken = "token"
+
+type testAuthorizer struct{}
+
+func (testAuthorizer) Authorize(_ context.Context, accessToken string) (int64, error) {
+	if accessToken != testToken {
+		return 0, errors.New("invalid token")
+	}
+
+	return 1, nil
+}
+
+// testStorage keeps addresses of user 1 in ID order.
+type testStorage struct {
+	addresses []entity.Address
+}
+
+func (s *testStorage) Addresses(uid, afterID int64, limit int) ([]entity.Address, error) {
+	var page []entity.Address
+	for _, addr := range s.addresses {
+		if addr.UserID == uid && addr.ID > afterID && len(page) < limit {
+			page = append(page, addr)
+		}
+	}
+
+	return page, nil
+}
+
+func (s *testStorage) CountAddresses(uid int64) (int, error) {
+	return len(s.addresses), nil
+}
+
+func (s *testStorage) SaveAddress(addr entity.Address) (entity.Address, error) {
+	addr.ID = int64(len(s.addresses) + 1)
+	s.addresses = append(s.addresses, addr)
+
+	return addr, nil
+}
+
+func (s *testStorage) UpdateAddress(addr entity.Address) (entity.Address, error) {
+	return addr, nil
+}
+
+func (s *testStorage) DeleteAddress(uid, addressID int64) error {
+	return nil
+}
+
+func newTestAddress(t *testing.T, count int) (*Address, *testStorage) {
+	t.Helper()
+
+	st := &testStorage{}
+	for i := 1; i <= count; i++ {
+		st.addresses = append(st.addresses, entity.Address{ID: int64(i), UserID: 1, FullAddress: "Москва"})
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, testAuthorizer{}, st, st, 3, 2, 5), st
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     entity.Address
+		wantFull string
+		wantErr  error
+	}{
+		{
+			name:     "full address is trimmed",
+			addr:     entity.Address{FullAddress: "  Москва, Тверская, 1  "},
+			wantFull: "Москва, Тверская, 1",
+		},
+		{
+			name:     "built from the structured form",
+			addr:     entity.Address{City: " Москва ", Street: "Тверская", Building: "1", Apartment: "12"},
+			wantFull: "Москва, Тверская, 1, 12",
+		},
+		{
+			name:     "missing parts are skipped",
+			addr:     entity.Address{City: "Москва", Building: "1"},
+			wantFull: "Москва, 1",
+		},
+		{
+			name:    "empty",
+			addr:    entity.Address{FullAddress: "   "},
+			wantErr: ErrInvalidAddress,
+		},
+		{
+			name:    "too long",
+			addr:    entity.Address{FullAddress: strings.Repeat("д", maxFullAddressLength+1)},
+			wantErr: ErrInvalidAddress,
+		},
+		{
+			name:    "long building",
+			addr:    entity.Address{City: "Москва", Building: strings.Repeat("1", maxShortPartLength+1)},
+			wantErr: ErrInvalidAddress,
+		},
+		{
+			name:    "latitude out of range",
+			addr:    entity.Address{FullAddress: "Москва", Location: &entity.GeoPoint{Latitude: 91}},
+			wantErr: ErrInvalidLocation,
+		},
+		{
+			name:     "edge coordinates",
+			addr:     entity.Address{FullAddress: "Москва", Location: &entity.GeoPoint{Latitude: -90, Longitude: 180}},
+			wantFull: "Москва",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := tt.addr
+
+			err := normalize(&addr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalize() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && addr.FullAddress != tt.wantFull {
+				t.Errorf("FullAddress = %q, want %q", addr.FullAddress, tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestListAddresses(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		count         int
+		pageSize      int
+		pageToken     string
+		wantIDs       []int64
+		wantNextToken string
+		wantErr       error
+	}{
+		{name: "empty", count: 0, wantIDs: nil},
+		{name: "default page size", count: 3, wantIDs: []int64{1, 2}, wantNextToken: "2"},
+		{name: "next page", count: 3, pageToken: "2", wantIDs: []int64{3}},
+		{name: "exact page", count: 2, pageSize: 2, wantIDs: []int64{1, 2}},
+		{name: "page size is capped", count: 7, pageSize: 100, wantIDs: []int64{1, 2, 3, 4, 5}, wantNextToken: "5"},
+		{name: "bad token", count: 3, pageToken: "abc", wantErr: ErrInvalidPageToken},
+		{name: "negative token", count: 3, pageToken: "-1", wantErr: ErrInvalidPageToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestAddress(t, tt.count)
+
+			addresses, next, err := a.ListAddresses(ctx, testToken, tt.pageSize, tt.pageToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListAddresses() = %v, want %v", err, tt.wantErr)
+			}
+
+			var ids []int64
+			for _, addr := range addresses {
+				ids = append(ids, addr.ID)
+			}
+
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+
+			if next != tt.wantNextToken {
+				t.Errorf("next page token = %q, want %q", next, tt.wantNextToken)
+			}
+		})
+	}
+}
+
+func TestAddAddress(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		count   int
+		addr    entity.Address
+		wantErr error
+	}{
+		{name: "first", count: 0, addr: entity.Address{FullAddress: "Москва"}},
+		{name: "below the limit", count: 2, addr: entity.Address{City: "Москва", Street: "Тверская"}},
+		{name: "limit reached", count: 3, addr: entity.Address{FullAddress: "Москва"}, wantErr: ErrLimitReached},
+		{name: "invalid", count: 0, addr: entity.Address{}, wantErr: ErrInvalidAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, st := newTestAddress(t, tt.count)
+
+			saved, err := a.AddAddress(ctx, testToken, tt.addr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddAddress() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if len(st.addresses) != tt.count {
+					t.Errorf("address was saved after an error")
+				}
+				return
+			}
+
+			if saved.UserID != 1 {
+				t.Errorf("UserID = %d, want the token's user", saved.UserID)
+			}
+
+			if saved.FullAddress == "" {
+				t.Errorf("address was saved without normalizing")
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrForbidden        = errors.New("admin role required")
+	ErrUnknownMethod    = errors.New("method has no required role")
+	ErrUnknownRole      = errors.New("unknown admin role")
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrReasonRequired   = errors.New("reason is required")
+	ErrNotBlocked       = errors.New("account is not blocked")
+	ErrNotDeleted       = errors.New("account is not deleted")
+)
+
+// Roles are cumulative: support can do everything a viewer can, and
+// superadmin can do everything.
+const (
+	RoleViewer     = "viewer"
+	RoleSupport    = "support"
+	RoleSuperadmin = "superadmin"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer:     1,
+	RoleSupport:    2,
+	RoleSuperadmin: 3,
+}
+
+const (
+	actionForcePasswordReset = "admin.force_password_reset"
+	actionRevokeSessions     = "admin.revoke_sessions"
+	actionGrantRole          = "admin.grant_role"
+	actionRevokeRole         = "admin.revoke_role"
+)
+
+type Admin struct {
+	log             *slog.Logger
+	userProvider    UserProvider
+	sessionStorage  SessionStorage
+	statusChanger   StatusChanger
+	resetRequester  ResetRequester
+	roleStorage     RoleStorage
+	auditExporter   AuditExporter
+	methodRoles     map[string]string
+	superusers      []string
+	defaultPageSize int
+	maxPageSize     int
+}
+
+type UserProvider interface {
+	SearchUsers(filter entity.UserFilter, afterID int64, limit int) ([]entity.UserDetails, error)
+	UserByID(uid int64) (entity.User, error)
+	Profile(uid int64) (entity.Profile, error)
+}
+
+type SessionStorage interface {
+	Sessions(uid int64) ([]entity.Session, error)
+	RevokeRefreshTokens(uid int64, audit entity.AuditRecord) error
+	RequirePasswordReset(uid int64, audit entity.AuditRecord) error
+}
+
+type StatusChanger interface {
+	ChangeStatus(ctx context.Context, actor string, uid int64, to entity.AccountStatus, reason string, until time.Time) error
+}
+
+type ResetRequester interface {
+	RequestPasswordReset(ctx context.Context, login string) (response string, err error)
+}
+
+type RoleStorage interface {
+	AdminRoles(subject string) ([]string, error)
+	GrantAdminRole(subject, role, grantedBy string, audit entity.AuditRecord) error
+	RevokeAdminRole(subject, role string, audit entity.AuditRecord) error
+}
+
+// AuditExporter passes audit records to the SIEM once the storage has
+// saved them together with the action.
+type AuditExporter interface {
+	ExportAuditRecord(record entity.AuditRecord)
+}
+
+// New takes methodRoles, the role each admin RPC requires keyed by full
+// method name, and superusers, the certificate names that get every role
+// without a grant so the first roles can be handed out.
+func New(log *slog.Logger,
+	userProvider UserProvider,
+	sessionStorage SessionStorage,
+	statusChanger StatusChanger,
+	resetRequester ResetRequester,
+	roleStorage RoleStorage,
+	auditExporter AuditExporter,
+	methodRoles map[string]string,
+	superusers []string,
+	defaultPageSize int,
+	maxPageSize int) *Admin {
+	return &Admin{
+		log:             log,
+		userProvider:    userProvider,
+		sessionStorage:  sessionStorage,
+		statusChanger:   statusChanger,
+		resetRequester:  resetRequester,
+		roleStorage:     roleStorage,
+		auditExporter:   auditExporter,
+		methodRoles:     methodRoles,
+		superusers:      superusers,
+		defaultPageSize: defaultPageSize,
+		maxPageSize:     maxPageSize,
+	}
+}
+
+// AuthorizeAdmin checks that subject has the role the method requires.
+// Methods missing from methodRoles are denied.
+func (a *Admin) AuthorizeAdmin(ctx context.Context, subject, method string) error {
+	const op = "admin.AuthorizeAdmin"
+
+	required, ok := a.methodRoles[method]
+	if !ok {
+		return fmt.Errorf("%s: %s: %w", op, method, ErrUnknownMethod)
+	}
+
+	if slices.Contains(a.superusers, subject) {
+		return nil
+	}
+
+	roles, err := a.roleStorage.AdminRoles(subject)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range roles {
+		if roleRanks[role] >= roleRanks[required] {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrForbidden)
+}
+
+// SearchUsers returns a page of users matching the filter. An empty
+// nextPageToken means there are no more pages.
+func (a *Admin) SearchUsers(ctx context.Context, filter entity.UserFilter, pageSize int, pageToken string,
+) (users []entity.UserDetails, nextPageToken string, err error) {
+	const op = "admin.SearchUsers"
+
+	if pageSize <= 0 {
+		pageSize = a.defaultPageSize
+	}
+	pageSize = min(pageSize, a.maxPageSize)
+
+	var afterID int64
+	if pageToken != "" {
+		afterID, err = strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || afterID < 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	users, err = a.userProvider.SearchUsers(filter, afterID, pageSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(users) == pageSize {
+		nextPageToken = strconv.FormatInt(users[pageSize-1].User.ID, 10)
+	}
+
+	return users, nextPageToken, nil
+}
+
+func (a *Admin) User(ctx context.Context, uid int64) (entity.UserDetails, []entity.Session, error) {
+	const op = "admin.User"
+
+	user, err := a.userProvider.UserByID(uid)
+	if err != nil {
+		return entity.UserDetails{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := a.userProvider.Profile(uid)
+	if err != nil {
+		return entity.UserDetails{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := a.sessionStorage.Sessions(uid)
+	if err != nil {
+		return entity.UserDetails{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entity.UserDetails{User: user, Profile: profile}, sessions, nil
+}
+
+// BlockUser suspends the account until the given time, or indefinitely
+// when until is zero.
+func (a *Admin) BlockUser(ctx context.Context, actor string, uid int64, reason string, until time.Time) error {
+	const op = "admin.BlockUser"
+
+	if err := a.statusChanger.ChangeStatus(ctx, actor, uid, entity.AccountSuspended, reason, until); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *Admin) UnblockUser(ctx context.Context, actor string, uid int64, reason string) error {
+	const op = "admin.UnblockUser"
+
+	user, err := a.userProvider.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	status := accountstate.Effective(user.State, time.Now())
+	if status != entity.AccountLocked && status != entity.AccountSuspended {
+		return fmt.Errorf("%s: %w", op, ErrNotBlocked)
+	}
+
+	if err := a.statusChanger.ChangeStatus(ctx, actor, uid, entity.AccountActive, reason, time.Time{}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RestoreUser cancels deletion of an account that is still in its grace
+// period.
+func (a *Admin) RestoreUser(ctx context.Context, actor string, uid int64, reason string) error {
+	const op = "admin.RestoreUser"
+
+	user, err := a.userProvider.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.State.Status != entity.AccountDeleted {
+		return fmt.Errorf("%s: %w", op, ErrNotDeleted)
+	}
+
+	if err := a.statusChanger.ChangeStatus(ctx, actor, uid, entity.AccountActive, reason, time.Time{}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ForcePasswordReset signs the user out, stops the current password from
+// working and sends a reset link by SMS.
+func (a *Admin) ForcePasswordReset(ctx context.Context, actor string, uid int64, reason string) error {
+	const op = "admin.ForcePasswordReset"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", uid), slog.String("actor", actor))
+
+	if reason == "" {
+		return fmt.Errorf("%s: %w", op, ErrReasonRequired)
+	}
+
+	user, err := a.userProvider.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	record, err := auditRecord(actor, actionForcePasswordReset, uid, map[string]string{"reason": reason})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.sessionStorage.RequirePasswordReset(uid, record); err != nil {
+		log.Error("failed to require password reset", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.auditExporter.ExportAuditRecord(record)
+
+	if _, err := a.resetRequester.RequestPasswordReset(ctx, user.Phone); err != nil {
+		log.Error("failed to send reset link", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset forced")
+
+	return nil
+}
+
+func (a *Admin) RevokeSessions(ctx context.Context, actor string, uid int64, reason string) error {
+	const op = "admin.RevokeSessions"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", uid), slog.String("actor", actor))
+
+	if reason == "" {
+		return fmt.Errorf("%s: %w", op, ErrReasonRequired)
+	}
+
+	if _, err := a.userProvider.UserByID(uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	record, err := auditRecord(actor, actionRevokeSessions, uid, map[string]string{"reason": reason})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.sessionStorage.RevokeRefreshTokens(uid, record); err != nil {
+		log.Error("failed to revoke sessions", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.auditExporter.ExportAuditRecord(record)
+
+	log.Info("sessions revoked")
+
+	return nil
+}
+
+func (a *Admin) GrantRole(ctx context.Context, actor, subject, role string) error {
+	const op = "admin.GrantRole"
+
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownRole)
+	}
+
+	record, err := auditRecord(actor, actionGrantRole, 0, map[string]string{"subject": subject, "role": role})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.roleStorage.GrantAdminRole(subject, role, actor, record); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.auditExporter.ExportAuditRecord(record)
+
+	a.log.Info("admin role granted", slog.String("op", op), slog.String("actor", actor),
+		slog.String("subject", subject), slog.String("role", role))
+
+	return nil
+}
+
+func (a *Admin) RevokeRole(ctx context.Context, actor, subject, role string) error {
+	const op = "admin.RevokeRole"
+
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownRole)
+	}
+
+	record, err := auditRecord(actor, actionRevokeRole, 0, map[string]string{"subject": subject, "role": role})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.roleStorage.RevokeAdminRole(subject, role, record); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.auditExporter.ExportAuditRecord(record)
+
+	a.log.Info("admin role revoked", slog.String("op", op), slog.String("actor", actor),
+		slog.String("subject", subject), slog.String("role", role))
+
+	return nil
+}
+
+// auditRecord builds the record the storage saves in the same transaction
+// as the action.
+func auditRecord(actor, action string, uid int64, details map[string]string) (entity.AuditRecord, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return entity.AuditRecord{}, err
+	}
+
+	return entity.AuditRecord{
+		Actor:        actor,
+		Action:       action,
+		TargetUserID: uid,
+		Details:      data,
+	}, nil
+}
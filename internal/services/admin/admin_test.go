@@ -0,0 +1,302 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+const testUID = 7
+
+// testStorage applies an action and its audit record together, or
+// neither when failure is set, the way the postgres transactions do.
+type testStorage struct {
+	failure       error
+	resetRequired bool
+	revoked       bool
+	roles         map[string][]string
+	audit         []entity.AuditRecord
+}
+
+func (s *testStorage) SearchUsers(entity.UserFilter, int64, int) ([]entity.UserDetails, error) {
+	return nil, nil
+}
+
+func (s *testStorage) UserByID(uid int64) (entity.User, error) {
+	if uid != testUID {
+		return entity.User{}, storage.ErrUserNotFound
+	}
+
+	return entity.User{ID: uid, Phone: "+79991234567"}, nil
+}
+
+func (s *testStorage) Profile(uid int64) (entity.Profile, error) {
+	return entity.Profile{UserID: uid}, nil
+}
+
+func (s *testStorage) Sessions(int64) ([]entity.Session, error) {
+	return nil, nil
+}
+
+func (s *testStorage) RevokeRefreshTokens(_ int64, audit entity.AuditRecord) error {
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.revoked = true
+	s.audit = append(s.audit, audit)
+
+	return nil
+}
+
+func (s *testStorage) RequirePasswordReset(_ int64, audit entity.AuditRecord) error {
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.resetRequired = true
+	s.revoked = true
+	s.audit = append(s.audit, audit)
+
+	return nil
+}
+
+func (s *testStorage) AdminRoles(subject string) ([]string, error) {
+	return s.roles[subject], nil
+}
+
+func (s *testStorage) GrantAdminRole(subject, role, _ string, audit entity.AuditRecord) error {
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.roles[subject] = append(s.roles[subject], role)
+	s.audit = append(s.audit, audit)
+
+	return nil
+}
+
+func (s *testStorage) RevokeAdminRole(subject, role string, audit entity.AuditRecord) error {
+	if s.failure != nil {
+		return s.failure
+	}
+
+	var kept []string
+	for _, r := range s.roles[subject] {
+		if r != role {
+			kept = append(kept, r)
+		}
+	}
+	s.roles[subject] = kept
+	s.audit = append(s.audit, audit)
+
+	return nil
+}
+
+type testStatusChanger struct{}
+
+func (testStatusChanger) ChangeStatus(context.Context, string, int64, entity.AccountStatus, string, time.Time) error {
+	return nil
+}
+
+type testResetRequester struct {
+	sent []string
+}
+
+func (r *testResetRequester) RequestPasswordReset(_ context.Context, login string) (string, error) {
+	r.sent = append(r.sent, login)
+	return "", nil
+}
+
+type testExporter struct {
+	records []entity.AuditRecord
+}
+
+func (e *testExporter) ExportAuditRecord(record entity.AuditRecord) {
+	e.records = append(e.records, record)
+}
+
+func newTestAdmin(st *testStorage) (*Admin, *testResetRequester, *testExporter) {
+	resets := &testResetRequester{}
+	exporter := &testExporter{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	a := New(log, st, st, testStatusChanger{}, resets, st, exporter, nil, nil, 50, 100)
+
+	return a, resets, exporter
+}
+
+func TestAuditedActions(t *testing.T) {
+	ctx := context.Background()
+	errStorage := errors.New("storage is down")
+
+	tests := []struct {
+		name        string
+		action      func(a *Admin) error
+		failure     error
+		wantErr     error
+		wantAction  string
+		wantUID     int64
+		wantDetails map[string]string
+		wantResets  int
+	}{
+		{
+			name:        "force password reset",
+			action:      func(a *Admin) error { return a.ForcePasswordReset(ctx, "ivanov", testUID, "leaked") },
+			wantAction:  actionForcePasswordReset,
+			wantUID:     testUID,
+			wantDetails: map[string]string{"reason": "leaked"},
+			wantResets:  1,
+		},
+		{
+			name:    "force password reset fails",
+			action:  func(a *Admin) error { return a.ForcePasswordReset(ctx, "ivanov", testUID, "leaked") },
+			failure: errStorage,
+			wantErr: errStorage,
+		},
+		{
+			name:    "force password reset without reason",
+			action:  func(a *Admin) error { return a.ForcePasswordReset(ctx, "ivanov", testUID, "") },
+			wantErr: ErrReasonRequired,
+		},
+		{
+			name:        "revoke sessions",
+			action:      func(a *Admin) error { return a.RevokeSessions(ctx, "ivanov", testUID, "stolen phone") },
+			wantAction:  actionRevokeSessions,
+			wantUID:     testUID,
+			wantDetails: map[string]string{"reason": "stolen phone"},
+		},
+		{
+			name:    "revoke sessions fails",
+			action:  func(a *Admin) error { return a.RevokeSessions(ctx, "ivanov", testUID, "stolen phone") },
+			failure: errStorage,
+			wantErr: errStorage,
+		},
+		{
+			name:    "revoke sessions of an unknown user",
+			action:  func(a *Admin) error { return a.RevokeSessions(ctx, "ivanov", testUID+1, "stolen phone") },
+			wantErr: storage.ErrUserNotFound,
+		},
+		{
+			name:        "grant role",
+			action:      func(a *Admin) error { return a.GrantRole(ctx, "ivanov", "petrov", RoleSupport) },
+			wantAction:  actionGrantRole,
+			wantDetails: map[string]string{"subject": "petrov", "role": RoleSupport},
+		},
+		{
+			name:    "grant role fails",
+			action:  func(a *Admin) error { return a.GrantRole(ctx, "ivanov", "petrov", RoleSupport) },
+			failure: errStorage,
+			wantErr: errStorage,
+		},
+		{
+			name:    "grant unknown role",
+			action:  func(a *Admin) error { return a.GrantRole(ctx, "ivanov", "petrov", "root") },
+			wantErr: ErrUnknownRole,
+		},
+		{
+			name:        "revoke role",
+			action:      func(a *Admin) error { return a.RevokeRole(ctx, "ivanov", "petrov", RoleViewer) },
+			wantAction:  actionRevokeRole,
+			wantDetails: map[string]string{"subject": "petrov", "role": RoleViewer},
+		},
+		{
+			name:    "revoke role fails",
+			action:  func(a *Admin) error { return a.RevokeRole(ctx, "ivanov", "petrov", RoleViewer) },
+			failure: errStorage,
+			wantErr: errStorage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &testStorage{
+				failure: tt.failure,
+				roles:   map[string][]string{"petrov": {RoleViewer}},
+			}
+			a, resets, exporter := newTestAdmin(st)
+
+			err := tt.action(a)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("action = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(resets.sent) != tt.wantResets {
+				t.Errorf("reset links sent = %d, want %d", len(resets.sent), tt.wantResets)
+			}
+
+			if tt.wantErr != nil {
+				if len(st.audit) != 0 || len(exporter.records) != 0 {
+					t.Fatalf("audit records saved = %d, exported = %d, want none", len(st.audit), len(exporter.records))
+				}
+				return
+			}
+
+			if len(st.audit) != 1 || len(exporter.records) != 1 {
+				t.Fatalf("audit records saved = %d, exported = %d, want 1", len(st.audit), len(exporter.records))
+			}
+
+			record := st.audit[0]
+			if record.Actor != "ivanov" || record.Action != tt.wantAction || record.TargetUserID != tt.wantUID {
+				t.Errorf("audit record = %+v", record)
+			}
+
+			var details map[string]string
+			if err := json.Unmarshal(record.Details, &details); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.wantDetails {
+				if details[k] != v {
+					t.Errorf("details[%q] = %q, want %q", k, details[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	methodRoles := map[string]string{
+		"/admin/User":      RoleViewer,
+		"/admin/BlockUser": RoleSupport,
+		"/admin/GrantRole": RoleSuperadmin,
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		method  string
+		wantErr error
+	}{
+		{name: "viewer reads", subject: "viewer", method: "/admin/User"},
+		{name: "viewer can't block", subject: "viewer", method: "/admin/BlockUser", wantErr: ErrForbidden},
+		{name: "support blocks", subject: "support", method: "/admin/BlockUser"},
+		{name: "support can't grant", subject: "support", method: "/admin/GrantRole", wantErr: ErrForbidden},
+		{name: "superuser without grants", subject: "root", method: "/admin/GrantRole"},
+		{name: "no roles", subject: "nobody", method: "/admin/User", wantErr: ErrForbidden},
+		{name: "unknown method", subject: "root", method: "/admin/Drop", wantErr: ErrUnknownMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &testStorage{roles: map[string][]string{
+				"viewer":  {RoleViewer},
+				"support": {RoleSupport},
+			}}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			a := New(log, st, st, testStatusChanger{}, &testResetRequester{}, st, &testExporter{},
+				methodRoles, []string{"root"}, 50, 100)
+
+			if err := a.AuthorizeAdmin(ctx, tt.subject, tt.method); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AuthorizeAdmin() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
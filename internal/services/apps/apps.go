@@ -0,0 +1,514 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidName         = errors.New("invalid app name")
+	ErrInvalidGrantType    = errors.New("invalid grant type")
+	ErrInvalidRedirectURI  = errors.New("invalid redirect uri")
+	ErrInvalidTTL          = errors.New("invalid token ttl")
+	ErrInvalidClientSecret = errors.New("invalid client secret")
+	ErrInvalidScope        = errors.New("invalid scope")
+	ErrInvalidRole         = errors.New("invalid role")
+)
+
+const (
+	actionCreateApp    = "app.create"
+	actionUpdateApp    = "app.update"
+	actionDisableApp   = "app.disable"
+	actionEnableApp    = "app.enable"
+	actionDeleteApp    = "app.delete"
+	actionCreateSecret = "app.secret_create"
+	actionRevokeSecret = "app.secret_revoke"
+	actionSetScopes    = "app.scopes_set"
+	actionGrantRole    = "app.role_grant"
+	actionRevokeRole   = "app.role_revoke"
+
+	signingKeyBytes   = 32
+	clientSecretBytes = 32
+	secretHintLength  = 4
+	maxNameLength     = 64
+	maxRoleLength     = 64
+)
+
+var grantTypes = []string{entity.GrantPassword, entity.GrantRefreshToken}
+
+type Apps struct {
+	log           *slog.Logger
+	appStorage    AppStorage
+	secretStorage SecretStorage
+	auditSaver    AuditSaver
+}
+
+type AppStorage interface {
+	App(appID int32) (entity.App, error)
+	Apps() ([]entity.App, error)
+	SaveApp(app entity.App) (entity.App, error)
+	UpdateApp(app entity.App) (entity.App, error)
+	SetAppDisabled(appID int32, disabled bool) error
+	SetAppScopes(appID int32, scopes []string) error
+	DeleteApp(appID int32) error
+	UserAppRoles(uid int64, appID int32) ([]string, error)
+	GrantUserAppRole(uid int64, appID int32, role, grantedBy string) error
+	RevokeUserAppRole(uid int64, appID int32, role string) error
+}
+
+type SecretStorage interface {
+	AppSecrets(appID int32) ([]entity.AppSecret, error)
+	AppSecretByHash(secretHash string) (entity.AppSecret, error)
+	SaveAppSecret(secret entity.AppSecret) (entity.AppSecret, error)
+	RevokeAppSecret(appID int32, secretID int64) error
+}
+
+type AuditSaver interface {
+	SaveAuditRecord(record entity.AuditRecord) error
+}
+
+func New(log *slog.Logger,
+	appStorage AppStorage,
+	secretStorage SecretStorage,
+	auditSaver AuditSaver) *Apps {
+	return &Apps{
+		log:           log,
+		appStorage:    appStorage,
+		secretStorage: secretStorage,
+		auditSaver:    auditSaver,
+	}
+}
+
+func (a *Apps) Apps(ctx context.Context) ([]entity.App, error) {
+	const op = "apps.Apps"
+
+	apps, err := a.appStorage.Apps()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+func (a *Apps) App(ctx context.Context, appID int32) (entity.App, []entity.AppSecret, error) {
+	const op = "apps.App"
+
+	app, err := a.appStorage.App(appID)
+	if err != nil {
+		return entity.App{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secrets, err := a.secretStorage.AppSecrets(appID)
+	if err != nil {
+		return entity.App{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, secrets, nil
+}
+
+// CreateApp registers an app with a new signing key and a first client
+// secret. The secret is returned only here and can't be read back later.
+func (a *Apps) CreateApp(ctx context.Context, actor string, app entity.App,
+) (created entity.App, clientSecret string, err error) {
+	const op = "apps.CreateApp"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor))
+
+	app, err = normalize(app)
+	if err != nil {
+		return entity.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app.SigningKey, err = otp.Token(signingKeyBytes)
+	if err != nil {
+		return entity.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err = a.appStorage.SaveApp(app)
+	if err != nil {
+		return entity.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("app_id", int(created.ID)))
+
+	clientSecret, _, err = a.createSecret(created.ID, time.Time{})
+	if err != nil {
+		log.Error("failed to create client secret", sl.Err(err))
+		return entity.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.audit(actor, actionCreateApp, created.ID, appDetails(created)); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return entity.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app created")
+
+	return created, clientSecret, nil
+}
+
+func (a *Apps) UpdateApp(ctx context.Context, actor string, app entity.App) (entity.App, error) {
+	const op = "apps.UpdateApp"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(app.ID)))
+
+	app, err := normalize(app)
+	if err != nil {
+		return entity.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, err := a.appStorage.UpdateApp(app)
+	if err != nil {
+		return entity.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.audit(actor, actionUpdateApp, updated.ID, appDetails(updated)); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return entity.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app updated")
+
+	return updated, nil
+}
+
+// SetAppDisabled turns an app off or back on. Users can't sign in to a
+// disabled app and its tokens stop validating.
+func (a *Apps) SetAppDisabled(ctx context.Context, actor string, appID int32, disabled bool) error {
+	const op = "apps.SetAppDisabled"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	if err := a.appStorage.SetAppDisabled(appID, disabled); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	action := actionEnableApp
+	if disabled {
+		action = actionDisableApp
+	}
+
+	if err := a.audit(actor, action, appID, nil); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app disabled flag changed", slog.Bool("disabled", disabled))
+
+	return nil
+}
+
+// SetAppScopes replaces the scopes issued in the app's access tokens.
+// Tokens issued before keep the old ones until they are refreshed.
+func (a *Apps) SetAppScopes(ctx context.Context, actor string, appID int32, scopes []string) error {
+	const op = "apps.SetAppScopes"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	if err := a.appStorage.SetAppScopes(appID, scopes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"scopes": strings.Join(scopes, " ")}
+	if err := a.audit(actor, actionSetScopes, appID, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app scopes set")
+
+	return nil
+}
+
+func (a *Apps) UserRoles(ctx context.Context, uid int64, appID int32) ([]string, error) {
+	const op = "apps.UserRoles"
+
+	roles, err := a.appStorage.UserAppRoles(uid, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// GrantRole gives the user a role in the app, issued in the roles claim
+// of the user's access tokens from the next refresh on.
+func (a *Apps) GrantRole(ctx context.Context, actor string, uid int64, appID int32, role string) error {
+	const op = "apps.GrantRole"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)),
+		slog.Int64("uid", uid))
+
+	if !validRole(role) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+
+	if err := a.appStorage.GrantUserAppRole(uid, appID, role, actor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"user_id": strconv.FormatInt(uid, 10), "role": role}
+	if err := a.audit(actor, actionGrantRole, appID, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role granted", slog.String("role", role))
+
+	return nil
+}
+
+// RevokeRole takes a role of the user in the app away. Access tokens
+// issued before keep it until they expire.
+func (a *Apps) RevokeRole(ctx context.Context, actor string, uid int64, appID int32, role string) error {
+	const op = "apps.RevokeRole"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)),
+		slog.Int64("uid", uid))
+
+	if err := a.appStorage.RevokeUserAppRole(uid, appID, role); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"user_id": strconv.FormatInt(uid, 10), "role": role}
+	if err := a.audit(actor, actionRevokeRole, appID, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role revoked", slog.String("role", role))
+
+	return nil
+}
+
+func (a *Apps) DeleteApp(ctx context.Context, actor string, appID int32) error {
+	const op = "apps.DeleteApp"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	if err := a.appStorage.DeleteApp(appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.audit(actor, actionDeleteApp, appID, nil); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app deleted")
+
+	return nil
+}
+
+// CreateSecret adds a client secret next to the existing ones, so the app
+// can switch to it before the old one is revoked. A zero expiresAt means
+// the secret doesn't expire.
+func (a *Apps) CreateSecret(ctx context.Context, actor string, appID int32, expiresAt time.Time,
+) (clientSecret string, secret entity.AppSecret, err error) {
+	const op = "apps.CreateSecret"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return "", entity.AppSecret{}, fmt.Errorf("%s: %w", op, ErrInvalidTTL)
+	}
+
+	clientSecret, secret, err = a.createSecret(appID, expiresAt)
+	if err != nil {
+		return "", entity.AppSecret{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"secret_id": strconv.FormatInt(secret.ID, 10), "hint": secret.Hint}
+	if err := a.audit(actor, actionCreateSecret, appID, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return "", entity.AppSecret{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("client secret created", slog.Int64("secret_id", secret.ID))
+
+	return clientSecret, secret, nil
+}
+
+func (a *Apps) RevokeSecret(ctx context.Context, actor string, appID int32, secretID int64) error {
+	const op = "apps.RevokeSecret"
+
+	log := a.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	if err := a.secretStorage.RevokeAppSecret(appID, secretID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"secret_id": strconv.FormatInt(secretID, 10)}
+	if err := a.audit(actor, actionRevokeSecret, appID, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("client secret revoked", slog.Int64("secret_id", secretID))
+
+	return nil
+}
+
+// AuthenticateClient checks a client secret presented by a confidential
+// app. Any of the app's secrets that is neither revoked nor expired works.
+func (a *Apps) AuthenticateClient(ctx context.Context, appID int32, clientSecret string) (entity.App, error) {
+	const op = "apps.AuthenticateClient"
+
+	secret, err := a.secretStorage.AppSecretByHash(otp.Hash(clientSecret))
+	if err != nil {
+		if errors.Is(err, storage.ErrAppSecretNotFound) {
+			return entity.App{}, fmt.Errorf("%s: %w", op, ErrInvalidClientSecret)
+		}
+		return entity.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if secret.AppID != appID || !secret.RevokedAt.IsZero() ||
+		(!secret.ExpiresAt.IsZero() && time.Now().After(secret.ExpiresAt)) {
+		return entity.App{}, fmt.Errorf("%s: %w", op, ErrInvalidClientSecret)
+	}
+
+	app, err := a.appStorage.App(appID)
+	if err != nil {
+		return entity.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if app.Disabled {
+		return entity.App{}, fmt.Errorf("%s: %w", op, ErrInvalidClientSecret)
+	}
+
+	return app, nil
+}
+
+func (a *Apps) createSecret(appID int32, expiresAt time.Time) (string, entity.AppSecret, error) {
+	clientSecret, err := otp.Token(clientSecretBytes)
+	if err != nil {
+		return "", entity.AppSecret{}, err
+	}
+
+	secret, err := a.secretStorage.SaveAppSecret(entity.AppSecret{
+		AppID:      appID,
+		SecretHash: otp.Hash(clientSecret),
+		Hint:       clientSecret[len(clientSecret)-secretHintLength:],
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return "", entity.AppSecret{}, err
+	}
+
+	return clientSecret, secret, nil
+}
+
+func (a *Apps) audit(actor, action string, appID int32, details map[string]string) error {
+	if details == nil {
+		details = map[string]string{}
+	}
+	details["app_id"] = strconv.Itoa(int(appID))
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return a.auditSaver.SaveAuditRecord(entity.AuditRecord{
+		Actor:   actor,
+		Action:  action,
+		Details: data,
+	})
+}
+
+// normalize checks the app settings and fills in defaults. New apps get
+// the password and refresh_token grants unless told otherwise.
+func normalize(app entity.App) (entity.App, error) {
+	app.Name = strings.TrimSpace(app.Name)
+	if app.Name == "" || len(app.Name) > maxNameLength {
+		return app, ErrInvalidName
+	}
+
+	if len(app.GrantTypes) == 0 {
+		app.GrantTypes = slices.Clone(grantTypes)
+	}
+	for _, grantType := range app.GrantTypes {
+		if !slices.Contains(grantTypes, grantType) {
+			return app, ErrInvalidGrantType
+		}
+	}
+
+	if app.RedirectURIs == nil {
+		app.RedirectURIs = []string{}
+	}
+	for _, uri := range app.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" || u.Fragment != "" {
+			return app, ErrInvalidRedirectURI
+		}
+	}
+
+	if app.AccessTokenTTL < 0 || app.RefreshTokenTTL < 0 || app.SessionLifetime < 0 || app.IdleTimeout < 0 {
+		return app, ErrInvalidTTL
+	}
+
+	return app, nil
+}
+
+// validScope follows scope-token of RFC 6749: printable ASCII without
+// spaces, quotes and backslashes.
+func validScope(scope string) bool {
+	if scope == "" {
+		return false
+	}
+
+	for _, r := range scope {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validRole(role string) bool {
+	return role != "" && len(role) <= maxRoleLength && !strings.ContainsFunc(role, unicode.IsSpace)
+}
+
+func appDetails(app entity.App) map[string]string {
+	return map[string]string{
+		"name":              app.Name,
+		"grant_types":       strings.Join(app.GrantTypes, ","),
+		"redirect_uris":     strings.Join(app.RedirectURIs, ","),
+		"access_token_ttl":  app.AccessTokenTTL.String(),
+		"refresh_token_ttl": app.RefreshTokenTTL.String(),
+		"session_lifetime":  app.SessionLifetime.String(),
+		"idle_timeout":      app.IdleTimeout.String(),
+		"sliding_refresh":   slidingDetail(app.SlidingRefresh),
+	}
+}
+
+func slidingDetail(sliding *bool) string {
+	if sliding == nil {
+		return "default"
+	}
+
+	return strconv.FormatBool(*sliding)
+}
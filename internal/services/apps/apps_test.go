@@ -0,0 +1,521 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStorage keeps apps and client secrets the way the postgres storage
+// does.
+type testStorage struct {
+	apps    map[int32]entity.App
+	secrets []entity.AppSecret
+	// roles are "uid/app_id/role" keys.
+	roles []string
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{apps: map[int32]entity.App{}}
+}
+
+func (s *testStorage) App(appID int32) (entity.App, error) {
+	app, ok := s.apps[appID]
+	if !ok {
+		return entity.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (s *testStorage) Apps() ([]entity.App, error) {
+	var apps []entity.App
+	for _, app := range s.apps {
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+func (s *testStorage) SaveApp(app entity.App) (entity.App, error) {
+	app.ID = int32(len(s.apps) + 1)
+	s.apps[app.ID] = app
+
+	return app, nil
+}
+
+func (s *testStorage) UpdateApp(app entity.App) (entity.App, error) {
+	old, ok := s.apps[app.ID]
+	if !ok {
+		return entity.App{}, storage.ErrAppNotFound
+	}
+
+	app.SigningKey = old.SigningKey
+	s.apps[app.ID] = app
+
+	return app, nil
+}
+
+func (s *testStorage) SetAppDisabled(appID int32, disabled bool) error {
+	app, ok := s.apps[appID]
+	if !ok {
+		return storage.ErrAppNotFound
+	}
+
+	app.Disabled = disabled
+	s.apps[appID] = app
+
+	return nil
+}
+
+func (s *testStorage) SetAppScopes(appID int32, scopes []string) error {
+	app, ok := s.apps[appID]
+	if !ok {
+		return storage.ErrAppNotFound
+	}
+
+	app.Scopes = scopes
+	s.apps[appID] = app
+
+	return nil
+}
+
+func (s *testStorage) UserAppRoles(uid int64, appID int32) ([]string, error) {
+	var roles []string
+	for _, key := range s.roles {
+		if role, ok := strings.CutPrefix(key, roleKey(uid, appID, "")); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+func (s *testStorage) GrantUserAppRole(uid int64, appID int32, role, _ string) error {
+	if _, ok := s.apps[appID]; !ok {
+		return storage.ErrAppNotFound
+	}
+
+	if !slices.Contains(s.roles, roleKey(uid, appID, role)) {
+		s.roles = append(s.roles, roleKey(uid, appID, role))
+	}
+
+	return nil
+}
+
+func (s *testStorage) RevokeUserAppRole(uid int64, appID int32, role string) error {
+	s.roles = slices.DeleteFunc(s.roles, func(key string) bool { return key == roleKey(uid, appID, role) })
+	return nil
+}
+
+func roleKey(uid int64, appID int32, role string) string {
+	return fmt.Sprintf("%d/%d/%s", uid, appID, role)
+}
+
+func (s *testStorage) DeleteApp(appID int32) error {
+	delete(s.apps, appID)
+	return nil
+}
+
+func (s *testStorage) AppSecrets(appID int32) ([]entity.AppSecret, error) {
+	var secrets []entity.AppSecret
+	for _, secret := range s.secrets {
+		if secret.AppID == appID {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	return secrets, nil
+}
+
+func (s *testStorage) AppSecretByHash(secretHash string) (entity.AppSecret, error) {
+	for _, secret := range s.secrets {
+		if secret.SecretHash == secretHash {
+			return secret, nil
+		}
+	}
+
+	return entity.AppSecret{}, storage.ErrAppSecretNotFound
+}
+
+func (s *testStorage) SaveAppSecret(secret entity.AppSecret) (entity.AppSecret, error) {
+	secret.ID = int64(len(s.secrets) + 1)
+	secret.CreatedAt = time.Now()
+	s.secrets = append(s.secrets, secret)
+
+	return secret, nil
+}
+
+func (s *testStorage) RevokeAppSecret(appID int32, secretID int64) error {
+	for i, secret := range s.secrets {
+		if secret.AppID == appID && secret.ID == secretID {
+			s.secrets[i].RevokedAt = time.Now()
+			return nil
+		}
+	}
+
+	return storage.ErrAppSecretNotFound
+}
+
+type testAudit struct {
+	records []entity.AuditRecord
+}
+
+func (a *testAudit) SaveAuditRecord(record entity.AuditRecord) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func newTestApps() (*Apps, *testStorage, *testAudit) {
+	st := newTestStorage()
+	audit := &testAudit{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, audit), st, audit
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		app        entity.App
+		wantGrants []string
+		wantErr    error
+	}{
+		{
+			name:       "defaults",
+			app:        entity.App{Name: "  Vizap  "},
+			wantGrants: []string{entity.GrantPassword, entity.GrantRefreshToken},
+		},
+		{
+			name:       "password only",
+			app:        entity.App{Name: "Admin", GrantTypes: []string{entity.GrantPassword}},
+			wantGrants: []string{entity.GrantPassword},
+		},
+		{
+			name:       "redirect uri",
+			app:        entity.App{Name: "Web", RedirectURIs: []string{"https://vizap.ru/callback", "vizap://auth"}},
+			wantGrants: []string{entity.GrantPassword, entity.GrantRefreshToken},
+		},
+		{name: "empty name", app: entity.App{Name: "   "}, wantErr: ErrInvalidName},
+		{name: "long name", app: entity.App{Name: strings.Repeat("a", maxNameLength+1)}, wantErr: ErrInvalidName},
+		{
+			name:    "unknown grant",
+			app:     entity.App{Name: "Web", GrantTypes: []string{"implicit"}},
+			wantErr: ErrInvalidGrantType,
+		},
+		{
+			name:    "relative redirect uri",
+			app:     entity.App{Name: "Web", RedirectURIs: []string{"/callback"}},
+			wantErr: ErrInvalidRedirectURI,
+		},
+		{
+			name:    "redirect uri with fragment",
+			app:     entity.App{Name: "Web", RedirectURIs: []string{"https://vizap.ru/cb#x"}},
+			wantErr: ErrInvalidRedirectURI,
+		},
+		{name: "negative ttl", app: entity.App{Name: "Web", AccessTokenTTL: -time.Minute}, wantErr: ErrInvalidTTL},
+		{name: "negative idle timeout", app: entity.App{Name: "Web", IdleTimeout: -time.Minute}, wantErr: ErrInvalidTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalize(tt.app)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalize() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.Name != strings.TrimSpace(tt.app.Name) {
+				t.Errorf("name = %q", got.Name)
+			}
+
+			if strings.Join(got.GrantTypes, ",") != strings.Join(tt.wantGrants, ",") {
+				t.Errorf("grant types = %v, want %v", got.GrantTypes, tt.wantGrants)
+			}
+
+			if got.RedirectURIs == nil {
+				t.Error("redirect uris are nil")
+			}
+		})
+	}
+}
+
+func TestCreateAppStoresOnlySecretHash(t *testing.T) {
+	ctx := context.Background()
+	a, st, audit := newTestApps()
+
+	app, clientSecret, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Vizap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.apps[app.ID].SigningKey == "" {
+		t.Error("app has no signing key")
+	}
+
+	secrets, _ := st.AppSecrets(app.ID)
+	if len(secrets) != 1 {
+		t.Fatalf("secrets = %d, want 1", len(secrets))
+	}
+
+	if secrets[0].SecretHash != otp.Hash(clientSecret) || secrets[0].SecretHash == clientSecret {
+		t.Error("client secret is not stored as its hash")
+	}
+
+	if !strings.HasSuffix(clientSecret, secrets[0].Hint) {
+		t.Errorf("hint %q is not the end of the secret", secrets[0].Hint)
+	}
+
+	for _, record := range audit.records {
+		if strings.Contains(string(record.Details), clientSecret) {
+			t.Errorf("audit record %s contains the secret", record.Action)
+		}
+	}
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// prepare changes the app or its secrets and returns the secret and
+		// app id to authenticate with.
+		prepare func(t *testing.T, a *Apps, app entity.App, clientSecret string) (string, int32)
+		wantErr error
+	}{
+		{
+			name: "first secret",
+			prepare: func(_ *testing.T, _ *Apps, app entity.App, clientSecret string) (string, int32) {
+				return clientSecret, app.ID
+			},
+		},
+		{
+			name: "rotated secret",
+			prepare: func(t *testing.T, a *Apps, app entity.App, _ string) (string, int32) {
+				secret, _, err := a.CreateSecret(ctx, "ivanov", app.ID, time.Now().Add(time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return secret, app.ID
+			},
+		},
+		{
+			name: "wrong secret",
+			prepare: func(_ *testing.T, _ *Apps, app entity.App, _ string) (string, int32) {
+				return "wrong", app.ID
+			},
+			wantErr: ErrInvalidClientSecret,
+		},
+		{
+			name: "secret of another app",
+			prepare: func(t *testing.T, a *Apps, _ entity.App, clientSecret string) (string, int32) {
+				other, _, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Other"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return clientSecret, other.ID
+			},
+			wantErr: ErrInvalidClientSecret,
+		},
+		{
+			name: "revoked secret",
+			prepare: func(t *testing.T, a *Apps, app entity.App, clientSecret string) (string, int32) {
+				if err := a.RevokeSecret(ctx, "ivanov", app.ID, 1); err != nil {
+					t.Fatal(err)
+				}
+				return clientSecret, app.ID
+			},
+			wantErr: ErrInvalidClientSecret,
+		},
+		{
+			name: "expired secret",
+			prepare: func(t *testing.T, a *Apps, app entity.App, _ string) (string, int32) {
+				secret, _, err := a.CreateSecret(ctx, "ivanov", app.ID, time.Now().Add(time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+				st := a.secretStorage.(*testStorage)
+				st.secrets[len(st.secrets)-1].ExpiresAt = time.Now().Add(-time.Minute)
+				return secret, app.ID
+			},
+			wantErr: ErrInvalidClientSecret,
+		},
+		{
+			name: "disabled app",
+			prepare: func(t *testing.T, a *Apps, app entity.App, clientSecret string) (string, int32) {
+				if err := a.SetAppDisabled(ctx, "ivanov", app.ID, true); err != nil {
+					t.Fatal(err)
+				}
+				return clientSecret, app.ID
+			},
+			wantErr: ErrInvalidClientSecret,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, _ := newTestApps()
+
+			app, clientSecret, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Vizap"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			secret, appID := tt.prepare(t, a, app, clientSecret)
+
+			if _, err := a.AuthenticateClient(ctx, appID, secret); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AuthenticateClient() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateSecretExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		wantErr   error
+	}{
+		{name: "never expires"},
+		{name: "expires later", expiresAt: time.Now().Add(24 * time.Hour)},
+		{name: "already expired", expiresAt: time.Now().Add(-time.Minute), wantErr: ErrInvalidTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, _ := newTestApps()
+
+			app, _, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Vizap"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, secret, err := a.CreateSecret(ctx, "ivanov", app.ID, tt.expiresAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateSecret() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !secret.ExpiresAt.Equal(tt.expiresAt) {
+				t.Errorf("expires at = %v, want %v", secret.ExpiresAt, tt.expiresAt)
+			}
+		})
+	}
+}
+
+func TestSetAppScopes(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		scopes     []string
+		wantScopes []string
+		wantErr    error
+	}{
+		{name: "scopes", scopes: []string{"orders:read", "orders:write"}, wantScopes: []string{"orders:read", "orders:write"}},
+		{name: "none", wantScopes: []string{}},
+		{name: "space", scopes: []string{"orders read"}, wantErr: ErrInvalidScope},
+		{name: "empty", scopes: []string{""}, wantErr: ErrInvalidScope},
+		{name: "quote", scopes: []string{`orders"`}, wantErr: ErrInvalidScope},
+		{name: "not ascii", scopes: []string{"заказы"}, wantErr: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, st, audit := newTestApps()
+
+			app, _, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Vizap"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = a.SetAppScopes(ctx, "ivanov", app.ID, tt.scopes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetAppScopes() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got := st.apps[app.ID].Scopes; got == nil || !slices.Equal(got, tt.wantScopes) {
+				t.Errorf("scopes = %v, want %v", got, tt.wantScopes)
+			}
+
+			if last := audit.records[len(audit.records)-1]; last.Action != actionSetScopes {
+				t.Errorf("audit action = %q, want %q", last.Action, actionSetScopes)
+			}
+		})
+	}
+}
+
+func TestGrantRole(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		role      string
+		appID     int32
+		wantRoles []string
+		wantErr   error
+	}{
+		{name: "role", role: "manager", wantRoles: []string{"manager"}},
+		{name: "empty", role: "", wantErr: ErrInvalidRole},
+		{name: "space", role: "order manager", wantErr: ErrInvalidRole},
+		{name: "long", role: strings.Repeat("a", maxRoleLength+1), wantErr: ErrInvalidRole},
+		{name: "unknown app", role: "manager", appID: 99, wantErr: storage.ErrAppNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, _ := newTestApps()
+
+			app, _, err := a.CreateApp(ctx, "ivanov", entity.App{Name: "Vizap"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			appID := app.ID
+			if tt.appID != 0 {
+				appID = tt.appID
+			}
+
+			err = a.GrantRole(ctx, "ivanov", 42, appID, tt.role)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GrantRole() = %v, want %v", err, tt.wantErr)
+			}
+
+			roles, err := a.UserRoles(ctx, 42, app.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(roles, tt.wantRoles) {
+				t.Errorf("roles = %v, want %v", roles, tt.wantRoles)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if err := a.RevokeRole(ctx, "ivanov", 42, app.ID, tt.role); err != nil {
+				t.Fatal(err)
+			}
+			if roles, _ := a.UserRoles(ctx, 42, app.ID); len(roles) != 0 {
+				t.Errorf("roles after revoke = %v", roles)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/auditchain"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testRecords serves records the way the postgres storage does.
+type testRecords []entity.AuditRecord
+
+func (r testRecords) AuditRecords(afterID int64, limit int) ([]entity.AuditRecord, error) {
+	var records []entity.AuditRecord
+	for _, record := range r {
+		if record.ID > afterID && len(records) < limit {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// chain returns n chained records after legacy unchained ones, with ids
+// starting at 1.
+func chain(t *testing.T, legacy, n int) testRecords {
+	t.Helper()
+
+	var records testRecords
+	var prevHash string
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= legacy+n; i++ {
+		record := entity.AuditRecord{
+			ID:        int64(i),
+			Actor:     "ivanov",
+			Action:    "admin.grant_role",
+			Details:   []byte(`{"n":"` + strconv.Itoa(i) + `"}`),
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}
+
+		if i > legacy {
+			hash, err := auditchain.Hash(prevHash, record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record.PrevHash = prevHash
+			record.Hash = hash
+			prevHash = hash
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func newTestAudit(records testRecords, key ed25519.PrivateKey) *Audit {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), records, key)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// tamper changes the chain of 5 records after 2 legacy ones.
+		tamper       func(records testRecords) testRecords
+		fromID, toID int64
+		wantChecked  int
+		wantProblems []Problem
+	}{
+		{name: "intact", tamper: func(r testRecords) testRecords { return r }, wantChecked: 7},
+		{
+			name: "modified record",
+			tamper: func(r testRecords) testRecords {
+				r[4].Actor = "petrov"
+				return r
+			},
+			wantChecked:  7,
+			wantProblems: []Problem{{RecordID: 5, Reason: ReasonModified}},
+		},
+		{
+			name: "deleted record",
+			tamper: func(r testRecords) testRecords {
+				return append(r[:3:3], r[4:]...)
+			},
+			wantChecked:  6,
+			wantProblems: []Problem{{RecordID: 5, Reason: ReasonBrokenLink}},
+		},
+		{
+			name: "hash removed",
+			tamper: func(r testRecords) testRecords {
+				r[5].Hash = ""
+				return r
+			},
+			wantChecked:  7,
+			wantProblems: []Problem{{RecordID: 6, Reason: ReasonUnchained}, {RecordID: 7, Reason: ReasonBrokenLink}},
+		},
+		{
+			name:        "range in the middle",
+			tamper:      func(r testRecords) testRecords { return r },
+			fromID:      4,
+			toID:        6,
+			wantChecked: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := tt.tamper(chain(t, 2, 5))
+
+			report, err := newTestAudit(records, nil).Verify(ctx, tt.fromID, tt.toID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Checked != tt.wantChecked {
+				t.Errorf("checked = %d, want %d", report.Checked, tt.wantChecked)
+			}
+
+			if len(report.Problems) != len(tt.wantProblems) {
+				t.Fatalf("problems = %+v, want %+v", report.Problems, tt.wantProblems)
+			}
+			for i, problem := range report.Problems {
+				if problem != tt.wantProblems[i] {
+					t.Errorf("problems[%d] = %+v, want %+v", i, problem, tt.wantProblems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		key          ed25519.PrivateKey
+		fromID, toID int64
+		wantIDs      []int64
+		wantErr      error
+	}{
+		{name: "whole log", key: key, wantIDs: []int64{1, 2, 3, 4}},
+		{name: "range", key: key, fromID: 2, toID: 3, wantIDs: []int64{2, 3}},
+		{name: "no key", wantErr: ErrNoSigningKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			count, err := newTestAudit(chain(t, 0, 4), tt.key).Export(ctx, &buf, tt.fromID, tt.toID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Export() = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if count != len(tt.wantIDs) {
+				t.Errorf("count = %d, want %d", count, len(tt.wantIDs))
+			}
+
+			public := key.Public().(ed25519.PublicKey)
+			scanner := bufio.NewScanner(&buf)
+			i := 0
+			for ; scanner.Scan(); i++ {
+				var line exportLine
+				if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+					t.Fatal(err)
+				}
+
+				sig, err := base64.StdEncoding.DecodeString(line.Signature)
+				if err != nil || !ed25519.Verify(public, line.Record, sig) {
+					t.Errorf("line %d: bad signature", i)
+				}
+				if line.KeyID != auditchain.KeyID(public) {
+					t.Errorf("line %d: key id = %s", i, line.KeyID)
+				}
+
+				var record exportRecord
+				if err := json.Unmarshal(line.Record, &record); err != nil {
+					t.Fatal(err)
+				}
+				if i < len(tt.wantIDs) && record.ID != tt.wantIDs[i] {
+					t.Errorf("line %d: id = %d, want %d", i, record.ID, tt.wantIDs[i])
+				}
+			}
+
+			if i != len(tt.wantIDs) {
+				t.Errorf("lines = %d, want %d", i, len(tt.wantIDs))
+			}
+		})
+	}
+}
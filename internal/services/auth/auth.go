@@ -4,16 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
-	"vizapSSO/internal/entity"
-	"vizapSSO/internal/lib/jwt"
-	"vizapSSO/internal/storage"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrInvalidCode           = errors.New("invalid confirmation code")
+	ErrPasswordResetRequired = errors.New("password reset is required")
+	ErrAppDisabled           = errors.New("app is disabled")
+	ErrGrantNotAllowed       = errors.New("grant type is not allowed for the app")
+	ErrTooManyAttempts       = errors.New("too many failed password attempts")
 )
 
 type Auth struct {
@@ -25,6 +33,32 @@ type Auth struct {
 	accessTokenTTL      time.Duration
 	refreshTokenTTL     time.Duration
 	userProvider        UserProvider
+	passHasher          PasswordHasher
+	regStorage          RegistrationStorage
+	smsSender           SMSSender
+	codeLimiter         CodeLimiter
+	regCfg              config.RegistrationConfig
+	phones              PhoneNormalizer
+	passStorage         PasswordStorage
+	passPolicy          PasswordPolicy
+	passwordHistory     int
+	failureStorage      LoginFailureStorage
+	lockoutCfg          config.LockoutConfig
+	phoneStorage        PhoneChangeStorage
+	phoneCfg            config.PhoneConfig
+	emailUserProvider   EmailUserProvider
+	emailSender         EmailSender
+	resetStorage        PasswordResetStorage
+	resetCfg            config.PasswordResetConfig
+	deletionStorage     DeletionStorage
+	accountCfg          config.AccountConfig
+	sessionStorage      SessionStorage
+	locator             Locator
+	sessionCfg          config.SessionConfig
+	eventSaver          SecurityEventSaver
+	tokenKeys           *jwt.Keys
+	// sends are the reset links being saved and sent in the background.
+	sends sync.WaitGroup
 }
 
 type UserSaver interface {
@@ -37,14 +71,102 @@ type UserProvider interface {
 
 type AppProvider interface {
 	App(appID int32) (entity.App, error)
+	UserAppRoles(uid int64, appID int32) ([]string, error)
 }
 
 type RefreshTokenSaver interface {
-	SaveRefreshToken(refreshToken string, uid int64) error
+	// SaveRefreshToken fails with storage.ErrRefreshTokenUsed when previous
+	// was already exchanged.
+	SaveRefreshToken(refreshToken string, uid, sessionID int64, previous string) error
 }
 
 type RefreshTokenChecker interface {
-	CheckRefreshToken(refreshToken string) error
+	RefreshToken(refreshToken string) (entity.RefreshToken, error)
+}
+
+type PasswordHasher interface {
+	Generate(ctx context.Context, password []byte) ([]byte, error)
+	Compare(ctx context.Context, hash, password []byte) error
+	Dummy() []byte
+}
+
+type RegistrationStorage interface {
+	SavePendingRegistration(phone string, passHash []byte, codeHash string, expiresAt time.Time) error
+	PendingRegistration(phone string) (entity.PendingRegistration, error)
+	IncPendingRegistrationAttempts(phone string) error
+	DeletePendingRegistration(phone string) error
+}
+
+type PasswordStorage interface {
+	UserByID(uid int64) (entity.User, error)
+	UpdatePassword(uid int64, passHash []byte) error
+	PasswordHistory(uid int64, limit int) ([][]byte, error)
+}
+
+// LoginFailureStorage counts password attempts per login.
+type LoginFailureStorage interface {
+	// TakeLoginAttempt counts an attempt of the key made at, unless limit
+	// attempts made since are already counted, and forgets the older ones.
+	// It returns the number of the attempt since then, or zero if it was
+	// not counted. Attempts of a key are counted one at a time.
+	TakeLoginAttempt(key string, at, since time.Time, limit int) (attempt int, err error)
+	DeleteLoginFailures(key string) error
+}
+
+type PasswordPolicy interface {
+	Validate(password string) error
+}
+
+type PhoneChangeStorage interface {
+	SavePhoneChange(change entity.PhoneChange) error
+	PhoneChange(uid int64) (entity.PhoneChange, error)
+	IncPhoneChangeAttempts(uid int64) error
+	DeletePhoneChange(uid int64) error
+	PhoneReleasedSince(phone string, uid int64, since time.Time) (bool, error)
+	// ChangeUserPhone also signs out every session of the user but
+	// keepSessionID.
+	ChangeUserPhone(uid int64, newPhone string, keepSessionID int64) error
+}
+
+type EmailUserProvider interface {
+	ProvideUserByEmail(email string) (entity.User, error)
+}
+
+type PasswordResetStorage interface {
+	SavePasswordReset(tokenHash string, uid int64, expiresAt time.Time) error
+	PasswordReset(tokenHash string) (entity.PasswordReset, error)
+	// ResetPassword spends the token, sets the password and signs the
+	// user out everywhere in one go.
+	ResetPassword(tokenHash string, passHash []byte) error
+}
+
+// DeletionStorage takes a nil audit record: users deleting and restoring
+// their own accounts are recorded as security events instead.
+type DeletionStorage interface {
+	MarkUserDeleted(uid int64, changedBy, reason string, purgeAfter time.Time, audit *entity.AuditRecord) error
+	RestoreUser(uid int64, changedBy, reason string, audit *entity.AuditRecord) error
+	SaveDeletionCode(uid int64, codeHash string, expiresAt time.Time) error
+	DeletionCode(uid int64) (entity.DeletionCode, error)
+	IncDeletionCodeAttempts(uid int64) error
+	DeleteDeletionCode(uid int64) error
+}
+
+type EmailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type PhoneNormalizer interface {
+	Normalize(raw string) (string, error)
+	Variants(e164 string) []string
+}
+
+type SMSSender interface {
+	Send(ctx context.Context, phone, text string) error
+}
+
+// CodeLimiter caps the messages with codes sent to one phone or email.
+type CodeLimiter interface {
+	Allow(purpose, target string) error
 }
 
 func New(log *slog.Logger,
@@ -53,21 +175,71 @@ func New(log *slog.Logger,
 	userProvider UserProvider,
 	refreshTokenSaver RefreshTokenSaver,
 	refreshTokenChecker RefreshTokenChecker,
+	passHasher PasswordHasher,
+	regStorage RegistrationStorage,
+	smsSender SMSSender,
+	codeLimiter CodeLimiter,
+	regCfg config.RegistrationConfig,
+	phones PhoneNormalizer,
+	passStorage PasswordStorage,
+	passPolicy PasswordPolicy,
+	passwordHistory int,
+	failureStorage LoginFailureStorage,
+	lockoutCfg config.LockoutConfig,
+	phoneStorage PhoneChangeStorage,
+	phoneCfg config.PhoneConfig,
+	emailUserProvider EmailUserProvider,
+	emailSender EmailSender,
+	resetStorage PasswordResetStorage,
+	resetCfg config.PasswordResetConfig,
+	deletionStorage DeletionStorage,
+	accountCfg config.AccountConfig,
+	sessionStorage SessionStorage,
+	locator Locator,
+	sessionCfg config.SessionConfig,
+	eventSaver SecurityEventSaver,
 	accessTokenTTL time.Duration,
-	refreshTokenTTL time.Duration) *Auth {
+	refreshTokenTTL time.Duration,
+	tokenKeys *jwt.Keys) *Auth {
 	return &Auth{
 		usrSaver:            userSaver,
 		appProvider:         appProvider,
 		userProvider:        userProvider,
 		refreshTokenSaver:   refreshTokenSaver,
 		refreshTokenChecker: refreshTokenChecker,
+		passHasher:          passHasher,
+		regStorage:          regStorage,
+		smsSender:           smsSender,
+		codeLimiter:         codeLimiter,
+		regCfg:              regCfg,
+		phones:              phones,
+		passStorage:         passStorage,
+		passPolicy:          passPolicy,
+		passwordHistory:     passwordHistory,
+		failureStorage:      failureStorage,
+		lockoutCfg:          lockoutCfg,
+		phoneStorage:        phoneStorage,
+		phoneCfg:            phoneCfg,
+		emailUserProvider:   emailUserProvider,
+		emailSender:         emailSender,
+		resetStorage:        resetStorage,
+		resetCfg:            resetCfg,
+		deletionStorage:     deletionStorage,
+		accountCfg:          accountCfg,
+		sessionStorage:      sessionStorage,
+		locator:             locator,
+		sessionCfg:          sessionCfg,
+		eventSaver:          eventSaver,
 		accessTokenTTL:      accessTokenTTL,
 		refreshTokenTTL:     refreshTokenTTL,
+		tokenKeys:           tokenKeys,
 		log:                 log,
 	}
 }
 
-func (a *Auth) Login(ctx context.Context, phone, password string, appID int32,
+// Login accepts a phone or a verified email as the login. Every login
+// starts a new session of the device.
+func (a *Auth) Login(ctx context.Context, login, password string, appID int32, device entity.Device,
 ) (accessToken, refreshToken string, err error) {
 	const op = "auth.Login"
 
@@ -75,20 +247,34 @@ func (a *Auth) Login(ctx context.Context, phone, password string, appID int32,
 
 	log.Info("login attempt")
 
-	user, err := a.userProvider.ProvideUser(phone)
+	user, err := a.checkCredentials(ctx, login, password)
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: user.ID,
+			Type:   entity.EventLogin,
+			Method: entity.MethodPassword,
+			AppID:  appID,
+		}, err)
+	}()
+
 	if err != nil {
-		log.Error("failed to provide user", err)
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if user.ID == 0 {
-		log.Error("phone not found", storage.ErrUserNotFound)
-		return "", "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	if user.State.Status == entity.AccountDeleted && user.State.ChangedBy == userActor(user.ID) {
+		log.Info("login to account scheduled for deletion", slog.Int64("uid", user.ID))
+		return "", "", fmt.Errorf("%s: %w", op, &PendingDeletionError{PurgeAfter: user.PurgeAfter})
+	}
+
+	if err := checkActive(user); err != nil {
+		log.Info("login to inactive account", slog.Int64("uid", user.ID), sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials", ErrInvalidCredentials)
-		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	if user.PasswordResetRequired {
+		log.Info("login while password reset is required", slog.Int64("uid", user.ID))
+		return "", "", fmt.Errorf("%s: %w", op, ErrPasswordResetRequired)
 	}
 
 	app, err := a.appProvider.App(appID)
@@ -96,54 +282,89 @@ func (a *Auth) Login(ctx context.Context, phone, password string, appID int32,
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("user logged in success")
-
-	accessToken, err = jwt.NewAccessToken(user, app, a.accessTokenTTL)
-	if err != nil {
-		a.log.Error("failed to generate access token", err)
-
+	if err := checkApp(app, entity.GrantPassword); err != nil {
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	lastChar := accessToken[len(accessToken)-6:]
+	log.Info("user logged in success")
 
-	refreshToken, err = jwt.NewRefreshToken(lastChar, app, a.refreshTokenTTL)
+	accessToken, refreshToken, err = a.startSession(user, app, device)
 	if err != nil {
-		a.log.Error("failed to generate refresh token", err)
+		log.Error("failed to issue tokens", sl.Err(err))
 
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := a.refreshTokenSaver.SaveRefreshToken(refreshToken, user.ID); err != nil {
-		a.log.Error("failed to save refresh token", err)
-	}
-
 	return accessToken, refreshToken, nil
 }
 
+// RegisterNewUser creates the user right away, or, when enumeration-safe
+// registration is on, only sends a confirmation code and returns pending=true.
+// The account is then created by ConfirmRegistration.
 func (a *Auth) RegisterNewUser(ctx context.Context, phone, password string,
-) (userID int64, err error) {
+) (userID int64, pending bool, err error) {
 	const op = "auth.RegisterNewUser"
 
 	log := a.log.With(slog.String("op", op))
 
 	log.Info("registering user")
 
-	passwordHashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	phone, err = a.phones.Normalize(phone)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.passPolicy.Validate(password); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	passwordHashed, err := a.passHasher.Generate(ctx, []byte(password))
 	if err != nil {
-		log.Error("failed to generate password hash", err)
-		return 0, fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to generate password hash", sl.Err(err))
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	released, err := a.phoneStorage.PhoneReleasedSince(phone, 0, time.Now().Add(-a.phoneCfg.ReuseCooldown))
+	if err != nil {
+		log.Error("failed to check phone history", sl.Err(err))
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if a.regCfg.EnumerationSafe {
+		if released {
+			// Answer as usual, the number just can't be claimed yet.
+			return 0, true, nil
+		}
+
+		if err := a.requestRegistration(ctx, phone, passwordHashed); err != nil {
+			return 0, false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return 0, true, nil
+	}
+
+	if released {
+		return 0, false, fmt.Errorf("%s: %w", op, ErrPhoneCooldown)
+	}
+
+	// SaveUser only sees the E.164 form; the account may still have the
+	// phone stored the old way.
+	if _, err := a.userByPhone(phone); err == nil {
+		return 0, false, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	} else if !errors.Is(err, storage.ErrUserNotFound) {
+		log.Error("failed to provide user", sl.Err(err))
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
 	id, err := a.usrSaver.SaveUser(phone, passwordHashed)
 	if err != nil {
-		log.Error("failed to save user", err)
-		return 0, fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to save user", sl.Err(err))
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("user successfully register")
 
-	return id, nil
+	return id, false, nil
 }
 
 func (a *Auth) ValidateSession(ctx context.Context, accessToken string) (isValid bool, uid int64, err error) {
@@ -153,15 +374,15 @@ func (a *Auth) ValidateSession(ctx context.Context, accessToken string) (isValid
 
 	log.Info("validate user token")
 
-	isValid, uid, err = jwt.ValidateToken(accessToken)
+	uid, _, err = a.authorize(ctx, accessToken)
 	if err != nil {
-		log.Error("failed validate token", err)
+		log.Info("token is not valid", sl.Err(err))
 		return false, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("token successfully validate")
 
-	return isValid, uid, nil
+	return true, uid, nil
 }
 
 func (a *Auth) RefreshSession(ctx context.Context, accessToken, refreshToken string) (newAccessToken, newRefreshToken string, err error) {
@@ -171,49 +392,87 @@ func (a *Auth) RefreshSession(ctx context.Context, accessToken, refreshToken str
 
 	log.Info("refresh user token")
 
-	err = jwt.CheckRefreshToken(refreshToken, accessToken)
+	appID, err := jwt.AppIDFromJWT(accessToken)
 	if err != nil {
-		log.Error("failed to validate token pair", err)
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
 	}
 
-	uid, err := jwt.IdFromJWT(accessToken)
-
-	err = a.refreshTokenChecker.CheckRefreshToken(refreshToken)
+	app, err := a.appProvider.App(appID)
 	if err != nil {
-		a.log.Error("invalid refresh token", err)
-		return "", "", err
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
+		}
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	var user entity.User
-	user.ID = uid
+	if err := checkApp(app, entity.GrantRefreshToken); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
 
-	//TODO: исправить данную реализацию
-	app, err := a.appProvider.App(1)
+	// The session limits are checked before the token itself: the refresh
+	// token expires with the session, and the user should learn which
+	// limit ended it rather than get a plain invalid token error.
+	stored, err := a.refreshTokenChecker.RefreshToken(refreshToken)
 	if err != nil {
+		log.Info("invalid refresh token", sl.Err(err))
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info(app.Secret)
+	// The named err is final by the time deferred calls run.
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: stored.UserID,
+			Type:   entity.EventRefresh,
+			Method: entity.MethodRefreshToken,
+			AppID:  app.ID,
+		}, err)
+	}()
+
+	if !stored.IsActive {
+		if !stored.SessionRevoked {
+			a.revokeReusedSession(log, stored)
+			return "", "", fmt.Errorf("%s: %w", op, ErrRefreshTokenReused)
+		}
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
+	}
 
-	newAccessToken, err = jwt.NewAccessToken(user, app, a.accessTokenTTL)
-	if err != nil {
-		a.log.Error("failed to generate access token", err)
+	policy := a.sessionPolicy(app)
 
+	if err := checkSessionLimits(policy, stored, time.Now()); err != nil {
+		log.Info("session is over", slog.Int64("uid", stored.UserID), sl.Err(err))
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	lastChar := newAccessToken[len(newAccessToken)-6:]
+	err = jwt.CheckRefreshToken(refreshToken, accessToken, verifyingApp(app, policy, time.Now()))
+	if err != nil {
+		log.Info("failed to validate token pair", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
+	}
+
+	uid, err := jwt.IdFromJWT(accessToken)
+	if err != nil || uid != stored.UserID {
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
+	}
 
-	newRefreshToken, err = jwt.NewRefreshToken(lastChar, app, a.refreshTokenTTL)
+	user, err := a.passStorage.UserByID(uid)
 	if err != nil {
-		a.log.Error("failed to generate refresh token", err)
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
 
+	if err := checkActive(user); err != nil {
 		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := a.refreshTokenSaver.SaveRefreshToken(newRefreshToken, user.ID); err != nil {
-		a.log.Error("failed to save refresh token", err)
+	newAccessToken, newRefreshToken, err = a.issueTokens(user, app, stored.SessionID, stored.StartedAt, refreshToken)
+	if err != nil {
+		// Another refresh exchanged the token between the check above and
+		// now.
+		if errors.Is(err, ErrRefreshTokenReused) {
+			a.revokeReusedSession(log, stored)
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+		log.Error("failed to issue tokens", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("user token successfully refreshed")
@@ -221,10 +480,86 @@ func (a *Auth) RefreshSession(ctx context.Context, accessToken, refreshToken str
 	return newAccessToken, newRefreshToken, nil
 }
 
-func (a *Auth) RequestPasswordReset(ctx context.Context, email string) (response string, err error) {
-	panic("implement me")
+// revokeReusedSession signs out the session of a refresh token that was
+// exchanged twice. Either the client retried or the token was stolen, and
+// there is no telling which pair the attacker holds, so neither may keep
+// the session. It is best-effort: the refresh is refused either way.
+func (a *Auth) revokeReusedSession(log *slog.Logger, stored entity.RefreshToken) {
+	log.Warn("refresh token reused", slog.Int64("uid", stored.UserID), slog.Int64("session_id", stored.SessionID))
+
+	err := a.sessionStorage.RevokeSession(stored.UserID, stored.SessionID, "refresh_token_reused")
+	if err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		log.Error("failed to revoke session", sl.Err(err))
+	}
+}
+
+// checkCredentials returns the user if the password matches. On a wrong
+// password the user is returned too, so the failed attempt can be shown to
+// them. An unknown login takes as long as a wrong password and is locked
+// out the same way, so neither tells which phones are registered.
+func (a *Auth) checkCredentials(ctx context.Context, login, password string) (entity.User, error) {
+	user, err := a.provideUser(login)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return entity.User{}, err
+	}
+
+	if user.ID == 0 {
+		key, err := a.loginFailureKey(login)
+		if err != nil {
+			return entity.User{}, err
+		}
+
+		if err := a.checkPassword(ctx, 0, key, a.passHasher.Dummy(), password); err != nil {
+			return entity.User{}, err
+		}
+
+		return entity.User{}, ErrInvalidCredentials
+	}
+
+	if err := a.checkPassword(ctx, user.ID, userFailureKey(user.ID), user.PassHash, password); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrTooManyAttempts) {
+			return user, err
+		}
+		return entity.User{}, err
+	}
+
+	return user, nil
+}
+
+// provideUser finds the user by phone or, when login looks like an email,
+// by a verified email.
+func (a *Auth) provideUser(login string) (entity.User, error) {
+	if isEmail(login) {
+		return a.emailUserProvider.ProvideUserByEmail(strings.TrimSpace(login))
+	}
+
+	phone, err := a.phones.Normalize(login)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	return a.userByPhone(phone)
+}
+
+// userByPhone finds the user by an E.164 phone. With phone.legacy_lookup,
+// it also looks for the forms numbers were stored in before cmd/phones
+// -apply rewrote them.
+func (a *Auth) userByPhone(phone string) (entity.User, error) {
+	user, err := a.userProvider.ProvideUser(phone)
+	if !errors.Is(err, storage.ErrUserNotFound) || !a.phoneCfg.LegacyLookup {
+		return user, err
+	}
+
+	for _, variant := range a.phones.Variants(phone) {
+		user, err = a.userProvider.ProvideUser(variant)
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return user, err
+		}
+	}
+
+	return entity.User{}, storage.ErrUserNotFound
 }
 
-func (a *Auth) PerformPasswordReset(ctx context.Context, token, newPassword string) (success bool, err error) {
-	panic("implement me")
+func isEmail(login string) bool {
+	return strings.Contains(login, "@")
 }
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/geoip"
+	"github.com/KVSH-user/vizapSSO/internal/lib/hasher"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"github.com/KVSH-user/vizapSSO/internal/lib/phone"
+	"github.com/KVSH-user/vizapSSO/internal/lib/siem"
+	"github.com/KVSH-user/vizapSSO/internal/storage/memory"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testPhone    = "+79991234567"
+	testPassword = "secret123"
+)
+
+// testEnv is an Auth on the in-memory storage with captured messages.
+type testEnv struct {
+	auth    *Auth
+	storage *memory.Storage
+	hasher  *hasher.Hasher
+	outbox  *testOutbox
+	app     entity.App
+	keys    *jwt.Keys
+}
+
+type testMessage struct {
+	to      string
+	subject string
+	text    string
+}
+
+type testOutbox struct {
+	mu       sync.Mutex
+	messages []testMessage
+	// failure, when set, is returned by every send.
+	failure error
+}
+
+func (o *testOutbox) add(msg testMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failure != nil {
+		return o.failure
+	}
+
+	o.messages = append(o.messages, msg)
+
+	return nil
+}
+
+func (o *testOutbox) to(to string) []testMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var messages []testMessage
+	for _, msg := range o.messages {
+		if msg.to == to {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+type testSMS struct{ outbox *testOutbox }
+
+func (s testSMS) Send(_ context.Context, phone, text string) error {
+	return s.outbox.add(testMessage{to: phone, text: text})
+}
+
+type testEmail struct{ outbox *testOutbox }
+
+func (s testEmail) Send(_ context.Context, to, subject, body string) error {
+	return s.outbox.add(testMessage{to: to, subject: subject, text: body})
+}
+
+var codePattern = regexp.MustCompile(`\d{6}`)
+
+// lastCode returns the code in the last message sent to the target.
+func (e *testEnv) lastCode(t *testing.T, to string) string {
+	t.Helper()
+
+	messages := e.outbox.to(to)
+	if len(messages) == 0 {
+		t.Fatalf("no messages to %s", to)
+	}
+
+	code := codePattern.FindString(messages[len(messages)-1].text)
+	if code == "" {
+		t.Fatalf("no code in %q", messages[len(messages)-1].text)
+	}
+
+	return code
+}
+
+type testOption func(*testConfig)
+
+type testConfig struct {
+	registration config.RegistrationConfig
+	lockout      config.LockoutConfig
+	phone        config.PhoneConfig
+	account      config.AccountConfig
+	reset        config.PasswordResetConfig
+	session      config.SessionConfig
+	codeLimit    int
+}
+
+func withEnumerationSafe() testOption {
+	return func(c *testConfig) { c.registration.EnumerationSafe = true }
+}
+
+func withLegacyLookup() testOption {
+	return func(c *testConfig) { c.phone.LegacyLookup = true }
+}
+
+func withCodeLimit(limit int) testOption {
+	return func(c *testConfig) { c.codeLimit = limit }
+}
+
+func newTestEnv(t *testing.T, opts ...testOption) *testEnv {
+	t.Helper()
+
+	cfg := testConfig{
+		registration: config.RegistrationConfig{CodeTTL: 10 * time.Minute, MaxAttempts: 3},
+		lockout:      config.LockoutConfig{MaxFailures: 3, Window: 15 * time.Minute},
+		phone: config.PhoneConfig{
+			DefaultRegion:     "RU",
+			ChangeCodeTTL:     10 * time.Minute,
+			ChangeMaxAttempts: 3,
+			ReuseCooldown:     720 * time.Hour,
+		},
+		account:   config.AccountConfig{DeletionGracePeriod: 720 * time.Hour, CodeTTL: 10 * time.Minute, CodeMaxAttempts: 3},
+		reset:     config.PasswordResetConfig{TokenTTL: time.Hour, LinkFormat: "https://sso.test/reset?token=%s"},
+		session:   config.SessionConfig{SlidingRefresh: true},
+		codeLimit: 100,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	phones, err := phone.New("RU", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locator, err := geoip.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := memory.New()
+	h := hasher.New(0, time.Second, bcrypt.MinCost)
+	outbox := &testOutbox{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := jwt.NewKeys(signingKey)
+
+	a := New(log, st, st, st, st, st, h, st, testSMS{outbox}, otp.NewLimiter(st, cfg.codeLimit, time.Hour),
+		cfg.registration, phones, st, password.NewPolicy(8, 72), 3, st, cfg.lockout, st, cfg.phone, st, testEmail{outbox}, st,
+		cfg.reset, st, cfg.account, st, locator, cfg.session, siem.NewRecorder(st, st, nil),
+		15*time.Minute, 720*time.Hour, keys)
+
+	app, err := st.SaveApp(entity.App{
+		Name:       "test",
+		SigningKey: "test-signing-key",
+		GrantTypes: []string{entity.GrantPassword, entity.GrantRefreshToken},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testEnv{auth: a, storage: st, hasher: h, outbox: outbox, app: app, keys: keys}
+}
+
+// addUser registers an active user with testPassword.
+func (e *testEnv) addUser(t *testing.T, phone string) entity.User {
+	t.Helper()
+
+	hash, err := e.hasher.Generate(context.Background(), []byte(testPassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, err := e.storage.SaveUser(phone, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := e.storage.UserByID(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// login signs the user in from a new device.
+func (e *testEnv) login(t *testing.T, phone string) (accessToken, refreshToken string) {
+	t.Helper()
+
+	accessToken, refreshToken, err := e.auth.Login(context.Background(), phone, testPassword, e.app.ID, entity.Device{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	return accessToken, refreshToken
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrAccountPendingDeletion = errors.New("account is scheduled for deletion")
+	ErrConfirmationRequired   = errors.New("password or code is required")
+)
+
+const (
+	msgDeletionCode    = "Код для удаления аккаунта Vizap: %s. Никому его не сообщайте."
+	msgAccountDeleted  = "Ваш аккаунт Vizap будет удалён %s. До этого его можно восстановить, просто войдя в него."
+	deletionDateLayout = "02.01.2006"
+)
+
+// PendingDeletionError is returned by Login for an account the user
+// deleted themselves and that is still in its grace period, so the app can
+// offer to restore it.
+type PendingDeletionError struct {
+	PurgeAfter time.Time
+}
+
+func (e *PendingDeletionError) Error() string {
+	return fmt.Sprintf("account is scheduled for deletion after %s", e.PurgeAfter.Format(time.RFC3339))
+}
+
+func (e *PendingDeletionError) Is(target error) bool {
+	return target == ErrAccountPendingDeletion
+}
+
+// RequestDeletionCode sends a code that can confirm account deletion
+// instead of the password.
+func (a *Auth) RequestDeletionCode(ctx context.Context, accessToken string) error {
+	const op = "auth.RequestDeletionCode"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, _, err := a.authorize(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.codeLimiter.Allow(otp.PurposeAccountDeletion, user.Phone); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := otp.Generate(codeDigits)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.dropExpiredDeletionCode(uid); err != nil {
+		log.Error("failed to get deletion code", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.deletionStorage.SaveDeletionCode(uid, otp.Hash(code), time.Now().Add(a.accountCfg.CodeTTL)); err != nil {
+		log.Error("failed to save deletion code", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.smsSender.Send(ctx, user.Phone, fmt.Sprintf(msgDeletionCode, code)); err != nil {
+		log.Error("failed to send sms", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteAccount schedules the account for deletion after the grace period.
+// The user confirms it with the password or a code from RequestDeletionCode.
+func (a *Auth) DeleteAccount(ctx context.Context, accessToken, password, code string) (purgeAfter time.Time, err error) {
+	const op = "auth.DeleteAccount"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, app, err := a.authorize(ctx, accessToken)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	method := entity.MethodPassword
+	if password == "" {
+		method = entity.MethodSMSCode
+	}
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: uid,
+			Type:   entity.EventAccountDelete,
+			Method: method,
+			AppID:  app.ID,
+		}, err)
+	}()
+
+	log = log.With(slog.Int64("uid", uid))
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case password != "":
+		if err := a.checkPassword(ctx, uid, userFailureKey(uid), user.PassHash, password); err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+	case code != "":
+		if err := a.checkDeletionCode(uid, code); err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("%s: %w", op, ErrConfirmationRequired)
+	}
+
+	purgeAfter = time.Now().Add(a.accountCfg.DeletionGracePeriod)
+
+	if err := a.deletionStorage.MarkUserDeleted(uid, userActor(uid), "deleted by user", purgeAfter, nil); err != nil {
+		log.Error("failed to mark user deleted", sl.Err(err))
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.deletionStorage.DeleteDeletionCode(uid); err != nil {
+		log.Error("failed to delete deletion code", sl.Err(err))
+	}
+
+	if err := a.smsSender.Send(ctx, user.Phone, fmt.Sprintf(msgAccountDeleted, purgeAfter.Format(deletionDateLayout))); err != nil {
+		log.Error("failed to send sms", sl.Err(err))
+	}
+
+	log.Info("account scheduled for deletion", slog.Time("purge_after", purgeAfter))
+
+	return purgeAfter, nil
+}
+
+// RestoreAccount cancels deletion of an account in its grace period and
+// signs the user in.
+func (a *Auth) RestoreAccount(ctx context.Context, login, password string, appID int32, device entity.Device,
+) (accessToken, refreshToken string, err error) {
+	const op = "auth.RestoreAccount"
+
+	log := a.log.With(slog.String("op", op))
+
+	user, err := a.checkCredentials(ctx, login, password)
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: user.ID,
+			Type:   entity.EventAccountRestore,
+			Method: entity.MethodPassword,
+			AppID:  appID,
+		}, err)
+	}()
+
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", user.ID))
+
+	if user.State.Status != entity.AccountDeleted {
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	// Accounts deleted by support can only be restored by support.
+	if user.State.ChangedBy != userActor(user.ID) {
+		return "", "", fmt.Errorf("%s: %w", op, checkActive(user))
+	}
+
+	if err := a.deletionStorage.RestoreUser(user.ID, userActor(user.ID), "restored by user", nil); err != nil {
+		log.Error("failed to restore user", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.appProvider.App(appID)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkApp(app, entity.GrantPassword); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, refreshToken, err = a.startSession(user, app, device)
+	if err != nil {
+		log.Error("failed to issue tokens", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("account restored")
+
+	return accessToken, refreshToken, nil
+}
+
+func (a *Auth) checkDeletionCode(uid int64, code string) error {
+	stored, err := a.deletionStorage.DeletionCode(uid)
+	if err != nil {
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+
+	if time.Now().After(stored.ExpiresAt) || stored.Attempts >= a.accountCfg.CodeMaxAttempts {
+		return ErrInvalidCode
+	}
+
+	if !otp.Equal(code, stored.CodeHash) {
+		if err := a.deletionStorage.IncDeletionCodeAttempts(uid); err != nil {
+			return err
+		}
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+// dropExpiredDeletionCode deletes the user's deletion code once it has
+// expired. A live one is overwritten by the new code and keeps its attempt
+// counter.
+func (a *Auth) dropExpiredDeletionCode(uid int64) error {
+	stored, err := a.deletionStorage.DeletionCode(uid)
+	if errors.Is(err, storage.ErrVerificationNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return a.deletionStorage.DeleteDeletionCode(uid)
+	}
+
+	return nil
+}
+
+// checkActive rejects accounts that are not active, see accountstate.Check.
+func checkActive(user entity.User) error {
+	return accountstate.Check(user.State, time.Now())
+}
+
+// userActor names the account owner in status changes and audit records.
+func userActor(uid int64) string {
+	return "user:" + strconv.FormatInt(uid, 10)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"testing"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		password string
+		// code returns the code to confirm with, after any requests it needs.
+		code    func(t *testing.T, env *testEnv, access string) string
+		wantErr error
+	}{
+		{name: "password", password: testPassword},
+		{name: "wrong password", password: "wrong123", wantErr: ErrInvalidCredentials},
+		{name: "nothing", wantErr: ErrConfirmationRequired},
+		{
+			name: "code",
+			code: func(t *testing.T, env *testEnv, access string) string {
+				if err := env.auth.RequestDeletionCode(ctx, access); err != nil {
+					t.Fatalf("RequestDeletionCode: %v", err)
+				}
+				return env.lastCode(t, testPhone)
+			},
+		},
+		{
+			name: "wrong code",
+			code: func(t *testing.T, env *testEnv, access string) string {
+				if err := env.auth.RequestDeletionCode(ctx, access); err != nil {
+					t.Fatalf("RequestDeletionCode: %v", err)
+				}
+				return "000000"
+			},
+			wantErr: ErrInvalidCode,
+		},
+		{
+			name: "attempts survive a resend",
+			code: func(t *testing.T, env *testEnv, access string) string {
+				if err := env.auth.RequestDeletionCode(ctx, access); err != nil {
+					t.Fatalf("RequestDeletionCode: %v", err)
+				}
+				for i := 0; i < 3; i++ {
+					if _, err := env.auth.DeleteAccount(ctx, access, "", "000000"); !errors.Is(err, ErrInvalidCode) {
+						t.Fatalf("DeleteAccount() = %v, want %v", err, ErrInvalidCode)
+					}
+				}
+				if err := env.auth.RequestDeletionCode(ctx, access); err != nil {
+					t.Fatalf("RequestDeletionCode: %v", err)
+				}
+				return env.lastCode(t, testPhone)
+			},
+			wantErr: ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			var code string
+			if tt.code != nil {
+				code = tt.code(t, env, access)
+			}
+
+			_, err := env.auth.DeleteAccount(ctx, access, tt.password, code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteAccount() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, _, loginErr := env.auth.Login(ctx, testPhone, testPassword, env.app.ID, entity.Device{})
+
+			if tt.wantErr != nil {
+				if loginErr != nil {
+					t.Fatalf("Login after a failed deletion: %v", loginErr)
+				}
+				return
+			}
+
+			if !errors.Is(loginErr, ErrAccountPendingDeletion) {
+				t.Fatalf("Login() = %v, want %v", loginErr, ErrAccountPendingDeletion)
+			}
+		})
+	}
+}
+
+func TestRequestDeletionCodeLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		requests int
+		wantErr  error
+	}{
+		{name: "within the limit", requests: 2},
+		{name: "over the limit", requests: 3, wantErr: otp.ErrTooManyCodes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withCodeLimit(2))
+			env.addUser(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			var err error
+			for i := 0; i < tt.requests; i++ {
+				err = env.auth.RequestDeletionCode(ctx, access)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestDeletionCode() = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := len(env.outbox.to(testPhone)); got > 2 {
+				t.Errorf("codes sent = %d, want at most 2", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/clientinfo"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+)
+
+type SecurityEventSaver interface {
+	SaveSecurityEvent(event entity.SecurityEvent) error
+}
+
+// recordEvent saves the outcome of an authentication attempt or a change of
+// credentials. UserID is zero for logins to unknown accounts. Failing to
+// record doesn't fail the request.
+func (a *Auth) recordEvent(ctx context.Context, event entity.SecurityEvent, err error) {
+	const op = "auth.recordEvent"
+
+	client := clientinfo.FromContext(ctx)
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+
+	event.Outcome = entity.OutcomeSuccess
+	if err != nil {
+		event.Outcome = entity.OutcomeFailure
+		event.Reason = eventReason(err)
+	}
+
+	if err := a.eventSaver.SaveSecurityEvent(event); err != nil {
+		a.log.Error("failed to save security event",
+			slog.String("op", op),
+			slog.String("type", event.Type),
+			slog.Int64("uid", event.UserID),
+			sl.Err(err),
+		)
+	}
+}
+
+// eventReason is a stable code of why an attempt failed. Errors not listed
+// here are internal ones and are recorded as "error".
+func eventReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return "invalid_credentials"
+	case errors.Is(err, ErrTooManyAttempts):
+		return "too_many_attempts"
+	case errors.Is(err, ErrInvalidCode):
+		return "invalid_code"
+	case errors.Is(err, ErrInvalidResetToken):
+		return "invalid_reset_token"
+	case errors.Is(err, ErrPasswordResetRequired):
+		return "password_reset_required"
+	case errors.Is(err, ErrPasswordReused):
+		return "password_reused"
+	case errors.Is(err, ErrAccountPendingDeletion):
+		return "account_pending_deletion"
+	case errors.Is(err, accountstate.ErrPending):
+		return "account_pending"
+	case errors.Is(err, accountstate.ErrLocked):
+		return "account_locked"
+	case errors.Is(err, accountstate.ErrSuspended):
+		return "account_suspended"
+	case errors.Is(err, accountstate.ErrDeleted):
+		return "account_deleted"
+	case errors.Is(err, ErrAppDisabled):
+		return "app_disabled"
+	case errors.Is(err, ErrGrantNotAllowed):
+		return "grant_not_allowed"
+	case errors.Is(err, ErrSessionExpired):
+		return "session_expired"
+	case errors.Is(err, ErrSessionIdle):
+		return "session_idle"
+	case errors.Is(err, ErrRefreshTokenReused):
+		return "refresh_token_reuse"
+	case errors.Is(err, storage.ErrInvalidRefreshToken):
+		return "invalid_refresh_token"
+	case errors.Is(err, ErrPhoneTaken), errors.Is(err, ErrPhoneCooldown):
+		return "phone_unavailable"
+	case errors.Is(err, storage.ErrSessionNotFound):
+		return "session_not_found"
+	}
+
+	return "error"
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/accountstate"
+	"github.com/KVSH-user/vizapSSO/internal/lib/clientinfo"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+)
+
+func TestEventReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "wrong password", err: fmt.Errorf("auth.Login: %w", ErrInvalidCredentials), want: "invalid_credentials"},
+		{name: "locked out", err: ErrTooManyAttempts, want: "too_many_attempts"},
+		{name: "locked account", err: accountstate.ErrLocked, want: "account_locked"},
+		{name: "idle session", err: ErrSessionIdle, want: "session_idle"},
+		{name: "reused refresh token", err: ErrRefreshTokenReused, want: "refresh_token_reuse"},
+		{name: "invalid refresh token", err: storage.ErrInvalidRefreshToken, want: "invalid_refresh_token"},
+		{name: "phone cooldown", err: ErrPhoneCooldown, want: "phone_unavailable"},
+		{name: "internal", err: errors.New("connection refused"), want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventReason(tt.err); got != tt.want {
+				t.Errorf("eventReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginRecordsEvent(t *testing.T) {
+	ctx := clientinfo.NewContext(context.Background(), "203.0.113.7", "vizap-ios/3.2")
+
+	tests := []struct {
+		name        string
+		login       string
+		password    string
+		wantUser    bool
+		wantOutcome string
+		wantReason  string
+	}{
+		{name: "success", login: testPhone, password: testPassword, wantUser: true, wantOutcome: entity.OutcomeSuccess},
+		{
+			name: "wrong password", login: testPhone, password: "wrong123",
+			wantUser: true, wantOutcome: entity.OutcomeFailure, wantReason: "invalid_credentials",
+		},
+		{
+			name: "unknown phone", login: "+79990000000", password: testPassword,
+			wantOutcome: entity.OutcomeFailure, wantReason: "invalid_credentials",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, testPhone)
+
+			_, _, _ = env.auth.Login(ctx, tt.login, tt.password, env.app.ID, entity.Device{})
+
+			events := env.storage.AllSecurityEvents()
+			if tt.wantUser != (len(events) == 1) {
+				t.Fatalf("saved events = %d", len(events))
+			}
+			if !tt.wantUser {
+				// Attempts on unknown accounts have nobody to be shown to.
+				return
+			}
+
+			event := events[0]
+			if event.UserID != user.ID || event.Type != entity.EventLogin || event.Method != entity.MethodPassword {
+				t.Errorf("event = %+v", event)
+			}
+			if event.Outcome != tt.wantOutcome || event.Reason != tt.wantReason {
+				t.Errorf("outcome = %q, reason = %q, want %q, %q", event.Outcome, event.Reason, tt.wantOutcome, tt.wantReason)
+			}
+			if event.IP != "203.0.113.7" || event.UserAgent != "vizap-ios/3.2" || event.AppID != env.app.ID {
+				t.Errorf("client = %q, %q, app %d", event.IP, event.UserAgent, event.AppID)
+			}
+		})
+	}
+}
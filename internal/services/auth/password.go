@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+)
+
+var (
+	ErrPasswordReused = errors.New("password was used recently")
+)
+
+// ChangePassword replaces the password of the token's user and signs out
+// every device. The caller gets a new session on the same device.
+func (a *Auth) ChangePassword(ctx context.Context, accessToken, oldPassword, newPassword string,
+) (newAccessToken, newRefreshToken string, err error) {
+	const op = "auth.ChangePassword"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, sessionID, app, err := a.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: uid,
+			Type:   entity.EventPasswordChange,
+			Method: entity.MethodPassword,
+			AppID:  app.ID,
+		}, err)
+	}()
+
+	log = log.With(slog.Int64("uid", uid))
+
+	log.Info("changing password")
+
+	var device entity.Device
+	if sessionID != 0 {
+		session, err := a.sessionStorage.Session(sessionID)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+		device = session.Device
+	}
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPassword(ctx, user.ID, userFailureKey(user.ID), user.PassHash, oldPassword); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.passPolicy.Validate(newPassword); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPasswordHistory(ctx, user.ID, user.PassHash, newPassword); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.passHasher.Generate(ctx, []byte(newPassword))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// UpdatePassword signs out every session in the same transaction, so
+	// the old ones can't outlive the old password.
+	if err := a.passStorage.UpdatePassword(user.ID, passHash); err != nil {
+		log.Error("failed to update password", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	newAccessToken, newRefreshToken, err = a.startSession(user, app, device)
+	if err != nil {
+		log.Error("failed to issue tokens", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return newAccessToken, newRefreshToken, nil
+}
+
+// checkPasswordHistory rejects the current password and the last
+// passwordHistory-1 ones.
+func (a *Auth) checkPasswordHistory(ctx context.Context, uid int64, currentHash []byte, password string) error {
+	hashes := [][]byte{currentHash}
+
+	if a.passwordHistory > 1 {
+		previous, err := a.passStorage.PasswordHistory(uid, a.passwordHistory-1)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, previous...)
+	}
+
+	for _, hash := range hashes {
+		err := a.passHasher.Compare(ctx, hash, []byte(password))
+		if err == nil {
+			return ErrPasswordReused
+		}
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/clientinfo"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"sync"
+	"testing"
+)
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		oldPassword string
+		newPassword string
+		wantErr     error
+	}{
+		{name: "ok", oldPassword: testPassword, newPassword: "newsecret123"},
+		{name: "wrong old password", oldPassword: "wrong123", newPassword: "newsecret123", wantErr: ErrInvalidCredentials},
+		{name: "same password", oldPassword: testPassword, newPassword: testPassword, wantErr: ErrPasswordReused},
+		{name: "too short", oldPassword: testPassword, newPassword: "a1", wantErr: password.ErrTooShort},
+		{name: "no digits", oldPassword: testPassword, newPassword: "onlyletters", wantErr: password.ErrTooWeak},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+
+			otherAccess, otherRefresh := env.login(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			newAccess, newRefresh, err := env.auth.ChangePassword(ctx, access, tt.oldPassword, tt.newPassword)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangePassword() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, _, refreshErr := env.auth.RefreshSession(ctx, otherAccess, otherRefresh)
+
+			if tt.wantErr != nil {
+				if refreshErr != nil {
+					t.Fatalf("other session was signed out after a failed change: %v", refreshErr)
+				}
+				return
+			}
+
+			if refreshErr == nil {
+				t.Fatal("other session survived the password change")
+			}
+
+			if _, _, err := env.auth.RefreshSession(ctx, newAccess, newRefresh); err != nil {
+				t.Fatalf("new session: %v", err)
+			}
+
+			if _, _, err := env.auth.Login(ctx, testPhone, tt.newPassword, env.app.ID, entity.Device{}); err != nil {
+				t.Fatalf("Login with the new password: %v", err)
+			}
+		})
+	}
+}
+
+func TestPasswordLockout(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// fail makes one wrong password attempt.
+		fail func(env *testEnv, access string) error
+	}{
+		{
+			name: "login",
+			fail: func(env *testEnv, _ string) error {
+				_, _, err := env.auth.Login(ctx, testPhone, "wrong123", env.app.ID, entity.Device{})
+				return err
+			},
+		},
+		{
+			name: "change password",
+			fail: func(env *testEnv, access string) error {
+				_, _, err := env.auth.ChangePassword(ctx, access, "wrong123", "newsecret123")
+				return err
+			},
+		},
+		{
+			name: "delete account",
+			fail: func(env *testEnv, access string) error {
+				_, err := env.auth.DeleteAccount(ctx, access, "wrong123", "")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			for i := 0; i < 3; i++ {
+				if err := tt.fail(env, access); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("attempt %d = %v, want %v", i+1, err, ErrInvalidCredentials)
+				}
+			}
+
+			if err := tt.fail(env, access); !errors.Is(err, ErrTooManyAttempts) {
+				t.Fatalf("attempt 4 = %v, want %v", err, ErrTooManyAttempts)
+			}
+
+			// Only the third wrong password starts the lockout.
+			var lockouts []entity.SecurityEvent
+			for _, event := range env.storage.AllSecurityEvents() {
+				if event.Type == entity.EventLockout {
+					lockouts = append(lockouts, event)
+				}
+			}
+			if len(lockouts) != 1 || lockouts[0].UserID != user.ID || lockouts[0].Reason != "too_many_attempts" {
+				t.Errorf("lockout events = %+v, want one of user %d", lockouts, user.ID)
+			}
+
+			// The right password doesn't help while locked out, wherever
+			// the failures came from.
+			_, _, err := env.auth.Login(ctx, testPhone, testPassword, env.app.ID, entity.Device{})
+			if !errors.Is(err, ErrTooManyAttempts) {
+				t.Fatalf("Login() = %v, want %v", err, ErrTooManyAttempts)
+			}
+		})
+	}
+}
+
+func TestLockoutResetOnSuccess(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.addUser(t, testPhone)
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 2; i++ {
+			if _, _, err := env.auth.Login(ctx, testPhone, "wrong123", env.app.ID, entity.Device{}); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Login(wrong) = %v", err)
+			}
+		}
+		env.login(t, testPhone)
+	}
+}
+
+func TestUnknownLoginLockout(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		login string
+	}{
+		{name: "registered", login: testPhone},
+		{name: "unknown phone", login: "+79990000000"},
+		{name: "unknown email", login: "Nobody@Example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+
+			var errs []error
+			for i := 0; i < 4; i++ {
+				_, _, err := env.auth.Login(ctx, tt.login, "wrong123", env.app.ID, entity.Device{})
+				errs = append(errs, err)
+			}
+
+			want := []error{ErrInvalidCredentials, ErrInvalidCredentials, ErrInvalidCredentials, ErrTooManyAttempts}
+			for i := range want {
+				if !errors.Is(errs[i], want[i]) {
+					t.Fatalf("attempt %d = %v, want %v", i+1, errs[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLockoutByClient(t *testing.T) {
+	attacker := clientinfo.NewContext(context.Background(), "203.0.113.7", "")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "same ip", ctx: attacker, wantErr: ErrTooManyAttempts},
+		{name: "other ip", ctx: clientinfo.NewContext(context.Background(), "198.51.100.20", "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+
+			for i := 0; i < 3; i++ {
+				_, _, err := env.auth.Login(attacker, testPhone, "wrong123", env.app.ID, entity.Device{})
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("attempt %d = %v, want %v", i+1, err, ErrInvalidCredentials)
+				}
+			}
+
+			_, _, err := env.auth.Login(tt.ctx, testPhone, testPassword, env.app.ID, entity.Device{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLockoutConcurrentGuesses(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.addUser(t, testPhone)
+
+	const guesses = 10
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		compared int
+	)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _, err := env.auth.Login(ctx, testPhone, "wrong123", env.app.ID, entity.Device{})
+			if errors.Is(err, ErrInvalidCredentials) {
+				mu.Lock()
+				compared++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// newTestEnv allows 3 wrong passwords in the window.
+	if compared != 3 {
+		t.Errorf("compared passwords = %d, want 3", compared)
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrPhoneTaken    = errors.New("phone is used by another account")
+	ErrPhoneCooldown = errors.New("phone was recently used by another account")
+	ErrSamePhone     = errors.New("new phone is the same as the current one")
+)
+
+const (
+	msgPhoneChangeCode    = "Код для смены номера в Vizap: %s"
+	msgPhoneChangeOldCode = "Код для подтверждения смены номера в Vizap: %s. Если это не вы, смените пароль."
+	msgPhoneChanged       = "Номер телефона вашего аккаунта Vizap изменён. Если это не вы, обратитесь в поддержку."
+)
+
+// RequestPhoneChange sends a code to the new phone and, when the user can
+// still receive SMS on the current one, a second code there. Without the
+// old number the current password stands in for its code, otherwise a
+// stolen session alone would be enough to take the account.
+func (a *Auth) RequestPhoneChange(ctx context.Context, accessToken, newPhone string, oldPhoneReachable bool,
+	password string,
+) error {
+	const op = "auth.RequestPhoneChange"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, _, err := a.authorize(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", uid))
+
+	log.Info("requesting phone change")
+
+	newPhone, err = a.phones.Normalize(newPhone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Phone == newPhone {
+		return fmt.Errorf("%s: %w", op, ErrSamePhone)
+	}
+
+	if err := a.checkPhoneAvailable(newPhone, uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !oldPhoneReachable {
+		if err := a.checkPassword(ctx, uid, userFailureKey(uid), user.PassHash, password); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.codeLimiter.Allow(otp.PurposePhoneChange, newPhone); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if oldPhoneReachable {
+		if err := a.codeLimiter.Allow(otp.PurposePhoneChange, user.Phone); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.dropExpiredPhoneChange(uid); err != nil {
+		log.Error("failed to get phone change", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	change := entity.PhoneChange{
+		UserID:    uid,
+		NewPhone:  newPhone,
+		ExpiresAt: time.Now().Add(a.phoneCfg.ChangeCodeTTL),
+	}
+
+	newCode, err := otp.Generate(codeDigits)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	change.NewCodeHash = otp.Hash(newCode)
+
+	var oldCode string
+	if oldPhoneReachable {
+		oldCode, err = otp.Generate(codeDigits)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		change.OldCodeHash = otp.Hash(oldCode)
+	}
+
+	if err := a.phoneStorage.SavePhoneChange(change); err != nil {
+		log.Error("failed to save phone change", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.smsSender.Send(ctx, newPhone, fmt.Sprintf(msgPhoneChangeCode, newCode)); err != nil {
+		log.Error("failed to send sms", sl.Err(err))
+	}
+
+	if oldCode != "" {
+		if err := a.smsSender.Send(ctx, user.Phone, fmt.Sprintf(msgPhoneChangeOldCode, oldCode)); err != nil {
+			log.Error("failed to send sms", sl.Err(err))
+		}
+	}
+
+	return nil
+}
+
+// ConfirmPhoneChange checks the codes from RequestPhoneChange and switches
+// the account to the new phone. The old one is kept in phone_history, and
+// every session but the current one is signed out.
+func (a *Auth) ConfirmPhoneChange(ctx context.Context, accessToken, newCode, oldCode string) (err error) {
+	const op = "auth.ConfirmPhoneChange"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, sessionID, app, err := a.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: uid,
+			Type:   entity.EventPhoneChange,
+			Method: entity.MethodSMSCode,
+			AppID:  app.ID,
+		}, err)
+	}()
+
+	log = log.With(slog.Int64("uid", uid))
+
+	log.Info("confirming phone change")
+
+	change, err := a.phoneStorage.PhoneChange(uid)
+	if err != nil {
+		if errors.Is(err, storage.ErrPhoneChangeNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(change.ExpiresAt) {
+		if err := a.phoneStorage.DeletePhoneChange(uid); err != nil {
+			log.Error("failed to delete phone change", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	// The request stays until it expires: deleting it would let a new one
+	// start the attempts over.
+	if change.Attempts >= a.phoneCfg.ChangeMaxAttempts {
+		return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	valid := otp.Equal(newCode, change.NewCodeHash)
+	if change.OldCodeHash != "" {
+		valid = valid && otp.Equal(oldCode, change.OldCodeHash)
+	}
+
+	if !valid {
+		if err := a.phoneStorage.IncPhoneChangeAttempts(uid); err != nil {
+			log.Error("failed to count attempt", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	// The phone could have been taken while the codes were on their way.
+	if err := a.checkPhoneAvailable(change.NewPhone, uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.phoneStorage.ChangeUserPhone(uid, change.NewPhone, sessionID); err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return fmt.Errorf("%s: %w", op, ErrPhoneTaken)
+		}
+
+		log.Error("failed to change phone", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, p := range []string{user.Phone, change.NewPhone} {
+		if err := a.smsSender.Send(ctx, p, msgPhoneChanged); err != nil {
+			log.Error("failed to send sms", sl.Err(err))
+		}
+	}
+
+	log.Info("phone changed")
+
+	return nil
+}
+
+// dropExpiredPhoneChange deletes the user's phone change once its codes
+// have expired. A live one is overwritten by the new codes and keeps its
+// attempt counter.
+func (a *Auth) dropExpiredPhoneChange(uid int64) error {
+	change, err := a.phoneStorage.PhoneChange(uid)
+	if errors.Is(err, storage.ErrPhoneChangeNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if time.Now().After(change.ExpiresAt) {
+		return a.phoneStorage.DeletePhoneChange(uid)
+	}
+
+	return nil
+}
+
+func (a *Auth) checkPhoneAvailable(phone string, uid int64) error {
+	existing, err := a.userByPhone(phone)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return err
+	}
+
+	if existing.ID != 0 && existing.ID != uid {
+		return ErrPhoneTaken
+	}
+
+	released, err := a.phoneStorage.PhoneReleasedSince(phone, uid, time.Now().Add(-a.phoneCfg.ReuseCooldown))
+	if err != nil {
+		return err
+	}
+
+	if released {
+		return ErrPhoneCooldown
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"testing"
+)
+
+const testNewPhone = "+79997654321"
+
+func TestRequestPhoneChange(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		oldReachable bool
+		password     string
+		wantErr      error
+		wantOldCode  bool
+	}{
+		{name: "old phone reachable", oldReachable: true, wantOldCode: true},
+		{name: "old phone lost, password", password: testPassword},
+		{name: "old phone lost, wrong password", password: "wrong123", wantErr: ErrInvalidCredentials},
+		{name: "old phone lost, no password", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			err := env.auth.RequestPhoneChange(ctx, access, testNewPhone, tt.oldReachable, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestPhoneChange() = %v, want %v", err, tt.wantErr)
+			}
+
+			wantNew := 1
+			if tt.wantErr != nil {
+				wantNew = 0
+			}
+			if got := len(env.outbox.to(testNewPhone)); got != wantNew {
+				t.Errorf("codes to the new phone = %d, want %d", got, wantNew)
+			}
+
+			if got := len(env.outbox.to(testPhone)) > 0; got != tt.wantOldCode {
+				t.Errorf("code to the old phone sent = %v, want %v", got, tt.wantOldCode)
+			}
+		})
+	}
+}
+
+func TestRequestPhoneChangeWrongPasswordLocksOut(t *testing.T) {
+	ctx := context.Background()
+
+	env := newTestEnv(t)
+	env.addUser(t, testPhone)
+	access, _ := env.login(t, testPhone)
+
+	for i := 0; i < 3; i++ {
+		err := env.auth.RequestPhoneChange(ctx, access, testNewPhone, false, "wrong123")
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: RequestPhoneChange() = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+
+	err := env.auth.RequestPhoneChange(ctx, access, testNewPhone, false, testPassword)
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("RequestPhoneChange() = %v, want %v", err, ErrTooManyAttempts)
+	}
+}
+
+func TestConfirmPhoneChange(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// before runs between the request and the confirmation.
+		before  func(t *testing.T, env *testEnv, access string)
+		code    func(t *testing.T, env *testEnv) string
+		wantErr error
+	}{
+		{
+			name: "ok",
+			code: func(t *testing.T, env *testEnv) string { return env.lastCode(t, testNewPhone) },
+		},
+		{
+			name:    "wrong code",
+			code:    func(*testing.T, *testEnv) string { return "000000" },
+			wantErr: ErrInvalidCode,
+		},
+		{
+			name: "attempts survive a resend",
+			before: func(t *testing.T, env *testEnv, access string) {
+				for i := 0; i < 3; i++ {
+					if err := env.auth.ConfirmPhoneChange(ctx, access, "000000", ""); !errors.Is(err, ErrInvalidCode) {
+						t.Fatalf("ConfirmPhoneChange() = %v, want %v", err, ErrInvalidCode)
+					}
+				}
+				if err := env.auth.RequestPhoneChange(ctx, access, testNewPhone, false, testPassword); err != nil {
+					t.Fatalf("RequestPhoneChange: %v", err)
+				}
+			},
+			code:    func(t *testing.T, env *testEnv) string { return env.lastCode(t, testNewPhone) },
+			wantErr: ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, testPhone)
+
+			otherAccess, otherRefresh := env.login(t, testPhone)
+			access, refresh := env.login(t, testPhone)
+
+			if err := env.auth.RequestPhoneChange(ctx, access, testNewPhone, false, testPassword); err != nil {
+				t.Fatalf("RequestPhoneChange: %v", err)
+			}
+
+			if tt.before != nil {
+				tt.before(t, env, access)
+			}
+
+			err := env.auth.ConfirmPhoneChange(ctx, access, tt.code(t, env), "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmPhoneChange() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, _, otherErr := env.auth.RefreshSession(ctx, otherAccess, otherRefresh)
+			if tt.wantErr != nil {
+				if otherErr != nil {
+					t.Fatalf("other session was signed out after a failed change: %v", otherErr)
+				}
+				return
+			}
+
+			if otherErr == nil {
+				t.Fatal("other session survived the phone change")
+			}
+
+			if _, _, err := env.auth.RefreshSession(ctx, access, refresh); err != nil {
+				t.Fatalf("current session: %v", err)
+			}
+
+			changed, err := env.storage.UserByID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed.Phone != testNewPhone {
+				t.Errorf("phone = %q, want %q", changed.Phone, testNewPhone)
+			}
+		})
+	}
+}
+
+func TestRequestPhoneChangeCodeLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		oldReachable bool
+		requests     int
+		wantErr      error
+	}{
+		{name: "within the limit", oldReachable: true, requests: 2},
+		{name: "over the limit", oldReachable: true, requests: 3, wantErr: otp.ErrTooManyCodes},
+		{name: "over the limit without old phone", requests: 3, wantErr: otp.ErrTooManyCodes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withCodeLimit(2))
+			env.addUser(t, testPhone)
+			access, _ := env.login(t, testPhone)
+
+			var err error
+			for i := 0; i < tt.requests; i++ {
+				err = env.auth.RequestPhoneChange(ctx, access, testNewPhone, tt.oldReachable, testPassword)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestPhoneChange() = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := len(env.outbox.to(testNewPhone)); got > 2 {
+				t.Errorf("codes to the new phone = %d, want at most 2", got)
+			}
+		})
+	}
+}
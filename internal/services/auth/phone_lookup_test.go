@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+)
+
+func TestLoginLegacyPhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  string
+		legacy  bool
+		wantErr error
+	}{
+		{name: "normalized", stored: "+79991234567"},
+		{name: "trunk prefix", stored: "89991234567", legacy: true},
+		{name: "without plus", stored: "79991234567", legacy: true},
+		{name: "national", stored: "9991234567", legacy: true},
+		{name: "legacy lookup off", stored: "89991234567", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []testOption
+			if tt.legacy {
+				opts = append(opts, withLegacyLookup())
+			}
+
+			env := newTestEnv(t, opts...)
+			env.addUser(t, tt.stored)
+
+			_, _, err := env.auth.Login(context.Background(), "+7 999 123-45-67", testPassword, env.app.ID, entity.Device{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterLegacyPhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  string
+		wantErr error
+	}{
+		{name: "new phone"},
+		{name: "normalized", stored: "+79991234567", wantErr: storage.ErrUserExists},
+		{name: "trunk prefix", stored: "89991234567", wantErr: storage.ErrUserExists},
+		{name: "without plus", stored: "79991234567", wantErr: storage.ErrUserExists},
+		{name: "national", stored: "9991234567", wantErr: storage.ErrUserExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withLegacyLookup())
+			if tt.stored != "" {
+				env.addUser(t, tt.stored)
+			}
+
+			uid, _, err := env.auth.RegisterNewUser(context.Background(), "+7 999 123-45-67", "another123")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RegisterNewUser() = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && uid == 0 {
+				t.Error("uid is zero")
+			}
+		})
+	}
+}
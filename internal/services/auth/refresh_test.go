@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"sync"
+	"testing"
+)
+
+func TestRefreshTokenReuse(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// refreshes is how many times the session is refreshed before the
+		// first pair comes back.
+		refreshes int
+	}{
+		{name: "right after the refresh", refreshes: 1},
+		{name: "after several refreshes", refreshes: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+			otherAccess, otherRefresh := env.login(t, testPhone)
+			firstAccess, firstRefresh := env.login(t, testPhone)
+
+			access, refresh := firstAccess, firstRefresh
+			for i := 0; i < tt.refreshes; i++ {
+				var err error
+				access, refresh, err = env.auth.RefreshSession(ctx, access, refresh)
+				if err != nil {
+					t.Fatalf("refresh %d: %v", i+1, err)
+				}
+			}
+
+			if _, _, err := env.auth.RefreshSession(ctx, firstAccess, firstRefresh); !errors.Is(err, ErrRefreshTokenReused) {
+				t.Fatalf("reuse = %v, want %v", err, ErrRefreshTokenReused)
+			}
+
+			if _, _, err := env.auth.RefreshSession(ctx, access, refresh); !errors.Is(err, storage.ErrInvalidRefreshToken) {
+				t.Errorf("latest pair = %v, want %v", err, storage.ErrInvalidRefreshToken)
+			}
+
+			if _, err := env.auth.Authorize(ctx, access); err == nil {
+				t.Error("access token of the revoked session still works")
+			}
+
+			if _, _, err := env.auth.RefreshSession(ctx, otherAccess, otherRefresh); err != nil {
+				t.Errorf("other session: %v", err)
+			}
+		})
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		parallel int
+	}{
+		{name: "two requests", parallel: 2},
+		{name: "many requests", parallel: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+			access, refresh := env.login(t, testPhone)
+
+			var wg sync.WaitGroup
+			errs := make([]error, tt.parallel)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, _, errs[i] = env.auth.RefreshSession(ctx, access, refresh)
+				}(i)
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					succeeded++
+				case !errors.Is(err, storage.ErrInvalidRefreshToken):
+					t.Errorf("refresh = %v, want success or %v", err, storage.ErrInvalidRefreshToken)
+				}
+			}
+
+			if succeeded > 1 {
+				t.Errorf("%d refreshes got a new pair from one token, want at most 1", succeeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"time"
+)
+
+const (
+	codeDigits = 6
+
+	msgRegistrationCode  = "Код подтверждения регистрации Vizap: %s"
+	msgAlreadyRegistered = "На этот номер уже зарегистрирован аккаунт Vizap. " +
+		"Если вы забыли пароль, воспользуйтесь восстановлением доступа."
+)
+
+// requestRegistration answers the same way whether or not the phone is
+// taken: existing users get a reminder, new ones get a confirmation code.
+func (a *Auth) requestRegistration(ctx context.Context, phone string, passHash []byte) error {
+	const op = "auth.requestRegistration"
+
+	log := a.log.With(slog.String("op", op))
+
+	user, err := a.userByPhone(phone)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		log.Error("failed to provide user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Both answers are limited, so the limit says nothing about the phone.
+	if err := a.codeLimiter.Allow(otp.PurposeRegistration, phone); err != nil {
+		if !errors.Is(err, otp.ErrTooManyCodes) {
+			log.Error("failed to check code limit", sl.Err(err))
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.ID != 0 {
+		if err := a.smsSender.Send(ctx, phone, msgAlreadyRegistered); err != nil {
+			log.Error("failed to send sms", sl.Err(err))
+		}
+
+		return nil
+	}
+
+	code, err := otp.Generate(codeDigits)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.dropExpiredRegistration(phone); err != nil {
+		log.Error("failed to get pending registration", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	expiresAt := time.Now().Add(a.regCfg.CodeTTL)
+
+	if err := a.regStorage.SavePendingRegistration(phone, passHash, otp.Hash(code), expiresAt); err != nil {
+		log.Error("failed to save pending registration", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.smsSender.Send(ctx, phone, fmt.Sprintf(msgRegistrationCode, code)); err != nil {
+		log.Error("failed to send sms", sl.Err(err))
+	}
+
+	return nil
+}
+
+// ConfirmRegistration creates the account from a pending registration once
+// the code sent to the phone is confirmed.
+func (a *Auth) ConfirmRegistration(ctx context.Context, phone, code string) (userID int64, err error) {
+	const op = "auth.ConfirmRegistration"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("confirming registration")
+
+	phone, err = a.phones.Normalize(phone)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reg, err := a.regStorage.PendingRegistration(phone)
+	if err != nil {
+		if errors.Is(err, storage.ErrRegistrationNotFound) {
+			return 0, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to get pending registration", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(reg.ExpiresAt) {
+		if err := a.regStorage.DeletePendingRegistration(phone); err != nil {
+			log.Error("failed to delete pending registration", sl.Err(err))
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	// The registration stays until it expires: deleting it would let a new
+	// request start the attempts over.
+	if reg.Attempts >= a.regCfg.MaxAttempts {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if !otp.Equal(code, reg.CodeHash) {
+		if err := a.regStorage.IncPendingRegistrationAttempts(phone); err != nil {
+			log.Error("failed to count attempt", sl.Err(err))
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	id, err := a.usrSaver.SaveUser(phone, reg.PassHash)
+	if err != nil {
+		log.Error("failed to save user", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.regStorage.DeletePendingRegistration(phone); err != nil {
+		log.Error("failed to delete pending registration", sl.Err(err))
+	}
+
+	log.Info("user successfully register")
+
+	return id, nil
+}
+
+// dropExpiredRegistration deletes the phone's registration once its code
+// has expired. A live one is overwritten by the new code and keeps its
+// attempt counter.
+func (a *Auth) dropExpiredRegistration(phone string) error {
+	reg, err := a.regStorage.PendingRegistration(phone)
+	if errors.Is(err, storage.ErrRegistrationNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if time.Now().After(reg.ExpiresAt) {
+		return a.regStorage.DeletePendingRegistration(phone)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, testPhone)
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{name: "ok", login: testPhone, password: testPassword},
+		{name: "local form of the phone", login: "89991234567", password: testPassword},
+		{name: "wrong password", login: testPhone, password: "secret124", wantErr: ErrInvalidCredentials},
+		{name: "unknown phone", login: "+79990000000", password: testPassword, wantErr: ErrInvalidCredentials},
+		{name: "unknown email", login: "nobody@example.com", password: testPassword, wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := env.auth.Login(context.Background(), tt.login, tt.password, env.app.ID, entity.Device{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterEnumerationSafe(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    bool
+		wantMessage string
+	}{
+		{name: "new phone", wantMessage: "Код подтверждения"},
+		{name: "registered phone", existing: true, wantMessage: "уже зарегистрирован"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withEnumerationSafe())
+			if tt.existing {
+				env.addUser(t, testPhone)
+			}
+
+			uid, pending, err := env.auth.RegisterNewUser(context.Background(), testPhone, "another123")
+			if err != nil || uid != 0 || !pending {
+				t.Fatalf("RegisterNewUser() = %d, %v, %v, want 0, true, nil", uid, pending, err)
+			}
+
+			messages := env.outbox.to(testPhone)
+			if len(messages) != 1 || !strings.Contains(messages[0].text, tt.wantMessage) {
+				t.Fatalf("messages = %v, want one with %q", messages, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestConfirmRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// run makes the calls before the right code is confirmed.
+		run     func(t *testing.T, env *testEnv)
+		wantErr error
+	}{
+		{
+			name: "right code",
+			run:  func(t *testing.T, env *testEnv) {},
+		},
+		{
+			name: "wrong codes below the limit",
+			run: func(t *testing.T, env *testEnv) {
+				for i := 0; i < 2; i++ {
+					if _, err := env.auth.ConfirmRegistration(ctx, testPhone, "000000"); !errors.Is(err, ErrInvalidCode) {
+						t.Fatalf("ConfirmRegistration(wrong) = %v", err)
+					}
+				}
+			},
+		},
+		{
+			name: "attempts used up",
+			run: func(t *testing.T, env *testEnv) {
+				for i := 0; i < 3; i++ {
+					_, _ = env.auth.ConfirmRegistration(ctx, testPhone, "000000")
+				}
+			},
+			wantErr: ErrInvalidCode,
+		},
+		{
+			name: "resend keeps the attempts",
+			run: func(t *testing.T, env *testEnv) {
+				for i := 0; i < 3; i++ {
+					_, _ = env.auth.ConfirmRegistration(ctx, testPhone, "000000")
+				}
+				if _, _, err := env.auth.RegisterNewUser(ctx, testPhone, "another123"); err != nil {
+					t.Fatalf("RegisterNewUser(resend): %v", err)
+				}
+			},
+			wantErr: ErrInvalidCode,
+		},
+		{
+			name: "new code after the old one expired",
+			run: func(t *testing.T, env *testEnv) {
+				for i := 0; i < 3; i++ {
+					_, _ = env.auth.ConfirmRegistration(ctx, testPhone, "000000")
+				}
+				expireRegistration(t, env, testPhone)
+				if _, _, err := env.auth.RegisterNewUser(ctx, testPhone, "another123"); err != nil {
+					t.Fatalf("RegisterNewUser(resend): %v", err)
+				}
+			},
+		},
+		{
+			name: "expired code",
+			run: func(t *testing.T, env *testEnv) {
+				expireRegistration(t, env, testPhone)
+			},
+			wantErr: ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withEnumerationSafe())
+
+			if _, _, err := env.auth.RegisterNewUser(ctx, testPhone, "another123"); err != nil {
+				t.Fatalf("RegisterNewUser: %v", err)
+			}
+
+			tt.run(t, env)
+
+			uid, err := env.auth.ConfirmRegistration(ctx, testPhone, env.lastCode(t, testPhone))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmRegistration() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, lookupErr := env.storage.ProvideUser(testPhone)
+			if created := lookupErr == nil; created != (tt.wantErr == nil) || (created && uid == 0) {
+				t.Fatalf("user created = %v, uid = %d, want created = %v", created, uid, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestRegisterCodeLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "new phone"},
+		{name: "registered phone", existing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withEnumerationSafe(), withCodeLimit(2))
+			if tt.existing {
+				env.addUser(t, testPhone)
+			}
+
+			for i := 0; i < 2; i++ {
+				if _, _, err := env.auth.RegisterNewUser(context.Background(), testPhone, "another123"); err != nil {
+					t.Fatalf("RegisterNewUser #%d: %v", i+1, err)
+				}
+			}
+
+			_, _, err := env.auth.RegisterNewUser(context.Background(), testPhone, "another123")
+			if !errors.Is(err, otp.ErrTooManyCodes) {
+				t.Fatalf("RegisterNewUser() = %v, want %v", err, otp.ErrTooManyCodes)
+			}
+
+			if n := len(env.outbox.to(testPhone)); n != 2 {
+				t.Fatalf("sent %d messages, want 2", n)
+			}
+		})
+	}
+}
+
+func expireRegistration(t *testing.T, env *testEnv, phone string) {
+	t.Helper()
+
+	reg, err := env.storage.PendingRegistration(phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.storage.SavePendingRegistration(phone, reg.PassHash, reg.CodeHash, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.storage.PendingRegistration(phone); errors.Is(err, storage.ErrRegistrationNotFound) {
+		t.Fatal("registration is gone")
+	}
+}
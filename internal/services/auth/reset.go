@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+)
+
+const (
+	resetTokenBytes = 32
+
+	msgResetRequested = "Если аккаунт с такими данными существует, мы отправили на него инструкции по восстановлению пароля."
+	msgResetSubject   = "Восстановление пароля"
+	msgResetLink      = "Для восстановления пароля Vizap перейдите по ссылке: %s"
+)
+
+// RequestPasswordReset sends a reset link to the phone or verified email.
+// The answer is the same whether or not the account exists: both are
+// limited per phone or email, and the link is saved and sent in the
+// background, so the answer takes as long either way.
+func (a *Auth) RequestPasswordReset(ctx context.Context, login string) (response string, err error) {
+	const op = "auth.RequestPasswordReset"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("password reset requested")
+
+	user, err := a.provideUser(login)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	target := strings.ToLower(strings.TrimSpace(login))
+	if !isEmail(login) {
+		if target, err = a.phones.Normalize(login); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.codeLimiter.Allow(otp.PurposePasswordReset, target); err != nil {
+		if !errors.Is(err, otp.ErrTooManyCodes) {
+			log.Error("failed to check code limit", sl.Err(err))
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.ID == 0 {
+		return msgResetRequested, nil
+	}
+
+	a.sends.Add(1)
+	go func() {
+		defer a.sends.Done()
+
+		// The link outlives the request.
+		a.sendPasswordReset(context.WithoutCancel(ctx), user, login)
+	}()
+
+	return msgResetRequested, nil
+}
+
+// sendPasswordReset saves a reset token of the user and sends the link to
+// the login it was requested for. Failures are only logged: the caller got
+// its answer already.
+func (a *Auth) sendPasswordReset(ctx context.Context, user entity.User, login string) {
+	const op = "auth.sendPasswordReset"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", user.ID))
+
+	token, err := otp.Token(resetTokenBytes)
+	if err != nil {
+		log.Error("failed to generate reset token", sl.Err(err))
+		return
+	}
+
+	expiresAt := time.Now().Add(a.resetCfg.TokenTTL)
+
+	if err := a.resetStorage.SavePasswordReset(otp.Hash(token), user.ID, expiresAt); err != nil {
+		log.Error("failed to save password reset", sl.Err(err))
+		return
+	}
+
+	text := fmt.Sprintf(msgResetLink, fmt.Sprintf(a.resetCfg.LinkFormat, token))
+
+	if isEmail(login) {
+		err = a.emailSender.Send(ctx, strings.TrimSpace(login), msgResetSubject, text)
+	} else {
+		err = a.smsSender.Send(ctx, user.Phone, text)
+	}
+	if err != nil {
+		log.Error("failed to send password reset", sl.Err(err))
+	}
+}
+
+// PerformPasswordReset sets a new password by a reset token and signs the
+// user out everywhere.
+func (a *Auth) PerformPasswordReset(ctx context.Context, token, newPassword string) (success bool, err error) {
+	const op = "auth.PerformPasswordReset"
+
+	log := a.log.With(slog.String("op", op))
+
+	tokenHash := otp.Hash(token)
+
+	reset, err := a.resetStorage.PasswordReset(tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrResetNotFound) {
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: reset.UserID,
+			Type:   entity.EventPasswordReset,
+			Method: entity.MethodResetLink,
+		}, err)
+	}()
+
+	if reset.Used || time.Now().After(reset.ExpiresAt) {
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+	}
+
+	log = log.With(slog.Int64("uid", reset.UserID))
+
+	if err := a.passPolicy.Validate(newPassword); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.passStorage.UserByID(reset.UserID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPasswordHistory(ctx, user.ID, user.PassHash, newPassword); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.passHasher.Generate(ctx, []byte(newPassword))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The token is spent together with the password change, so two
+	// concurrent requests can't both use it and a failed change keeps it.
+	if err := a.resetStorage.ResetPassword(tokenHash, passHash); err != nil {
+		if errors.Is(err, storage.ErrResetNotFound) {
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+
+		log.Error("failed to reset password", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset")
+
+	return true, nil
+}
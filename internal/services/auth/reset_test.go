@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"regexp"
+	"testing"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// resetToken requests a password reset and returns the token from the link.
+func (e *testEnv) resetToken(t *testing.T, phone string) string {
+	t.Helper()
+
+	if _, err := e.auth.RequestPasswordReset(context.Background(), phone); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	e.auth.sends.Wait()
+
+	messages := e.outbox.to(phone)
+	if len(messages) == 0 {
+		t.Fatal("no reset link sent")
+	}
+
+	m := resetTokenPattern.FindStringSubmatch(messages[len(messages)-1].text)
+	if m == nil {
+		t.Fatalf("no reset link in %q", messages[len(messages)-1].text)
+	}
+
+	return m[1]
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		login    string
+		sendFail bool
+		wantSent int
+	}{
+		{name: "known phone", login: testPhone, wantSent: 1},
+		{name: "unknown phone", login: "+79990000000"},
+		{name: "known phone, sending fails", login: testPhone, sendFail: true},
+		{name: "unknown phone, sending fails", login: "+79990000000", sendFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+
+			if tt.sendFail {
+				env.outbox.failure = errors.New("sms gateway is down")
+			}
+
+			response, err := env.auth.RequestPasswordReset(ctx, tt.login)
+			if err != nil {
+				t.Fatalf("RequestPasswordReset() = %v, want nil", err)
+			}
+
+			if response != msgResetRequested {
+				t.Errorf("RequestPasswordReset() = %q, want %q", response, msgResetRequested)
+			}
+
+			env.auth.sends.Wait()
+			if sent := len(env.outbox.to(tt.login)); sent != tt.wantSent {
+				t.Errorf("sent %d messages, want %d", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestRequestPasswordResetLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		login string
+	}{
+		{name: "known phone", login: testPhone},
+		{name: "known phone in local form", login: "89991234567"},
+		{name: "unknown phone", login: "+79990000000"},
+		{name: "unknown email", login: "Nobody@Example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withCodeLimit(2))
+			env.addUser(t, testPhone)
+
+			for i := 0; i < 2; i++ {
+				if _, err := env.auth.RequestPasswordReset(ctx, tt.login); err != nil {
+					t.Fatalf("request %d = %v, want nil", i+1, err)
+				}
+			}
+
+			// Known and unknown logins are limited alike.
+			if _, err := env.auth.RequestPasswordReset(ctx, tt.login); !errors.Is(err, otp.ErrTooManyCodes) {
+				t.Fatalf("request 3 = %v, want %v", err, otp.ErrTooManyCodes)
+			}
+		})
+	}
+}
+
+func TestPerformPasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		newPassword string
+		// reuse resets the password with the token once before.
+		reuse   bool
+		wantErr error
+	}{
+		{name: "ok", newPassword: "newsecret123"},
+		{name: "used token", newPassword: "newsecret123", reuse: true, wantErr: ErrInvalidResetToken},
+		{name: "weak password", newPassword: "a1", wantErr: password.ErrTooShort},
+		{name: "current password", newPassword: testPassword, wantErr: ErrPasswordReused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+			access, refresh := env.login(t, testPhone)
+
+			token := env.resetToken(t, testPhone)
+
+			if tt.reuse {
+				if _, err := env.auth.PerformPasswordReset(ctx, token, "othersecret123"); err != nil {
+					t.Fatalf("PerformPasswordReset: %v", err)
+				}
+			}
+
+			_, err := env.auth.PerformPasswordReset(ctx, token, tt.newPassword)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PerformPasswordReset() = %v, want %v", err, tt.wantErr)
+			}
+
+			_, _, refreshErr := env.auth.RefreshSession(ctx, access, refresh)
+
+			if tt.wantErr != nil {
+				if !tt.reuse && refreshErr != nil {
+					t.Fatalf("session was signed out after a failed reset: %v", refreshErr)
+				}
+
+				// A failed reset doesn't spend the token.
+				if !tt.reuse {
+					if _, err := env.auth.PerformPasswordReset(ctx, token, "othersecret123"); err != nil {
+						t.Fatalf("PerformPasswordReset after a failed one: %v", err)
+					}
+				}
+				return
+			}
+
+			if refreshErr == nil {
+				t.Fatal("session survived the password reset")
+			}
+
+			if _, _, err := env.auth.Login(ctx, testPhone, tt.newPassword, env.app.ID, entity.Device{}); err != nil {
+				t.Fatalf("Login with the new password: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"time"
+)
+
+type SessionStorage interface {
+	SaveSession(session entity.Session) (entity.Session, error)
+	Session(sessionID int64) (entity.Session, error)
+	Sessions(uid int64) ([]entity.Session, error)
+	RevokeSession(uid, sessionID int64, reason string) error
+}
+
+// Locator gives an approximate location of an IP address, or an empty
+// string if it is unknown.
+type Locator interface {
+	Locate(ip string) string
+}
+
+// startSession records a new session of the user on the device and issues
+// its first token pair.
+func (a *Auth) startSession(user entity.User, app entity.App, device entity.Device,
+) (accessToken, refreshToken string, err error) {
+	const op = "auth.startSession"
+
+	if device.Location == "" && device.IP != "" {
+		device.Location = a.locator.Locate(device.IP)
+	}
+
+	session, err := a.sessionStorage.SaveSession(entity.Session{
+		UserID: user.ID,
+		AppID:  app.ID,
+		Device: device,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, refreshToken, err = a.issueTokens(user, app, session.ID, session.CreatedAt, "")
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// ListSessions returns the sessions the user is signed in with, newest
+// first, and the id of the session of the token.
+func (a *Auth) ListSessions(ctx context.Context, accessToken string,
+) (sessions []entity.Session, currentID int64, err error) {
+	const op = "auth.ListSessions"
+
+	uid, currentID, _, err := a.authorizeSession(ctx, accessToken)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	all, err := a.sessionStorage.Sessions(uid)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	policies := make(map[int32]entity.SessionPolicy)
+
+	for _, session := range all {
+		if !session.IsActive {
+			continue
+		}
+
+		policy, ok := policies[session.AppID]
+		if !ok {
+			policy, err = a.appSessionPolicy(session.AppID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: %w", op, err)
+			}
+			policies[session.AppID] = policy
+		}
+
+		// Sessions nobody refreshed in time are over even if not revoked.
+		if !sessionExpiry(policy, session.CreatedAt, session.LastSeenAt).After(now) {
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, currentID, nil
+}
+
+// RevokeSession signs the user out of one of their sessions, including the
+// current one.
+func (a *Auth) RevokeSession(ctx context.Context, accessToken string, sessionID int64) (err error) {
+	const op = "auth.RevokeSession"
+
+	log := a.log.With(slog.String("op", op))
+
+	uid, app, err := a.authorize(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		a.recordEvent(ctx, entity.SecurityEvent{
+			UserID: uid,
+			Type:   entity.EventSessionRevoke,
+			Method: entity.MethodAccessToken,
+			AppID:  app.ID,
+		}, err)
+	}()
+
+	if err := a.sessionStorage.RevokeSession(uid, sessionID, "signed_out"); err != nil {
+		if !errors.Is(err, storage.ErrSessionNotFound) {
+			log.Error("failed to revoke session", sl.Err(err))
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("session revoked", slog.Int64("uid", uid), slog.Int64("session_id", sessionID))
+
+	return nil
+}
+
+// appSessionPolicy is the session policy of the app. Sessions recorded
+// before apps were tracked, or of deleted apps, get the global one.
+func (a *Auth) appSessionPolicy(appID int32) (entity.SessionPolicy, error) {
+	if appID == 0 {
+		return a.sessionPolicy(entity.App{}), nil
+	}
+
+	app, err := a.appProvider.App(appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return a.sessionPolicy(entity.App{}), nil
+		}
+		return entity.SessionPolicy{}, err
+	}
+
+	return a.sessionPolicy(app), nil
+}
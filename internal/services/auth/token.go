@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid access token")
+	// ErrSessionExpired means the absolute session lifetime is over.
+	ErrSessionExpired = errors.New("session lifetime is over")
+	// ErrSessionIdle means the session was not used for longer than the
+	// idle timeout.
+	ErrSessionIdle = errors.New("session expired after inactivity")
+	// ErrRefreshTokenReused means a refresh token that was already
+	// exchanged came back while its session is still alive: either the
+	// client retried, or the token was stolen. The session is revoked.
+	// Callers see it as an invalid refresh token.
+	ErrRefreshTokenReused = fmt.Errorf("refresh token was already used: %w", storage.ErrInvalidRefreshToken)
+)
+
+// authorize verifies the access token with the public keys of the SSO and
+// checks that the app that got it is still enabled.
+func (a *Auth) authorize(ctx context.Context, accessToken string) (uid int64, app entity.App, err error) {
+	uid, _, app, err = a.authorizeSession(ctx, accessToken)
+
+	return uid, app, err
+}
+
+// authorizeSession is authorize that also returns the session of the
+// token. Tokens of revoked sessions are rejected right away, not when
+// they expire.
+func (a *Auth) authorizeSession(ctx context.Context, accessToken string,
+) (uid, sessionID int64, app entity.App, err error) {
+	const op = "auth.authorizeSession"
+
+	uid, sessionID, appID, err := jwt.ParseAccessToken(accessToken, a.tokenKeys)
+	if err != nil {
+		return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	app, err = a.appProvider.App(appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return 0, 0, app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if app.Disabled {
+		return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if sessionID != 0 {
+		session, err := a.sessionStorage.Session(sessionID)
+		if err != nil {
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+			}
+			return 0, 0, app, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !session.IsActive || session.UserID != uid {
+			return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+	}
+
+	user, err := a.passStorage.UserByID(uid)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return 0, 0, app, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return 0, 0, app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkActive(user); err != nil {
+		return 0, 0, app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return uid, sessionID, app, nil
+}
+
+// issueTokens creates a new token pair for the session sessionID that
+// started at sessionStartedAt. The access token carries the user's roles
+// in the app as they are now, so a change reaches the app on the next
+// refresh. Saving the refresh token deactivates the
+// previous refresh token of the session; on refresh it is previous, and
+// the pair is only issued if no other refresh exchanged it first.
+func (a *Auth) issueTokens(user entity.User, app entity.App, sessionID int64, sessionStartedAt time.Time,
+	previous string) (accessToken, refreshToken string, err error) {
+	const op = "auth.issueTokens"
+
+	now := time.Now()
+	policy := a.sessionPolicy(app)
+
+	refreshExpiresAt := sessionExpiry(policy, sessionStartedAt, now)
+	if !refreshExpiresAt.After(now) {
+		return "", "", fmt.Errorf("%s: %w", op, storage.ErrInvalidRefreshToken)
+	}
+
+	// The access token never outlives the session.
+	accessTTL := min(policy.AccessTokenTTL, refreshExpiresAt.Sub(now))
+
+	roles, err := a.appProvider.UserAppRoles(user.ID, app.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err = jwt.NewAccessToken(a.tokenKeys, user, app, sessionID, roles, accessTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	lastChar := accessToken[len(accessToken)-6:]
+
+	refreshToken, err = jwt.NewRefreshToken(lastChar, app, refreshExpiresAt.Sub(now))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.refreshTokenSaver.SaveRefreshToken(refreshToken, user.ID, sessionID, previous); err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenUsed) {
+			return "", "", fmt.Errorf("%s: %w", op, ErrRefreshTokenReused)
+		}
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// sessionPolicy applies the global session settings to what the app
+// doesn't set itself.
+func (a *Auth) sessionPolicy(app entity.App) entity.SessionPolicy {
+	policy := entity.SessionPolicy{
+		AccessTokenTTL:  a.accessTokenTTL,
+		RefreshTokenTTL: a.refreshTokenTTL,
+		SessionLifetime: a.sessionCfg.AbsoluteLifetime,
+		IdleTimeout:     a.sessionCfg.IdleTimeout,
+		SlidingRefresh:  a.sessionCfg.SlidingRefresh,
+	}
+
+	if app.AccessTokenTTL > 0 {
+		policy.AccessTokenTTL = app.AccessTokenTTL
+	}
+	if app.RefreshTokenTTL > 0 {
+		policy.RefreshTokenTTL = app.RefreshTokenTTL
+	}
+	if app.SessionLifetime > 0 {
+		policy.SessionLifetime = app.SessionLifetime
+	}
+	if app.IdleTimeout > 0 {
+		policy.IdleTimeout = app.IdleTimeout
+	}
+	if app.SlidingRefresh != nil {
+		policy.SlidingRefresh = *app.SlidingRefresh
+	}
+
+	return policy
+}
+
+// verifyingApp drops the retired signing key of the app once every refresh
+// token it signed has expired: they were all issued before the rotation
+// and live at most policy.RefreshTokenTTL.
+func verifyingApp(app entity.App, policy entity.SessionPolicy, now time.Time) entity.App {
+	if now.After(app.SigningKeyRotatedAt.Add(policy.RefreshTokenTTL)) {
+		app.RetiredSigningKey = ""
+	}
+
+	return app
+}
+
+// sessionExpiry is when a refresh token issued at now must expire: the
+// refresh TTL counted from now, or from the login without sliding refresh,
+// cut by the idle timeout and the absolute session lifetime.
+func sessionExpiry(policy entity.SessionPolicy, sessionStartedAt, now time.Time) time.Time {
+	expiresAt := now.Add(policy.RefreshTokenTTL)
+	if !policy.SlidingRefresh {
+		expiresAt = sessionStartedAt.Add(policy.RefreshTokenTTL)
+	}
+
+	if policy.IdleTimeout > 0 {
+		expiresAt = minTime(expiresAt, now.Add(policy.IdleTimeout))
+	}
+	if policy.SessionLifetime > 0 {
+		expiresAt = minTime(expiresAt, sessionStartedAt.Add(policy.SessionLifetime))
+	}
+
+	return expiresAt
+}
+
+// checkSessionLimits tells whether the session of a refresh token is over
+// by the absolute lifetime or by the idle timeout.
+func checkSessionLimits(policy entity.SessionPolicy, token entity.RefreshToken, now time.Time) error {
+	if policy.SessionLifetime > 0 && !now.Before(token.StartedAt.Add(policy.SessionLifetime)) {
+		return ErrSessionExpired
+	}
+
+	if policy.IdleTimeout > 0 && !now.Before(token.LastUsedAt.Add(policy.IdleTimeout)) {
+		return ErrSessionIdle
+	}
+
+	return nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
+
+// Authorize returns the id of the user the access token was issued to.
+// Other services use it to accept the SSO's own tokens.
+func (a *Auth) Authorize(ctx context.Context, accessToken string) (uid int64, err error) {
+	uid, _, err = a.authorize(ctx, accessToken)
+
+	return uid, err
+}
+
+// checkApp rejects disabled apps and grant types the app is not allowed
+// to use.
+func checkApp(app entity.App, grantType string) error {
+	if app.Disabled {
+		return ErrAppDisabled
+	}
+
+	if !slices.Contains(app.GrantTypes, grantType) {
+		return ErrGrantNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	ssojwt "github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+func TestSessionPolicy(t *testing.T) {
+	yes, no := true, false
+
+	global := entity.SessionPolicy{
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 720 * time.Hour,
+		SessionLifetime: 2160 * time.Hour,
+		IdleTimeout:     168 * time.Hour,
+		SlidingRefresh:  true,
+	}
+
+	tests := []struct {
+		name string
+		app  entity.App
+		want entity.SessionPolicy
+	}{
+		{name: "app sets nothing", want: global},
+		{
+			name: "admin panel",
+			app:  entity.App{AccessTokenTTL: 5 * time.Minute, IdleTimeout: 30 * time.Minute, SlidingRefresh: &no},
+			want: entity.SessionPolicy{
+				AccessTokenTTL:  5 * time.Minute,
+				RefreshTokenTTL: 720 * time.Hour,
+				SessionLifetime: 2160 * time.Hour,
+				IdleTimeout:     30 * time.Minute,
+			},
+		},
+		{
+			name: "mobile app",
+			app:  entity.App{RefreshTokenTTL: 2160 * time.Hour, SessionLifetime: 8760 * time.Hour, SlidingRefresh: &yes},
+			want: entity.SessionPolicy{
+				AccessTokenTTL:  15 * time.Minute,
+				RefreshTokenTTL: 2160 * time.Hour,
+				SessionLifetime: 8760 * time.Hour,
+				IdleTimeout:     168 * time.Hour,
+				SlidingRefresh:  true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Auth{
+				accessTokenTTL:  global.AccessTokenTTL,
+				refreshTokenTTL: global.RefreshTokenTTL,
+				sessionCfg: config.SessionConfig{
+					AbsoluteLifetime: global.SessionLifetime,
+					IdleTimeout:      global.IdleTimeout,
+					SlidingRefresh:   global.SlidingRefresh,
+				},
+			}
+
+			if got := a.sessionPolicy(tt.app); got != tt.want {
+				t.Errorf("sessionPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	started := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	now := started.Add(10 * 24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		policy entity.SessionPolicy
+		want   time.Time
+	}{
+		{
+			name:   "sliding",
+			policy: entity.SessionPolicy{RefreshTokenTTL: 30 * 24 * time.Hour, SlidingRefresh: true},
+			want:   now.Add(30 * 24 * time.Hour),
+		},
+		{
+			name:   "fixed from login",
+			policy: entity.SessionPolicy{RefreshTokenTTL: 30 * 24 * time.Hour},
+			want:   started.Add(30 * 24 * time.Hour),
+		},
+		{
+			name:   "cut by idle timeout",
+			policy: entity.SessionPolicy{RefreshTokenTTL: 30 * 24 * time.Hour, SlidingRefresh: true, IdleTimeout: time.Hour},
+			want:   now.Add(time.Hour),
+		},
+		{
+			name: "cut by session lifetime",
+			policy: entity.SessionPolicy{
+				RefreshTokenTTL: 30 * 24 * time.Hour,
+				SlidingRefresh:  true,
+				SessionLifetime: 14 * 24 * time.Hour,
+			},
+			want: started.Add(14 * 24 * time.Hour),
+		},
+		{
+			name:   "lifetime already over",
+			policy: entity.SessionPolicy{RefreshTokenTTL: 30 * 24 * time.Hour, SessionLifetime: 24 * time.Hour},
+			want:   started.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionExpiry(tt.policy, started, now); !got.Equal(tt.want) {
+				t.Errorf("sessionExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginUsesAppTTL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		accessTTL time.Duration
+		want      time.Duration
+	}{
+		{name: "global ttl", want: 15 * time.Minute},
+		{name: "app ttl", accessTTL: 5 * time.Minute, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addUser(t, testPhone)
+
+			app, err := env.storage.SaveApp(entity.App{
+				Name:           "admin panel",
+				SigningKey:     "admin-signing-key",
+				GrantTypes:     []string{entity.GrantPassword, entity.GrantRefreshToken},
+				AccessTokenTTL: tt.accessTTL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			access, _, err := env.auth.Login(ctx, testPhone, testPassword, app.ID, entity.Device{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(access, claims); err != nil {
+				t.Fatal(err)
+			}
+
+			exp, err := claims.GetExpirationTime()
+			if err != nil {
+				t.Fatal(err)
+			}
+			iat, err := claims.GetIssuedAt()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := exp.Sub(iat.Time); got != tt.want {
+				t.Errorf("access token ttl = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokensCarryGrants(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, testPhone)
+
+	access, refresh, err := env.auth.Login(ctx, testPhone, testPassword, env.app.ID, entity.Device{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(access, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["roles"] != nil || claims["scope"] != nil {
+		t.Errorf("token without grants has roles %v, scope %v", claims["roles"], claims["scope"])
+	}
+
+	// Grants reach the app on the next refresh.
+	if err := env.storage.GrantUserAppRole(user.ID, env.app.ID, "manager", "ivanov"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.storage.SetAppScopes(env.app.ID, []string{"orders:read", "orders:write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	access, _, err = env.auth.RefreshSession(ctx, access, refresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims = jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(access, claims); err != nil {
+		t.Fatal(err)
+	}
+
+	if roles, _ := claims["roles"].([]any); len(roles) != 1 || roles[0] != "manager" {
+		t.Errorf("roles = %v, want [manager]", claims["roles"])
+	}
+	if claims["scope"] != "orders:read orders:write" {
+		t.Errorf("scope = %v, want orders:read orders:write", claims["scope"])
+	}
+}
+
+func TestCheckSessionLimits(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		policy  entity.SessionPolicy
+		token   entity.RefreshToken
+		wantErr error
+	}{
+		{
+			name:   "no limits",
+			policy: entity.SessionPolicy{},
+			token:  entity.RefreshToken{StartedAt: now.Add(-8760 * time.Hour), LastUsedAt: now.Add(-720 * time.Hour)},
+		},
+		{
+			name:   "within both limits",
+			policy: entity.SessionPolicy{SessionLifetime: 24 * time.Hour, IdleTimeout: time.Hour},
+			token:  entity.RefreshToken{StartedAt: now.Add(-23 * time.Hour), LastUsedAt: now.Add(-59 * time.Minute)},
+		},
+		{
+			name:    "lifetime over",
+			policy:  entity.SessionPolicy{SessionLifetime: 24 * time.Hour, IdleTimeout: time.Hour},
+			token:   entity.RefreshToken{StartedAt: now.Add(-24 * time.Hour), LastUsedAt: now},
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name:    "idle",
+			policy:  entity.SessionPolicy{SessionLifetime: 24 * time.Hour, IdleTimeout: time.Hour},
+			token:   entity.RefreshToken{StartedAt: now.Add(-2 * time.Hour), LastUsedAt: now.Add(-time.Hour)},
+			wantErr: ErrSessionIdle,
+		},
+		{
+			name:    "both over reports the lifetime",
+			policy:  entity.SessionPolicy{SessionLifetime: 24 * time.Hour, IdleTimeout: time.Hour},
+			token:   entity.RefreshToken{StartedAt: now.Add(-48 * time.Hour), LastUsedAt: now.Add(-48 * time.Hour)},
+			wantErr: ErrSessionExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSessionLimits(tt.policy, tt.token, now); err != tt.wantErr {
+				t.Fatalf("checkSessionLimits() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyingApp(t *testing.T) {
+	now := time.Now()
+	policy := entity.SessionPolicy{RefreshTokenTTL: 24 * time.Hour}
+
+	tests := []struct {
+		name        string
+		rotatedAt   time.Time
+		wantRetired string
+	}{
+		{name: "tokens of the old key live", rotatedAt: now.Add(-23 * time.Hour), wantRetired: "old"},
+		{name: "tokens of the old key expired", rotatedAt: now.Add(-25 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := entity.App{SigningKey: "new", RetiredSigningKey: "old", SigningKeyRotatedAt: tt.rotatedAt}
+
+			got := verifyingApp(app, policy, now)
+			if got.RetiredSigningKey != tt.wantRetired || got.SigningKey != "new" {
+				t.Errorf("verifyingApp() keys = %q, %q, want new, %q", got.SigningKey, got.RetiredSigningKey, tt.wantRetired)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T, env *testEnv, user entity.User) string
+		wantErr error
+	}{
+		{
+			name: "own token",
+			token: func(t *testing.T, env *testEnv, _ entity.User) string {
+				access, _ := env.login(t, testPhone)
+				return access
+			},
+		},
+		{
+			name: "signed by another key",
+			token: func(t *testing.T, env *testEnv, user entity.User) string {
+				access, err := ssojwt.NewAccessToken(ssojwt.NewKeys(otherKey), user, env.app, 0, nil, time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return access
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "signed with the app key",
+			token: func(t *testing.T, env *testEnv, user entity.User) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"uid":    user.ID,
+					"app_id": env.app.ID,
+					"exp":    time.Now().Add(time.Minute).Unix(),
+				})
+				access, err := token.SignedString([]byte(env.app.SigningKey))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return access
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T, env *testEnv, user entity.User) string {
+				access, err := ssojwt.NewAccessToken(env.keys, user, env.app, 0, nil, -time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return access
+			},
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, testPhone)
+
+			uid, err := env.auth.Authorize(ctx, tt.token(t, env, user))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && uid != user.ID {
+				t.Errorf("uid = %d, want %d", uid, user.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"slices"
+	"time"
+)
+
+// document is the JSON layout of an export. Field names are part of the
+// format users and support receive, so rename them only with a new version.
+// Secrets such as password hashes and tokens are never included.
+type document struct {
+	Version         int             `json:"version"`
+	ExportedAt      time.Time       `json:"exported_at"`
+	Account         account         `json:"account"`
+	Profile         profile         `json:"profile"`
+	Addresses       []address       `json:"addresses"`
+	Sessions        []session       `json:"sessions"`
+	PasswordChanges []time.Time     `json:"password_changes"`
+	PhoneHistory    []phoneRelease  `json:"phone_history"`
+	SecurityEvents  []securityEvent `json:"security_events"`
+	Consents        []consent       `json:"consents"`
+}
+
+const documentVersion = 1
+
+type account struct {
+	ID         int64      `json:"id"`
+	Phone      string     `json:"phone"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Status     string     `json:"status"`
+	PurgeAfter *time.Time `json:"purge_after,omitempty"`
+}
+
+type profile struct {
+	FullName      string    `json:"full_name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type address struct {
+	ID          int64     `json:"id"`
+	FullAddress string    `json:"full_address"`
+	City        string    `json:"city,omitempty"`
+	Street      string    `json:"street,omitempty"`
+	Building    string    `json:"building,omitempty"`
+	Apartment   string    `json:"apartment,omitempty"`
+	Latitude    *float64  `json:"latitude,omitempty"`
+	Longitude   *float64  `json:"longitude,omitempty"`
+	IsDefault   bool      `json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type session struct {
+	ID         int64      `json:"id"`
+	AppID      int32      `json:"app_id,omitempty"`
+	DeviceName string     `json:"device_name,omitempty"`
+	Platform   string     `json:"platform,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	Location   string     `json:"location,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	IsActive   bool       `json:"is_active"`
+}
+
+type securityEvent struct {
+	Type      string    `json:"type"`
+	Outcome   string    `json:"outcome"`
+	Method    string    `json:"method,omitempty"`
+	AppID     int32     `json:"app_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// consent is an app the user let into their account by signing in to it.
+// The SSO keeps no separate consent records, such as marketing opt-ins:
+// those belong to the apps. Grants are derived from sessions, so apps
+// whose sessions were all cleaned up are missing.
+type consent struct {
+	AppID      int32     `json:"app_id"`
+	GrantedAt  time.Time `json:"granted_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	IsActive   bool      `json:"is_active"`
+}
+
+type phoneRelease struct {
+	Phone      string    `json:"phone"`
+	ReleasedAt time.Time `json:"released_at"`
+}
+
+func newDocument(exportedAt time.Time,
+	user entity.User,
+	prof entity.Profile,
+	addresses []entity.Address,
+	sessions []entity.Session,
+	passwordChanges []time.Time,
+	phoneHistory []entity.PhoneRelease,
+	securityEvents []entity.SecurityEvent) document {
+	doc := document{
+		Version:    documentVersion,
+		ExportedAt: exportedAt.UTC(),
+		Account: account{
+			ID:        user.ID,
+			Phone:     user.Phone,
+			CreatedAt: user.CreatedAt,
+			Status:    string(user.State.Status),
+		},
+		Profile: profile{
+			FullName:      prof.FullName,
+			Email:         prof.Email,
+			EmailVerified: prof.EmailVerified,
+			UpdatedAt:     prof.UpdatedAt,
+		},
+		Addresses:       []address{},
+		Sessions:        []session{},
+		PasswordChanges: []time.Time{},
+		PhoneHistory:    []phoneRelease{},
+		SecurityEvents:  []securityEvent{},
+		Consents:        []consent{},
+	}
+
+	if !user.PurgeAfter.IsZero() {
+		purgeAfter := user.PurgeAfter
+		doc.Account.PurgeAfter = &purgeAfter
+	}
+
+	for _, addr := range addresses {
+		item := address{
+			ID:          addr.ID,
+			FullAddress: addr.FullAddress,
+			City:        addr.City,
+			Street:      addr.Street,
+			Building:    addr.Building,
+			Apartment:   addr.Apartment,
+			IsDefault:   addr.IsDefault,
+			CreatedAt:   addr.CreatedAt,
+			UpdatedAt:   addr.UpdatedAt,
+		}
+		if addr.Location != nil {
+			item.Latitude = &addr.Location.Latitude
+			item.Longitude = &addr.Location.Longitude
+		}
+		doc.Addresses = append(doc.Addresses, item)
+	}
+
+	for _, s := range sessions {
+		item := session{
+			ID:         s.ID,
+			AppID:      s.AppID,
+			DeviceName: s.Device.Name,
+			Platform:   s.Device.Platform,
+			UserAgent:  s.Device.UserAgent,
+			IP:         s.Device.IP,
+			Location:   s.Device.Location,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			IsActive:   s.IsActive,
+		}
+		if !s.RevokedAt.IsZero() {
+			item.RevokedAt = &s.RevokedAt
+		}
+		doc.Sessions = append(doc.Sessions, item)
+	}
+
+	doc.Consents = consents(sessions)
+
+	doc.PasswordChanges = append(doc.PasswordChanges, passwordChanges...)
+
+	for _, release := range phoneHistory {
+		doc.PhoneHistory = append(doc.PhoneHistory, phoneRelease{
+			Phone:      release.Phone,
+			ReleasedAt: release.ReleasedAt,
+		})
+	}
+
+	for _, event := range securityEvents {
+		doc.SecurityEvents = append(doc.SecurityEvents, securityEvent{
+			Type:      event.Type,
+			Outcome:   event.Outcome,
+			Method:    event.Method,
+			AppID:     event.AppID,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Reason:    event.Reason,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return doc
+}
+
+// consents folds the sessions into one grant per app, oldest first.
+func consents(sessions []entity.Session) []consent {
+	byApp := make(map[int32]int)
+	grants := []consent{}
+
+	for _, s := range sessions {
+		if s.AppID == 0 {
+			continue
+		}
+
+		i, ok := byApp[s.AppID]
+		if !ok {
+			byApp[s.AppID] = len(grants)
+			grants = append(grants, consent{
+				AppID:      s.AppID,
+				GrantedAt:  s.CreatedAt,
+				LastUsedAt: s.LastSeenAt,
+				IsActive:   s.IsActive,
+			})
+			continue
+		}
+
+		grant := &grants[i]
+		if s.CreatedAt.Before(grant.GrantedAt) {
+			grant.GrantedAt = s.CreatedAt
+		}
+		if s.LastSeenAt.After(grant.LastUsedAt) {
+			grant.LastUsedAt = s.LastSeenAt
+		}
+		grant.IsActive = grant.IsActive || s.IsActive
+	}
+
+	slices.SortFunc(grants, func(a, b consent) int {
+		return a.GrantedAt.Compare(b.GrantedAt)
+	})
+
+	return grants
+}
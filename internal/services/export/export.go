@@ -0,0 +1,285 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrActorRequired = errors.New("actor is required")
+)
+
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatZip
+)
+
+const (
+	actionSelfExport  = "data_export.self"
+	actionAdminExport = "data_export.admin"
+
+	addressPageSize = 100
+	eventPageSize   = 500
+	exportFileName  = "vizap-data"
+)
+
+// Bundle is a ready-to-send export file.
+type Bundle struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Export struct {
+	log              *slog.Logger
+	authorizer       Authorizer
+	userProvider     UserProvider
+	profileProvider  ProfileProvider
+	addressProvider  AddressProvider
+	activityProvider ActivityProvider
+	auditSaver       AuditSaver
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, accessToken string) (uid int64, err error)
+}
+
+type UserProvider interface {
+	UserByID(uid int64) (entity.User, error)
+}
+
+type ProfileProvider interface {
+	Profile(uid int64) (entity.Profile, error)
+}
+
+type AddressProvider interface {
+	Addresses(uid, afterID int64, limit int) ([]entity.Address, error)
+}
+
+type ActivityProvider interface {
+	Sessions(uid int64) ([]entity.Session, error)
+	PasswordChanges(uid int64) ([]time.Time, error)
+	PhoneHistory(uid int64) ([]entity.PhoneRelease, error)
+	SecurityEvents(uid, beforeID int64, limit int) ([]entity.SecurityEvent, error)
+}
+
+type AuditSaver interface {
+	SaveAuditRecord(record entity.AuditRecord) error
+}
+
+func New(log *slog.Logger,
+	authorizer Authorizer,
+	userProvider UserProvider,
+	profileProvider ProfileProvider,
+	addressProvider AddressProvider,
+	activityProvider ActivityProvider,
+	auditSaver AuditSaver) *Export {
+	return &Export{
+		log:              log,
+		authorizer:       authorizer,
+		userProvider:     userProvider,
+		profileProvider:  profileProvider,
+		addressProvider:  addressProvider,
+		activityProvider: activityProvider,
+		auditSaver:       auditSaver,
+	}
+}
+
+// ExportMyData builds an export of the signed-in user's own data.
+func (e *Export) ExportMyData(ctx context.Context, accessToken string, format Format) (Bundle, error) {
+	const op = "export.ExportMyData"
+
+	uid, err := e.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	bundle, err := e.export(uid, "user:"+strconv.FormatInt(uid, 10), actionSelfExport, format)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bundle, nil
+}
+
+// ExportUserData builds an export of any user's data on behalf of
+// support staff. actor names the staff member and ends up in the audit log.
+func (e *Export) ExportUserData(ctx context.Context, actor string, uid int64, format Format) (Bundle, error) {
+	const op = "export.ExportUserData"
+
+	if actor == "" {
+		return Bundle{}, fmt.Errorf("%s: %w", op, ErrActorRequired)
+	}
+
+	bundle, err := e.export(uid, actor, actionAdminExport, format)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bundle, nil
+}
+
+// export records the request in the audit log before collecting any data,
+// so a failed or abandoned export is still on record.
+func (e *Export) export(uid int64, actor, action string, format Format) (Bundle, error) {
+	log := e.log.With(slog.Int64("uid", uid), slog.String("actor", actor))
+
+	if format != FormatJSON && format != FormatZip {
+		return Bundle{}, ErrUnknownFormat
+	}
+
+	details, err := json.Marshal(map[string]string{"format": format.String()})
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	err = e.auditSaver.SaveAuditRecord(entity.AuditRecord{
+		Actor:        actor,
+		Action:       action,
+		TargetUserID: uid,
+		Details:      details,
+	})
+	if err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return Bundle{}, err
+	}
+
+	data, err := e.collect(uid)
+	if err != nil {
+		log.Error("failed to collect user data", sl.Err(err))
+		return Bundle{}, err
+	}
+
+	document, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	if format == FormatJSON {
+		log.Info("user data exported", slog.Int("size", len(document)))
+
+		return Bundle{
+			Filename:    exportFileName + ".json",
+			ContentType: "application/json",
+			Data:        document,
+		}, nil
+	}
+
+	archive, err := zipDocument(exportFileName+".json", document)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	log.Info("user data exported", slog.Int("size", len(archive)))
+
+	return Bundle{
+		Filename:    exportFileName + ".zip",
+		ContentType: "application/zip",
+		Data:        archive,
+	}, nil
+}
+
+func (e *Export) collect(uid int64) (document, error) {
+	user, err := e.userProvider.UserByID(uid)
+	if err != nil {
+		return document{}, err
+	}
+
+	profile, err := e.profileProvider.Profile(uid)
+	if err != nil {
+		return document{}, err
+	}
+
+	var addresses []entity.Address
+	var afterID int64
+	for {
+		page, err := e.addressProvider.Addresses(uid, afterID, addressPageSize)
+		if err != nil {
+			return document{}, err
+		}
+
+		addresses = append(addresses, page...)
+
+		if len(page) < addressPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	sessions, err := e.activityProvider.Sessions(uid)
+	if err != nil {
+		return document{}, err
+	}
+
+	passwordChanges, err := e.activityProvider.PasswordChanges(uid)
+	if err != nil {
+		return document{}, err
+	}
+
+	phoneHistory, err := e.activityProvider.PhoneHistory(uid)
+	if err != nil {
+		return document{}, err
+	}
+
+	var events []entity.SecurityEvent
+	var beforeID int64
+	for {
+		page, err := e.activityProvider.SecurityEvents(uid, beforeID, eventPageSize)
+		if err != nil {
+			return document{}, err
+		}
+
+		events = append(events, page...)
+
+		if len(page) < eventPageSize {
+			break
+		}
+		beforeID = page[len(page)-1].ID
+	}
+
+	return newDocument(time.Now(), user, profile, addresses, sessions, passwordChanges, phoneHistory, events), nil
+}
+
+func zipDocument(name string, document []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(document); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatZip:
+		return "zip"
+	}
+
+	return "unknown"
+}
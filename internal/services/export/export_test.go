@@ -0,0 +1,211 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type testAuthorizer struct{}
+
+func (testAuthorizer) Authorize(_ context.Context, accessToken string) (int64, error) {
+	if accessToken != "token" {
+		return 0, errors.New("invalid token")
+	}
+
+	return 1, nil
+}
+
+// testStorage holds the data of user 1 and the audit records written.
+type testStorage struct {
+	sessions []entity.Session
+	failure  error
+	audit    []entity.AuditRecord
+}
+
+func (s *testStorage) UserByID(uid int64) (entity.User, error) {
+	if s.failure != nil {
+		return entity.User{}, s.failure
+	}
+
+	return entity.User{ID: uid, Phone: "+79991234567"}, nil
+}
+
+func (s *testStorage) Profile(uid int64) (entity.Profile, error) {
+	return entity.Profile{UserID: uid, FullName: "Иван Петров"}, nil
+}
+
+func (s *testStorage) Addresses(uid, afterID int64, limit int) ([]entity.Address, error) {
+	return nil, nil
+}
+
+func (s *testStorage) Sessions(uid int64) ([]entity.Session, error) {
+	return s.sessions, nil
+}
+
+func (s *testStorage) PasswordChanges(uid int64) ([]time.Time, error) {
+	return nil, nil
+}
+
+func (s *testStorage) PhoneHistory(uid int64) ([]entity.PhoneRelease, error) {
+	return nil, nil
+}
+
+func (s *testStorage) SecurityEvents(uid, beforeID int64, limit int) ([]entity.SecurityEvent, error) {
+	return nil, nil
+}
+
+func (s *testStorage) SaveAuditRecord(record entity.AuditRecord) error {
+	s.audit = append(s.audit, record)
+	return nil
+}
+
+func newTestExport(st *testStorage) *Export {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, testAuthorizer{}, st, st, st, st, st)
+}
+
+func TestExportMyData(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		format          Format
+		failure         error
+		wantContentType string
+		wantErr         bool
+	}{
+		{name: "json", format: FormatJSON, wantContentType: "application/json"},
+		{name: "zip", format: FormatZip, wantContentType: "application/zip"},
+		{name: "unknown format", format: Format(7), wantErr: true},
+		{name: "storage failure", format: FormatJSON, failure: errors.New("db is down"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &testStorage{failure: tt.failure}
+
+			bundle, err := newTestExport(st).ExportMyData(ctx, "token", tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExportMyData() = %v, want error %v", err, tt.wantErr)
+			}
+
+			// Even a failed collection stays on record.
+			wantAudit := 1
+			if errors.Is(err, ErrUnknownFormat) {
+				wantAudit = 0
+			}
+			if len(st.audit) != wantAudit {
+				t.Fatalf("audit records = %d, want %d", len(st.audit), wantAudit)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if st.audit[0].Action != actionSelfExport || st.audit[0].TargetUserID != 1 {
+				t.Errorf("audit record = %+v", st.audit[0])
+			}
+
+			if bundle.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %q, want %q", bundle.ContentType, tt.wantContentType)
+			}
+
+			data := bundle.Data
+			if tt.format == FormatZip {
+				data = unzip(t, data)
+			}
+
+			var doc map[string]any
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("export is not JSON: %v", err)
+			}
+
+			for _, section := range []string{"account", "profile", "addresses", "sessions", "security_events", "consents"} {
+				if _, ok := doc[section]; !ok {
+					t.Errorf("export has no %q section", section)
+				}
+			}
+		})
+	}
+}
+
+func unzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zr.File) != 1 {
+		t.Fatalf("archive has %d files, want 1", len(zr.File))
+	}
+
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+func TestConsents(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		sessions []entity.Session
+		want     []consent
+	}{
+		{name: "no sessions", want: []consent{}},
+		{
+			name:     "sessions without an app",
+			sessions: []entity.Session{{ID: 1, CreatedAt: day(1), LastSeenAt: day(2)}},
+			want:     []consent{},
+		},
+		{
+			name: "one grant per app, oldest first",
+			sessions: []entity.Session{
+				{ID: 3, AppID: 2, CreatedAt: day(5), LastSeenAt: day(6)},
+				{ID: 2, AppID: 1, CreatedAt: day(3), LastSeenAt: day(9), IsActive: true},
+				{ID: 1, AppID: 1, CreatedAt: day(1), LastSeenAt: day(4)},
+			},
+			want: []consent{
+				{AppID: 1, GrantedAt: day(1), LastUsedAt: day(9), IsActive: true},
+				{AppID: 2, GrantedAt: day(5), LastUsedAt: day(6)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := consents(tt.sessions)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("consents() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("consents() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
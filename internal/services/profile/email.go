@@ -0,0 +1,155 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCode       = errors.New("invalid verification code")
+	ErrNothingToVerify   = errors.New("no unverified email in profile")
+	ErrEmailAlreadyInUse = errors.New("email is verified by another account")
+)
+
+const (
+	codeDigits = 6
+
+	msgEmailVerificationSubject = "Подтверждение email"
+	msgEmailVerificationCode    = "Ваш код подтверждения email в Vizap: %s"
+)
+
+// VerifyEmail confirms the profile email with the code sent to it. After
+// that the email can be used to sign in.
+func (p *Profile) VerifyEmail(ctx context.Context, accessToken, code string) (email string, err error) {
+	const op = "profile.VerifyEmail"
+
+	log := p.log.With(slog.String("op", op))
+
+	uid, err := p.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", uid))
+
+	v, err := p.emailStorage.EmailVerification(uid)
+	if err != nil {
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(v.ExpiresAt) {
+		if err := p.emailStorage.DeleteEmailVerification(uid); err != nil {
+			log.Error("failed to delete email verification", sl.Err(err))
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	// The verification stays until it expires: deleting it would let a
+	// resend start the attempts over.
+	if v.Attempts >= p.emailCfg.MaxAttempts {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if !otp.Equal(code, v.CodeHash) {
+		if err := p.emailStorage.IncEmailVerificationAttempts(uid); err != nil {
+			log.Error("failed to count attempt", sl.Err(err))
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if err := p.emailStorage.VerifyEmail(uid, v.Email); err != nil {
+		if errors.Is(err, storage.ErrEmailExists) {
+			return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyInUse)
+		}
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			// The email in the profile was changed after the code was sent.
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to verify email", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified")
+
+	return v.Email, nil
+}
+
+func (p *Profile) ResendEmailVerification(ctx context.Context, accessToken string) error {
+	const op = "profile.ResendEmailVerification"
+
+	uid, err := p.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := p.profileProvider.Profile(uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if profile.Email == "" || profile.EmailVerified {
+		return fmt.Errorf("%s: %w", op, ErrNothingToVerify)
+	}
+
+	if err := p.sendEmailCode(ctx, uid, profile.Email); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sendEmailCode sends a new code to the email. A live verification keeps
+// its attempt counter, and the limiter caps how many codes an address
+// gets.
+func (p *Profile) sendEmailCode(ctx context.Context, uid int64, email string) error {
+	email = strings.ToLower(email)
+
+	if err := p.codeLimiter.Allow(otp.PurposeEmailVerification, email); err != nil {
+		return err
+	}
+
+	code, err := otp.Generate(codeDigits)
+	if err != nil {
+		return err
+	}
+
+	if err := p.dropExpiredVerification(uid); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(p.emailCfg.CodeTTL)
+
+	if err := p.emailStorage.SaveEmailVerification(uid, email, otp.Hash(code), expiresAt); err != nil {
+		return err
+	}
+
+	return p.emailSender.Send(ctx, email, msgEmailVerificationSubject, fmt.Sprintf(msgEmailVerificationCode, code))
+}
+
+func (p *Profile) dropExpiredVerification(uid int64) error {
+	v, err := p.emailStorage.EmailVerification(uid)
+	if errors.Is(err, storage.ErrVerificationNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if time.Now().After(v.ExpiresAt) {
+		return p.emailStorage.DeleteEmailVerification(uid)
+	}
+
+	return nil
+}
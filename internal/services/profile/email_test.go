@@ -0,0 +1,130 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"regexp"
+	"testing"
+)
+
+const testEmail = "ivan@example.com"
+
+var codePattern = regexp.MustCompile(`\d{6}`)
+
+// lastCode returns the code in the last email sent.
+func (s *testEmailSender) lastCode(t *testing.T) string {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.sent) == 0 {
+		t.Fatal("no emails sent")
+	}
+
+	return codePattern.FindString(s.sent[len(s.sent)-1])
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// before runs between the first code and the verification.
+		before       func(t *testing.T, p *Profile)
+		code         func(t *testing.T, sender *testEmailSender) string
+		wantErr      error
+		wantVerified bool
+	}{
+		{
+			name:         "ok",
+			code:         func(t *testing.T, sender *testEmailSender) string { return sender.lastCode(t) },
+			wantVerified: true,
+		},
+		{
+			name:    "wrong code",
+			code:    func(*testing.T, *testEmailSender) string { return "000000" },
+			wantErr: ErrInvalidCode,
+		},
+		{
+			name: "attempts survive a resend",
+			before: func(t *testing.T, p *Profile) {
+				for i := 0; i < 3; i++ {
+					if _, err := p.VerifyEmail(ctx, testToken, "000000"); !errors.Is(err, ErrInvalidCode) {
+						t.Fatalf("VerifyEmail() = %v, want %v", err, ErrInvalidCode)
+					}
+				}
+				if err := p.ResendEmailVerification(ctx, testToken); err != nil {
+					t.Fatalf("ResendEmailVerification: %v", err)
+				}
+			},
+			code:    func(t *testing.T, sender *testEmailSender) string { return sender.lastCode(t) },
+			wantErr: ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, st, sender := newTestProfile(t, entity.Profile{Version: 1}, 100)
+
+			if _, err := p.UpdateProfile(ctx, testToken, nil, ptr(testEmail), 0); err != nil {
+				t.Fatalf("UpdateProfile: %v", err)
+			}
+
+			if tt.before != nil {
+				tt.before(t, p)
+			}
+
+			email, err := p.VerifyEmail(ctx, testToken, tt.code(t, sender))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyEmail() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && email != testEmail {
+				t.Errorf("VerifyEmail() = %q, want %q", email, testEmail)
+			}
+
+			if st.profile.EmailVerified != tt.wantVerified {
+				t.Errorf("verified = %v, want %v", st.profile.EmailVerified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestResendEmailVerificationLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		resends int
+		wantErr error
+	}{
+		{name: "within the limit", resends: 1},
+		{name: "over the limit", resends: 2, wantErr: otp.ErrTooManyCodes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, sender := newTestProfile(t, entity.Profile{Version: 1}, 2)
+
+			if _, err := p.UpdateProfile(ctx, testToken, nil, ptr(testEmail), 0); err != nil {
+				t.Fatalf("UpdateProfile: %v", err)
+			}
+
+			var err error
+			for i := 0; i < tt.resends; i++ {
+				err = p.ResendEmailVerification(ctx, testToken)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResendEmailVerification() = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(sender.sent) > 2 {
+				t.Errorf("codes sent = %d, want at most 2", len(sender.sent))
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidFullName = errors.New("invalid full name")
+	ErrInvalidEmail    = errors.New("invalid email")
+)
+
+const maxFieldLength = 255
+
+type Profile struct {
+	log             *slog.Logger
+	authorizer      Authorizer
+	profileProvider ProfileProvider
+	profileSaver    ProfileSaver
+	emailStorage    EmailVerificationStorage
+	emailSender     EmailSender
+	codeLimiter     CodeLimiter
+	emailCfg        config.EmailConfig
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, accessToken string) (uid int64, err error)
+}
+
+type ProfileProvider interface {
+	Profile(uid int64) (entity.Profile, error)
+}
+
+type ProfileSaver interface {
+	UpdateProfile(update entity.ProfileUpdate, expectedVersion int) (entity.Profile, error)
+}
+
+type EmailVerificationStorage interface {
+	SaveEmailVerification(uid int64, email, codeHash string, expiresAt time.Time) error
+	EmailVerification(uid int64) (entity.EmailVerification, error)
+	IncEmailVerificationAttempts(uid int64) error
+	DeleteEmailVerification(uid int64) error
+	VerifyEmail(uid int64, email string) error
+}
+
+type EmailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type CodeLimiter interface {
+	Allow(purpose, target string) error
+}
+
+func New(log *slog.Logger,
+	authorizer Authorizer,
+	profileProvider ProfileProvider,
+	profileSaver ProfileSaver,
+	emailStorage EmailVerificationStorage,
+	emailSender EmailSender,
+	codeLimiter CodeLimiter,
+	emailCfg config.EmailConfig) *Profile {
+	return &Profile{
+		log:             log,
+		authorizer:      authorizer,
+		profileProvider: profileProvider,
+		profileSaver:    profileSaver,
+		emailStorage:    emailStorage,
+		emailSender:     emailSender,
+		codeLimiter:     codeLimiter,
+		emailCfg:        emailCfg,
+	}
+}
+
+func (p *Profile) GetProfile(ctx context.Context, accessToken string) (entity.Profile, error) {
+	const op = "profile.GetProfile"
+
+	log := p.log.With(slog.String("op", op))
+
+	uid, err := p.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := p.profileProvider.Profile(uid)
+	if err != nil {
+		log.Error("failed to get profile", sl.Err(err))
+		return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile changes the given fields and keeps the nil ones.
+// expectedVersion, when set, must match the stored version, otherwise
+// storage.ErrVersionConflict is returned. An empty email clears it.
+func (p *Profile) UpdateProfile(ctx context.Context, accessToken string, fullName, email *string, expectedVersion int,
+) (entity.Profile, error) {
+	const op = "profile.UpdateProfile"
+
+	log := p.log.With(slog.String("op", op))
+
+	uid, err := p.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", uid))
+
+	update := entity.ProfileUpdate{UserID: uid}
+
+	if fullName != nil {
+		normalized, err := normalizeFullName(*fullName)
+		if err != nil {
+			return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+		update.FullName = &normalized
+	}
+
+	if email != nil {
+		normalized, err := normalizeEmail(*email)
+		if err != nil {
+			return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+		update.Email = &normalized
+	}
+
+	current, err := p.profileProvider.Profile(uid)
+	if err != nil {
+		log.Error("failed to get profile", sl.Err(err))
+		return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := p.profileSaver.UpdateProfile(update, expectedVersion)
+	if err != nil {
+		log.Warn("failed to update profile", sl.Err(err))
+		return entity.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("profile updated", slog.Int("version", profile.Version))
+
+	if !strings.EqualFold(current.Email, profile.Email) {
+		if profile.Email == "" {
+			if err := p.emailStorage.DeleteEmailVerification(uid); err != nil {
+				log.Error("failed to delete email verification", sl.Err(err))
+			}
+		} else if err := p.sendEmailCode(ctx, uid, profile.Email); err != nil {
+			// The profile is saved, the user can ask for the code again.
+			log.Error("failed to send email verification", sl.Err(err))
+		}
+	}
+
+	return profile, nil
+}
+
+func normalizeFullName(fullName string) (string, error) {
+	fullName = strings.Join(strings.Fields(fullName), " ")
+
+	if utf8.RuneCountInString(fullName) > maxFieldLength {
+		return "", ErrInvalidFullName
+	}
+
+	for _, c := range fullName {
+		if !unicode.IsLetter(c) && c != ' ' && c != '-' && c != '\'' && c != '.' {
+			return "", ErrInvalidFullName
+		}
+	}
+
+	return fullName, nil
+}
+
+// normalizeEmail allows an empty email, which clears it.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+
+	if len(email) > maxFieldLength {
+		return "", ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return "", ErrInvalidEmail
+	}
+
+	return email, nil
+}
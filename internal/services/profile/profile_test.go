@@ -0,0 +1,251 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/KVSH-user/vizapSSO/internal/storage/memory"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "token"
+
+type testAuthorizer struct{ uid int64 }
+
+func (a testAuthorizer) Authorize(_ context.Context, accessToken string) (int64, error) {
+	if accessToken != testToken {
+		return 0, errors.New("invalid token")
+	}
+
+	return a.uid, nil
+}
+
+// testStorage keeps one user's profile and email verification the way
+// the postgres storage does.
+type testStorage struct {
+	mu           sync.Mutex
+	profile      entity.Profile
+	verification *entity.EmailVerification
+}
+
+func (s *testStorage) Profile(uid int64) (entity.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if uid != s.profile.UserID {
+		return entity.Profile{}, storage.ErrUserNotFound
+	}
+
+	return s.profile, nil
+}
+
+func (s *testStorage) UpdateProfile(update entity.ProfileUpdate, expectedVersion int) (entity.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expectedVersion != 0 && expectedVersion != s.profile.Version {
+		return entity.Profile{}, storage.ErrVersionConflict
+	}
+
+	if update.FullName != nil {
+		s.profile.FullName = *update.FullName
+	}
+	if update.Email != nil {
+		if !strings.EqualFold(*update.Email, s.profile.Email) {
+			s.profile.EmailVerified = false
+		}
+		s.profile.Email = *update.Email
+	}
+	s.profile.Version++
+	s.profile.UpdatedAt = time.Now()
+
+	return s.profile, nil
+}
+
+func (s *testStorage) SaveEmailVerification(uid int64, email, codeHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := entity.EmailVerification{UserID: uid, Email: email, CodeHash: codeHash, ExpiresAt: expiresAt}
+	if s.verification != nil {
+		v.Attempts = s.verification.Attempts
+	}
+	s.verification = &v
+
+	return nil
+}
+
+func (s *testStorage) EmailVerification(uid int64) (entity.EmailVerification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.verification == nil {
+		return entity.EmailVerification{}, storage.ErrVerificationNotFound
+	}
+
+	return *s.verification, nil
+}
+
+func (s *testStorage) IncEmailVerificationAttempts(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.verification != nil {
+		s.verification.Attempts++
+	}
+
+	return nil
+}
+
+func (s *testStorage) DeleteEmailVerification(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.verification = nil
+
+	return nil
+}
+
+func (s *testStorage) VerifyEmail(uid int64, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.verification == nil || !strings.EqualFold(s.profile.Email, email) {
+		return storage.ErrVerificationNotFound
+	}
+
+	s.profile.EmailVerified = true
+	s.verification = nil
+
+	return nil
+}
+
+type testEmailSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *testEmailSender) Send(_ context.Context, to, _, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, to+": "+body)
+
+	return nil
+}
+
+// newTestProfile runs the service for user 1 with the current profile.
+// Each address gets up to codeLimit codes an hour.
+func newTestProfile(t *testing.T, current entity.Profile, codeLimit int) (*Profile, *testStorage, *testEmailSender) {
+	t.Helper()
+
+	current.UserID = 1
+	st := &testStorage{profile: current}
+	sender := &testEmailSender{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := otp.NewLimiter(memory.New(), codeLimit, time.Hour)
+
+	p := New(log, testAuthorizer{uid: 1}, st, st, st, sender, limiter, config.EmailConfig{CodeTTL: time.Minute, MaxAttempts: 3})
+
+	return p, st, sender
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+
+	current := entity.Profile{FullName: "Иван Петров", Email: "ivan@example.com", EmailVerified: true, Version: 2}
+
+	tests := []struct {
+		name         string
+		fullName     *string
+		email        *string
+		version      int
+		want         entity.Profile
+		wantErr      error
+		wantCodeSent bool
+	}{
+		{
+			name:     "name only keeps the verified email",
+			fullName: ptr("  Пётр   Иванов "),
+			want:     entity.Profile{FullName: "Пётр Иванов", Email: "ivan@example.com", EmailVerified: true},
+		},
+		{
+			name:  "same email in another case stays verified",
+			email: ptr("Ivan@Example.com"),
+			want:  entity.Profile{FullName: "Иван Петров", Email: "ivan@example.com", EmailVerified: true},
+		},
+		{
+			name:         "new email",
+			email:        ptr("petr@example.com"),
+			want:         entity.Profile{FullName: "Иван Петров", Email: "petr@example.com"},
+			wantCodeSent: true,
+		},
+		{
+			name:  "empty email clears it",
+			email: ptr(""),
+			want:  entity.Profile{FullName: "Иван Петров"},
+		},
+		{
+			name: "nothing set",
+			want: entity.Profile{FullName: "Иван Петров", Email: "ivan@example.com", EmailVerified: true},
+		},
+		{
+			name:     "invalid name",
+			fullName: ptr("Иван2"),
+			wantErr:  ErrInvalidFullName,
+		},
+		{
+			name:    "invalid email",
+			email:   ptr("ivan@localhost"),
+			wantErr: ErrInvalidEmail,
+		},
+		{
+			name:     "stale version",
+			fullName: ptr("Пётр"),
+			version:  1,
+			wantErr:  storage.ErrVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, st, sender := newTestProfile(t, current, 100)
+
+			got, err := p.UpdateProfile(ctx, testToken, tt.fullName, tt.email, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProfile() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if st.profile.Version != current.Version {
+					t.Errorf("profile was saved after an error")
+				}
+				return
+			}
+
+			if got.FullName != tt.want.FullName || got.Email != tt.want.Email || got.EmailVerified != tt.want.EmailVerified {
+				t.Errorf("UpdateProfile() = %+v, want %+v", got, tt.want)
+			}
+
+			if got.Version != current.Version+1 {
+				t.Errorf("version = %d, want %d", got.Version, current.Version+1)
+			}
+
+			if codeSent := len(sender.sent) > 0; codeSent != tt.wantCodeSent {
+				t.Errorf("code sent = %v, want %v", codeSent, tt.wantCodeSent)
+			}
+		})
+	}
+}
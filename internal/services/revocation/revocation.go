@@ -0,0 +1,217 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStopped means the server is shutting down; the watcher should
+	// reconnect, possibly to another replica, with its last cursor.
+	ErrStopped = errors.New("revocation feed stopped")
+)
+
+type ClientAuthenticator interface {
+	AuthenticateClient(ctx context.Context, appID int32, clientSecret string) (entity.App, error)
+}
+
+type RevocationProvider interface {
+	Revocations(afterID int64, limit int) ([]entity.Revocation, error)
+	DeleteRevocationsBefore(cutoff time.Time, limit int) (int64, error)
+}
+
+// Listener signals new revocations made on any replica.
+type Listener interface {
+	Notify() <-chan struct{}
+	Close() error
+}
+
+// Feed streams the revocation feed to watchers. Every watcher reads the
+// feed from its own cursor; a signal from the listener only wakes them up.
+type Feed struct {
+	log        *slog.Logger
+	clientAuth ClientAuthenticator
+	provider   RevocationProvider
+	listener   Listener
+	cfg        config.RevocationsConfig
+
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(log *slog.Logger,
+	clientAuth ClientAuthenticator,
+	provider RevocationProvider,
+	listener Listener,
+	cfg config.RevocationsConfig) *Feed {
+	return &Feed{
+		log:        log,
+		clientAuth: clientAuth,
+		provider:   provider,
+		listener:   listener,
+		cfg:        cfg,
+		watchers:   make(map[chan struct{}]struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run wakes watchers on notifications and every PollInterval, and removes
+// entries older than the retention.
+func (f *Feed) Run() {
+	const op = "revocation.Run"
+
+	log := f.log.With(slog.String("op", op))
+
+	log.Info("starting revocation feed")
+
+	defer close(f.done)
+
+	poll := time.NewTicker(f.cfg.PollInterval)
+	defer poll.Stop()
+
+	cleanup := time.NewTicker(f.cfg.CleanupInterval)
+	defer cleanup.Stop()
+
+	f.cleanup()
+
+	notify := f.listener.Notify()
+
+	for {
+		select {
+		case _, ok := <-notify:
+			if !ok {
+				log.Error("revocation listener closed, falling back to polling")
+				notify = nil
+				continue
+			}
+			f.wake()
+		case <-poll.C:
+			f.wake()
+		case <-cleanup.C:
+			f.cleanup()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Stop ends every watch with ErrStopped. It must be called before the gRPC
+// server is stopped, which waits for open streams.
+func (f *Feed) Stop() {
+	const op = "revocation.Stop"
+
+	close(f.stop)
+	<-f.done
+
+	if err := f.listener.Close(); err != nil {
+		f.log.Error("failed to close revocation listener", slog.String("op", op), sl.Err(err))
+	}
+
+	f.log.Info("revocation feed stopped", slog.String("op", op))
+}
+
+// Watch authenticates the app and sends it the entries after cursor, then
+// new ones as they come, until ctx is done or the feed stops. A cursor of
+// zero starts from the oldest retained entry; a cursor below entries that
+// were already removed gets storage.ErrCursorExpired.
+func (f *Feed) Watch(ctx context.Context, appID int32, clientSecret string, cursor int64,
+	send func(entity.Revocation) error) error {
+	const op = "revocation.Watch"
+
+	app, err := f.clientAuth.AuthenticateClient(ctx, appID, clientSecret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := f.log.With(slog.String("op", op), slog.Int("app_id", int(app.ID)))
+
+	log.Info("watcher connected", slog.Int64("cursor", cursor))
+
+	wake := make(chan struct{}, 1)
+
+	f.mu.Lock()
+	f.watchers[wake] = struct{}{}
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.watchers, wake)
+		f.mu.Unlock()
+	}()
+
+	for {
+		revocations, err := f.provider.Revocations(cursor, f.cfg.BatchSize)
+		if errors.Is(err, storage.ErrCursorExpired) {
+			log.Info("watcher cursor expired", slog.Int64("cursor", cursor))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err != nil {
+			log.Error("failed to get revocations", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, revocation := range revocations {
+			if err := send(revocation); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			cursor = revocation.ID
+		}
+
+		if len(revocations) == f.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		case <-f.stop:
+			return fmt.Errorf("%s: %w", op, ErrStopped)
+		}
+	}
+}
+
+func (f *Feed) wake() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for wake := range f.watchers {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// cleanup removes entries older than the retention.
+func (f *Feed) cleanup() {
+	const op = "revocation.cleanup"
+
+	log := f.log.With(slog.String("op", op))
+
+	cutoff := time.Now().Add(-f.cfg.Retention)
+
+	for {
+		n, err := f.provider.DeleteRevocationsBefore(cutoff, f.cfg.BatchSize)
+		if err != nil {
+			log.Error("failed to delete old revocations", sl.Err(err))
+			return
+		}
+
+		if n < int64(f.cfg.BatchSize) {
+			return
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "client-secret"
+
+var errBadSecret = errors.New("invalid client secret")
+
+type testClientAuth struct{}
+
+func (testClientAuth) AuthenticateClient(_ context.Context, appID int32, clientSecret string) (entity.App, error) {
+	if clientSecret != testSecret {
+		return entity.App{}, errBadSecret
+	}
+
+	return entity.App{ID: appID}, nil
+}
+
+// testProvider keeps the feed the way the revocations table does.
+type testProvider struct {
+	mu          sync.Mutex
+	revocations []entity.Revocation
+	deleted     int64
+}
+
+func newTestProvider(n int) *testProvider {
+	p := &testProvider{}
+	for i := 0; i < n; i++ {
+		p.add()
+	}
+
+	return p
+}
+
+func (p *testProvider) add() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := int64(len(p.revocations) + 1)
+	p.revocations = append(p.revocations, entity.Revocation{ID: id, Kind: entity.RevokedSession, UserID: 42, SessionID: id})
+}
+
+func (p *testProvider) Revocations(afterID int64, limit int) ([]entity.Revocation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if afterID > 0 && afterID < p.deleted {
+		return nil, storage.ErrCursorExpired
+	}
+
+	var list []entity.Revocation
+	for _, revocation := range p.revocations[p.deleted:] {
+		if revocation.ID > afterID && len(list) < limit {
+			list = append(list, revocation)
+		}
+	}
+
+	return list, nil
+}
+
+func (p *testProvider) DeleteRevocationsBefore(_ time.Time, limit int) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := min(int64(len(p.revocations))-p.deleted, int64(limit))
+	p.deleted += n
+
+	return n, nil
+}
+
+type testListener struct{}
+
+func (testListener) Notify() <-chan struct{} { return nil }
+func (testListener) Close() error            { return nil }
+
+func newTestFeed(provider *testProvider) *Feed {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, testClientAuth{}, provider, testListener{}, config.RevocationsConfig{
+		Retention:       time.Hour,
+		PollInterval:    time.Minute,
+		CleanupInterval: time.Minute,
+		BatchSize:       2,
+	})
+}
+
+func TestWatch(t *testing.T) {
+	errSend := errors.New("stream closed")
+
+	tests := []struct {
+		name    string
+		entries int
+		// deleted entries were removed by cleanup.
+		deleted int
+		secret  string
+		cursor  int64
+		// stopped closes the feed before the watch.
+		stopped bool
+		sendErr error
+		wantIDs []int64
+		wantErr error
+	}{
+		{name: "from the start", entries: 5, secret: testSecret, wantIDs: []int64{1, 2, 3, 4, 5}, wantErr: context.Canceled},
+		{name: "resume after cursor", entries: 5, secret: testSecret, cursor: 3, wantIDs: []int64{4, 5}, wantErr: context.Canceled},
+		{name: "caught up", entries: 2, secret: testSecret, cursor: 2, wantErr: context.Canceled},
+		{name: "wrong secret", entries: 2, secret: "wrong", wantErr: errBadSecret},
+		{name: "send fails", entries: 2, secret: testSecret, sendErr: errSend, wantIDs: []int64{1}, wantErr: errSend},
+		{name: "resume after cleanup", entries: 5, deleted: 2, secret: testSecret, cursor: 2, wantIDs: []int64{3, 4, 5}, wantErr: context.Canceled},
+		{name: "cursor expired", entries: 5, deleted: 3, secret: testSecret, cursor: 2, wantErr: storage.ErrCursorExpired},
+		{name: "start after cleanup", entries: 5, deleted: 3, secret: testSecret, wantIDs: []int64{4, 5}, wantErr: context.Canceled},
+		{name: "feed stops", entries: 1, secret: testSecret, stopped: true, wantIDs: []int64{1}, wantErr: ErrStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(tt.entries)
+			provider.deleted = int64(tt.deleted)
+			f := newTestFeed(provider)
+
+			// Watch returns after sending what is there: the context is
+			// already done, or the feed is stopped.
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.stopped {
+				close(f.stop)
+			} else {
+				cancel()
+			}
+			defer cancel()
+
+			var got []int64
+			err := f.Watch(ctx, 1, tt.secret, tt.cursor, func(revocation entity.Revocation) error {
+				got = append(got, revocation.ID)
+				return tt.sendErr
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Watch() = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("sent %v, want %v", got, tt.wantIDs)
+			}
+
+			if len(f.watchers) != 0 {
+				t.Errorf("%d watchers left registered", len(f.watchers))
+			}
+		})
+	}
+}
+
+func TestWatchWakesOnNewRevocation(t *testing.T) {
+	tests := []struct {
+		name  string
+		added int
+	}{
+		{name: "one new entry", added: 1},
+		{name: "more than a batch", added: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(1)
+			f := newTestFeed(provider)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sent := make(chan int64, 16)
+			done := make(chan error, 1)
+			go func() {
+				done <- f.Watch(ctx, 1, testSecret, 0, func(revocation entity.Revocation) error {
+					sent <- revocation.ID
+					return nil
+				})
+			}()
+
+			if id := receive(t, sent); id != 1 {
+				t.Fatalf("first entry = %d, want 1", id)
+			}
+
+			for i := 0; i < tt.added; i++ {
+				provider.add()
+			}
+			f.wake()
+
+			for want := int64(2); want <= int64(tt.added+1); want++ {
+				if id := receive(t, sent); id != want {
+					t.Fatalf("entry = %d, want %d", id, want)
+				}
+			}
+
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Errorf("Watch() = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     int
+		wantDeleted int64
+	}{
+		{name: "nothing to delete"},
+		{name: "less than a batch", entries: 1, wantDeleted: 1},
+		{name: "several batches", entries: 5, wantDeleted: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(tt.entries)
+
+			newTestFeed(provider).cleanup()
+
+			if provider.deleted != tt.wantDeleted {
+				t.Errorf("deleted %d, want %d", provider.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func receive(t *testing.T, sent <-chan int64) int64 {
+	t.Helper()
+
+	select {
+	case id := <-sent:
+		return id
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry was sent")
+		return 0
+	}
+}
@@ -0,0 +1,98 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"log/slog"
+	"strconv"
+)
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+type Security struct {
+	log             *slog.Logger
+	authorizer      Authorizer
+	eventProvider   EventProvider
+	defaultPageSize int
+	maxPageSize     int
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, accessToken string) (uid int64, err error)
+}
+
+type EventProvider interface {
+	SecurityEvents(uid, beforeID int64, limit int) ([]entity.SecurityEvent, error)
+}
+
+func New(log *slog.Logger,
+	authorizer Authorizer,
+	eventProvider EventProvider,
+	defaultPageSize int,
+	maxPageSize int) *Security {
+	return &Security{
+		log:             log,
+		authorizer:      authorizer,
+		eventProvider:   eventProvider,
+		defaultPageSize: defaultPageSize,
+		maxPageSize:     maxPageSize,
+	}
+}
+
+// ListMyEvents returns a page of the security events of the token's user,
+// newest first. An empty nextPageToken means there are no more pages.
+func (s *Security) ListMyEvents(ctx context.Context, accessToken string, pageSize int, pageToken string,
+) (events []entity.SecurityEvent, nextPageToken string, err error) {
+	const op = "security.ListMyEvents"
+
+	uid, err := s.authorizer.Authorize(ctx, accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, nextPageToken, err = s.ListUserEvents(ctx, uid, pageSize, pageToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nextPageToken, nil
+}
+
+// ListUserEvents is ListMyEvents for any user, for the admin API.
+func (s *Security) ListUserEvents(ctx context.Context, uid int64, pageSize int, pageToken string,
+) (events []entity.SecurityEvent, nextPageToken string, err error) {
+	const op = "security.ListUserEvents"
+
+	var beforeID int64
+	if pageToken != "" {
+		beforeID, err = strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	if pageSize <= 0 {
+		pageSize = s.defaultPageSize
+	}
+	if pageSize > s.maxPageSize {
+		pageSize = s.maxPageSize
+	}
+
+	events, err = s.eventProvider.SecurityEvents(uid, beforeID, pageSize+1)
+	if err != nil {
+		s.log.Error("failed to get security events", slog.String("op", op), sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		nextPageToken = strconv.FormatInt(events[pageSize-1].ID, 10)
+	}
+
+	return events, nextPageToken, nil
+}
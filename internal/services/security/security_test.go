@@ -0,0 +1,95 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+const testToken = "token"
+
+type testAuthorizer struct{}
+
+func (testAuthorizer) Authorize(_ context.Context, accessToken string) (int64, error) {
+	if accessToken != testToken {
+		return 0, errors.New("invalid token")
+	}
+
+	return 1, nil
+}
+
+// testEvents has events with ids 1..n of user 1 and returns them newest
+// first, like the postgres storage.
+type testEvents struct{ n int64 }
+
+func (e testEvents) SecurityEvents(uid, beforeID int64, limit int) ([]entity.SecurityEvent, error) {
+	if beforeID == 0 {
+		beforeID = e.n + 1
+	}
+
+	var events []entity.SecurityEvent
+	for id := beforeID - 1; id >= 1 && len(events) < limit; id-- {
+		events = append(events, entity.SecurityEvent{ID: id, UserID: uid})
+	}
+
+	return events, nil
+}
+
+func TestListMyEvents(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		events    int64
+		pageSize  int
+		pageToken string
+		wantIDs   []int64
+		wantNext  string
+		wantErr   error
+	}{
+		{name: "default page size", events: 5, wantIDs: []int64{5, 4, 3}, wantNext: "3"},
+		{name: "capped page size", events: 12, pageSize: 100, wantIDs: []int64{12, 11, 10, 9, 8, 7, 6, 5, 4, 3}, wantNext: "3"},
+		{name: "next page", events: 5, pageSize: 2, pageToken: "3", wantIDs: []int64{2, 1}},
+		{name: "last page exactly full", events: 3, wantIDs: []int64{3, 2, 1}},
+		{name: "no events", events: 0},
+		{name: "bad page token", events: 5, pageToken: "abc", wantErr: ErrInvalidPageToken},
+		{name: "zero page token", events: 5, pageToken: "0", wantErr: ErrInvalidPageToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			s := New(log, testAuthorizer{}, testEvents{n: tt.events}, 3, 10)
+
+			events, next, err := s.ListMyEvents(ctx, testToken, tt.pageSize, tt.pageToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListMyEvents() = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(events) != len(tt.wantIDs) {
+				t.Fatalf("events = %d, want %d", len(events), len(tt.wantIDs))
+			}
+			for i, event := range events {
+				if event.ID != tt.wantIDs[i] {
+					t.Errorf("events[%d].ID = %d, want %d", i, event.ID, tt.wantIDs[i])
+				}
+			}
+
+			if next != tt.wantNext {
+				t.Errorf("next page token = %q, want %q", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestListMyEventsInvalidToken(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := New(log, testAuthorizer{}, testEvents{n: 5}, 3, 10)
+
+	if _, _, err := s.ListMyEvents(context.Background(), "stolen", 0, ""); err == nil {
+		t.Fatal("ListMyEvents() with an invalid token succeeded")
+	}
+}
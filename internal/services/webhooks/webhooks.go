@@ -0,0 +1,356 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/logger/sl"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/publisher"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid webhook url")
+	ErrInvalidEvent     = errors.New("unknown event type")
+	ErrTooManyWebhooks  = errors.New("too many webhooks")
+	ErrInvalidStatus    = errors.New("invalid delivery status")
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+const (
+	actionCreateWebhook  = "app.webhook_create"
+	actionDeleteWebhook  = "app.webhook_delete"
+	actionReplayDelivery = "app.webhook_replay"
+
+	secretBytes = 32
+)
+
+// personalFields are payload fields never sent to apps.
+var personalFields = []string{"phone", "email"}
+
+// eventTypes are the events a webhook can subscribe to.
+var eventTypes = []string{entity.UserRegistered, entity.UserDeleted, entity.SessionRevoked, entity.PasswordChanged}
+
+type Webhooks struct {
+	log             *slog.Logger
+	appProvider     AppProvider
+	webhookStorage  WebhookStorage
+	deliveryStorage DeliveryStorage
+	auditSaver      AuditSaver
+	cfg             config.WebhooksConfig
+}
+
+type AppProvider interface {
+	App(appID int32) (entity.App, error)
+}
+
+type WebhookStorage interface {
+	SaveWebhook(webhook entity.Webhook) (entity.Webhook, error)
+	Webhook(webhookID int64) (entity.Webhook, error)
+	Webhooks(appID int32) ([]entity.Webhook, error)
+	DeleteWebhook(appID int32, webhookID int64) error
+}
+
+type DeliveryStorage interface {
+	EnqueueWebhookDeliveries(eventID, uid int64, eventType string, body []byte) (int64, error)
+	WebhookDeliveries(webhookID int64, status string, beforeID int64, limit int) ([]entity.WebhookDelivery, error)
+	ReplayWebhookDelivery(deliveryID int64) error
+	ReplayDeadWebhookDeliveries(webhookID int64) (int64, error)
+}
+
+type AuditSaver interface {
+	SaveAuditRecord(record entity.AuditRecord) error
+}
+
+func New(log *slog.Logger,
+	appProvider AppProvider,
+	webhookStorage WebhookStorage,
+	deliveryStorage DeliveryStorage,
+	auditSaver AuditSaver,
+	cfg config.WebhooksConfig) *Webhooks {
+	return &Webhooks{
+		log:             log,
+		appProvider:     appProvider,
+		webhookStorage:  webhookStorage,
+		deliveryStorage: deliveryStorage,
+		auditSaver:      auditSaver,
+		cfg:             cfg,
+	}
+}
+
+// CreateWebhook registers an endpoint of the app for the events, or for
+// every event when events is empty. The returned webhook carries the
+// signing secret; the admin API shows it only once.
+func (w *Webhooks) CreateWebhook(ctx context.Context, actor string, appID int32, rawURL string, events []string,
+) (entity.Webhook, error) {
+	const op = "webhooks.CreateWebhook"
+
+	log := w.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int("app_id", int(appID)))
+
+	webhook := entity.Webhook{
+		AppID:  appID,
+		URL:    strings.TrimSpace(rawURL),
+		Events: []string{},
+	}
+
+	if err := w.checkURL(webhook.URL); err != nil {
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, event := range events {
+		if !slices.Contains(eventTypes, event) {
+			return entity.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidEvent)
+		}
+		if !slices.Contains(webhook.Events, event) {
+			webhook.Events = append(webhook.Events, event)
+		}
+	}
+
+	existing, err := w.Webhooks(ctx, appID)
+	if err != nil {
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(existing) >= w.cfg.MaxPerApp {
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, ErrTooManyWebhooks)
+	}
+
+	webhook.Secret, err = otp.Token(secretBytes)
+	if err != nil {
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook, err = w.webhookStorage.SaveWebhook(webhook)
+	if err != nil {
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("webhook_id", webhook.ID))
+
+	details := map[string]string{"url": webhook.URL, "events": strings.Join(webhook.Events, ",")}
+	if err := w.audit(actor, actionCreateWebhook, webhook, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return entity.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook created")
+
+	return webhook, nil
+}
+
+func (w *Webhooks) Webhooks(ctx context.Context, appID int32) ([]entity.Webhook, error) {
+	const op = "webhooks.Webhooks"
+
+	if _, err := w.appProvider.App(appID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks, err := w.webhookStorage.Webhooks(appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook. Its pending and dead deliveries are
+// dropped with it.
+func (w *Webhooks) DeleteWebhook(ctx context.Context, actor string, appID int32, webhookID int64) error {
+	const op = "webhooks.DeleteWebhook"
+
+	log := w.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int64("webhook_id", webhookID))
+
+	if err := w.webhookStorage.DeleteWebhook(appID, webhookID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook := entity.Webhook{ID: webhookID, AppID: appID}
+	if err := w.audit(actor, actionDeleteWebhook, webhook, nil); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook deleted")
+
+	return nil
+}
+
+// Deliveries returns a page of deliveries of the webhook, newest first.
+// The dead-letter list is the deliveries with status dead.
+func (w *Webhooks) Deliveries(ctx context.Context, webhookID int64, status string, pageSize int, pageToken string,
+) (deliveries []entity.WebhookDelivery, nextPageToken string, err error) {
+	const op = "webhooks.Deliveries"
+
+	if status != "" && status != entity.DeliveryPending && status != entity.DeliveryDelivered &&
+		status != entity.DeliveryDead {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+
+	var beforeID int64
+	if pageToken != "" {
+		beforeID, err = strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	if pageSize <= 0 {
+		pageSize = w.cfg.DefaultPageSize
+	}
+	if pageSize > w.cfg.MaxPageSize {
+		pageSize = w.cfg.MaxPageSize
+	}
+
+	if _, err := w.webhookStorage.Webhook(webhookID); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err = w.deliveryStorage.WebhookDeliveries(webhookID, status, beforeID, pageSize+1)
+	if err != nil {
+		w.log.Error("failed to get webhook deliveries", slog.String("op", op), sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(deliveries) > pageSize {
+		deliveries = deliveries[:pageSize]
+		nextPageToken = strconv.FormatInt(deliveries[pageSize-1].ID, 10)
+	}
+
+	return deliveries, nextPageToken, nil
+}
+
+// ReplayDelivery sends a dead or already delivered delivery again.
+func (w *Webhooks) ReplayDelivery(ctx context.Context, actor string, deliveryID int64) error {
+	const op = "webhooks.ReplayDelivery"
+
+	log := w.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int64("delivery_id", deliveryID))
+
+	if err := w.deliveryStorage.ReplayWebhookDelivery(deliveryID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"delivery_id": strconv.FormatInt(deliveryID, 10)}
+	if err := w.audit(actor, actionReplayDelivery, entity.Webhook{}, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook delivery replayed")
+
+	return nil
+}
+
+// ReplayDeadDeliveries sends every dead delivery of the webhook again and
+// returns how many there were.
+func (w *Webhooks) ReplayDeadDeliveries(ctx context.Context, actor string, webhookID int64) (int64, error) {
+	const op = "webhooks.ReplayDeadDeliveries"
+
+	log := w.log.With(slog.String("op", op), slog.String("actor", actor), slog.Int64("webhook_id", webhookID))
+
+	webhook, err := w.webhookStorage.Webhook(webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := w.deliveryStorage.ReplayDeadWebhookDeliveries(webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]string{"replayed": strconv.FormatInt(n, 10)}
+	if err := w.audit(actor, actionReplayDelivery, webhook, details); err != nil {
+		log.Error("failed to save audit record", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("dead webhook deliveries replayed", slog.Int64("count", n))
+
+	return n, nil
+}
+
+// Publish queues the event for the webhooks subscribed to it. Only apps
+// the user has signed in to get the event: other apps have no business
+// knowing the account exists. It lets the outbox relay feed webhooks like
+// any other publisher.
+func (w *Webhooks) Publish(ctx context.Context, event entity.DomainEvent) error {
+	const op = "webhooks.Publish"
+
+	payload, err := withoutPersonalFields(event.Payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	event.Payload = payload
+
+	body, err := json.Marshal(publisher.NewMessage(event))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := w.deliveryStorage.EnqueueWebhookDeliveries(event.ID, event.UserID, event.Type, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// withoutPersonalFields removes personalFields from the JSON payload.
+func withoutPersonalFields(payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, name := range personalFields {
+		delete(fields, name)
+	}
+
+	return json.Marshal(fields)
+}
+
+func (w *Webhooks) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.User != nil || u.Fragment != "" {
+		return ErrInvalidURL
+	}
+
+	if u.Scheme != "https" && (u.Scheme != "http" || !w.cfg.AllowHTTP) {
+		return ErrInvalidURL
+	}
+
+	return nil
+}
+
+func (w *Webhooks) audit(actor, action string, webhook entity.Webhook, details map[string]string) error {
+	if details == nil {
+		details = map[string]string{}
+	}
+	if webhook.AppID != 0 {
+		details["app_id"] = strconv.Itoa(int(webhook.AppID))
+	}
+	if webhook.ID != 0 {
+		details["webhook_id"] = strconv.FormatInt(webhook.ID, 10)
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return w.auditSaver.SaveAuditRecord(entity.AuditRecord{
+		Actor:   actor,
+		Action:  action,
+		Details: data,
+	})
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+// SetAccountState changes the account status if it is still from. Locking
+// or suspending the account also deactivates its refresh tokens. A non-nil
+// audit record is saved with the change.
+func (s *Storage) SetAccountState(uid int64, from entity.AccountStatus, state entity.AccountState,
+	audit *entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok || user.State.Status != from {
+		return storage.ErrVersionConflict
+	}
+
+	state.ChangedAt = time.Now()
+	user.State = state
+	s.users[uid] = user
+
+	if state.Status == entity.AccountLocked || state.Status == entity.AccountSuspended {
+		s.revokeUserSessions(uid)
+	}
+	s.saveOptionalAuditRecord(audit)
+
+	return nil
+}
+
+// MarkUserDeleted starts the grace period of the account and deactivates
+// its refresh tokens. A non-nil audit record is saved with the change.
+func (s *Storage) MarkUserDeleted(uid int64, changedBy, reason string, purgeAfter time.Time,
+	audit *entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok || user.State.Status == entity.AccountDeleted {
+		return storage.ErrUserNotFound
+	}
+
+	user.State = entity.AccountState{
+		Status:    entity.AccountDeleted,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+	user.PurgeAfter = purgeAfter
+	s.users[uid] = user
+
+	s.revokeUserSessions(uid)
+	s.saveOptionalAuditRecord(audit)
+
+	return nil
+}
+
+// RestoreUser cancels deletion of the account. A non-nil audit record is
+// saved with the change.
+func (s *Storage) RestoreUser(uid int64, changedBy, reason string, audit *entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok || user.State.Status != entity.AccountDeleted {
+		return storage.ErrUserNotFound
+	}
+
+	user.State = entity.AccountState{
+		Status:    entity.AccountActive,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+	user.PurgeAfter = time.Time{}
+	s.users[uid] = user
+
+	s.saveOptionalAuditRecord(audit)
+
+	return nil
+}
+
+func (s *Storage) SaveDeletionCode(uid int64, codeHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	code := entity.DeletionCode{UserID: uid, CodeHash: codeHash, ExpiresAt: expiresAt}
+	if old, ok := s.deletionCodes[uid]; ok {
+		code.Attempts = old.Attempts
+	}
+	s.deletionCodes[uid] = code
+
+	return nil
+}
+
+func (s *Storage) DeletionCode(uid int64) (entity.DeletionCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.DeletionCode{}, s.failure
+	}
+
+	code, ok := s.deletionCodes[uid]
+	if !ok {
+		return entity.DeletionCode{}, storage.ErrVerificationNotFound
+	}
+
+	return code, nil
+}
+
+func (s *Storage) IncDeletionCodeAttempts(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if code, ok := s.deletionCodes[uid]; ok {
+		code.Attempts++
+		s.deletionCodes[uid] = code
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteDeletionCode(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	delete(s.deletionCodes, uid)
+
+	return nil
+}
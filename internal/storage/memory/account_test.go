@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+)
+
+func TestSetAccountState(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          entity.AccountStatus
+		to            entity.AccountStatus
+		wantErr       error
+		wantStatus    entity.AccountStatus
+		wantTokenLive bool
+	}{
+		{
+			name:       "lock",
+			from:       entity.AccountActive,
+			to:         entity.AccountLocked,
+			wantStatus: entity.AccountLocked,
+		},
+		{
+			name:       "suspend",
+			from:       entity.AccountActive,
+			to:         entity.AccountSuspended,
+			wantStatus: entity.AccountSuspended,
+		},
+		{
+			name:          "status changed meanwhile",
+			from:          entity.AccountLocked,
+			to:            entity.AccountSuspended,
+			wantErr:       storage.ErrVersionConflict,
+			wantStatus:    entity.AccountActive,
+			wantTokenLive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+
+			uid, err := s.SaveUser("+79991234567", []byte("hash"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			session, err := s.SaveSession(entity.Session{UserID: uid})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveRefreshToken("token", uid, session.ID, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.SetAccountState(uid, tt.from, entity.AccountState{Status: tt.to}, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			user, err := s.UserByID(uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.State.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", user.State.Status, tt.wantStatus)
+			}
+
+			token, err := s.RefreshToken("token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.IsActive != tt.wantTokenLive {
+				t.Errorf("refresh token active = %v, want %v", token.IsActive, tt.wantTokenLive)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"slices"
+	"time"
+)
+
+func (s *Storage) CodeSends(purpose, target string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	sends := 0
+	for _, send := range s.codeSends {
+		if send.purpose == purpose && send.target == target && !send.sentAt.Before(since) {
+			sends++
+		}
+	}
+
+	return sends, nil
+}
+
+func (s *Storage) SaveCodeSend(purpose, target string, sentAt, expired time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.codeSends = slices.DeleteFunc(s.codeSends, func(send codeSend) bool {
+		return send.purpose == purpose && send.target == target && send.sentAt.Before(expired)
+	})
+	s.codeSends = append(s.codeSends, codeSend{purpose: purpose, target: target, sentAt: sentAt})
+
+	return nil
+}
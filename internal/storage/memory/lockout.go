@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"slices"
+	"time"
+)
+
+func (s *Storage) TakeLoginAttempt(key string, at, since time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	s.loginFailures = slices.DeleteFunc(s.loginFailures, func(f loginFailure) bool {
+		return f.key == key && f.failedAt.Before(since)
+	})
+
+	attempts := 0
+	for _, f := range s.loginFailures {
+		if f.key == key {
+			attempts++
+		}
+	}
+
+	if attempts >= limit {
+		return 0, nil
+	}
+
+	s.loginFailures = append(s.loginFailures, loginFailure{key: key, failedAt: at})
+
+	return attempts + 1, nil
+}
+
+func (s *Storage) DeleteLoginFailures(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.loginFailures = slices.DeleteFunc(s.loginFailures, func(f loginFailure) bool {
+		return f.key == key
+	})
+
+	return nil
+}
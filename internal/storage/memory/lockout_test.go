@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeLoginAttempt(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		earlier  []time.Time
+		deleted  bool
+		wantTake int
+	}{
+		{name: "first attempt", wantTake: 1},
+		{name: "below the limit", earlier: []time.Time{now.Add(-time.Minute)}, wantTake: 2},
+		{name: "at the limit", earlier: []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute)}},
+		{
+			name:     "old attempts left the window",
+			earlier:  []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour)},
+			wantTake: 1,
+		},
+		{
+			name:     "attempts forgotten",
+			earlier:  []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute)},
+			deleted:  true,
+			wantTake: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+
+			for _, at := range tt.earlier {
+				if _, err := s.TakeLoginAttempt("user:1", at, at.Add(-time.Hour), 2); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Attempts of other keys don't count.
+			if _, err := s.TakeLoginAttempt("user:2", now, since, 2); err != nil {
+				t.Fatal(err)
+			}
+			if tt.deleted {
+				if err := s.DeleteLoginFailures("user:1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.TakeLoginAttempt("user:1", now, since, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantTake {
+				t.Errorf("TakeLoginAttempt() = %v, want %v", got, tt.wantTake)
+			}
+		})
+	}
+}
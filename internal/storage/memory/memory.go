@@ -0,0 +1,417 @@
+// Package memory is a storage kept in process memory. It implements what
+// the auth service needs and backs the fake SSO of pkg/ssotest; it keeps
+// no outbox, revocation feed or audit chain.
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Storage struct {
+	mu sync.Mutex
+	// failure, when set, is returned by every call.
+	failure error
+	// lastID numbers every kind of row, like one shared sequence.
+	lastID int64
+
+	users           map[int64]entity.User
+	emails          map[int64]string
+	passwordHistory map[int64][][]byte
+	phoneHistory    []phoneRelease
+	apps            map[int32]entity.App
+	userAppRoles    []userAppRole
+	sessions        map[int64]entity.Session
+	refreshTokens   map[string]refreshToken
+	registrations   map[string]entity.PendingRegistration
+	phoneChanges    map[int64]entity.PhoneChange
+	resets          map[string]entity.PasswordReset
+	deletionCodes   map[int64]entity.DeletionCode
+	codeSends       []codeSend
+	loginFailures   []loginFailure
+	securityEvents  []entity.SecurityEvent
+	auditRecords    []entity.AuditRecord
+}
+
+type phoneRelease struct {
+	userID     int64
+	phone      string
+	releasedAt time.Time
+}
+
+type userAppRole struct {
+	userID int64
+	appID  int32
+	role   string
+}
+
+type codeSend struct {
+	purpose string
+	target  string
+	sentAt  time.Time
+}
+
+type loginFailure struct {
+	key      string
+	failedAt time.Time
+}
+
+type refreshToken struct {
+	userID    int64
+	sessionID int64
+	isActive  bool
+}
+
+func New() *Storage {
+	return &Storage{
+		users:           make(map[int64]entity.User),
+		emails:          make(map[int64]string),
+		passwordHistory: make(map[int64][][]byte),
+		apps:            make(map[int32]entity.App),
+		sessions:        make(map[int64]entity.Session),
+		refreshTokens:   make(map[string]refreshToken),
+		registrations:   make(map[string]entity.PendingRegistration),
+		phoneChanges:    make(map[int64]entity.PhoneChange),
+		resets:          make(map[string]entity.PasswordReset),
+		deletionCodes:   make(map[int64]entity.DeletionCode),
+	}
+}
+
+// Fail makes every call return err until Fail(nil), to see how callers
+// handle a storage outage.
+func (s *Storage) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failure = err
+}
+
+func (s *Storage) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *Storage) SaveUser(phone string, passHash []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	for _, user := range s.users {
+		if user.Phone == phone {
+			return 0, storage.ErrUserExists
+		}
+	}
+
+	user := entity.User{
+		ID:        s.nextID(),
+		Phone:     phone,
+		PassHash:  passHash,
+		CreatedAt: time.Now(),
+		State:     entity.AccountState{Status: entity.AccountActive},
+	}
+	s.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (s *Storage) ProvideUser(phone string) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.User{}, s.failure
+	}
+
+	for _, user := range s.users {
+		if user.Phone == phone {
+			return user, nil
+		}
+	}
+
+	return entity.User{}, storage.ErrUserNotFound
+}
+
+func (s *Storage) UserByID(uid int64) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.User{}, s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok {
+		return entity.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// SetVerifiedEmail gives the user a verified email, which lets them sign
+// in with it.
+func (s *Storage) SetVerifiedEmail(uid int64, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if _, ok := s.users[uid]; !ok {
+		return storage.ErrUserNotFound
+	}
+	s.emails[uid] = email
+
+	return nil
+}
+
+func (s *Storage) ProvideUserByEmail(email string) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.User{}, s.failure
+	}
+
+	for uid, e := range s.emails {
+		if strings.EqualFold(e, email) {
+			return s.users[uid], nil
+		}
+	}
+
+	return entity.User{}, storage.ErrUserNotFound
+}
+
+func (s *Storage) SaveApp(app entity.App) (entity.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.App{}, s.failure
+	}
+
+	for _, existing := range s.apps {
+		if existing.Name == app.Name {
+			return entity.App{}, storage.ErrAppExists
+		}
+	}
+
+	app.ID = int32(s.nextID())
+	app.CreatedAt = time.Now()
+	app.UpdatedAt = app.CreatedAt
+	app.GrantTypes = slices.Clone(app.GrantTypes)
+	app.Scopes = slices.Clone(app.Scopes)
+	s.apps[app.ID] = app
+
+	return app, nil
+}
+
+// DisableApp turns the app off or on again.
+func (s *Storage) DisableApp(appID int32, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return storage.ErrAppNotFound
+	}
+	app.Disabled = disabled
+	s.apps[appID] = app
+
+	return nil
+}
+
+func (s *Storage) App(appID int32) (entity.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.App{}, s.failure
+	}
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return entity.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (s *Storage) SetAppScopes(appID int32, scopes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return storage.ErrAppNotFound
+	}
+	app.Scopes = slices.Clone(scopes)
+	s.apps[appID] = app
+
+	return nil
+}
+
+// UserAppRoles returns the roles of the user in the app, sorted.
+func (s *Storage) UserAppRoles(uid int64, appID int32) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	var roles []string
+	for _, granted := range s.userAppRoles {
+		if granted.userID == uid && granted.appID == appID {
+			roles = append(roles, granted.role)
+		}
+	}
+	slices.Sort(roles)
+
+	return roles, nil
+}
+
+func (s *Storage) GrantUserAppRole(uid int64, appID int32, role, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if _, ok := s.apps[appID]; !ok {
+		return storage.ErrAppNotFound
+	}
+	if _, ok := s.users[uid]; !ok {
+		return storage.ErrUserNotFound
+	}
+
+	granted := userAppRole{userID: uid, appID: appID, role: role}
+	if !slices.Contains(s.userAppRoles, granted) {
+		s.userAppRoles = append(s.userAppRoles, granted)
+	}
+
+	return nil
+}
+
+// SaveRefreshToken stores a new refresh token of the session, deactivates
+// the previous ones and marks the session as seen. A non-empty previous
+// token must still be active, so only one refresh can exchange it.
+func (s *Storage) SaveRefreshToken(token string, uid, sessionID int64, previous string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if previous != "" {
+		stored, ok := s.refreshTokens[previous]
+		if !ok || stored.sessionID != sessionID || !stored.isActive {
+			return storage.ErrRefreshTokenUsed
+		}
+	}
+
+	for t, stored := range s.refreshTokens {
+		if stored.sessionID == sessionID {
+			stored.isActive = false
+			s.refreshTokens[t] = stored
+		}
+	}
+
+	s.refreshTokens[token] = refreshToken{userID: uid, sessionID: sessionID, isActive: true}
+
+	if session, ok := s.sessions[sessionID]; ok {
+		session.LastSeenAt = time.Now()
+		s.sessions[sessionID] = session
+	}
+
+	return nil
+}
+
+// RefreshToken returns a stored refresh token, active or not. A token of a
+// revoked session is not active.
+func (s *Storage) RefreshToken(token string) (entity.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.RefreshToken{}, s.failure
+	}
+
+	stored, ok := s.refreshTokens[token]
+	if !ok {
+		return entity.RefreshToken{}, storage.ErrInvalidRefreshToken
+	}
+
+	session, ok := s.sessions[stored.sessionID]
+	if !ok {
+		return entity.RefreshToken{}, storage.ErrInvalidRefreshToken
+	}
+
+	return entity.RefreshToken{
+		UserID:         stored.userID,
+		SessionID:      stored.sessionID,
+		IsActive:       stored.isActive && session.IsActive,
+		SessionRevoked: !session.IsActive,
+		StartedAt:      session.CreatedAt,
+		LastUsedAt:     session.LastSeenAt,
+	}, nil
+}
+
+// revokeUserSessions signs the user out everywhere. The caller holds the
+// mutex.
+func (s *Storage) revokeUserSessions(uid int64) {
+	now := time.Now()
+
+	for id, session := range s.sessions {
+		if session.UserID == uid && session.IsActive {
+			session.IsActive = false
+			session.RevokedAt = now
+			s.sessions[id] = session
+		}
+	}
+
+	for t, stored := range s.refreshTokens {
+		if stored.userID == uid {
+			stored.isActive = false
+			s.refreshTokens[t] = stored
+		}
+	}
+}
+
+// revokeOtherSessions signs the user out of every session but keepID. The
+// caller holds the mutex.
+func (s *Storage) revokeOtherSessions(uid, keepID int64) {
+	now := time.Now()
+
+	for id, session := range s.sessions {
+		if session.UserID == uid && id != keepID && session.IsActive {
+			session.IsActive = false
+			session.RevokedAt = now
+			s.sessions[id] = session
+		}
+	}
+
+	for t, stored := range s.refreshTokens {
+		if stored.userID == uid && stored.sessionID != keepID {
+			stored.isActive = false
+			s.refreshTokens[t] = stored
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestSaveUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		phone    string
+		wantErr  error
+	}{
+		{name: "new phone", phone: "+79991234567"},
+		{name: "other phone taken", existing: []string{"+79990000000"}, phone: "+79991234567"},
+		{name: "phone taken", existing: []string{"+79991234567"}, phone: "+79991234567", wantErr: storage.ErrUserExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, phone := range tt.existing {
+				if _, err := s.SaveUser(phone, []byte("hash")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			uid, err := s.SaveUser(tt.phone, []byte("hash"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			user, err := s.ProvideUser(tt.phone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != uid || user.State.Status != entity.AccountActive {
+				t.Errorf("user = %+v, want id %d and an active account", user, uid)
+			}
+		})
+	}
+}
+
+func TestProvideUserByEmail(t *testing.T) {
+	s := New()
+
+	uid, err := s.SaveUser("+79991234567", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetVerifiedEmail(uid, "User@Example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{name: "same case", email: "User@Example.com"},
+		{name: "other case", email: "user@example.com"},
+		{name: "unknown", email: "other@example.com", wantErr: storage.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.ProvideUserByEmail(tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.ID != uid {
+				t.Errorf("uid = %d, want %d", user.ID, uid)
+			}
+		})
+	}
+}
+
+func TestApp(t *testing.T) {
+	tests := []struct {
+		name         string
+		disable      bool
+		appID        func(app entity.App) int32
+		wantErr      error
+		wantDisabled bool
+	}{
+		{name: "saved", appID: func(app entity.App) int32 { return app.ID }},
+		{name: "disabled", disable: true, appID: func(app entity.App) int32 { return app.ID }, wantDisabled: true},
+		{name: "unknown", appID: func(app entity.App) int32 { return app.ID + 100 }, wantErr: storage.ErrAppNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+
+			app, err := s.SaveApp(entity.App{Name: "shop", GrantTypes: []string{entity.GrantPassword}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.disable {
+				if err := s.DisableApp(app.ID, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.App(tt.appID(app))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Name != "shop" || got.Disabled != tt.wantDisabled) {
+				t.Errorf("app = %+v, want shop with disabled %v", got, tt.wantDisabled)
+			}
+		})
+	}
+}
+
+func TestSaveAppNameTaken(t *testing.T) {
+	s := New()
+
+	if _, err := s.SaveApp(entity.App{Name: "shop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SaveApp(entity.App{Name: "shop"}); !errors.Is(err, storage.ErrAppExists) {
+		t.Errorf("err = %v, want %v", err, storage.ErrAppExists)
+	}
+}
+
+func TestFail(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	s := New()
+	uid, err := s.SaveUser("+79991234567", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "SaveUser", call: func() error { _, err := s.SaveUser("+79990000000", nil); return err }},
+		{name: "UserByID", call: func() error { _, err := s.UserByID(uid); return err }},
+		{name: "App", call: func() error { _, err := s.App(1); return err }},
+		{name: "SaveSession", call: func() error { _, err := s.SaveSession(entity.Session{UserID: uid}); return err }},
+		{name: "TakeLoginAttempt", call: func() error { _, err := s.TakeLoginAttempt("key", time.Now(), time.Now(), 1); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Fail(failure)
+			if err := tt.call(); !errors.Is(err, failure) {
+				t.Errorf("err = %v, want %v", err, failure)
+			}
+
+			s.Fail(nil)
+			if _, err := s.UserByID(uid); err != nil {
+				t.Errorf("after Fail(nil): %v", err)
+			}
+		})
+	}
+}
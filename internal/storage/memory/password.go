@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"slices"
+	"time"
+)
+
+// UpdatePassword moves the current hash to the password history, stores
+// the new one and deactivates the user's refresh tokens.
+func (s *Storage) UpdatePassword(uid int64, passHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	s.passwordHistory[uid] = append(s.passwordHistory[uid], user.PassHash)
+
+	user.PassHash = passHash
+	user.PasswordResetRequired = false
+	s.users[uid] = user
+
+	s.revokeUserSessions(uid)
+
+	return nil
+}
+
+// PasswordHistory returns up to limit previous hashes, newest first.
+func (s *Storage) PasswordHistory(uid int64, limit int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	hashes := slices.Clone(s.passwordHistory[uid])
+	slices.Reverse(hashes)
+
+	return hashes[:min(limit, len(hashes))], nil
+}
+
+func (s *Storage) SavePasswordReset(tokenHash string, uid int64, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.resets[tokenHash] = entity.PasswordReset{UserID: uid, ExpiresAt: expiresAt}
+
+	return nil
+}
+
+func (s *Storage) PasswordReset(tokenHash string) (entity.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.PasswordReset{}, s.failure
+	}
+
+	reset, ok := s.resets[tokenHash]
+	if !ok {
+		return entity.PasswordReset{}, storage.ErrResetNotFound
+	}
+
+	return reset, nil
+}
+
+// ResetPassword spends the reset token and sets the new password of its
+// user the way UpdatePassword does. It returns ErrResetNotFound if the
+// token was already used, so a token works only once.
+func (s *Storage) ResetPassword(tokenHash string, passHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	reset, ok := s.resets[tokenHash]
+	if !ok || reset.Used {
+		return storage.ErrResetNotFound
+	}
+
+	user, ok := s.users[reset.UserID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	reset.Used = true
+	s.resets[tokenHash] = reset
+
+	s.passwordHistory[user.ID] = append(s.passwordHistory[user.ID], user.PassHash)
+
+	user.PassHash = passHash
+	user.PasswordResetRequired = false
+	s.users[user.ID] = user
+
+	s.revokeUserSessions(user.ID)
+
+	return nil
+}
+
+// RequirePasswordReset blocks sign-in with the current password,
+// deactivates the user's refresh tokens and saves the audit record.
+func (s *Storage) RequirePasswordReset(uid int64, audit entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	user.PasswordResetRequired = true
+	s.users[uid] = user
+
+	s.revokeUserSessions(uid)
+	s.saveAuditRecord(audit)
+
+	return nil
+}
+
+// RevokeRefreshTokens signs the user out everywhere and saves the audit
+// record.
+func (s *Storage) RevokeRefreshTokens(uid int64, audit entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.revokeUserSessions(uid)
+	s.saveAuditRecord(audit)
+
+	return nil
+}
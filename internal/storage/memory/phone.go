@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+func (s *Storage) SavePhoneChange(change entity.PhoneChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if old, ok := s.phoneChanges[change.UserID]; ok {
+		change.Attempts = old.Attempts
+	}
+	s.phoneChanges[change.UserID] = change
+
+	return nil
+}
+
+func (s *Storage) PhoneChange(uid int64) (entity.PhoneChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.PhoneChange{}, s.failure
+	}
+
+	change, ok := s.phoneChanges[uid]
+	if !ok {
+		return entity.PhoneChange{}, storage.ErrPhoneChangeNotFound
+	}
+
+	return change, nil
+}
+
+func (s *Storage) IncPhoneChangeAttempts(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if change, ok := s.phoneChanges[uid]; ok {
+		change.Attempts++
+		s.phoneChanges[uid] = change
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletePhoneChange(uid int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	delete(s.phoneChanges, uid)
+
+	return nil
+}
+
+// PhoneReleasedSince reports whether another account gave up this phone
+// after the given time.
+func (s *Storage) PhoneReleasedSince(phone string, uid int64, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return false, s.failure
+	}
+
+	for _, release := range s.phoneHistory {
+		if release.phone == phone && release.userID != uid && release.releasedAt.After(since) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ChangeUserPhone moves the current phone to the phone history, sets the
+// new one, removes the change request and signs out every session of the
+// user but keepSessionID.
+func (s *Storage) ChangeUserPhone(uid int64, newPhone string, keepSessionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	user, ok := s.users[uid]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	for _, other := range s.users {
+		if other.ID != uid && other.Phone == newPhone {
+			return storage.ErrUserExists
+		}
+	}
+
+	s.phoneHistory = append(s.phoneHistory, phoneRelease{
+		userID:     uid,
+		phone:      user.Phone,
+		releasedAt: time.Now(),
+	})
+
+	user.Phone = newPhone
+	s.users[uid] = user
+
+	delete(s.phoneChanges, uid)
+
+	s.revokeOtherSessions(uid, keepSessionID)
+
+	return nil
+}
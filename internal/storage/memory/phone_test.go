@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestChangeUserPhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		newPhone string
+		wantErr  error
+	}{
+		{name: "free phone", newPhone: "+79995555555"},
+		{name: "taken by another user", newPhone: "+79990000000", wantErr: storage.ErrUserExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			start := time.Now()
+
+			uid, err := s.SaveUser("+79991234567", []byte("hash"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SaveUser("+79990000000", []byte("hash")); err != nil {
+				t.Fatal(err)
+			}
+
+			kept, err := s.SaveSession(entity.Session{UserID: uid})
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := s.SaveSession(entity.Session{UserID: uid})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.ChangeUserPhone(uid, tt.newPhone, kept.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			user, err := s.UserByID(uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantPhone, wantOtherActive, wantReleased := tt.newPhone, false, true
+			if tt.wantErr != nil {
+				wantPhone, wantOtherActive, wantReleased = "+79991234567", true, false
+			}
+
+			if user.Phone != wantPhone {
+				t.Errorf("phone = %q, want %q", user.Phone, wantPhone)
+			}
+
+			if session, _ := s.Session(kept.ID); !session.IsActive {
+				t.Error("kept session was revoked")
+			}
+			if session, _ := s.Session(other.ID); session.IsActive != wantOtherActive {
+				t.Errorf("other session active = %v, want %v", session.IsActive, wantOtherActive)
+			}
+
+			// The old phone counts as released for other accounts only.
+			released, err := s.PhoneReleasedSince("+79991234567", uid+100, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if released != wantReleased {
+				t.Errorf("released for another account = %v, want %v", released, wantReleased)
+			}
+
+			released, err = s.PhoneReleasedSince("+79991234567", uid, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if released {
+				t.Error("released for the same account")
+			}
+		})
+	}
+}
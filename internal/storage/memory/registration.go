@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+// SavePendingRegistration keeps the attempt counter of an existing
+// registration of the phone.
+func (s *Storage) SavePendingRegistration(phone string, passHash []byte, codeHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.registrations[phone] = entity.PendingRegistration{
+		Phone:     phone,
+		PassHash:  passHash,
+		CodeHash:  codeHash,
+		ExpiresAt: expiresAt,
+		Attempts:  s.registrations[phone].Attempts,
+	}
+
+	return nil
+}
+
+func (s *Storage) PendingRegistration(phone string) (entity.PendingRegistration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.PendingRegistration{}, s.failure
+	}
+
+	reg, ok := s.registrations[phone]
+	if !ok {
+		return entity.PendingRegistration{}, storage.ErrRegistrationNotFound
+	}
+
+	return reg, nil
+}
+
+func (s *Storage) IncPendingRegistrationAttempts(phone string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if reg, ok := s.registrations[phone]; ok {
+		reg.Attempts++
+		s.registrations[phone] = reg
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletePendingRegistration(phone string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	delete(s.registrations, phone)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"slices"
+	"time"
+)
+
+func (s *Storage) SaveSecurityEvent(event entity.SecurityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	event.ID = s.nextID()
+	event.CreatedAt = time.Now()
+	s.securityEvents = append(s.securityEvents, event)
+
+	return nil
+}
+
+// AllSecurityEvents returns the saved events of every user, oldest first.
+func (s *Storage) AllSecurityEvents() []entity.SecurityEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.securityEvents)
+}
+
+// SaveAuditRecord keeps the record without chaining it.
+func (s *Storage) SaveAuditRecord(record entity.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	s.saveAuditRecord(record)
+
+	return nil
+}
+
+// saveAuditRecord keeps the record. The caller holds the lock, so the
+// record is saved together with the change it describes.
+func (s *Storage) saveAuditRecord(record entity.AuditRecord) {
+	record.ID = s.nextID()
+	record.CreatedAt = time.Now()
+	s.auditRecords = append(s.auditRecords, record)
+}
+
+// saveOptionalAuditRecord is saveAuditRecord for changes that are only
+// audited when an admin makes them.
+func (s *Storage) saveOptionalAuditRecord(record *entity.AuditRecord) {
+	if record != nil {
+		s.saveAuditRecord(*record)
+	}
+}
+
+// AuditRecords returns up to limit records with id greater than afterID,
+// in the order they were written.
+func (s *Storage) AuditRecords(afterID int64, limit int) ([]entity.AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	var records []entity.AuditRecord
+	for _, record := range s.auditRecords {
+		if record.ID > afterID && len(records) < limit {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
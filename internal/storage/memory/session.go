@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"cmp"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"slices"
+	"time"
+)
+
+func (s *Storage) SaveSession(session entity.Session) (entity.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.Session{}, s.failure
+	}
+
+	session.ID = s.nextID()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	session.RevokedAt = time.Time{}
+	session.IsActive = true
+	s.sessions[session.ID] = session
+
+	return session, nil
+}
+
+func (s *Storage) Session(sessionID int64) (entity.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return entity.Session{}, s.failure
+	}
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return entity.Session{}, storage.ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// Sessions returns every session of the user, revoked ones included,
+// newest first.
+func (s *Storage) Sessions(uid int64) ([]entity.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	var sessions []entity.Session
+	for _, session := range s.sessions {
+		if session.UserID == uid {
+			sessions = append(sessions, session)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b entity.Session) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession signs the user out of one session.
+func (s *Storage) RevokeSession(uid, sessionID int64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != uid || !session.IsActive {
+		return storage.ErrSessionNotFound
+	}
+
+	session.IsActive = false
+	session.RevokedAt = time.Now()
+	s.sessions[sessionID] = session
+
+	for t, stored := range s.refreshTokens {
+		if stored.sessionID == sessionID {
+			stored.isActive = false
+			s.refreshTokens[t] = stored
+		}
+	}
+
+	return nil
+}
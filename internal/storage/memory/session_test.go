@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"testing"
+)
+
+func TestSaveRefreshTokenConsumesPrevious(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare runs after "first" is saved on login and returns the
+		// previous token to exchange.
+		prepare func(t *testing.T, s *Storage, sessionID int64) string
+		wantErr error
+	}{
+		{
+			name:    "active token",
+			prepare: func(*testing.T, *Storage, int64) string { return "first" },
+		},
+		{
+			name: "already exchanged",
+			prepare: func(t *testing.T, s *Storage, sessionID int64) string {
+				if err := s.SaveRefreshToken("second", 1, sessionID, "first"); err != nil {
+					t.Fatal(err)
+				}
+				return "first"
+			},
+			wantErr: storage.ErrRefreshTokenUsed,
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T, s *Storage, sessionID int64) string {
+				if err := s.RevokeSession(1, sessionID, "signed_out"); err != nil {
+					t.Fatal(err)
+				}
+				return "first"
+			},
+			wantErr: storage.ErrRefreshTokenUsed,
+		},
+		{
+			name:    "unknown token",
+			prepare: func(*testing.T, *Storage, int64) string { return "forged" },
+			wantErr: storage.ErrRefreshTokenUsed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+
+			session, err := s.SaveSession(entity.Session{UserID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.SaveRefreshToken("first", 1, session.ID, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			previous := tt.prepare(t, s, session.ID)
+
+			err = s.SaveRefreshToken("next", 1, session.ID, previous)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveRefreshToken() = %v, want %v", err, tt.wantErr)
+			}
+
+			token, err := s.RefreshToken("next")
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Error("token was saved after a failed exchange")
+				}
+				return
+			}
+			if err != nil || !token.IsActive {
+				t.Errorf("RefreshToken(next) = %+v, %v, want an active token", token, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+const userColumns = `
+		users.id,
+		users.phone,
+		users.password_hashed,
+		users.created_at,
+		users.status,
+		users.status_reason,
+		users.status_changed_by,
+		users.status_changed_at,
+		users.status_until,
+		users.purge_after,
+		users.password_reset_required`
+
+func scanUser(row rowScanner) (entity.User, error) {
+	var user entity.User
+	var changedAt, until, purgeAfter sql.NullTime
+
+	err := row.Scan(&user.ID, &user.Phone, &user.PassHash, &user.CreatedAt, &user.State.Status,
+		&user.State.Reason, &user.State.ChangedBy, &changedAt, &until, &purgeAfter, &user.PasswordResetRequired)
+	if err != nil {
+		return user, err
+	}
+
+	user.State.ChangedAt = changedAt.Time
+	user.State.Until = until.Time
+	user.PurgeAfter = purgeAfter.Time
+
+	return user, nil
+}
+
+// SetAccountState changes the account status if it is still from, so two
+// concurrent changes can't both apply. Locking or suspending the account
+// also deactivates its refresh tokens. A non-nil audit record is saved in
+// the same transaction.
+func (s *Storage) SetAccountState(uid int64, from entity.AccountStatus, state entity.AccountState,
+	audit *entity.AuditRecord) error {
+	const op = "postgres.SetAccountState"
+
+	query := `
+		UPDATE users
+		SET status = $3,
+		status_reason = $4,
+		status_changed_by = $5,
+		status_changed_at = CURRENT_TIMESTAMP,
+		status_until = $6
+		WHERE id = $1
+		AND status = $2;
+		`
+
+	until := sql.NullTime{Time: state.Until, Valid: !state.Until.IsZero()}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, uid, from, state.Status, state.Reason, state.ChangedBy, until)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrVersionConflict
+	}
+
+	if state.Status == entity.AccountLocked || state.Status == entity.AccountSuspended {
+		if err := revokeUserSessions(tx, uid, "account_"+string(state.Status)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := saveOptionalAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkUserDeleted starts the grace period of the account and deactivates
+// its refresh tokens. A non-nil audit record is saved in the same
+// transaction.
+func (s *Storage) MarkUserDeleted(uid int64, changedBy, reason string, purgeAfter time.Time,
+	audit *entity.AuditRecord) error {
+	const op = "postgres.MarkUserDeleted"
+
+	query := `
+		UPDATE users
+		SET status = 'deleted',
+		status_reason = $3,
+		status_changed_by = $4,
+		status_changed_at = CURRENT_TIMESTAMP,
+		status_until = NULL,
+		deleted_at = CURRENT_TIMESTAMP,
+		purge_after = $2
+		WHERE id = $1
+		AND status <> 'deleted';
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, uid, purgeAfter, reason, changedBy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if err := revokeUserSessions(tx, uid, "account_deleted"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = saveDomainEvent(tx, uid, entity.UserDeleted, map[string]any{
+		"deleted_by":  changedBy,
+		"purge_after": purgeAfter.UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveOptionalAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RestoreUser cancels deletion if the account has not been purged yet. A
+// non-nil audit record is saved in the same transaction.
+func (s *Storage) RestoreUser(uid int64, changedBy, reason string, audit *entity.AuditRecord) error {
+	const op = "postgres.RestoreUser"
+
+	query := `
+		UPDATE users
+		SET status = 'active',
+		status_reason = $3,
+		status_changed_by = $2,
+		status_changed_at = CURRENT_TIMESTAMP,
+		deleted_at = NULL,
+		purge_after = NULL
+		WHERE id = $1
+		AND status = 'deleted'
+		AND purged_at IS NULL;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, uid, changedBy, reason)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if err := saveOptionalAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UsersToPurge(now time.Time, limit int) ([]int64, error) {
+	const op = "postgres.UsersToPurge"
+
+	query := `
+		SELECT id
+		FROM users
+		WHERE status = 'deleted'
+		AND purged_at IS NULL
+		AND purge_after <= $1
+		ORDER BY purge_after
+		LIMIT $2;
+		`
+
+	rows, err := s.db.Query(query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// purgeTargets selects every phone and email of the user $1 that codes,
+// links or login attempts could have been recorded for.
+const purgeTargets = `
+		SELECT phone FROM users WHERE id = $1
+		UNION SELECT phone FROM phone_history WHERE user_id = $1
+		UNION SELECT new_phone FROM phone_change_requests WHERE user_id = $1
+		UNION SELECT lower(email) FROM users_data WHERE user_id = $1 AND email <> ''
+		UNION SELECT lower(email) FROM email_verifications WHERE user_id = $1`
+
+// PurgeUser removes personal data of a deleted account, including what its
+// outbox events and webhook deliveries carry, and the code sends, login
+// attempts and pending registrations kept under its phones and emails.
+// The users row stays so foreign keys and audit records keep pointing
+// somewhere.
+func (s *Storage) PurgeUser(uid int64) error {
+	const op = "postgres.PurgeUser"
+
+	queries := []string{
+		// These go first: they find the rows by the phones and emails the
+		// queries below scrub.
+		`DELETE FROM code_sends WHERE target IN (` + purgeTargets + `);`,
+		`DELETE FROM login_failures
+		WHERE split_part(key, '|', 1) IN (
+			SELECT 'login:' || phone FROM (` + purgeTargets + `) AS targets
+			UNION SELECT 'user:' || $1::BIGINT
+		);`,
+		`DELETE FROM pending_registrations WHERE phone IN (` + purgeTargets + `);`,
+		`UPDATE users
+		SET phone = 'del:' || id,
+		password_hashed = '',
+		purged_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		AND status = 'deleted';`,
+		`UPDATE users_data
+		SET full_name = '',
+		email = '',
+		email_verified_at = NULL,
+		version = version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1;`,
+		`UPDATE addresses
+		SET full_address = '',
+		city = '',
+		street = '',
+		building = '',
+		apartment = '',
+		latitude = NULL,
+		longitude = NULL,
+		is_default = false,
+		is_deleted = true,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1;`,
+		`DELETE FROM refresh_token WHERE user_id = $1;`,
+		`DELETE FROM sessions WHERE user_id = $1;`,
+		`DELETE FROM security_events WHERE user_id = $1;`,
+		`DELETE FROM password_history WHERE user_id = $1;`,
+		`DELETE FROM phone_history WHERE user_id = $1;`,
+		`DELETE FROM phone_change_requests WHERE user_id = $1;`,
+		`DELETE FROM email_verifications WHERE user_id = $1;`,
+		`DELETE FROM password_resets WHERE user_id = $1;`,
+		`DELETE FROM deletion_codes WHERE user_id = $1;`,
+		`DELETE FROM user_app_roles WHERE user_id = $1;`,
+		// Events stay for consumers that haven't read them yet, but lose
+		// every field except the user id.
+		`UPDATE webhook_deliveries
+		SET body = jsonb_set(body::JSONB, '{payload}', jsonb_build_object('user_id', $1::BIGINT))::TEXT
+		WHERE event_id IN (SELECT id FROM outbox WHERE user_id = $1);`,
+		`UPDATE outbox
+		SET payload = jsonb_build_object('user_id', user_id)
+		WHERE user_id = $1;`,
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, uid); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveDeletionCode(uid int64, codeHash string, expiresAt time.Time) error {
+	const op = "postgres.SaveDeletionCode"
+
+	query := `
+		INSERT INTO deletion_codes (user_id, code_hash, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash,
+		expires_at = EXCLUDED.expires_at;
+		`
+
+	_, err := s.db.Exec(query, uid, codeHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletionCode(uid int64) (entity.DeletionCode, error) {
+	const op = "postgres.DeletionCode"
+
+	query := `
+		SELECT user_id,
+		code_hash,
+		expires_at,
+		attempts
+		FROM deletion_codes
+		WHERE user_id = $1
+		LIMIT 1;
+		`
+
+	var code entity.DeletionCode
+
+	err := s.db.QueryRow(query, uid).Scan(&code.UserID, &code.CodeHash, &code.ExpiresAt, &code.Attempts)
+	if err == sql.ErrNoRows {
+		return code, storage.ErrVerificationNotFound
+	} else if err != nil {
+		return code, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return code, nil
+}
+
+func (s *Storage) IncDeletionCodeAttempts(uid int64) error {
+	const op = "postgres.IncDeletionCodeAttempts"
+
+	query := `
+		UPDATE deletion_codes
+		SET attempts = attempts + 1
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteDeletionCode(uid int64) error {
+	const op = "postgres.DeleteDeletionCode"
+
+	query := `
+		DELETE FROM deletion_codes
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
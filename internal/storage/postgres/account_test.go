@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPurgeUser(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	uid, err := s.SaveUser("+79991234567", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.SaveUser("+79990000000", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveEmailVerification(uid, "User@Example.com", "code", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"+79991234567", "user@example.com", "+79990000000"} {
+		if err := s.SaveCodeSend("registration", target, now, now.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{
+		"login:+79991234567|ip:10.0.0.1",
+		"login:user@example.com",
+		userKey(uid) + "|ip:10.0.0.1",
+		"login:+79990000000|ip:10.0.0.1",
+		userKey(other),
+	} {
+		if _, err := s.TakeLoginAttempt(key, now, now.Add(-time.Hour), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, phone := range []string{"+79991234567", "+79995555555"} {
+		if err := s.SavePendingRegistration(phone, []byte("hash"), "code", now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.MarkUserDeleted(uid, "user", "", now, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PurgeUser(uid); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "code sends", query: `SELECT target FROM code_sends`, want: 1},
+		{name: "code sends of the user", query: `SELECT target FROM code_sends WHERE target <> '+79990000000'`},
+		{name: "login failures", query: `SELECT key FROM login_failures`, want: 2},
+		{
+			name:  "login failures of the user",
+			query: `SELECT key FROM login_failures WHERE key LIKE '%+79991234567%' OR key LIKE '%user@example.com%'`,
+		},
+		{name: "pending registrations", query: `SELECT phone FROM pending_registrations`, want: 1},
+		{name: "pending registration of the user", query: `SELECT phone FROM pending_registrations WHERE phone = '+79991234567'`},
+		{name: "phone", query: `SELECT id FROM users WHERE phone = '+79991234567'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := count(t, s, tt.query); got != tt.want {
+				t.Errorf("%d rows, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func userKey(uid int64) string {
+	return fmt.Sprintf("user:%d", uid)
+}
@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+)
+
+const addressColumns = `
+		addresses.id,
+		addresses.user_id,
+		addresses.full_address,
+		addresses.city,
+		addresses.street,
+		addresses.building,
+		addresses.apartment,
+		addresses.latitude,
+		addresses.longitude,
+		addresses.is_default,
+		addresses.created_at,
+		addresses.updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row rowScanner) (entity.Address, error) {
+	var addr entity.Address
+	var lat, lon sql.NullFloat64
+
+	err := row.Scan(&addr.ID, &addr.UserID, &addr.FullAddress, &addr.City, &addr.Street, &addr.Building,
+		&addr.Apartment, &lat, &lon, &addr.IsDefault, &addr.CreatedAt, &addr.UpdatedAt)
+	if err != nil {
+		return addr, err
+	}
+
+	if lat.Valid && lon.Valid {
+		addr.Location = &entity.GeoPoint{Latitude: lat.Float64, Longitude: lon.Float64}
+	}
+
+	return addr, nil
+}
+
+func geoArgs(p *entity.GeoPoint) (lat, lon sql.NullFloat64) {
+	if p == nil {
+		return lat, lon
+	}
+
+	return sql.NullFloat64{Float64: p.Latitude, Valid: true}, sql.NullFloat64{Float64: p.Longitude, Valid: true}
+}
+
+// Addresses returns up to limit addresses of the user with id greater
+// than afterID.
+func (s *Storage) Addresses(uid, afterID int64, limit int) ([]entity.Address, error) {
+	const op = "postgres.Addresses"
+
+	query := `
+		SELECT` + addressColumns + `
+		FROM addresses
+		WHERE user_id = $1
+		AND NOT is_deleted
+		AND id > $2
+		ORDER BY id
+		LIMIT $3;
+		`
+
+	rows, err := s.db.Query(query, uid, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var addresses []entity.Address
+
+	for rows.Next() {
+		addr, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		addresses = append(addresses, addr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return addresses, nil
+}
+
+func (s *Storage) CountAddresses(uid int64) (int, error) {
+	const op = "postgres.CountAddresses"
+
+	query := `
+		SELECT COUNT(*)
+		FROM addresses
+		WHERE user_id = $1
+		AND NOT is_deleted;
+		`
+
+	var count int
+
+	if err := s.db.QueryRow(query, uid).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// SaveAddress inserts a new address. The first address of a user always
+// becomes the default one.
+func (s *Storage) SaveAddress(addr entity.Address) (entity.Address, error) {
+	const op = "postgres.SaveAddress"
+
+	query := `
+		INSERT INTO addresses (user_id, full_address, city, street, building, apartment, latitude, longitude, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+			$9 OR NOT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND NOT is_deleted))
+		RETURNING` + addressColumns + `;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if addr.IsDefault {
+		if err := unsetDefaultAddress(tx, addr.UserID); err != nil {
+			return addr, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	lat, lon := geoArgs(addr.Location)
+
+	saved, err := scanAddress(tx.QueryRow(query, addr.UserID, addr.FullAddress, addr.City, addr.Street,
+		addr.Building, addr.Apartment, lat, lon, addr.IsDefault))
+	if err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdateAddress replaces the fields of an address of the user. Clearing the
+// default flag is ignored: a user with addresses always has a default one.
+func (s *Storage) UpdateAddress(addr entity.Address) (entity.Address, error) {
+	const op = "postgres.UpdateAddress"
+
+	query := `
+		UPDATE addresses
+		SET full_address = $3,
+		city = $4,
+		street = $5,
+		building = $6,
+		apartment = $7,
+		latitude = $8,
+		longitude = $9,
+		is_default = is_default OR $10,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		AND user_id = $2
+		AND NOT is_deleted
+		RETURNING` + addressColumns + `;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if addr.IsDefault {
+		if err := unsetDefaultAddress(tx, addr.UserID); err != nil {
+			return addr, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	lat, lon := geoArgs(addr.Location)
+
+	saved, err := scanAddress(tx.QueryRow(query, addr.ID, addr.UserID, addr.FullAddress, addr.City, addr.Street,
+		addr.Building, addr.Apartment, lat, lon, addr.IsDefault))
+	if err == sql.ErrNoRows {
+		return addr, storage.ErrAddressNotFound
+	} else if err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return addr, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeleteAddress marks the address as deleted. If it was the default one,
+// the most recently added address takes its place.
+func (s *Storage) DeleteAddress(uid, addressID int64) error {
+	const op = "postgres.DeleteAddress"
+
+	selectQuery := `
+		SELECT is_default
+		FROM addresses
+		WHERE id = $1
+		AND user_id = $2
+		AND NOT is_deleted
+		FOR UPDATE;
+		`
+
+	query := `
+		UPDATE addresses
+		SET is_deleted = true,
+		is_default = false,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+		`
+
+	promoteQuery := `
+		UPDATE addresses
+		SET is_default = true
+		WHERE id = (
+			SELECT id
+			FROM addresses
+			WHERE user_id = $1
+			AND NOT is_deleted
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		);
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+
+	err = tx.QueryRow(selectQuery, addressID, uid).Scan(&wasDefault)
+	if err == sql.ErrNoRows {
+		return storage.ErrAddressNotFound
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(query, addressID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if wasDefault {
+		if _, err := tx.Exec(promoteQuery, uid); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func unsetDefaultAddress(tx *sql.Tx, uid int64) error {
+	query := `
+		UPDATE addresses
+		SET is_default = false
+		WHERE user_id = $1
+		AND is_default;
+		`
+
+	_, err := tx.Exec(query, uid)
+
+	return err
+}
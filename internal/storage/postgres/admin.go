@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"strings"
+)
+
+// SearchUsers returns up to limit users matching the filter with id
+// greater than afterID, including deleted ones.
+func (s *Storage) SearchUsers(filter entity.UserFilter, afterID int64, limit int) ([]entity.UserDetails, error) {
+	const op = "postgres.SearchUsers"
+
+	query := `
+		SELECT` + userColumns + `,
+		COALESCE(users_data.full_name, ''),
+		COALESCE(users_data.email, ''),
+		users_data.email_verified_at IS NOT NULL
+		FROM users
+		LEFT JOIN users_data ON users_data.user_id = users.id
+		WHERE ($1 = 0 OR users.id = $1)
+		AND ($2 = '' OR users.phone LIKE $2 || '%')
+		AND ($3 = '' OR lower(users_data.email) LIKE lower($3) || '%')
+		AND users.id > $4
+		ORDER BY users.id
+		LIMIT $5;
+		`
+
+	rows, err := s.db.Query(query, filter.ID, likePrefix(filter.Phone), likePrefix(filter.Email), afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []entity.UserDetails
+
+	for rows.Next() {
+		var details entity.UserDetails
+
+		user, err := scanUser(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &details.Profile.FullName, &details.Profile.Email,
+				&details.Profile.EmailVerified)...)
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		details.User = user
+		details.Profile.UserID = user.ID
+		users = append(users, details)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) AdminRoles(subject string) ([]string, error) {
+	const op = "postgres.AdminRoles"
+
+	query := `
+		SELECT role
+		FROM admin_roles
+		WHERE subject = $1
+		ORDER BY role;
+		`
+
+	rows, err := s.db.Query(query, subject)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) GrantAdminRole(subject, role, grantedBy string, audit entity.AuditRecord) error {
+	const op = "postgres.GrantAdminRole"
+
+	query := `
+		INSERT INTO admin_roles (subject, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (subject, role) DO NOTHING;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, subject, role, grantedBy); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeAdminRole(subject, role string, audit entity.AuditRecord) error {
+	const op = "postgres.RevokeAdminRole"
+
+	query := `
+		DELETE FROM admin_roles
+		WHERE subject = $1
+		AND role = $2;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, subject, role); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// scanFunc lets a plain function act as a rowScanner, so scanUser can be
+// reused when the query selects extra columns after the user ones.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
+
+// likePrefix escapes LIKE wildcards so user input only matches literally.
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,439 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+const appColumns = `
+		apps.id,
+		apps.name,
+		apps.signing_key,
+		apps.retired_signing_key,
+		apps.signing_key_rotated_at,
+		apps.is_disabled,
+		apps.grant_types,
+		apps.redirect_uris,
+		apps.scopes,
+		apps.access_token_ttl_seconds,
+		apps.refresh_token_ttl_seconds,
+		apps.session_lifetime_seconds,
+		apps.idle_timeout_seconds,
+		apps.sliding_refresh,
+		apps.created_at,
+		apps.updated_at`
+
+func scanApp(row rowScanner) (entity.App, error) {
+	var app entity.App
+	var accessTTL, refreshTTL, lifetime, idleTimeout int64
+	var sliding sql.NullBool
+	var rotatedAt sql.NullTime
+
+	err := row.Scan(&app.ID, &app.Name, &app.SigningKey, &app.RetiredSigningKey, &rotatedAt, &app.Disabled,
+		pq.Array(&app.GrantTypes), pq.Array(&app.RedirectURIs), pq.Array(&app.Scopes), &accessTTL, &refreshTTL, &lifetime,
+		&idleTimeout, &sliding, &app.CreatedAt, &app.UpdatedAt)
+	if err != nil {
+		return app, err
+	}
+
+	app.SigningKeyRotatedAt = rotatedAt.Time
+	app.AccessTokenTTL = time.Duration(accessTTL) * time.Second
+	app.RefreshTokenTTL = time.Duration(refreshTTL) * time.Second
+	app.SessionLifetime = time.Duration(lifetime) * time.Second
+	app.IdleTimeout = time.Duration(idleTimeout) * time.Second
+	if sliding.Valid {
+		app.SlidingRefresh = &sliding.Bool
+	}
+
+	return app, nil
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+func slidingArg(sliding *bool) sql.NullBool {
+	if sliding == nil {
+		return sql.NullBool{}
+	}
+
+	return sql.NullBool{Bool: *sliding, Valid: true}
+}
+
+func (s *Storage) App(appID int32) (entity.App, error) {
+	const op = "postgres.App"
+
+	query := `
+		SELECT` + appColumns + `
+		FROM apps
+		WHERE id = $1
+		LIMIT 1;
+		`
+
+	app, err := scanApp(s.db.QueryRow(query, appID))
+	if err == sql.ErrNoRows {
+		return app, storage.ErrAppNotFound
+	} else if err != nil {
+		return app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+func (s *Storage) Apps() ([]entity.App, error) {
+	const op = "postgres.Apps"
+
+	query := `
+		SELECT` + appColumns + `
+		FROM apps
+		ORDER BY id;
+		`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []entity.App
+
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		apps = append(apps, app)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+func (s *Storage) SaveApp(app entity.App) (entity.App, error) {
+	const op = "postgres.SaveApp"
+
+	query := `
+		INSERT INTO apps (name, signing_key, grant_types, redirect_uris, access_token_ttl_seconds,
+		refresh_token_ttl_seconds, session_lifetime_seconds, idle_timeout_seconds, sliding_refresh)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING` + appColumns + `;
+		`
+
+	app, err := scanApp(s.db.QueryRow(query, app.Name, app.SigningKey, pq.Array(app.GrantTypes),
+		pq.Array(app.RedirectURIs), seconds(app.AccessTokenTTL), seconds(app.RefreshTokenTTL),
+		seconds(app.SessionLifetime), seconds(app.IdleTimeout), slidingArg(app.SlidingRefresh)))
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return app, storage.ErrAppExists
+		}
+		return app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// UpdateApp saves the app's settings. The signing key, disabled flag and
+// scopes are not changed here.
+func (s *Storage) UpdateApp(app entity.App) (entity.App, error) {
+	const op = "postgres.UpdateApp"
+
+	query := `
+		UPDATE apps
+		SET name = $2,
+		grant_types = $3,
+		redirect_uris = $4,
+		access_token_ttl_seconds = $5,
+		refresh_token_ttl_seconds = $6,
+		session_lifetime_seconds = $7,
+		idle_timeout_seconds = $8,
+		sliding_refresh = $9,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING` + appColumns + `;
+		`
+
+	app, err := scanApp(s.db.QueryRow(query, app.ID, app.Name, pq.Array(app.GrantTypes),
+		pq.Array(app.RedirectURIs), seconds(app.AccessTokenTTL), seconds(app.RefreshTokenTTL),
+		seconds(app.SessionLifetime), seconds(app.IdleTimeout), slidingArg(app.SlidingRefresh)))
+	if err == sql.ErrNoRows {
+		return app, storage.ErrAppNotFound
+	} else if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return app, storage.ErrAppExists
+		}
+		return app, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+func (s *Storage) SetAppScopes(appID int32, scopes []string) error {
+	const op = "postgres.SetAppScopes"
+
+	query := `
+		UPDATE apps
+		SET scopes = $2,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+		`
+
+	res, err := s.db.Exec(query, appID, pq.Array(scopes))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrAppNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SetAppDisabled(appID int32, disabled bool) error {
+	const op = "postgres.SetAppDisabled"
+
+	query := `
+		UPDATE apps
+		SET is_disabled = $2,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+		`
+
+	res, err := s.db.Exec(query, appID, disabled)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrAppNotFound
+	}
+
+	return nil
+}
+
+// DeleteApp removes the app and its client secrets. Tokens it issued stop
+// validating because the signing key is gone.
+func (s *Storage) DeleteApp(appID int32) error {
+	const op = "postgres.DeleteApp"
+
+	query := `
+		DELETE FROM apps
+		WHERE id = $1;
+		`
+
+	res, err := s.db.Exec(query, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrAppNotFound
+	}
+
+	return nil
+}
+
+const appSecretColumns = `
+		app_secrets.id,
+		app_secrets.app_id,
+		app_secrets.secret_hash,
+		app_secrets.hint,
+		app_secrets.created_at,
+		app_secrets.expires_at,
+		app_secrets.revoked_at`
+
+func scanAppSecret(row rowScanner) (entity.AppSecret, error) {
+	var secret entity.AppSecret
+	var expiresAt, revokedAt sql.NullTime
+
+	err := row.Scan(&secret.ID, &secret.AppID, &secret.SecretHash, &secret.Hint, &secret.CreatedAt,
+		&expiresAt, &revokedAt)
+	if err != nil {
+		return secret, err
+	}
+
+	secret.ExpiresAt = expiresAt.Time
+	secret.RevokedAt = revokedAt.Time
+
+	return secret, nil
+}
+
+func (s *Storage) AppSecrets(appID int32) ([]entity.AppSecret, error) {
+	const op = "postgres.AppSecrets"
+
+	query := `
+		SELECT` + appSecretColumns + `
+		FROM app_secrets
+		WHERE app_id = $1
+		ORDER BY id;
+		`
+
+	rows, err := s.db.Query(query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var secrets []entity.AppSecret
+
+	for rows.Next() {
+		secret, err := scanAppSecret(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secrets, nil
+}
+
+func (s *Storage) AppSecretByHash(secretHash string) (entity.AppSecret, error) {
+	const op = "postgres.AppSecretByHash"
+
+	query := `
+		SELECT` + appSecretColumns + `
+		FROM app_secrets
+		WHERE secret_hash = $1
+		LIMIT 1;
+		`
+
+	secret, err := scanAppSecret(s.db.QueryRow(query, secretHash))
+	if err == sql.ErrNoRows {
+		return secret, storage.ErrAppSecretNotFound
+	} else if err != nil {
+		return secret, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secret, nil
+}
+
+func (s *Storage) SaveAppSecret(secret entity.AppSecret) (entity.AppSecret, error) {
+	const op = "postgres.SaveAppSecret"
+
+	query := `
+		INSERT INTO app_secrets (app_id, secret_hash, hint, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING` + appSecretColumns + `;
+		`
+
+	expiresAt := sql.NullTime{Time: secret.ExpiresAt, Valid: !secret.ExpiresAt.IsZero()}
+
+	secret, err := scanAppSecret(s.db.QueryRow(query, secret.AppID, secret.SecretHash, secret.Hint, expiresAt))
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23503" {
+			return secret, storage.ErrAppNotFound
+		}
+		return secret, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secret, nil
+}
+
+func (s *Storage) RevokeAppSecret(appID int32, secretID int64) error {
+	const op = "postgres.RevokeAppSecret"
+
+	query := `
+		UPDATE app_secrets
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		AND app_id = $2
+		AND revoked_at IS NULL;
+		`
+
+	res, err := s.db.Exec(query, secretID, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrAppSecretNotFound
+	}
+
+	return nil
+}
+
+// UserAppRoles returns the roles of the user in the app.
+func (s *Storage) UserAppRoles(uid int64, appID int32) ([]string, error) {
+	const op = "postgres.UserAppRoles"
+
+	query := `
+		SELECT role
+		FROM user_app_roles
+		WHERE user_id = $1
+		AND app_id = $2
+		ORDER BY role;
+		`
+
+	rows, err := s.db.Query(query, uid, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) GrantUserAppRole(uid int64, appID int32, role, grantedBy string) error {
+	const op = "postgres.GrantUserAppRole"
+
+	query := `
+		INSERT INTO user_app_roles (user_id, app_id, role, granted_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, app_id, role) DO NOTHING;
+		`
+
+	if _, err := s.db.Exec(query, uid, appID, role, grantedBy); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23503" {
+			if strings.Contains(err.Constraint, "app_id") {
+				return storage.ErrAppNotFound
+			}
+			return storage.ErrUserNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeUserAppRole(uid int64, appID int32, role string) error {
+	const op = "postgres.RevokeUserAppRole"
+
+	query := `
+		DELETE FROM user_app_roles
+		WHERE user_id = $1
+		AND app_id = $2
+		AND role = $3;
+		`
+
+	if _, err := s.db.Exec(query, uid, appID, role); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
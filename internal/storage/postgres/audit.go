@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/auditchain"
+	"time"
+)
+
+// auditChainLock is the advisory lock key that serializes appends to the
+// audit log, so every record is chained to the one written right before it.
+const auditChainLock = 0x61756469
+
+const auditRecordColumns = `
+		audit_log.id,
+		audit_log.actor,
+		audit_log.action,
+		audit_log.target_user_id,
+		audit_log.details,
+		audit_log.created_at,
+		audit_log.prev_hash,
+		audit_log.hash`
+
+func scanAuditRecord(row rowScanner) (entity.AuditRecord, error) {
+	var record entity.AuditRecord
+	var targetUserID sql.NullInt64
+
+	err := row.Scan(&record.ID, &record.Actor, &record.Action, &targetUserID, &record.Details,
+		&record.CreatedAt, &record.PrevHash, &record.Hash)
+	if err != nil {
+		return record, err
+	}
+
+	record.TargetUserID = targetUserID.Int64
+
+	return record, nil
+}
+
+// SaveAuditRecord appends the record to the hash chain of the audit log.
+func (s *Storage) SaveAuditRecord(record entity.AuditRecord) error {
+	const op = "postgres.SaveAuditRecord"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := saveAuditRecord(tx, record); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// saveAuditRecord appends the record inside tx, so the record is only
+// kept if the action it describes is.
+func saveAuditRecord(tx *sql.Tx, record entity.AuditRecord) error {
+	details, err := auditchain.CanonicalDetails(record.Details)
+	if err != nil {
+		return err
+	}
+	record.Details = details
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, auditChainLock); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1;`).Scan(&record.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// The id is part of the hash, so it is taken before the insert.
+	if err := tx.QueryRow(`SELECT nextval('audit_log_id_seq');`).Scan(&record.ID); err != nil {
+		return err
+	}
+
+	record.CreatedAt = auditchain.Timestamp(time.Now())
+
+	record.Hash, err = auditchain.Hash(record.PrevHash, record)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (id, actor, action, target_user_id, details, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+		`
+
+	targetUserID := sql.NullInt64{Int64: record.TargetUserID, Valid: record.TargetUserID != 0}
+
+	_, err = tx.Exec(query, record.ID, record.Actor, record.Action, targetUserID, record.Details,
+		record.CreatedAt, record.PrevHash, record.Hash)
+
+	return err
+}
+
+// saveOptionalAuditRecord is saveAuditRecord for changes that are only
+// audited when an admin makes them.
+func saveOptionalAuditRecord(tx *sql.Tx, record *entity.AuditRecord) error {
+	if record == nil {
+		return nil
+	}
+
+	return saveAuditRecord(tx, *record)
+}
+
+// AuditRecords returns up to limit records with id greater than afterID,
+// in the order they were written.
+func (s *Storage) AuditRecords(afterID int64, limit int) ([]entity.AuditRecord, error) {
+	const op = "postgres.AuditRecords"
+
+	query := `
+		SELECT` + auditRecordColumns + `
+		FROM audit_log
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2;
+		`
+
+	rows, err := s.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var records []entity.AuditRecord
+
+	for rows.Next() {
+		record, err := scanAuditRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+)
+
+func (s *Storage) CodeSends(purpose, target string, since time.Time) (int, error) {
+	const op = "postgres.CodeSends"
+
+	query := `
+		SELECT count(*)
+		FROM code_sends
+		WHERE purpose = $1 AND target = $2 AND sent_at >= $3;
+		`
+
+	var sends int
+
+	if err := s.db.QueryRow(query, purpose, target, since).Scan(&sends); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sends, nil
+}
+
+// SaveCodeSend records a send. Old sends of the same target are removed
+// here; DeleteCodeSendsBefore removes those of targets that get no more
+// codes.
+func (s *Storage) SaveCodeSend(purpose, target string, sentAt, expired time.Time) error {
+	const op = "postgres.SaveCodeSend"
+
+	pruneQuery := `
+		DELETE FROM code_sends
+		WHERE purpose = $1 AND target = $2 AND sent_at < $3;
+		`
+
+	query := `
+		INSERT INTO code_sends (purpose, target, sent_at)
+		VALUES ($1, $2, $3);
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(pruneQuery, purpose, target, expired); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(query, purpose, target, sentAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteCodeSendsBefore removes the sends made before cutoff, the phones
+// and emails they went to with them.
+func (s *Storage) DeleteCodeSendsBefore(cutoff time.Time) (int64, error) {
+	const op = "postgres.DeleteCodeSendsBefore"
+
+	query := `
+		DELETE FROM code_sends
+		WHERE sent_at < $1;
+		`
+
+	res, err := s.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+	"time"
+)
+
+// ProvideUserByEmail looks the user up by a verified email only.
+func (s *Storage) ProvideUserByEmail(email string) (entity.User, error) {
+	const op = "postgres.ProvideUserByEmail"
+
+	query := `
+		SELECT` + userColumns + `
+		FROM users
+		JOIN users_data ON users_data.user_id = users.id
+		WHERE lower(users_data.email) = lower($1)
+		AND users_data.email_verified_at IS NOT NULL
+		LIMIT 1;
+		`
+
+	user, err := scanUser(s.db.QueryRow(query, email))
+	if err == sql.ErrNoRows {
+		return user, storage.ErrUserNotFound
+	} else if err != nil {
+		return user, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) SaveEmailVerification(uid int64, email, codeHash string, expiresAt time.Time) error {
+	const op = "postgres.SaveEmailVerification"
+
+	query := `
+		INSERT INTO email_verifications (user_id, email, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = EXCLUDED.email,
+		code_hash = EXCLUDED.code_hash,
+		expires_at = EXCLUDED.expires_at,
+		created_at = CURRENT_TIMESTAMP;
+		`
+
+	_, err := s.db.Exec(query, uid, email, codeHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) EmailVerification(uid int64) (entity.EmailVerification, error) {
+	const op = "postgres.EmailVerification"
+
+	query := `
+		SELECT user_id,
+		email,
+		code_hash,
+		expires_at,
+		attempts
+		FROM email_verifications
+		WHERE user_id = $1
+		LIMIT 1;
+		`
+
+	var v entity.EmailVerification
+
+	err := s.db.QueryRow(query, uid).Scan(&v.UserID, &v.Email, &v.CodeHash, &v.ExpiresAt, &v.Attempts)
+	if err == sql.ErrNoRows {
+		return v, storage.ErrVerificationNotFound
+	} else if err != nil {
+		return v, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return v, nil
+}
+
+func (s *Storage) IncEmailVerificationAttempts(uid int64) error {
+	const op = "postgres.IncEmailVerificationAttempts"
+
+	query := `
+		UPDATE email_verifications
+		SET attempts = attempts + 1
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteEmailVerification(uid int64) error {
+	const op = "postgres.DeleteEmailVerification"
+
+	query := `
+		DELETE FROM email_verifications
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email as verified if it is still the one in the
+// profile, and removes the verification request.
+func (s *Storage) VerifyEmail(uid int64, email string) error {
+	const op = "postgres.VerifyEmail"
+
+	query := `
+		UPDATE users_data
+		SET email_verified_at = CURRENT_TIMESTAMP,
+		version = version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+		AND lower(email) = lower($2);
+		`
+
+	deleteQuery := `
+		DELETE FROM email_verifications
+		WHERE user_id = $1;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, uid, email)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return storage.ErrEmailExists
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrVerificationNotFound
+	}
+
+	if _, err := tx.Exec(deleteQuery, uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"time"
+)
+
+// PasswordChanges returns when the user set each of their passwords,
+// oldest first. The hashes themselves are not returned.
+func (s *Storage) PasswordChanges(uid int64) ([]time.Time, error) {
+	const op = "postgres.PasswordChanges"
+
+	query := `
+		SELECT created_at
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY id;
+		`
+
+	rows, err := s.db.Query(query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var changes []time.Time
+
+	for rows.Next() {
+		var changedAt time.Time
+		if err := rows.Scan(&changedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		changes = append(changes, changedAt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changes, nil
+}
+
+func (s *Storage) PhoneHistory(uid int64) ([]entity.PhoneRelease, error) {
+	const op = "postgres.PhoneHistory"
+
+	query := `
+		SELECT phone,
+		released_at
+		FROM phone_history
+		WHERE user_id = $1
+		ORDER BY id;
+		`
+
+	rows, err := s.db.Query(query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []entity.PhoneRelease
+
+	for rows.Next() {
+		var release entity.PhoneRelease
+		if err := rows.Scan(&release.Phone, &release.ReleasedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, release)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+)
+
+// loginAttemptLock is the advisory lock class of login attempts; the key
+// hash is the second half of the lock, so only attempts of the same key
+// wait for each other.
+const loginAttemptLock = 0x6c6f636b
+
+// TakeLoginAttempt counts an attempt of the key unless limit attempts
+// since then are already counted, and returns its number, or zero if it
+// was not counted. The key is locked for the transaction,
+// so concurrent attempts can't all see the count below the limit. Old
+// attempts of the key are removed here; DeleteLoginFailuresBefore removes
+// those of keys that are not tried again.
+func (s *Storage) TakeLoginAttempt(key string, at, since time.Time, limit int) (int, error) {
+	const op = "postgres.TakeLoginAttempt"
+
+	pruneQuery := `
+		DELETE FROM login_failures
+		WHERE key = $1 AND failed_at < $2;
+		`
+
+	countQuery := `
+		SELECT count(*)
+		FROM login_failures
+		WHERE key = $1;
+		`
+
+	query := `
+		INSERT INTO login_failures (key, failed_at)
+		VALUES ($1, $2);
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, hashtext($2));`, loginAttemptLock, key); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(pruneQuery, key, since); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var attempts int
+
+	if err := tx.QueryRow(countQuery, key).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	attempt := 0
+	if attempts < limit {
+		if _, err := tx.Exec(query, key, at); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		attempt = attempts + 1
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempt, nil
+}
+
+func (s *Storage) DeleteLoginFailures(key string) error {
+	const op = "postgres.DeleteLoginFailures"
+
+	query := `
+		DELETE FROM login_failures
+		WHERE key = $1;
+		`
+
+	if _, err := s.db.Exec(query, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteLoginFailuresBefore removes the attempts made before cutoff, the
+// phones and emails of their keys with them.
+func (s *Storage) DeleteLoginFailuresBefore(cutoff time.Time) (int64, error) {
+	const op = "postgres.DeleteLoginFailuresBefore"
+
+	query := `
+		DELETE FROM login_failures
+		WHERE failed_at < $1;
+		`
+
+	res, err := s.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/lib/pq"
+	"slices"
+	"time"
+)
+
+// outboxUserLock is the advisory lock class of a user's outbox events; the
+// user id is the second half of the lock. Transactions take it before the
+// audit chain lock.
+const outboxUserLock = 0x6f757462
+
+// outboxClaimLock serializes claims of outbox events, so two relays never
+// split the pending events of one user between them.
+const outboxClaimLock = 0x636c6169
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// saveDomainEvent writes an event to the outbox. It is called inside the
+// transaction of the change it describes, so the event is published if and
+// only if the change is committed. The user's events are written one
+// transaction at a time, so their ids follow the order of the commits.
+func saveDomainEvent(tx execer, uid int64, eventType string, payload map[string]any) error {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	payload["user_id"] = uid
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2);`, outboxUserLock, uid); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox (user_id, type, payload)
+		VALUES ($1, $2, $3);
+		`
+
+	_, err = tx.Exec(query, uid, eventType, data)
+
+	return err
+}
+
+// ClaimOutboxEvents leases up to limit unpublished events for lease and
+// returns them, lowest id first. Events of users that have events leased
+// by another claim are skipped, so a user's events go through one relay
+// at a time, in the order their changes were committed.
+func (s *Storage) ClaimOutboxEvents(limit int, lease time.Duration) ([]entity.DomainEvent, error) {
+	const op = "postgres.ClaimOutboxEvents"
+
+	query := `
+		UPDATE outbox
+		SET locked_until = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM outbox AS pending
+			WHERE published_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1
+				FROM outbox AS leased
+				WHERE leased.user_id = pending.user_id
+				AND leased.published_at IS NULL
+				AND leased.locked_until > CURRENT_TIMESTAMP
+			)
+			ORDER BY id
+			LIMIT $1
+		)
+		RETURNING id,
+		user_id,
+		type,
+		payload,
+		created_at;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, outboxClaimLock); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.Query(query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []entity.DomainEvent
+
+	for rows.Next() {
+		var event entity.DomainEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// RETURNING keeps no order.
+	slices.SortFunc(events, func(a, b entity.DomainEvent) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return events, nil
+}
+
+// MarkOutboxPublished marks the events as published, so they are not
+// claimed again.
+func (s *Storage) MarkOutboxPublished(ids []int64) error {
+	const op = "postgres.MarkOutboxPublished"
+
+	query := `
+		UPDATE outbox
+		SET published_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+		AND published_at IS NULL;
+		`
+
+	if _, err := s.db.Exec(query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReleaseOutboxEvents ends the lease of the events that are still
+// unpublished, so the next claim takes them again.
+func (s *Storage) ReleaseOutboxEvents(ids []int64) error {
+	const op = "postgres.ReleaseOutboxEvents"
+
+	query := `
+		UPDATE outbox
+		SET locked_until = NULL
+		WHERE id = ANY($1)
+		AND published_at IS NULL;
+		`
+
+	if _, err := s.db.Exec(query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeletePublishedOutbox removes up to limit events published before
+// cutoff and returns how many were removed.
+func (s *Storage) DeletePublishedOutbox(cutoff time.Time, limit int) (int64, error) {
+	const op = "postgres.DeletePublishedOutbox"
+
+	query := `
+		DELETE FROM outbox
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE published_at < $1
+			ORDER BY id
+			LIMIT $2
+		);
+		`
+
+	res, err := s.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestClaimOutboxEvents(t *testing.T) {
+	s := newTestStorage(t)
+
+	first, err := s.SaveUser("+79991234567", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeRefreshTokens(first, entity.AuditRecord{Actor: "admin", Action: "sessions_revoke", TargetUserID: first}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.SaveUser("+79990000000", []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batch ends in the middle of the first user's events.
+	claimed, err := s.ClaimOutboxEvents(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].UserID != first {
+		t.Fatalf("first claim = %+v, want the first event of user %d", claimed, first)
+	}
+
+	// The rest of the first user's events wait for that claim.
+	other, err := s.ClaimOutboxEvents(10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 1 || other[0].UserID != second {
+		t.Fatalf("second claim = %+v, want only the event of user %d", other, second)
+	}
+
+	if err := s.ReleaseOutboxEvents([]int64{claimed[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := s.ClaimOutboxEvents(10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 2 || again[0].ID != claimed[0].ID || again[1].ID <= again[0].ID {
+		t.Fatalf("claim after release = %+v, want both events of user %d in order", again, first)
+	}
+}
@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+func (s *Storage) UserByID(uid int64) (entity.User, error) {
+	const op = "postgres.UserByID"
+
+	query := `
+		SELECT` + userColumns + `
+		FROM users
+		WHERE id = $1
+		LIMIT 1;
+		`
+
+	user, err := scanUser(s.db.QueryRow(query, uid))
+	if err == sql.ErrNoRows {
+		return user, storage.ErrUserNotFound
+	} else if err != nil {
+		return user, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UpdatePassword moves the current hash to password_history, stores the
+// new one and signs out every session of the user, all in one transaction.
+func (s *Storage) UpdatePassword(uid int64, passHash []byte) error {
+	const op = "postgres.UpdatePassword"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := updatePassword(tx, uid, passHash); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func updatePassword(tx *sql.Tx, uid int64, passHash []byte) error {
+	historyQuery := `
+		INSERT INTO password_history (user_id, password_hashed)
+		SELECT id, password_hashed
+		FROM users
+		WHERE id = $1;
+		`
+
+	query := `
+		UPDATE users
+		SET password_hashed = $1,
+		password_reset_required = false
+		WHERE id = $2;
+		`
+
+	if _, err := tx.Exec(historyQuery, uid); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, passHash, uid)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if err := revokeUserSessions(tx, uid, "password_changed"); err != nil {
+		return err
+	}
+
+	return saveDomainEvent(tx, uid, entity.PasswordChanged, nil)
+}
+
+func (s *Storage) PasswordHistory(uid int64, limit int) ([][]byte, error) {
+	const op = "postgres.PasswordHistory"
+
+	query := `
+		SELECT password_hashed
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2;
+		`
+
+	rows, err := s.db.Query(query, uid, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var hashes [][]byte
+
+	for rows.Next() {
+		var hash []byte
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hashes, nil
+}
+
+func (s *Storage) SavePasswordReset(tokenHash string, uid int64, expiresAt time.Time) error {
+	const op = "postgres.SavePasswordReset"
+
+	query := `
+		INSERT INTO password_resets (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3);
+		`
+
+	_, err := s.db.Exec(query, tokenHash, uid, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PasswordReset(tokenHash string) (entity.PasswordReset, error) {
+	const op = "postgres.PasswordReset"
+
+	query := `
+		SELECT user_id,
+		expires_at,
+		used_at IS NOT NULL
+		FROM password_resets
+		WHERE token_hash = $1
+		LIMIT 1;
+		`
+
+	var reset entity.PasswordReset
+
+	err := s.db.QueryRow(query, tokenHash).Scan(&reset.UserID, &reset.ExpiresAt, &reset.Used)
+	if err == sql.ErrNoRows {
+		return reset, storage.ErrResetNotFound
+	} else if err != nil {
+		return reset, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reset, nil
+}
+
+// ResetPassword spends the reset token and sets the new password of its
+// user the way UpdatePassword does, in one transaction. It returns
+// ErrResetNotFound if the token was already used, so a token works only
+// once.
+func (s *Storage) ResetPassword(tokenHash string, passHash []byte) error {
+	const op = "postgres.ResetPassword"
+
+	query := `
+		UPDATE password_resets
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1
+		AND used_at IS NULL
+		RETURNING user_id;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var uid int64
+
+	err = tx.QueryRow(query, tokenHash).Scan(&uid)
+	if err == sql.ErrNoRows {
+		return storage.ErrResetNotFound
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := updatePassword(tx, uid, passHash); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RequirePasswordReset blocks sign-in with the current password,
+// deactivates the user's refresh tokens and saves the audit record.
+func (s *Storage) RequirePasswordReset(uid int64, audit entity.AuditRecord) error {
+	const op = "postgres.RequirePasswordReset"
+
+	query := `
+		UPDATE users
+		SET password_reset_required = true
+		WHERE id = $1;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if err := revokeUserSessions(tx, uid, "password_reset_required"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokens signs the user out everywhere and saves the audit
+// record.
+func (s *Storage) RevokeRefreshTokens(uid int64, audit entity.AuditRecord) error {
+	const op = "postgres.RevokeRefreshTokens"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := revokeUserSessions(tx, uid, "signed_out"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveAuditRecord(tx, audit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+	"time"
+)
+
+func (s *Storage) UserPhones() ([]entity.User, error) {
+	const op = "postgres.UserPhones"
+
+	query := `
+		SELECT users.id,
+		users.phone
+		FROM users
+		ORDER BY users.id;
+		`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+
+	for rows.Next() {
+		var user entity.User
+		if err := rows.Scan(&user.ID, &user.Phone); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) UpdateUserPhone(uid int64, phone string) error {
+	const op = "postgres.UpdateUserPhone"
+
+	query := `
+		UPDATE users
+		SET phone = $1
+		WHERE id = $2;
+		`
+
+	_, err := s.db.Exec(query, phone, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SavePhoneChange(change entity.PhoneChange) error {
+	const op = "postgres.SavePhoneChange"
+
+	query := `
+		INSERT INTO phone_change_requests (user_id, new_phone, new_code_hash, old_code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET new_phone = EXCLUDED.new_phone,
+		new_code_hash = EXCLUDED.new_code_hash,
+		old_code_hash = EXCLUDED.old_code_hash,
+		expires_at = EXCLUDED.expires_at,
+		created_at = CURRENT_TIMESTAMP;
+		`
+
+	oldCodeHash := sql.NullString{String: change.OldCodeHash, Valid: change.OldCodeHash != ""}
+
+	_, err := s.db.Exec(query, change.UserID, change.NewPhone, change.NewCodeHash, oldCodeHash, change.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PhoneChange(uid int64) (entity.PhoneChange, error) {
+	const op = "postgres.PhoneChange"
+
+	query := `
+		SELECT user_id,
+		new_phone,
+		new_code_hash,
+		old_code_hash,
+		expires_at,
+		attempts
+		FROM phone_change_requests
+		WHERE user_id = $1
+		LIMIT 1;
+		`
+
+	var change entity.PhoneChange
+	var oldCodeHash sql.NullString
+
+	err := s.db.QueryRow(query, uid).Scan(&change.UserID, &change.NewPhone, &change.NewCodeHash,
+		&oldCodeHash, &change.ExpiresAt, &change.Attempts)
+	if err == sql.ErrNoRows {
+		return change, storage.ErrPhoneChangeNotFound
+	} else if err != nil {
+		return change, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change.OldCodeHash = oldCodeHash.String
+
+	return change, nil
+}
+
+func (s *Storage) IncPhoneChangeAttempts(uid int64) error {
+	const op = "postgres.IncPhoneChangeAttempts"
+
+	query := `
+		UPDATE phone_change_requests
+		SET attempts = attempts + 1
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletePhoneChange(uid int64) error {
+	const op = "postgres.DeletePhoneChange"
+
+	query := `
+		DELETE FROM phone_change_requests
+		WHERE user_id = $1;
+		`
+
+	_, err := s.db.Exec(query, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PhoneReleasedSince reports whether another account gave up this phone
+// after the given time.
+func (s *Storage) PhoneReleasedSince(phone string, uid int64, since time.Time) (bool, error) {
+	const op = "postgres.PhoneReleasedSince"
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM phone_history
+			WHERE phone = $1
+			AND user_id <> $2
+			AND released_at > $3
+		);
+		`
+
+	var released bool
+
+	err := s.db.QueryRow(query, phone, uid, since).Scan(&released)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return released, nil
+}
+
+// ChangeUserPhone moves the current phone to phone_history, sets the new one,
+// removes the change request and signs out every session of the user but
+// keepSessionID.
+func (s *Storage) ChangeUserPhone(uid int64, newPhone string, keepSessionID int64) error {
+	const op = "postgres.ChangeUserPhone"
+
+	historyQuery := `
+		INSERT INTO phone_history (user_id, phone)
+		SELECT id, phone
+		FROM users
+		WHERE id = $1;
+		`
+
+	query := `
+		UPDATE users
+		SET phone = $1
+		WHERE id = $2;
+		`
+
+	deleteQuery := `
+		DELETE FROM phone_change_requests
+		WHERE user_id = $1;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(historyQuery, uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(query, newPhone, uid); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return storage.ErrUserExists
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(deleteQuery, uid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := revokeOtherSessions(tx, uid, keepSessionID, "phone_changed"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
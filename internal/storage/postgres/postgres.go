@@ -3,15 +3,17 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose"
-	"vizapSSO/internal/entity"
-	"vizapSSO/internal/storage"
 )
 
 type Storage struct {
 	db *sql.DB
+	// psqlInfo opens connections outside the pool, for LISTEN.
+	psqlInfo string
 }
 
 func New(host, port, user, password, dbName string) (*Storage, error) {
@@ -31,7 +33,7 @@ func New(host, port, user, password, dbName string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	storage := &Storage{db: db}
+	storage := &Storage{db: db, psqlInfo: psqlInfo}
 
 	err = goose.Up(storage.db, "migrations")
 	if err != nil {
@@ -52,7 +54,13 @@ func (s *Storage) SaveUser(phone string, passHash []byte) (int64, error) {
 
 	var id int64
 
-	err := s.db.QueryRow(query, phone, passHash).Scan(&id)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(query, phone, passHash).Scan(&id)
 	if err != nil {
 		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
 			return 0, storage.ErrUserExists
@@ -60,6 +68,14 @@ func (s *Storage) SaveUser(phone string, passHash []byte) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := saveDomainEvent(tx, id, entity.UserRegistered, nil); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
@@ -67,15 +83,13 @@ func (s *Storage) ProvideUser(phone string) (entity.User, error) {
 	const op = "postgres.ProvideUser"
 
 	query := `
-		SELECT users.id,
-		users.password_hashed
+		SELECT` + userColumns + `
 		FROM users
 		WHERE phone = $1
 		LIMIT 1;
 		`
-	var user entity.User
 
-	err := s.db.QueryRow(query, phone).Scan(&user.ID, &user.PassHash)
+	user, err := scanUser(s.db.QueryRow(query, phone))
 	if err == sql.ErrNoRows {
 		return user, storage.ErrUserNotFound
 	} else if err != nil {
@@ -85,85 +99,107 @@ func (s *Storage) ProvideUser(phone string) (entity.User, error) {
 	return user, nil
 }
 
-func (s *Storage) SaveRefreshToken(refreshToken string, uid int64) error {
+// SaveRefreshToken stores a new refresh token of the session, deactivates
+// the previous ones and marks the session as seen. On refresh, previous
+// is the token being exchanged: it is deactivated only if it is still
+// active, under its row lock, so of two concurrent refreshes with one
+// token only the first gets a new pair and the second gets
+// storage.ErrRefreshTokenUsed. On login previous is empty.
+func (s *Storage) SaveRefreshToken(refreshToken string, uid, sessionID int64, previous string) error {
 	const op = "postgres.SaveRefreshToken"
 
-	secondQuery := `
-		INSERT INTO refresh_token (token, user_id)
-		VALUES ($1, $2);
+	consumeQuery := `
+		UPDATE refresh_token
+		SET is_active = false
+		WHERE token = $1
+		AND session_id = $2
+		AND is_active
+		RETURNING id;
 		`
 
 	query := `
 		UPDATE refresh_token
 		SET is_active = false
-		WHERE user_id = $1;
+		WHERE session_id = $1;
 		`
 
-	tx, err := s.db.Begin()
+	secondQuery := `
+		INSERT INTO refresh_token (token, user_id, session_id)
+		VALUES ($1, $2, $3);
+		`
+
+	seenQuery := `
+		UPDATE sessions
+		SET last_seen_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+		`
 
-	_, err = tx.Exec(query, uid)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback()
+
+	if previous != "" {
+		var id int64
+		err = tx.QueryRow(consumeQuery, previous, sessionID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return storage.ErrRefreshTokenUsed
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-	_, err = tx.Exec(secondQuery, refreshToken, uid)
+	_, err = tx.Exec(query, sessionID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = tx.Commit()
+	_, err = tx.Exec(secondQuery, refreshToken, uid, sessionID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
-}
-
-func (s *Storage) App(appID int32) (entity.App, error) {
-	const op = "postgres.App"
-
-	query := `
-		SELECT apps.name,
-		apps.secret,
-		apps.id
-		FROM apps
-		WHERE id = $1
-		LIMIT 1;
-		`
-	var app entity.App
+	_, err = tx.Exec(seenQuery, sessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	err := s.db.QueryRow(query, appID).Scan(&app.Name, &app.Secret, &app.ID)
-	if err == sql.ErrNoRows {
-		return app, storage.ErrAppNotFound
-	} else if err != nil {
-		return app, fmt.Errorf("%s: %w", op, err)
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return app, nil
+	return nil
 }
 
-func (s *Storage) CheckRefreshToken(refreshToken string) error {
-	const op = "postgres.CheckRefreshToken"
+// RefreshToken returns a stored refresh token, active or not. A token of a
+// revoked session is not active.
+func (s *Storage) RefreshToken(refreshToken string) (entity.RefreshToken, error) {
+	const op = "postgres.RefreshToken"
 
 	query := `
-		SELECT refresh_token.is_active
+		SELECT refresh_token.user_id,
+		refresh_token.session_id,
+		refresh_token.is_active AND sessions.revoked_at IS NULL,
+		sessions.revoked_at IS NOT NULL,
+		sessions.created_at,
+		sessions.last_seen_at
 		FROM refresh_token
+		JOIN sessions ON sessions.id = refresh_token.session_id
 		WHERE token = $1
 		LIMIT 1;
 		`
 
-	var isValid bool
+	var token entity.RefreshToken
 
-	err := s.db.QueryRow(query, refreshToken).Scan(&isValid)
+	err := s.db.QueryRow(query, refreshToken).Scan(&token.UserID, &token.SessionID, &token.IsActive,
+		&token.SessionRevoked, &token.StartedAt, &token.LastUsedAt)
 	if err == sql.ErrNoRows {
-		return storage.ErrInvalidRefreshToken
+		return token, storage.ErrInvalidRefreshToken
 	} else if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return token, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if isValid != true {
-		return fmt.Errorf("error %w", storage.ErrInvalidRefreshToken)
-	}
-
-	return nil
+	return token, nil
 }
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pressly/goose"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestStorage migrates a schema of its own in the database given by
+// SSO_TEST_POSTGRES, a lib/pq connection string, and drops it when the
+// test ends. Without the variable the test is skipped.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv("SSO_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("SSO_TEST_POSTGRES is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema + `;`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE;`); err != nil {
+			t.Error(err)
+		}
+	})
+
+	psqlInfo := dsn + " search_path=" + schema
+
+	db, err := sql.Open("postgres", psqlInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := goose.Up(db, "../../../migrations"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Storage{db: db, psqlInfo: psqlInfo}
+}
+
+// count returns the number of rows the query selects.
+func count(t *testing.T, s *Storage, query string, args ...any) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow(`SELECT count(*) FROM (`+query+`) AS selected;`, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
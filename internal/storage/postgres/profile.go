@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+)
+
+// Profile returns an empty profile with Version 0 when the user has not
+// filled it in yet.
+func (s *Storage) Profile(uid int64) (entity.Profile, error) {
+	const op = "postgres.Profile"
+
+	query := `
+		SELECT users.id,
+		COALESCE(users_data.full_name, ''),
+		COALESCE(users_data.email, ''),
+		users_data.email_verified_at IS NOT NULL,
+		COALESCE(users_data.version, 0),
+		COALESCE(users_data.updated_at, users.created_at)
+		FROM users
+		LEFT JOIN users_data ON users_data.user_id = users.id
+		WHERE users.id = $1
+		LIMIT 1;
+		`
+
+	var profile entity.Profile
+
+	err := s.db.QueryRow(query, uid).Scan(&profile.UserID, &profile.FullName, &profile.Email,
+		&profile.EmailVerified, &profile.Version, &profile.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return profile, storage.ErrUserNotFound
+	} else if err != nil {
+		return profile, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile saves the set fields of the update and bumps the version.
+// With a non-zero expectedVersion the update only happens if nobody
+// changed the profile in between. Changing the email drops its
+// verification.
+func (s *Storage) UpdateProfile(update entity.ProfileUpdate, expectedVersion int) (entity.Profile, error) {
+	const op = "postgres.UpdateProfile"
+
+	query := `
+		INSERT INTO users_data (user_id, full_name, email, version, updated_at)
+		VALUES ($1, COALESCE($2::TEXT, ''), COALESCE($3::TEXT, ''), 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE
+		SET full_name = COALESCE($2::TEXT, users_data.full_name),
+		email = COALESCE($3::TEXT, users_data.email),
+		email_verified_at = CASE
+			WHEN lower(users_data.email) = lower(COALESCE($3::TEXT, users_data.email)) THEN users_data.email_verified_at
+		END,
+		version = users_data.version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE $4 = 0 OR users_data.version = $4
+		RETURNING full_name, email, email_verified_at IS NOT NULL, version, updated_at;
+		`
+
+	profile := entity.Profile{UserID: update.UserID}
+
+	err := s.db.QueryRow(query, update.UserID, nullString(update.FullName), nullString(update.Email), expectedVersion).
+		Scan(&profile.FullName, &profile.Email, &profile.EmailVerified, &profile.Version, &profile.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return profile, storage.ErrVersionConflict
+	} else if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23503" {
+			return profile, storage.ErrUserNotFound
+		}
+		return profile, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return profile, nil
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *s, Valid: true}
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"time"
+)
+
+// SavePendingRegistration keeps the attempt counter of an existing
+// registration of the phone, so asking for a new code gives no new guesses.
+func (s *Storage) SavePendingRegistration(phone string, passHash []byte, codeHash string, expiresAt time.Time) error {
+	const op = "postgres.SavePendingRegistration"
+
+	query := `
+		INSERT INTO pending_registrations (phone, password_hashed, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (phone) DO UPDATE
+		SET password_hashed = EXCLUDED.password_hashed,
+		code_hash = EXCLUDED.code_hash,
+		expires_at = EXCLUDED.expires_at;
+		`
+
+	_, err := s.db.Exec(query, phone, passHash, codeHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PendingRegistration(phone string) (entity.PendingRegistration, error) {
+	const op = "postgres.PendingRegistration"
+
+	query := `
+		SELECT phone,
+		password_hashed,
+		code_hash,
+		expires_at,
+		attempts
+		FROM pending_registrations
+		WHERE phone = $1
+		LIMIT 1;
+		`
+
+	var reg entity.PendingRegistration
+
+	err := s.db.QueryRow(query, phone).Scan(&reg.Phone, &reg.PassHash, &reg.CodeHash, &reg.ExpiresAt, &reg.Attempts)
+	if err == sql.ErrNoRows {
+		return reg, storage.ErrRegistrationNotFound
+	} else if err != nil {
+		return reg, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reg, nil
+}
+
+func (s *Storage) IncPendingRegistrationAttempts(phone string) error {
+	const op = "postgres.IncPendingRegistrationAttempts"
+
+	query := `
+		UPDATE pending_registrations
+		SET attempts = attempts + 1
+		WHERE phone = $1;
+		`
+
+	_, err := s.db.Exec(query, phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletePendingRegistration(phone string) error {
+	const op = "postgres.DeletePendingRegistration"
+
+	query := `
+		DELETE FROM pending_registrations
+		WHERE phone = $1;
+		`
+
+	_, err := s.db.Exec(query, phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+	"time"
+)
+
+// revocationsChannel is notified by a trigger on every new revocation.
+const revocationsChannel = "sso_revocations"
+
+// revocationFeedLock is the advisory lock key that serializes appends to
+// the revocation feed until commit, so entries commit in id order and a
+// watcher never passes an id that commits later. Transactions take it
+// before the outbox and audit locks.
+const revocationFeedLock = 0x7265766f
+
+// saveRevocation adds an entry to the revocation feed in the transaction
+// of the revocation itself. User entries get the current second as not
+// before: access tokens carry iat in whole seconds, and a token issued
+// later in the same second, such as the new session after a password
+// change, must stay valid.
+func saveRevocation(tx execer, kind string, uid, sessionID int64, tokenID string) error {
+	query := `
+		INSERT INTO revocations (kind, user_id, session_id, token_id, not_before)
+		VALUES ($1, $2, $3, $4, CASE WHEN $1 = 'user' THEN date_trunc('second', CURRENT_TIMESTAMP) END);
+		`
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, revocationFeedLock); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(query, kind, uid, sessionID, tokenID)
+
+	return err
+}
+
+// Revocations returns up to limit entries of the feed after the cursor
+// afterID, oldest first. A cursor below entries that were already removed
+// gets storage.ErrCursorExpired; zero starts from the oldest entry.
+func (s *Storage) Revocations(afterID int64, limit int) ([]entity.Revocation, error) {
+	const op = "postgres.Revocations"
+
+	purgedQuery := `
+		SELECT purged_id
+		FROM revocations_purged;
+		`
+
+	query := `
+		SELECT id,
+		kind,
+		user_id,
+		session_id,
+		token_id,
+		not_before,
+		created_at
+		FROM revocations
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2;
+		`
+
+	// Both reads see one snapshot, so cleanup can't remove entries
+	// between the check and the read.
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var purgedID int64
+
+	if err := tx.QueryRow(purgedQuery).Scan(&purgedID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if afterID > 0 && afterID < purgedID {
+		return nil, storage.ErrCursorExpired
+	}
+
+	rows, err := tx.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var revocations []entity.Revocation
+
+	for rows.Next() {
+		var revocation entity.Revocation
+		var notBefore sql.NullTime
+
+		err := rows.Scan(&revocation.ID, &revocation.Kind, &revocation.UserID, &revocation.SessionID,
+			&revocation.TokenID, &notBefore, &revocation.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		revocation.NotBefore = notBefore.Time
+		revocations = append(revocations, revocation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revocations, nil
+}
+
+// DeleteRevocationsBefore removes up to limit entries created before
+// cutoff and returns how many were removed. It remembers the highest
+// removed id, see Revocations.
+func (s *Storage) DeleteRevocationsBefore(cutoff time.Time, limit int) (int64, error) {
+	const op = "postgres.DeleteRevocationsBefore"
+
+	query := `
+		WITH deleted AS (
+			DELETE FROM revocations
+			WHERE id IN (
+				SELECT id
+				FROM revocations
+				WHERE created_at < $1
+				ORDER BY id
+				LIMIT $2
+			)
+			RETURNING id
+		), purged AS (
+			UPDATE revocations_purged
+			SET purged_id = GREATEST(purged_id, (SELECT max(id) FROM deleted))
+		)
+		SELECT count(*)
+		FROM deleted;
+		`
+
+	var n int64
+
+	if err := s.db.QueryRow(query, cutoff, limit).Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// RevocationListener signals new entries of the revocation feed, made on
+// any replica. Signals are coalesced: one signal may stand for several
+// entries, and one is also sent after a reconnect, when notifications may
+// have been lost.
+type RevocationListener struct {
+	listener *pq.Listener
+	notify   chan struct{}
+}
+
+func (s *Storage) ListenRevocations() (*RevocationListener, error) {
+	const op = "postgres.ListenRevocations"
+
+	l := &RevocationListener{
+		listener: pq.NewListener(s.psqlInfo, time.Second, time.Minute, nil),
+		notify:   make(chan struct{}, 1),
+	}
+
+	if err := l.listener.Listen(revocationsChannel); err != nil {
+		l.listener.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	go l.run()
+
+	return l, nil
+}
+
+// Notify is closed when the listener is closed.
+func (l *RevocationListener) Notify() <-chan struct{} {
+	return l.notify
+}
+
+func (l *RevocationListener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *RevocationListener) run() {
+	defer close(l.notify)
+
+	// pq sends nil after a reconnect; it is a signal like any other.
+	for range l.listener.Notify {
+		select {
+		case l.notify <- struct{}{}:
+		default:
+		}
+	}
+}
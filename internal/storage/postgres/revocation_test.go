@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"slices"
+	"testing"
+	"time"
+)
+
+// revocationIDs returns the ids of the entries after afterID.
+func revocationIDs(t *testing.T, s *Storage, afterID int64) []int64 {
+	t.Helper()
+
+	revocations, err := s.Revocations(afterID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, revocation := range revocations {
+		ids = append(ids, revocation.ID)
+	}
+
+	return ids
+}
+
+func TestRevocationsCommitOrder(t *testing.T) {
+	s := newTestStorage(t)
+
+	first, err := s.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback()
+
+	if err := saveRevocation(first, entity.RevokedSession, 42, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second append gets a later id. Without the lock it could commit
+	// first, and a watcher would move its cursor past the first entry.
+	done := make(chan error, 1)
+	go func() {
+		second, err := s.db.Begin()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer second.Rollback()
+
+		if err := saveRevocation(second, entity.RevokedSession, 42, 2, ""); err != nil {
+			done <- err
+			return
+		}
+
+		done <- second.Commit()
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	if ids := revocationIDs(t, s, 0); len(ids) != 0 {
+		t.Fatalf("entries %v visible before the first append commits", ids)
+	}
+
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ids := revocationIDs(t, s, 0)
+	if len(ids) != 2 || ids[0] >= ids[1] {
+		t.Fatalf("entries = %v, want two in commit order", ids)
+	}
+
+	var sessions []int64
+	revocations, err := s.Revocations(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, revocation := range revocations {
+		sessions = append(sessions, revocation.SessionID)
+	}
+	if !slices.Equal(sessions, []int64{1, 2}) {
+		t.Errorf("sessions = %v, want [1 2]", sessions)
+	}
+}
+
+func TestRevocationsCursorExpired(t *testing.T) {
+	s := newTestStorage(t)
+
+	for session := int64(1); session <= 3; session++ {
+		if err := saveRevocation(s.db, entity.RevokedSession, 42, session, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := revocationIDs(t, s, 0)
+
+	n, err := s.DeleteRevocationsBefore(time.Now().Add(time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("deleted %d, want 2", n)
+	}
+
+	tests := []struct {
+		name    string
+		cursor  int64
+		wantIDs []int64
+		wantErr error
+	}{
+		{name: "from the start", wantIDs: ids[2:]},
+		{name: "last removed entry", cursor: ids[1], wantIDs: ids[2:]},
+		{name: "older than the retained entries", cursor: ids[0], wantErr: storage.ErrCursorExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations, err := s.Revocations(tt.cursor, 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Revocations() = %v, want %v", err, tt.wantErr)
+			}
+
+			var got []int64
+			for _, revocation := range revocations {
+				got = append(got, revocation.ID)
+			}
+			if !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("entries = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
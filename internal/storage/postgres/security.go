@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"time"
+)
+
+const securityEventColumns = `
+		security_events.id,
+		security_events.user_id,
+		security_events.type,
+		security_events.outcome,
+		security_events.method,
+		security_events.app_id,
+		security_events.ip,
+		security_events.user_agent,
+		security_events.reason,
+		security_events.created_at`
+
+func scanSecurityEvent(row rowScanner) (entity.SecurityEvent, error) {
+	var event entity.SecurityEvent
+	var appID sql.NullInt32
+
+	err := row.Scan(&event.ID, &event.UserID, &event.Type, &event.Outcome, &event.Method, &appID,
+		&event.IP, &event.UserAgent, &event.Reason, &event.CreatedAt)
+	if err != nil {
+		return event, err
+	}
+
+	event.AppID = appID.Int32
+
+	return event, nil
+}
+
+func (s *Storage) SaveSecurityEvent(event entity.SecurityEvent) error {
+	const op = "postgres.SaveSecurityEvent"
+
+	query := `
+		INSERT INTO security_events (user_id, type, outcome, method, app_id, ip, user_agent, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+		`
+
+	appID := sql.NullInt32{Int32: event.AppID, Valid: event.AppID != 0}
+
+	_, err := s.db.Exec(query, event.UserID, event.Type, event.Outcome, event.Method, appID,
+		event.IP, event.UserAgent, event.Reason)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SecurityEvents returns up to limit events of the user, newest first,
+// with id less than beforeID. A zero beforeID starts from the newest.
+func (s *Storage) SecurityEvents(uid, beforeID int64, limit int) ([]entity.SecurityEvent, error) {
+	const op = "postgres.SecurityEvents"
+
+	query := `
+		SELECT` + securityEventColumns + `
+		FROM security_events
+		WHERE user_id = $1
+		AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3;
+		`
+
+	rows, err := s.db.Query(query, uid, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []entity.SecurityEvent
+
+	for rows.Next() {
+		event, err := scanSecurityEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// DeleteSecurityEventsBefore removes up to limit events older than cutoff
+// and returns how many were removed.
+func (s *Storage) DeleteSecurityEventsBefore(cutoff time.Time, limit int) (int64, error) {
+	const op = "postgres.DeleteSecurityEventsBefore"
+
+	query := `
+		DELETE FROM security_events
+		WHERE id IN (
+			SELECT id
+			FROM security_events
+			WHERE created_at < $1
+			ORDER BY id
+			LIMIT $2
+		);
+		`
+
+	res, err := s.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+)
+
+const sessionColumns = `
+		sessions.id,
+		sessions.user_id,
+		sessions.app_id,
+		sessions.device_name,
+		sessions.platform,
+		sessions.user_agent,
+		sessions.ip,
+		sessions.location,
+		sessions.created_at,
+		sessions.last_seen_at,
+		sessions.revoked_at`
+
+// revokeUserSessionsQuery signs the user out everywhere: it revokes the
+// sessions and deactivates every refresh token, including ones issued
+// before sessions were recorded.
+const revokeUserSessionsQuery = `
+		WITH revoked AS (
+			UPDATE sessions
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1
+			AND revoked_at IS NULL
+		)
+		UPDATE refresh_token
+		SET is_active = false
+		WHERE user_id = $1;
+		`
+
+// revokeUserSessions signs the user out everywhere, see
+// revokeUserSessionsQuery, and records why.
+func revokeUserSessions(tx execer, uid int64, reason string) error {
+	if _, err := tx.Exec(revokeUserSessionsQuery, uid); err != nil {
+		return err
+	}
+
+	if err := saveRevocation(tx, entity.RevokedUser, uid, 0, ""); err != nil {
+		return err
+	}
+
+	return saveDomainEvent(tx, uid, entity.SessionRevoked, map[string]any{
+		"all_sessions": true,
+		"reason":       reason,
+	})
+}
+
+// revokeOtherSessions signs the user out of every session but keepID and
+// deactivates the refresh tokens outside it, then records why. Each
+// session gets its own revocation entry: a user entry would also cut off
+// the session that stays.
+func revokeOtherSessions(tx *sql.Tx, uid, keepID int64, reason string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+		AND id <> $2
+		AND revoked_at IS NULL
+		RETURNING id;
+		`
+
+	tokenQuery := `
+		UPDATE refresh_token
+		SET is_active = false
+		WHERE user_id = $1
+		AND session_id IS DISTINCT FROM $2;
+		`
+
+	rows, err := tx.Query(query, uid, keepID)
+	if err != nil {
+		return err
+	}
+
+	var revoked []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		revoked = append(revoked, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(tokenQuery, uid, keepID); err != nil {
+		return err
+	}
+
+	for _, id := range revoked {
+		if err := saveRevocation(tx, entity.RevokedSession, uid, id, ""); err != nil {
+			return err
+		}
+	}
+
+	return saveDomainEvent(tx, uid, entity.SessionRevoked, map[string]any{
+		"other_sessions": true,
+		"reason":         reason,
+	})
+}
+
+func scanSession(row rowScanner) (entity.Session, error) {
+	var session entity.Session
+	var appID sql.NullInt32
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&session.ID, &session.UserID, &appID, &session.Device.Name, &session.Device.Platform,
+		&session.Device.UserAgent, &session.Device.IP, &session.Device.Location, &session.CreatedAt,
+		&session.LastSeenAt, &revokedAt)
+	if err != nil {
+		return session, err
+	}
+
+	session.AppID = appID.Int32
+	session.RevokedAt = revokedAt.Time
+	session.IsActive = !revokedAt.Valid
+
+	return session, nil
+}
+
+func (s *Storage) SaveSession(session entity.Session) (entity.Session, error) {
+	const op = "postgres.SaveSession"
+
+	query := `
+		INSERT INTO sessions (user_id, app_id, device_name, platform, user_agent, ip, location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING` + sessionColumns + `;
+		`
+
+	saved, err := scanSession(s.db.QueryRow(query, session.UserID, session.AppID, session.Device.Name,
+		session.Device.Platform, session.Device.UserAgent, session.Device.IP, session.Device.Location))
+	if err != nil {
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+func (s *Storage) Session(sessionID int64) (entity.Session, error) {
+	const op = "postgres.Session"
+
+	query := `
+		SELECT` + sessionColumns + `
+		FROM sessions
+		WHERE id = $1
+		LIMIT 1;
+		`
+
+	session, err := scanSession(s.db.QueryRow(query, sessionID))
+	if err == sql.ErrNoRows {
+		return session, storage.ErrSessionNotFound
+	} else if err != nil {
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// Sessions returns every session of the user, revoked ones included,
+// newest first.
+func (s *Storage) Sessions(uid int64) ([]entity.Session, error) {
+	const op = "postgres.Sessions"
+
+	query := `
+		SELECT` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY id DESC;
+		`
+
+	rows, err := s.db.Query(query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []entity.Session
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs the user out of one session. Its refresh tokens stop
+// working at once, access tokens as soon as they are next checked.
+func (s *Storage) RevokeSession(uid, sessionID int64, reason string) error {
+	const op = "postgres.RevokeSession"
+
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		AND user_id = $2
+		AND revoked_at IS NULL;
+		`
+
+	tokenQuery := `
+		UPDATE refresh_token
+		SET is_active = false
+		WHERE session_id = $1;
+		`
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, sessionID, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrSessionNotFound
+	}
+
+	if _, err := tx.Exec(tokenQuery, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := saveRevocation(tx, entity.RevokedSession, uid, sessionID, ""); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = saveDomainEvent(tx, uid, entity.SessionRevoked, map[string]any{
+		"session_id": sessionID,
+		"reason":     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
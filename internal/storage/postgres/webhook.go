@@ -0,0 +1,404 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/storage"
+	"github.com/lib/pq"
+	"time"
+)
+
+const webhookColumns = `
+		webhooks.id,
+		webhooks.app_id,
+		webhooks.url,
+		webhooks.secret,
+		webhooks.events,
+		webhooks.created_at`
+
+func scanWebhook(row rowScanner) (entity.Webhook, error) {
+	var webhook entity.Webhook
+
+	err := row.Scan(&webhook.ID, &webhook.AppID, &webhook.URL, &webhook.Secret, pq.Array(&webhook.Events),
+		&webhook.CreatedAt)
+
+	return webhook, err
+}
+
+func (s *Storage) SaveWebhook(webhook entity.Webhook) (entity.Webhook, error) {
+	const op = "postgres.SaveWebhook"
+
+	query := `
+		INSERT INTO webhooks (app_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING` + webhookColumns + `;
+		`
+
+	webhook, err := scanWebhook(s.db.QueryRow(query, webhook.AppID, webhook.URL, webhook.Secret,
+		pq.Array(webhook.Events)))
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23503" {
+			return webhook, storage.ErrAppNotFound
+		}
+		return webhook, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) Webhook(webhookID int64) (entity.Webhook, error) {
+	const op = "postgres.Webhook"
+
+	query := `
+		SELECT` + webhookColumns + `
+		FROM webhooks
+		WHERE id = $1
+		LIMIT 1;
+		`
+
+	webhook, err := scanWebhook(s.db.QueryRow(query, webhookID))
+	if err == sql.ErrNoRows {
+		return webhook, storage.ErrWebhookNotFound
+	} else if err != nil {
+		return webhook, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) Webhooks(appID int32) ([]entity.Webhook, error) {
+	const op = "postgres.Webhooks"
+
+	query := `
+		SELECT` + webhookColumns + `
+		FROM webhooks
+		WHERE app_id = $1
+		ORDER BY id;
+		`
+
+	rows, err := s.db.Query(query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []entity.Webhook
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook together with its deliveries.
+func (s *Storage) DeleteWebhook(appID int32, webhookID int64) error {
+	const op = "postgres.DeleteWebhook"
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1
+		AND app_id = $2;
+		`
+
+	res, err := s.db.Exec(query, webhookID, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+const webhookDeliveryColumns = `
+		webhook_deliveries.id,
+		webhook_deliveries.webhook_id,
+		webhook_deliveries.event_id,
+		webhook_deliveries.event_type,
+		webhook_deliveries.body,
+		webhook_deliveries.status,
+		webhook_deliveries.attempts,
+		webhook_deliveries.next_attempt_at,
+		webhook_deliveries.last_status_code,
+		webhook_deliveries.last_error,
+		webhook_deliveries.created_at,
+		webhook_deliveries.delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	var body string
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &body,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode,
+		&delivery.LastError, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return delivery, err
+	}
+
+	delivery.Body = []byte(body)
+	delivery.DeliveredAt = deliveredAt.Time
+
+	return delivery, nil
+}
+
+// EnqueueWebhookDeliveries creates a delivery of the event of user uid
+// for every webhook subscribed to its type whose app the user has had a
+// session with. Enqueueing the same event again does nothing, so the
+// outbox relay may retry it. It returns the number of new deliveries.
+func (s *Storage) EnqueueWebhookDeliveries(eventID, uid int64, eventType string, body []byte) (int64, error) {
+	const op = "postgres.EnqueueWebhookDeliveries"
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, body)
+		SELECT webhooks.id, $1, $3, $4
+		FROM webhooks
+		WHERE (cardinality(webhooks.events) = 0 OR $3 = ANY(webhooks.events))
+		AND EXISTS (
+			SELECT 1
+			FROM sessions
+			WHERE sessions.app_id = webhooks.app_id
+			AND sessions.user_id = $2
+		)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING;
+		`
+
+	res, err := s.db.Exec(query, eventID, uid, eventType, string(body))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+// due at now, skipping webhooks of disabled apps. Claimed deliveries are
+// not due again until leaseUntil, so other replicas don't send them at the
+// same time and a crashed sender's deliveries are retried later.
+func (s *Storage) ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	const op = "postgres.ClaimWebhookDeliveries"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT webhook_deliveries.id
+			FROM webhook_deliveries
+			JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+			JOIN apps ON apps.id = webhooks.app_id
+			WHERE webhook_deliveries.status = 'pending'
+			AND webhook_deliveries.next_attempt_at <= $1
+			AND NOT apps.is_disabled
+			ORDER BY webhook_deliveries.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF webhook_deliveries SKIP LOCKED
+		)
+		RETURNING` + webhookDeliveryColumns + `;
+		`
+
+	rows, err := s.db.Query(query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []entity.WebhookDelivery
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) WebhookDelivered(deliveryID int64, statusCode int) error {
+	const op = "postgres.WebhookDelivered"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered',
+		attempts = attempts + 1,
+		last_status_code = $2,
+		last_error = '',
+		delivered_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+		`
+
+	if _, err := s.db.Exec(query, deliveryID, statusCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// WebhookDeliveryFailed records a failed attempt. The delivery is tried
+// again at nextAttemptAt, or goes to the dead-letter list when dead.
+func (s *Storage) WebhookDeliveryFailed(deliveryID int64, statusCode int, lastError string,
+	nextAttemptAt time.Time, dead bool) error {
+	const op = "postgres.WebhookDeliveryFailed"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $5::boolean THEN 'dead' ELSE 'pending' END,
+		attempts = attempts + 1,
+		last_status_code = $2,
+		last_error = $3,
+		next_attempt_at = $4
+		WHERE id = $1;
+		`
+
+	if _, err := s.db.Exec(query, deliveryID, statusCode, lastError, nextAttemptAt, dead); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// WebhookDeliveries returns deliveries of the webhook with the status, or
+// with any status when it is empty, newest first.
+func (s *Storage) WebhookDeliveries(webhookID int64, status string, beforeID int64, limit int,
+) ([]entity.WebhookDelivery, error) {
+	const op = "postgres.WebhookDeliveries"
+
+	query := `
+		SELECT` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		AND ($2 = '' OR status = $2)
+		AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4;
+		`
+
+	rows, err := s.db.Query(query, webhookID, status, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []entity.WebhookDelivery
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery sends a delivered or dead delivery again with a
+// fresh set of attempts.
+func (s *Storage) ReplayWebhookDelivery(deliveryID int64) error {
+	const op = "postgres.ReplayWebhookDelivery"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending',
+		attempts = 0,
+		next_attempt_at = CURRENT_TIMESTAMP,
+		delivered_at = NULL
+		WHERE id = $1
+		AND status <> 'pending';
+		`
+
+	res, err := s.db.Exec(query, deliveryID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+// ReplayDeadWebhookDeliveries moves every dead delivery of the webhook
+// back to pending and returns how many there were.
+func (s *Storage) ReplayDeadWebhookDeliveries(webhookID int64) (int64, error) {
+	const op = "postgres.ReplayDeadWebhookDeliveries"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending',
+		attempts = 0,
+		next_attempt_at = CURRENT_TIMESTAMP
+		WHERE webhook_id = $1
+		AND status = 'dead';
+		`
+
+	res, err := s.db.Exec(query, webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// DeleteDeliveredWebhookDeliveries removes up to limit deliveries
+// delivered before cutoff and returns how many were removed. Dead ones
+// stay until they are replayed or the webhook is deleted.
+func (s *Storage) DeleteDeliveredWebhookDeliveries(cutoff time.Time, limit int) (int64, error) {
+	const op = "postgres.DeleteDeliveredWebhookDeliveries"
+
+	query := `
+		DELETE FROM webhook_deliveries
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'delivered'
+			AND delivered_at < $1
+			ORDER BY id
+			LIMIT $2
+		);
+		`
+
+	res, err := s.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -3,8 +3,22 @@ package storage
 import "errors"
 
 var (
-	ErrUserExists          = errors.New("user already exists")
-	ErrUserNotFound        = errors.New("user not found")
-	ErrAppNotFound         = errors.New("app not found")
-	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrUserExists           = errors.New("user already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrAppNotFound          = errors.New("app not found")
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRefreshTokenUsed     = errors.New("refresh token was already used")
+	ErrRegistrationNotFound = errors.New("pending registration not found")
+	ErrPhoneChangeNotFound  = errors.New("phone change request not found")
+	ErrVersionConflict      = errors.New("record was changed by another request")
+	ErrEmailExists          = errors.New("email is verified by another user")
+	ErrVerificationNotFound = errors.New("email verification not found")
+	ErrResetNotFound        = errors.New("password reset not found")
+	ErrAddressNotFound      = errors.New("address not found")
+	ErrAppExists            = errors.New("app already exists")
+	ErrAppSecretNotFound    = errors.New("app secret not found")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrCursorExpired        = errors.New("cursor is older than the retained entries")
 )
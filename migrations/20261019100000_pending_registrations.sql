@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pending_registrations (
+                                                     phone VARCHAR(15) PRIMARY KEY,
+                                                     password_hashed TEXT NOT NULL,
+                                                     code_hash TEXT NOT NULL,
+                                                     attempts INT NOT NULL DEFAULT 0,
+                                                     expires_at TIMESTAMP NOT NULL,
+                                                     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pending_registrations;
+-- +goose StatementEnd
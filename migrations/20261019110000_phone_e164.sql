@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- E.164 allows 15 digits plus the leading "+".
+ALTER TABLE users ALTER COLUMN phone TYPE VARCHAR(16);
+ALTER TABLE pending_registrations ALTER COLUMN phone TYPE VARCHAR(16);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE pending_registrations ALTER COLUMN phone TYPE VARCHAR(15);
+ALTER TABLE users ALTER COLUMN phone TYPE VARCHAR(15);
+-- +goose StatementEnd
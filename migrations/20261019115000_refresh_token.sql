@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The code has always used refresh_token, while the first migration created
+-- refresh_tokens, so fresh databases had no table for refresh tokens.
+CREATE TABLE IF NOT EXISTS refresh_token (
+                                             id SERIAL PRIMARY KEY,
+                                             token TEXT NOT NULL,
+                                             user_id INT REFERENCES users(id),
+                                             is_active BOOLEAN NOT NULL DEFAULT TRUE,
+                                             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_token ON refresh_token(token);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user_id ON refresh_token(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_refresh_token_user_id;
+DROP INDEX IF EXISTS idx_refresh_token_token;
+DROP TABLE IF EXISTS refresh_token;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS phone_change_requests (
+                                                     user_id INT PRIMARY KEY REFERENCES users(id),
+                                                     new_phone VARCHAR(16) NOT NULL,
+                                                     new_code_hash TEXT NOT NULL,
+                                                     old_code_hash TEXT,
+                                                     attempts INT NOT NULL DEFAULT 0,
+                                                     expires_at TIMESTAMP NOT NULL,
+                                                     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS phone_history (
+                                             id SERIAL PRIMARY KEY,
+                                             user_id INT NOT NULL REFERENCES users(id),
+                                             phone VARCHAR(16) NOT NULL,
+                                             released_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_phone_history_user_id ON phone_history(user_id);
+CREATE INDEX IF NOT EXISTS idx_phone_history_phone ON phone_history(phone);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS phone_history;
+DROP TABLE IF EXISTS phone_change_requests;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One profile per user. Duplicate rows are not dropped here: which of
+-- them holds the real data is for a person to decide, so the migration
+-- stops and lists the users to merge by hand.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(user_id::TEXT, ', ' ORDER BY user_id)
+    INTO duplicates
+    FROM (
+        SELECT user_id
+        FROM users_data
+        GROUP BY user_id
+        HAVING COUNT(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users_data has several rows for users %; merge them and run the migration again', duplicates;
+    END IF;
+END $$;
+
+ALTER TABLE users_data ALTER COLUMN full_name SET DEFAULT '';
+ALTER TABLE users_data ALTER COLUMN email SET DEFAULT '';
+ALTER TABLE users_data ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE users_data ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_users_data_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_data_user_id ON users_data(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_data_user_id;
+CREATE INDEX IF NOT EXISTS idx_users_data_user_id ON users_data(user_id);
+
+ALTER TABLE users_data DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users_data DROP COLUMN IF EXISTS version;
+ALTER TABLE users_data ALTER COLUMN email DROP DEFAULT;
+ALTER TABLE users_data ALTER COLUMN full_name DROP DEFAULT;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users_data ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Only verified emails identify a user.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_data_verified_email
+    ON users_data (lower(email))
+    WHERE email_verified_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+                                                   user_id INT PRIMARY KEY REFERENCES users(id),
+                                                   email VARCHAR(255) NOT NULL,
+                                                   code_hash TEXT NOT NULL,
+                                                   attempts INT NOT NULL DEFAULT 0,
+                                                   expires_at TIMESTAMP NOT NULL,
+                                                   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+                                               token_hash TEXT PRIMARY KEY,
+                                               user_id INT NOT NULL REFERENCES users(id),
+                                               expires_at TIMESTAMP NOT NULL,
+                                               used_at TIMESTAMP,
+                                               created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS email_verifications;
+DROP INDEX IF EXISTS idx_users_data_verified_email;
+ALTER TABLE users_data DROP COLUMN IF EXISTS email_verified_at;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS city VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS street VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS building VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS apartment VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- At most one default address per user.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default
+    ON addresses(user_id)
+    WHERE is_default AND NOT is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_addresses_default;
+
+ALTER TABLE addresses DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE addresses DROP COLUMN IF EXISTS created_at;
+ALTER TABLE addresses DROP COLUMN IF EXISTS is_default;
+ALTER TABLE addresses DROP COLUMN IF EXISTS longitude;
+ALTER TABLE addresses DROP COLUMN IF EXISTS latitude;
+ALTER TABLE addresses DROP COLUMN IF EXISTS apartment;
+ALTER TABLE addresses DROP COLUMN IF EXISTS building;
+ALTER TABLE addresses DROP COLUMN IF EXISTS street;
+ALTER TABLE addresses DROP COLUMN IF EXISTS city;
+-- +goose StatementEnd
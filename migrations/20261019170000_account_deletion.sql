@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_after TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after
+    ON users(purge_after)
+    WHERE is_deleted AND purged_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS deletion_codes (
+                                              user_id INT PRIMARY KEY REFERENCES users(id),
+                                              code_hash TEXT NOT NULL,
+                                              attempts INT NOT NULL DEFAULT 0,
+                                              expires_at TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS deletion_codes;
+DROP INDEX IF EXISTS idx_users_purge_after;
+ALTER TABLE users DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE users DROP COLUMN IF EXISTS purge_after;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_until TIMESTAMP;
+
+UPDATE users
+SET status = 'deleted',
+    status_changed_at = deleted_at
+WHERE is_deleted;
+
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('pending', 'active', 'locked', 'suspended', 'deleted'));
+
+DROP INDEX IF EXISTS idx_users_purge_after;
+ALTER TABLE users DROP COLUMN IF EXISTS is_deleted;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after
+    ON users(purge_after)
+    WHERE status = 'deleted' AND purged_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users
+SET is_deleted = true
+WHERE status = 'deleted';
+
+DROP INDEX IF EXISTS idx_users_purge_after;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after
+    ON users(purge_after)
+    WHERE is_deleted AND purged_at IS NULL;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users DROP COLUMN IF EXISTS status_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_by;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS admin_roles (
+                                           subject VARCHAR(255) NOT NULL,
+                                           role VARCHAR(32) NOT NULL,
+                                           granted_by VARCHAR(255) NOT NULL,
+                                           granted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                           PRIMARY KEY (subject, role)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS admin_roles;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+-- +goose StatementEnd
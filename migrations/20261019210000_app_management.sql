@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apps RENAME COLUMN secret TO signing_key;
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_secret_key;
+
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS is_disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL DEFAULT '{password,refresh_token}';
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS access_token_ttl_seconds BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS refresh_token_ttl_seconds BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS retired_signing_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS signing_key_rotated_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS app_secrets (
+                                           id SERIAL PRIMARY KEY,
+                                           app_id INT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+                                           secret_hash TEXT NOT NULL UNIQUE,
+                                           hint VARCHAR(8) NOT NULL,
+                                           created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                           expires_at TIMESTAMP,
+                                           revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_secrets_app_id ON app_secrets(app_id);
+
+-- The old secret becomes the first client secret of each app. Apps know
+-- it, so it can't sign refresh tokens any more: each app gets a random
+-- signing key, and the old one only verifies the refresh tokens it signed
+-- until they expire. gen_random_uuid is built in since PostgreSQL 13.
+INSERT INTO app_secrets (app_id, secret_hash, hint)
+SELECT id, encode(sha256(convert_to(signing_key, 'UTF8')), 'hex'), right(signing_key, 4)
+FROM apps;
+
+UPDATE apps
+SET retired_signing_key = signing_key,
+signing_key = replace(gen_random_uuid()::TEXT || gen_random_uuid()::TEXT, '-', ''),
+signing_key_rotated_at = CURRENT_TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS app_secrets;
+
+UPDATE apps
+SET signing_key = retired_signing_key
+WHERE retired_signing_key <> '';
+
+ALTER TABLE apps DROP COLUMN IF EXISTS signing_key_rotated_at;
+ALTER TABLE apps DROP COLUMN IF EXISTS retired_signing_key;
+ALTER TABLE apps DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE apps DROP COLUMN IF EXISTS created_at;
+ALTER TABLE apps DROP COLUMN IF EXISTS refresh_token_ttl_seconds;
+ALTER TABLE apps DROP COLUMN IF EXISTS access_token_ttl_seconds;
+ALTER TABLE apps DROP COLUMN IF EXISTS redirect_uris;
+ALTER TABLE apps DROP COLUMN IF EXISTS grant_types;
+ALTER TABLE apps DROP COLUMN IF EXISTS is_disabled;
+
+ALTER TABLE apps RENAME COLUMN signing_key TO secret;
+ALTER TABLE apps ADD CONSTRAINT apps_secret_key UNIQUE (secret);
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS session_lifetime_seconds BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS idle_timeout_seconds BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS sliding_refresh BOOLEAN;
+
+-- Refreshed tokens keep the time of the login they descend from.
+ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_token DROP COLUMN IF EXISTS session_started_at;
+
+ALTER TABLE apps DROP COLUMN IF EXISTS sliding_refresh;
+ALTER TABLE apps DROP COLUMN IF EXISTS idle_timeout_seconds;
+ALTER TABLE apps DROP COLUMN IF EXISTS session_lifetime_seconds;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sessions (
+                                        id BIGSERIAL PRIMARY KEY,
+                                        user_id INT NOT NULL REFERENCES users(id),
+                                        app_id INT REFERENCES apps(id) ON DELETE SET NULL,
+                                        device_name VARCHAR(128) NOT NULL DEFAULT '',
+                                        platform VARCHAR(32) NOT NULL DEFAULT '',
+                                        user_agent TEXT NOT NULL DEFAULT '',
+                                        ip VARCHAR(45) NOT NULL DEFAULT '',
+                                        location TEXT NOT NULL DEFAULT '',
+                                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                        last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                        revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS session_id BIGINT REFERENCES sessions(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_session_id ON refresh_token(session_id);
+
+-- A user had at most one active refresh token, so every active token
+-- becomes a session of its own.
+INSERT INTO sessions (user_id, created_at, last_seen_at)
+SELECT user_id, session_started_at, last_used_at
+FROM refresh_token
+WHERE is_active;
+
+UPDATE refresh_token
+SET session_id = sessions.id
+FROM sessions
+WHERE refresh_token.is_active
+AND sessions.user_id = refresh_token.user_id;
+
+ALTER TABLE refresh_token DROP COLUMN IF EXISTS session_started_at;
+ALTER TABLE refresh_token DROP COLUMN IF EXISTS last_used_at;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE refresh_token
+SET session_started_at = sessions.created_at,
+last_used_at = sessions.last_seen_at
+FROM sessions
+WHERE sessions.id = refresh_token.session_id;
+
+DROP INDEX IF EXISTS idx_refresh_token_session_id;
+ALTER TABLE refresh_token DROP COLUMN IF EXISTS session_id;
+
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS security_events (
+                                               id BIGSERIAL PRIMARY KEY,
+                                               user_id INT NOT NULL REFERENCES users(id),
+                                               type VARCHAR(32) NOT NULL,
+                                               outcome VARCHAR(16) NOT NULL,
+                                               method VARCHAR(32) NOT NULL DEFAULT '',
+                                               app_id INT,
+                                               ip VARCHAR(45) NOT NULL DEFAULT '',
+                                               user_agent TEXT NOT NULL DEFAULT '',
+                                               reason VARCHAR(64) NOT NULL DEFAULT '',
+                                               created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS security_events;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- locked_until is when the lease of the relay that claimed the event ends.
+CREATE TABLE IF NOT EXISTS outbox (
+                                      id BIGSERIAL PRIMARY KEY,
+                                      user_id INT NOT NULL,
+                                      type VARCHAR(64) NOT NULL,
+                                      payload JSONB NOT NULL DEFAULT '{}',
+                                      created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                      published_at TIMESTAMP,
+                                      locked_until TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished_user_id ON outbox(user_id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
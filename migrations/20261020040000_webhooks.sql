@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhooks (
+                                        id BIGSERIAL PRIMARY KEY,
+                                        app_id INT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+                                        url TEXT NOT NULL,
+                                        secret TEXT NOT NULL,
+                                        events TEXT[] NOT NULL DEFAULT '{}',
+                                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_app_id ON webhooks(app_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+                                                  id BIGSERIAL PRIMARY KEY,
+                                                  webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+                                                  event_id BIGINT NOT NULL,
+                                                  event_type VARCHAR(64) NOT NULL,
+                                                  body TEXT NOT NULL,
+                                                  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+                                                  attempts INT NOT NULL DEFAULT 0,
+                                                  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                  last_status_code INT NOT NULL DEFAULT 0,
+                                                  last_error TEXT NOT NULL DEFAULT '',
+                                                  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                  delivered_at TIMESTAMP,
+                                                  UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, status, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivered_at ON webhook_deliveries(delivered_at) WHERE status = 'delivered';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS revocations (
+                                           id BIGSERIAL PRIMARY KEY,
+                                           kind VARCHAR(16) NOT NULL,
+                                           user_id INT NOT NULL,
+                                           session_id BIGINT NOT NULL DEFAULT 0,
+                                           token_id VARCHAR(64) NOT NULL DEFAULT '',
+                                           not_before TIMESTAMP,
+                                           created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revocations_created_at ON revocations(created_at);
+
+-- purged_id is the highest id cleanup has removed: a watcher with a lower
+-- cursor has missed entries.
+CREATE TABLE IF NOT EXISTS revocations_purged (
+                                                  purged_id BIGINT NOT NULL
+);
+
+INSERT INTO revocations_purged (purged_id) VALUES (0);
+
+-- Every replica listens on the channel, so a revocation made on one of
+-- them reaches the watchers of all. The notification is sent on commit.
+CREATE OR REPLACE FUNCTION revocations_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('sso_revocations', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS revocations_notify ON revocations;
+CREATE TRIGGER revocations_notify
+    AFTER INSERT ON revocations
+    FOR EACH ROW EXECUTE FUNCTION revocations_notify();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS revocations_notify ON revocations;
+DROP FUNCTION IF EXISTS revocations_notify();
+DROP TABLE IF EXISTS revocations_purged;
+DROP TABLE IF EXISTS revocations;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per SMS or email with a code, to cap how many a phone or email
+-- gets in a window.
+CREATE TABLE IF NOT EXISTS code_sends (
+                                          id BIGSERIAL PRIMARY KEY,
+                                          purpose VARCHAR(32) NOT NULL,
+                                          target VARCHAR(255) NOT NULL,
+                                          sent_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_sends_target ON code_sends(purpose, target, sent_at);
+CREATE INDEX IF NOT EXISTS idx_code_sends_sent_at ON code_sends(sent_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS code_sends;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Password attempts by login: "user:<id>" for users, "login:<phone or
+-- email>" for logins that match no user, with "|ip:<ip>" of the client.
+CREATE TABLE IF NOT EXISTS login_failures (
+                                              id BIGSERIAL PRIMARY KEY,
+                                              key VARCHAR(255) NOT NULL,
+                                              failed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_key ON login_failures(key, failed_at);
+CREATE INDEX IF NOT EXISTS idx_login_failures_failed_at ON login_failures(failed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users purged before PurgeUser scrubbed events still have their data in
+-- outbox payloads and webhook bodies.
+UPDATE webhook_deliveries
+SET body = jsonb_set(body::JSONB, '{payload}', jsonb_build_object('user_id', outbox.user_id))::TEXT
+FROM outbox
+         JOIN users ON users.id = outbox.user_id
+WHERE webhook_deliveries.event_id = outbox.id
+  AND users.purged_at IS NOT NULL;
+
+UPDATE outbox
+SET payload = jsonb_build_object('user_id', outbox.user_id)
+FROM users
+WHERE users.id = outbox.user_id
+  AND users.purged_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The scrubbed data is gone for good.
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- user.registered used to carry the phone number; events only identify
+-- the user now.
+UPDATE webhook_deliveries
+SET body = jsonb_set(body::JSONB, '{payload}', jsonb_build_object('user_id', outbox.user_id))::TEXT
+FROM outbox
+WHERE webhook_deliveries.event_id = outbox.id
+  AND outbox.type = 'user.registered';
+
+UPDATE outbox
+SET payload = jsonb_build_object('user_id', user_id)
+WHERE type = 'user.registered';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The removed phone numbers are gone for good.
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- scopes go into the scope claim of every access token of the app.
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- Roles of a user in an app, issued in the roles claim of its tokens.
+CREATE TABLE IF NOT EXISTS user_app_roles (
+                                              user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              app_id INT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+                                              role VARCHAR(64) NOT NULL,
+                                              granted_by VARCHAR(255) NOT NULL,
+                                              created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                              PRIMARY KEY (user_id, app_id, role)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_app_roles;
+ALTER TABLE apps DROP COLUMN IF EXISTS scopes;
+-- +goose StatementEnd
@@ -0,0 +1,121 @@
+package ssoauth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor authenticates calls by the bearer token in the
+// authorization metadata and checks them against the rule of the method,
+// keyed by full method name. Methods missing from rules are denied.
+func UnaryServerInterceptor(verifier Verifier, rules map[string]Rule) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authenticateCall(ctx, verifier, rules, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(verifier Verifier, rules map[string]Rule) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authenticateCall(ss.Context(), verifier, rules, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticateCall(ctx context.Context, verifier Verifier, rules map[string]Rule, method string,
+) (context.Context, error) {
+	rule, ok := rules[method]
+	if !ok {
+		return nil, grpcStatus(ErrUnknownMethod)
+	}
+
+	token, err := bearerToken(firstValue(ctx, "authorization"))
+	if err != nil {
+		if rule.Public {
+			return ctx, nil
+		}
+		return nil, grpcStatus(err)
+	}
+
+	ctx, err = authenticate(ctx, verifier, rule, token)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+
+	return ctx, nil
+}
+
+// authenticate verifies the token, checks the rule and puts the principal
+// into the context. It is shared by the interceptors and the middleware.
+func authenticate(ctx context.Context, verifier Verifier, rule Rule, token string) (context.Context, error) {
+	p, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rule.Allows(p); err != nil {
+		return nil, err
+	}
+
+	return NewContext(ctx, p), nil
+}
+
+// grpcStatus uses the messages of the SSO itself, so clients handle them
+// the same way.
+func grpcStatus(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, ErrUnavailable):
+		return status.Error(codes.Unavailable, "Сервис перегружен. Попробуйте позже.")
+	case errors.Is(err, ErrNoToken), errors.Is(err, ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "Сессия недействительна. Войдите заново.")
+	}
+
+	return status.Error(codes.PermissionDenied, "Недостаточно прав")
+}
+
+func firstValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,50 @@
+package ssoauth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Middleware authenticates requests by the bearer token in the
+// Authorization header and checks them against rule. Wrap each route with
+// the rule it needs.
+func Middleware(verifier Verifier, rule Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := bearerToken(r.Header.Get("Authorization"))
+			if err != nil {
+				if rule.Public {
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeError(w, err)
+				return
+			}
+
+			ctx, err := authenticate(r.Context(), verifier, rule, token)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeError answers as RFC 6750 describes for bearer tokens.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNoToken):
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	case errors.Is(err, ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	case errors.Is(err, ErrForbidden):
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,268 @@
+package ssoauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetch limits how often a token with an unknown kid makes the
+// key set be fetched again, so forged kids can't flood its server.
+const jwksMinRefetch = 30 * time.Second
+
+const jwksFetchTimeout = 10 * time.Second
+
+var errBadKey = errors.New("malformed key")
+
+// JWKS is a KeySource backed by a JSON Web Key Set. The set is cached and
+// fetched again every refresh, or earlier when a token names a key it
+// doesn't have, which picks up rotated keys. When the set can't be fetched
+// the cached keys stay in use. Lookups that need a fetch share the one in
+// progress; the others go on with the cached keys meanwhile.
+type JWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+	call      *fetchCall
+}
+
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+func NewJWKS(url string, refresh time.Duration, client *http.Client) *JWKS {
+	return &JWKS{
+		url:     url,
+		refresh: refresh,
+		client:  client,
+	}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string) (any, error) {
+	const op = "ssoauth.JWKS.Key"
+
+	if call := j.startFetch(j.refresh, false); call != nil {
+		if err := call.wait(ctx); err != nil && !j.hasKeys() {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+
+	if call := j.startFetch(jwksMinRefetch, true); call != nil {
+		if err := call.wait(ctx); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if key, ok := j.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidToken
+}
+
+// startFetch starts a fetch if the last one started at least after ago and
+// none is in progress. It returns the fetch to wait for: the new one, or
+// the one in progress if the caller needs a key it lacks or there are no
+// keys yet. It is nil when there is nothing to wait for.
+func (j *JWKS) startFetch(after time.Duration, needKey bool) *fetchCall {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.call != nil {
+		if needKey || j.keys == nil {
+			return j.call
+		}
+		return nil
+	}
+
+	if time.Since(j.fetchedAt) < after {
+		return nil
+	}
+
+	// A failed fetch counts too, so a down server isn't asked on every
+	// request.
+	j.fetchedAt = time.Now()
+	j.call = &fetchCall{done: make(chan struct{})}
+	go j.fetch(j.call)
+
+	return j.call
+}
+
+// wait returns the error of the fetch. A canceled ctx stops the wait, not
+// the fetch other lookups may share.
+func (c *fetchCall) wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *JWKS) hasKeys() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.keys != nil
+}
+
+// lookup finds the key by kid. A token without kid matches a set of one.
+func (j *JWKS) lookup(kid string) (any, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+
+	key, ok := j.keys[kid]
+
+	return key, ok
+}
+
+func (j *JWKS) fetch(call *fetchCall) {
+	keys, err := j.load()
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+	}
+	j.call = nil
+	j.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// load fetches the set and returns its signing keys by kid.
+func (j *JWKS) load() (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// Keys of unknown types, including symmetric "oct" keys, are
+		// skipped, not fatal: the set may hold keys for other consumers.
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the key in the form jwt expects for its type.
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errBadKey
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errBadKey
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errBadKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errBadKey
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, errBadKey
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errBadKey
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
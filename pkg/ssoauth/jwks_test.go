@@ -0,0 +1,195 @@
+package ssoauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWKS(t *testing.T) {
+	key := testKey(t)
+	x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	secret := base64.RawURLEncoding.EncodeToString([]byte("shared-secret"))
+
+	set := fmt.Sprintf(`{"keys":[
+		{"kty":"OKP","crv":"Ed25519","x":%q,"kid":%q,"use":"sig","alg":"EdDSA"},
+		{"kty":"oct","k":%q,"kid":"hmac"},
+		{"kty":"OKP","crv":"Ed25519","x":%q,"kid":"encryption","use":"enc"}
+	]}`, x, testKid, secret, x)
+
+	tests := []struct {
+		name    string
+		token   string
+		down    bool
+		wantErr error
+	}{
+		{name: "published key", token: testToken(t, jwt.SigningMethodEdDSA, key, testKid, nil)},
+		{name: "unknown kid", token: testToken(t, jwt.SigningMethodEdDSA, key, "other", nil), wantErr: ErrInvalidToken},
+		{name: "symmetric key is ignored", token: testToken(t, jwt.SigningMethodHS256, []byte("shared-secret"), "hmac", nil), wantErr: ErrInvalidToken},
+		{name: "encryption key is ignored", token: testToken(t, jwt.SigningMethodEdDSA, key, "encryption", nil), wantErr: ErrInvalidToken},
+		{name: "server down", token: testToken(t, jwt.SigningMethodEdDSA, key, testKid, nil), down: true, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.down {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(set))
+			}))
+			defer server.Close()
+
+			jwks := NewJWKS(server.URL, time.Hour, server.Client())
+
+			_, err := NewOfflineVerifier(jwks, nil).Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSKeepsKeysWhenDown(t *testing.T) {
+	key := testKey(t)
+	x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name string
+		kid  string
+		// wantErr is the error once the server is down.
+		wantErr error
+	}{
+		{name: "cached key", kid: testKid},
+		{name: "unknown kid", kid: "other", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if down {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprintf(w, `{"keys":[{"kty":"OKP","crv":"Ed25519","x":%q,"kid":%q}]}`, x, testKid)
+			}))
+			defer server.Close()
+
+			// Every lookup fetches the set again.
+			jwks := NewJWKS(server.URL, 0, server.Client())
+
+			if _, err := jwks.Key(context.Background(), testKid); err != nil {
+				t.Fatal(err)
+			}
+
+			down = true
+
+			if _, err := jwks.Key(context.Background(), tt.kid); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Key(%q) = %v, want %v", tt.kid, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSSharesFetch(t *testing.T) {
+	key := testKey(t)
+	x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name    string
+		callers int
+	}{
+		{name: "one caller", callers: 1},
+		{name: "many callers", callers: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches.Add(1)
+				<-release
+				fmt.Fprintf(w, `{"keys":[{"kty":"OKP","crv":"Ed25519","x":%q,"kid":%q}]}`, x, testKid)
+			}))
+			defer server.Close()
+
+			jwks := NewJWKS(server.URL, time.Hour, server.Client())
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := jwks.Key(context.Background(), testKid); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+
+			// Let the callers pile up on the fetch.
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if n := fetches.Load(); n != 1 {
+				t.Errorf("fetched %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestJWKSServesCachedKeysDuringFetch(t *testing.T) {
+	key := testKey(t)
+	x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+
+	var slow atomic.Bool
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-release
+		}
+		fmt.Fprintf(w, `{"keys":[{"kty":"OKP","crv":"Ed25519","x":%q,"kid":%q}]}`, x, testKid)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	jwks := NewJWKS(server.URL, time.Hour, server.Client())
+	if _, err := jwks.Key(context.Background(), testKid); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown kid starts a fetch that hangs.
+	slow.Store(true)
+	jwks.fetchedAt = time.Time{}
+	go jwks.Key(context.Background(), "other")
+
+	for jwks.inFlight() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := jwks.Key(ctx, testKid); err != nil {
+		t.Errorf("Key() of a cached key during a fetch = %v", err)
+	}
+}
+
+func (j *JWKS) inFlight() *fetchCall {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.call
+}
@@ -0,0 +1,215 @@
+package ssoauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/KVSH-user/vizapSSO/pkg/ssoclient"
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxCachedTokens bounds the cache of OnlineVerifier.
+const maxCachedTokens = 10000
+
+// Verifier checks an access token and returns its principal. It returns
+// ErrInvalidToken for tokens that must be rejected and ErrUnavailable when
+// the token can't be checked right now.
+type Verifier interface {
+	Verify(ctx context.Context, accessToken string) (Principal, error)
+}
+
+// KeySource returns the public key the kid header of a token names.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (any, error)
+}
+
+// Keys are public keys of the SSO by kid, for services given the keys
+// instead of the JWKS url. The SSO signs access tokens with Ed25519, so
+// the values are usually ed25519.PublicKey.
+type Keys map[string]crypto.PublicKey
+
+func (k Keys) Key(ctx context.Context, kid string) (any, error) {
+	if kid == "" && len(k) == 1 {
+		for _, key := range k {
+			return key, nil
+		}
+	}
+
+	key, ok := k[kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return key, nil
+}
+
+// OfflineVerifier checks the signature and expiry of tokens itself. It
+// doesn't see sessions revoked before their tokens expire; services that
+// need that should also follow WatchRevocations or use OnlineVerifier.
+type OfflineVerifier struct {
+	keys   KeySource
+	apps   []int32
+	parser *jwt.Parser
+}
+
+// NewOfflineVerifier accepts tokens of the apps, or of any app when apps
+// is empty.
+func NewOfflineVerifier(keys KeySource, apps []int32) *OfflineVerifier {
+	return &OfflineVerifier{
+		keys: keys,
+		apps: apps,
+		parser: jwt.NewParser(
+			// Only asymmetric algorithms: a verifier never holds a key
+			// that could sign tokens.
+			jwt.WithValidMethods([]string{"EdDSA", "RS256", "ES256"}),
+			jwt.WithExpirationRequired(),
+		),
+	}
+}
+
+func (v *OfflineVerifier) Verify(ctx context.Context, accessToken string) (Principal, error) {
+	const op = "ssoauth.OfflineVerifier.Verify"
+
+	var keyErr error
+
+	token, err := v.parser.Parse(accessToken, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := v.keys.Key(ctx, kid)
+		keyErr = err
+
+		return key, err
+	})
+	if err != nil {
+		// A key set that can't be fetched is an outage, not a bad token.
+		if keyErr != nil && !errors.Is(keyErr, ErrInvalidToken) {
+			return Principal{}, fmt.Errorf("%s: %w: %w", op, ErrUnavailable, keyErr)
+		}
+		return Principal{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	p, err := principalFromClaims(token.Claims.(jwt.MapClaims))
+	if err != nil {
+		return Principal{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(v.apps) > 0 && !slices.Contains(v.apps, p.AppID) {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return p, nil
+}
+
+// OnlineVerifier asks the SSO through ValidateSession, so revoked sessions
+// are rejected right away. Valid tokens are remembered for cacheTTL to
+// spare the SSO a call per request; zero disables the cache.
+type OnlineVerifier struct {
+	client   *ssoclient.Client
+	apps     []int32
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	principal Principal
+	until     time.Time
+}
+
+func NewOnlineVerifier(client *ssoclient.Client, apps []int32, cacheTTL time.Duration) *OnlineVerifier {
+	return &OnlineVerifier{
+		client:   client,
+		apps:     apps,
+		cacheTTL: cacheTTL,
+		cache:    make(map[[sha256.Size]byte]cachedPrincipal),
+	}
+}
+
+func (v *OnlineVerifier) Verify(ctx context.Context, accessToken string) (Principal, error) {
+	const op = "ssoauth.OnlineVerifier.Verify"
+
+	key := sha256.Sum256([]byte(accessToken))
+	now := time.Now()
+
+	if p, ok := v.cached(key, now); ok {
+		return p, nil
+	}
+
+	// The claims are read before the SSO vouches for the token only to
+	// reject tokens of other apps without a call.
+	token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	p, err := principalFromClaims(token.Claims.(jwt.MapClaims))
+	if err != nil {
+		return Principal{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(v.apps) > 0 && !slices.Contains(v.apps, p.AppID) {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	uid, err := v.client.ValidateSession(ctx, accessToken)
+	if err != nil {
+		if errors.Is(err, ssoclient.ErrUnavailable) || errors.Is(err, ssoclient.ErrInternal) ||
+			errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return Principal{}, fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+		}
+		return Principal{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	if uid != p.UserID {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	v.remember(key, p, now)
+
+	return p, nil
+}
+
+func (v *OnlineVerifier) cached(key [sha256.Size]byte, now time.Time) (Principal, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.cache[key]
+	if !ok || !now.Before(c.until) {
+		return Principal{}, false
+	}
+
+	return c.principal, true
+}
+
+func (v *OnlineVerifier) remember(key [sha256.Size]byte, p Principal, now time.Time) {
+	if v.cacheTTL <= 0 {
+		return
+	}
+
+	until := now.Add(v.cacheTTL)
+	if !p.ExpiresAt.IsZero() && p.ExpiresAt.Before(until) {
+		until = p.ExpiresAt
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.cache) >= maxCachedTokens {
+		for k, c := range v.cache {
+			if !now.Before(c.until) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= maxCachedTokens {
+			clear(v.cache)
+		}
+	}
+
+	v.cache[key] = cachedPrincipal{principal: p, until: until}
+}
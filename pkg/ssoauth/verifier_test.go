@@ -0,0 +1,172 @@
+package ssoauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testKid = "test-key"
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// testToken signs claims over the defaults of an SSO access token.
+func testToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	mapClaims := jwt.MapClaims{
+		"uid":    42,
+		"app_id": 3,
+		"sid":    7,
+		"jti":    "0f1e2d3c",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(mapClaims, name)
+			continue
+		}
+		mapClaims[name] = value
+	}
+
+	token := jwt.NewWithClaims(method, mapClaims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestOfflineVerifier(t *testing.T) {
+	key := testKey(t)
+	public := key.Public().(ed25519.PublicKey)
+	other := testKey(t)
+
+	keys := Keys{testKid: public}
+
+	tests := []struct {
+		name    string
+		token   string
+		apps    []int32
+		want    Principal
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: testToken(t, jwt.SigningMethodEdDSA, key, testKid, nil),
+			want:  Principal{UserID: 42, AppID: 3, SessionID: 7, TokenID: "0f1e2d3c"},
+		},
+		{
+			name:  "roles and scope",
+			token: testToken(t, jwt.SigningMethodEdDSA, key, testKid, jwt.MapClaims{"roles": []string{"admin"}, "scope": "orders:read orders:write"}),
+			want:  Principal{UserID: 42, AppID: 3, SessionID: 7, TokenID: "0f1e2d3c", Roles: []string{"admin"}, Scopes: []string{"orders:read", "orders:write"}},
+		},
+		{
+			name:  "allowed app",
+			token: testToken(t, jwt.SigningMethodEdDSA, key, testKid, nil),
+			apps:  []int32{1, 3},
+			want:  Principal{UserID: 42, AppID: 3, SessionID: 7, TokenID: "0f1e2d3c"},
+		},
+		{
+			name:    "other app",
+			token:   testToken(t, jwt.SigningMethodEdDSA, key, testKid, nil),
+			apps:    []int32{1},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "expired",
+			token:   testToken(t, jwt.SigningMethodEdDSA, key, testKid, jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "no exp",
+			token:   testToken(t, jwt.SigningMethodEdDSA, key, testKid, jwt.MapClaims{"exp": nil}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "no uid",
+			token:   testToken(t, jwt.SigningMethodEdDSA, key, testKid, jwt.MapClaims{"uid": nil}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "signed with another key",
+			token:   testToken(t, jwt.SigningMethodEdDSA, other, testKid, nil),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "unknown kid",
+			token:   testToken(t, jwt.SigningMethodEdDSA, key, "other-key", nil),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "hmac with the public key",
+			token:   testToken(t, jwt.SigningMethodHS256, []byte(public), testKid, nil),
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewOfflineVerifier(keys, tt.apps).Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if p.UserID != tt.want.UserID || p.AppID != tt.want.AppID || p.SessionID != tt.want.SessionID ||
+				p.TokenID != tt.want.TokenID || !slices.Equal(p.Roles, tt.want.Roles) || !slices.Equal(p.Scopes, tt.want.Scopes) {
+				t.Errorf("Verify() = %+v, want %+v", p, tt.want)
+			}
+
+			if p.ExpiresAt.IsZero() {
+				t.Error("principal has no expiry")
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	public := testKey(t).Public()
+
+	tests := []struct {
+		name    string
+		keys    Keys
+		kid     string
+		wantErr error
+	}{
+		{name: "known kid", keys: Keys{testKid: public}, kid: testKid},
+		{name: "unknown kid", keys: Keys{testKid: public}, kid: "other", wantErr: ErrInvalidToken},
+		{name: "no kid, one key", keys: Keys{testKid: public}},
+		{name: "no kid, several keys", keys: Keys{testKid: public, "other": public}, wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.keys.Key(context.Background(), tt.kid)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Key(%q) = %v, want %v", tt.kid, err, tt.wantErr)
+			}
+		})
+	}
+}
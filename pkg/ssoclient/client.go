@@ -0,0 +1,119 @@
+// Package ssoclient is the Go client of the SSO: a connection with typed
+// errors and retries, and a TokenSource that keeps the access token fresh.
+package ssoclient
+
+import (
+	"context"
+	"fmt"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Tokens is a session's pair of tokens.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Client is a connection to the SSO. Errors of its calls, including calls
+// through the generated clients it returns, are converted with FromError.
+type Client struct {
+	conn *grpc.ClientConn
+	auth ssov1.AuthClient
+}
+
+// Dial connects to the SSO at target. Idempotent calls are retried
+// according to policy.
+func Dial(target string, creds credentials.TransportCredentials, policy RetryPolicy, opts ...grpc.DialOption,
+) (*Client, error) {
+	const op = "ssoclient.Dial"
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(unaryInterceptor(policy)),
+		grpc.WithChainStreamInterceptor(streamInterceptor()),
+	}, opts...)
+
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Client{
+		conn: conn,
+		auth: ssov1.NewAuthClient(conn),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *Client) Auth() ssov1.AuthClient {
+	return c.auth
+}
+
+func (c *Client) Profile() ssov1.ProfileClient {
+	return ssov1.NewProfileClient(c.conn)
+}
+
+func (c *Client) AddressBook() ssov1.AddressBookClient {
+	return ssov1.NewAddressBookClient(c.conn)
+}
+
+func (c *Client) Security() ssov1.SecurityClient {
+	return ssov1.NewSecurityClient(c.conn)
+}
+
+func (c *Client) Revocations() ssov1.RevocationsClient {
+	return ssov1.NewRevocationsClient(c.conn)
+}
+
+func (c *Client) Login(ctx context.Context, req *ssov1.LoginRequest) (Tokens, error) {
+	resp, err := c.auth.Login(ctx, req)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  resp.GetAccessToken(),
+		RefreshToken: resp.GetRefreshToken(),
+	}, nil
+}
+
+// Refresh exchanges the pair for a new one. The old refresh token can't be
+// used again: sending it twice ends the session.
+func (c *Client) Refresh(ctx context.Context, tokens Tokens) (Tokens, error) {
+	resp, err := c.auth.RefreshSession(ctx, &ssov1.RefreshRequest{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  resp.GetNewAccessToken(),
+		RefreshToken: resp.GetNewRefreshToken(),
+	}, nil
+}
+
+// ValidateSession returns the user of the access token, or
+// ErrSessionInvalid when the token is not valid.
+func (c *Client) ValidateSession(ctx context.Context, accessToken string) (uid int64, err error) {
+	resp, err := c.auth.ValidateSession(ctx, &ssov1.ValidateRequest{AccessToken: accessToken})
+	if err != nil {
+		return 0, err
+	}
+
+	if !resp.GetIsValid() {
+		return 0, ErrSessionInvalid
+	}
+
+	return resp.GetUid(), nil
+}
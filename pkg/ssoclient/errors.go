@@ -0,0 +1,178 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// Errors returned by the SSO. Errors of calls wrap one of them, so callers
+// check them with errors.Is.
+var (
+	ErrInvalidCredentials     = errors.New("invalid login or password")
+	ErrInvalidPhone           = errors.New("invalid phone number")
+	ErrInvalidCode            = errors.New("invalid or expired code")
+	ErrInvalidResetToken      = errors.New("invalid or expired reset link")
+	ErrWeakPassword           = errors.New("password doesn't meet the policy")
+	ErrPasswordReused         = errors.New("password was used recently")
+	ErrPasswordChangeRequired = errors.New("password must be changed")
+	ErrPhoneCooldown          = errors.New("phone was recently used by another account")
+	ErrSessionInvalid         = errors.New("session is invalid")
+	ErrSessionExpired         = errors.New("session expired")
+	ErrSessionIdle            = errors.New("session ended after inactivity")
+	ErrInvalidRefreshToken    = errors.New("invalid refresh token")
+	ErrAccountNotActivated    = errors.New("account is not activated")
+	ErrAccountLocked          = errors.New("account is temporarily locked")
+	ErrAccountSuspended       = errors.New("account is suspended")
+	ErrAccountDeleted         = errors.New("account is deleted")
+	ErrAccountUnavailable     = errors.New("account is unavailable")
+	ErrAppUnavailable         = errors.New("app not found or disabled")
+	ErrGrantNotAllowed        = errors.New("sign-in method is not allowed for the app")
+	ErrAlreadyExists          = errors.New("already exists")
+	ErrNotFound               = errors.New("not found")
+	ErrInvalidArgument        = errors.New("invalid argument")
+	ErrPermissionDenied       = errors.New("permission denied")
+	ErrFailedPrecondition     = errors.New("failed precondition")
+	ErrLimitExceeded          = errors.New("limit exceeded")
+	ErrUnavailable            = errors.New("sso is unavailable")
+	ErrInternal               = errors.New("sso internal error")
+)
+
+// Reasons of the ErrorInfo details the SSO attaches to some errors.
+const (
+	errorDomain          = "sso.vizap"
+	reasonSessionExpired = "SESSION_EXPIRED"
+	reasonSessionIdle    = "SESSION_IDLE_TIMEOUT"
+	reasonInvalidRefresh = "INVALID_REFRESH_TOKEN"
+)
+
+var reasons = map[string]error{
+	reasonSessionExpired: ErrSessionExpired,
+	reasonSessionIdle:    ErrSessionIdle,
+	reasonInvalidRefresh: ErrInvalidRefreshToken,
+}
+
+// messages are the texts of serverAPI that tell errors with the same code
+// apart. Keep them in sync with internal/grpc/auth.
+var messages = map[string]error{
+	"Неверный логин или пароль!":                                            ErrInvalidCredentials,
+	"Неверный текущий пароль!":                                              ErrInvalidCredentials,
+	"Неверный пароль!":                                                      ErrInvalidCredentials,
+	"Некорректный номер телефона":                                           ErrInvalidPhone,
+	"Укажите номер мобильного телефона":                                     ErrInvalidPhone,
+	"Неверный или просроченный код!":                                        ErrInvalidCode,
+	"Ссылка для восстановления недействительна или устарела":                ErrInvalidResetToken,
+	"Этот пароль уже использовался. Придумайте новый.":                      ErrPasswordReused,
+	"Необходимо сменить пароль. Воспользуйтесь восстановлением пароля.":     ErrPasswordChangeRequired,
+	"Этот номер недавно был привязан к другому аккаунту. Попробуйте позже.": ErrPhoneCooldown,
+	"Аккаунт ещё не активирован":                                            ErrAccountNotActivated,
+	"Аккаунт удалён":                                                        ErrAccountDeleted,
+	"Аккаунт недоступен":                                                    ErrAccountUnavailable,
+	"Приложение не найдено или отключено":                                   ErrAppUnavailable,
+	"Этот способ входа недоступен для приложения":                           ErrGrantNotAllowed,
+}
+
+// prefixes match messages that end with details, such as the date a lock
+// ends. Longer prefixes go first.
+var prefixes = []struct {
+	prefix string
+	err    error
+}{
+	{"Аккаунт временно заблокирован", ErrAccountLocked},
+	{"Аккаунт заблокирован", ErrAccountSuspended},
+	{"Пароль слишком", ErrWeakPassword},
+	{"Пароль должен", ErrWeakPassword},
+}
+
+var byCode = map[codes.Code]error{
+	codes.Canceled:           context.Canceled,
+	codes.DeadlineExceeded:   context.DeadlineExceeded,
+	codes.Unauthenticated:    ErrSessionInvalid,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.AlreadyExists:      ErrAlreadyExists,
+	codes.NotFound:           ErrNotFound,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.FailedPrecondition: ErrFailedPrecondition,
+	codes.ResourceExhausted:  ErrLimitExceeded,
+	codes.Unavailable:        ErrUnavailable,
+}
+
+// Error is an error returned by the SSO.
+type Error struct {
+	Code codes.Code
+	// Message is the text sent by the server. For user-facing calls it is
+	// in Russian and can be shown to the user as is.
+	Message string
+	// Reason is the ErrorInfo reason, when the server sent one.
+	Reason string
+
+	kind error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sso: %s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the sentinel error the status maps to.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// FromError converts a gRPC status error to an *Error. Other errors are
+// returned as is.
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sdkErr *Error
+	if errors.As(err, &sdkErr) {
+		return err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	e := &Error{
+		Code:    st.Code(),
+		Message: st.Message(),
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			e.Reason = info.GetReason()
+		}
+	}
+
+	e.kind = kindOf(e)
+
+	return e
+}
+
+func kindOf(e *Error) error {
+	if kind, ok := reasons[e.Reason]; ok {
+		return kind
+	}
+
+	if kind, ok := messages[e.Message]; ok {
+		return kind
+	}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(e.Message, p.prefix) {
+			return p.err
+		}
+	}
+
+	if kind, ok := byCode[e.Code]; ok {
+		return kind
+	}
+
+	return ErrInternal
+}
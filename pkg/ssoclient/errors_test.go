@@ -0,0 +1,68 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	withReason := func(code codes.Code, msg, reason string) error {
+		st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{Domain: errorDomain, Reason: reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st.Err()
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "reason", err: withReason(codes.Unauthenticated, "Сессия истекла", reasonSessionIdle), want: ErrSessionIdle},
+		{name: "message", err: status.Error(codes.InvalidArgument, "Неверный логин или пароль!"), want: ErrInvalidCredentials},
+		{name: "message prefix", err: status.Error(codes.PermissionDenied, "Аккаунт временно заблокирован до 12:00"), want: ErrAccountLocked},
+		{name: "code", err: status.Error(codes.Unavailable, "connection refused"), want: ErrUnavailable},
+		{name: "canceled", err: status.Error(codes.Canceled, "context canceled"), want: context.Canceled},
+		{name: "unknown code", err: status.Error(codes.DataLoss, "boom"), want: ErrInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromError(tt.err)
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FromError() = %v, want %v", err, tt.want)
+			}
+
+			var sdkErr *Error
+			if !errors.As(err, &sdkErr) || sdkErr.Code != status.Code(tt.err) {
+				t.Errorf("FromError() = %#v, want *Error with the status code", err)
+			}
+		})
+	}
+}
+
+func TestFromErrorPassesOtherErrors(t *testing.T) {
+	plain := errors.New("dial failed")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "nil"},
+		{name: "not a status", err: plain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromError(tt.err); got != tt.err {
+				t.Errorf("FromError() = %v, want %v", got, tt.err)
+			}
+		})
+	}
+}
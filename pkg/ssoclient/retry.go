@@ -0,0 +1,95 @@
+package ssoclient
+
+import (
+	"context"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how idempotent calls are retried when the SSO is
+// unavailable.
+type RetryPolicy struct {
+	// MaxAttempts counts the first call too; 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// idempotentMethods are safe to send again: they only read. Login,
+// RefreshSession and the rest change state; RefreshSession in particular
+// rotates the refresh token, so a retry after a lost response would look
+// like reuse and end the session.
+var idempotentMethods = map[string]bool{
+	ssov1.Auth_ValidateSession_FullMethodName:        true,
+	ssov1.Auth_ListSessions_FullMethodName:           true,
+	ssov1.Profile_GetProfile_FullMethodName:          true,
+	ssov1.AddressBook_ListAddresses_FullMethodName:   true,
+	ssov1.Security_ListSecurityEvents_FullMethodName: true,
+}
+
+// backoff is the pause after the given attempt: it doubles from
+// InitialBackoff up to MaxBackoff, with up to a fifth added at random.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+
+	return d + rand.N(d/5+1)
+}
+
+// unaryInterceptor retries idempotent calls on Unavailable and converts
+// the errors of every call with FromError.
+func unaryInterceptor(policy RetryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		attempts := 1
+		if idempotentMethods[method] {
+			attempts = max(policy.MaxAttempts, 1)
+		}
+
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil {
+				return nil
+			}
+
+			if attempt >= attempts || status.Code(err) != codes.Unavailable {
+				return FromError(err)
+			}
+
+			timer := time.NewTimer(policy.backoff(attempt))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return FromError(err)
+			}
+		}
+	}
+}
+
+// streamInterceptor converts the error of opening a stream. Errors of
+// RecvMsg are left to the caller, see FromError.
+func streamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromError(err)
+		}
+
+		return stream, nil
+	}
+}
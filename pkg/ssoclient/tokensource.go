@@ -0,0 +1,186 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"sync"
+	"time"
+)
+
+const refreshTimeout = 10 * time.Second
+
+// refreshBackoff spaces out refreshes after temporary failures, so a
+// source doesn't call the SSO on every Token while it is down.
+var refreshBackoff = RetryPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// TokenSource hands out the access token of a session and refreshes the
+// pair through RefreshSession shortly before the token expires. It is safe
+// for concurrent use: callers that find the token stale share one refresh,
+// so the rotating refresh token is never sent twice.
+type TokenSource struct {
+	client    *Client
+	early     time.Duration
+	onRefresh func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+	expiry time.Time
+	call   *refreshCall
+	// err is set once a refresh fails for good, e.g. the session was
+	// revoked; the source returns it from then on.
+	err error
+	// failures counts temporary failures in a row. The next refresh waits
+	// until retryAt; lastErr is returned meanwhile if the token expires.
+	failures int
+	retryAt  time.Time
+	lastErr  error
+}
+
+type refreshCall struct {
+	done   chan struct{}
+	tokens Tokens
+	err    error
+}
+
+// NewTokenSource starts from the pair of a session and refreshes it early
+// before the access token expires. onRefresh, if not nil, gets every new
+// pair, e.g. to persist the refresh token; it is called before the new
+// access token is handed out and never concurrently.
+func NewTokenSource(client *Client, tokens Tokens, early time.Duration, onRefresh func(Tokens)) *TokenSource {
+	return &TokenSource{
+		client:    client,
+		early:     early,
+		onRefresh: onRefresh,
+		tokens:    tokens,
+		expiry:    expiryOf(tokens.AccessToken),
+	}
+}
+
+// Token returns a valid access token, refreshing the pair when needed. If
+// the refresh fails with a temporary error, the current token is returned
+// as long as it hasn't expired, and a call after a backoff tries again. A
+// refresh that timed out is not retried: the SSO may have rotated the pair
+// already, and sending the old refresh token again would end the session.
+// The source fails with the error instead and the user signs in again. A
+// canceled ctx stops the wait, not the refresh other callers may share.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+
+	if ts.err != nil {
+		ts.mu.Unlock()
+		return "", ts.err
+	}
+
+	accessToken, expiry := ts.tokens.AccessToken, ts.expiry
+
+	if time.Now().Before(expiry.Add(-ts.early)) {
+		ts.mu.Unlock()
+		return accessToken, nil
+	}
+
+	call := ts.call
+	if call == nil && time.Now().Before(ts.retryAt) {
+		lastErr := ts.lastErr
+		ts.mu.Unlock()
+
+		if time.Now().Before(expiry) {
+			return accessToken, nil
+		}
+		return "", lastErr
+	}
+
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		ts.call = call
+		go ts.refresh(call, ts.tokens)
+	}
+
+	ts.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if call.err != nil {
+		// The refresh is early: while the SSO is briefly unavailable the
+		// current token is still good until it actually expires.
+		if temporary(call.err) && time.Now().Before(expiry) {
+			return accessToken, nil
+		}
+		return "", call.err
+	}
+
+	return call.tokens.AccessToken, nil
+}
+
+// Tokens returns the current pair.
+func (ts *TokenSource) Tokens() Tokens {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.tokens
+}
+
+func (ts *TokenSource) refresh(call *refreshCall, tokens Tokens) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	call.tokens, call.err = ts.client.Refresh(ctx, tokens)
+
+	if call.err == nil && ts.onRefresh != nil {
+		ts.onRefresh(call.tokens)
+	}
+
+	ts.mu.Lock()
+	switch {
+	case call.err == nil:
+		ts.tokens = call.tokens
+		ts.expiry = expiryOf(call.tokens.AccessToken)
+		ts.failures, ts.retryAt, ts.lastErr = 0, time.Time{}, nil
+	case temporary(call.err):
+		ts.failures++
+		ts.retryAt = time.Now().Add(refreshBackoff.backoff(ts.failures))
+		ts.lastErr = call.err
+	default:
+		ts.err = call.err
+	}
+	ts.call = nil
+	ts.mu.Unlock()
+
+	close(call.done)
+}
+
+// temporary tells whether a refresh that failed with err may be sent again.
+// A deadline or cancellation is ambiguous: the server may have rotated the
+// refresh token before the call ended.
+func temporary(err error) bool {
+	var sdkErr *Error
+	if !errors.As(err, &sdkErr) {
+		return !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled)
+	}
+
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrInternal)
+}
+
+// expiryOf reads exp of the access token without checking the signature:
+// the client doesn't have the key and only needs to know when to refresh.
+// A token it can't read counts as expired.
+func expiryOf(accessToken string) time.Time {
+	token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return time.Time{}
+	}
+
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+
+	return exp.Time
+}
@@ -0,0 +1,250 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testAuth answers RefreshSession with a token valid for ttl, or with err.
+type testAuth struct {
+	ssov1.AuthClient
+
+	ttl   time.Duration
+	err   error
+	calls atomic.Int32
+}
+
+func (a *testAuth) RefreshSession(context.Context, *ssov1.RefreshRequest, ...grpc.CallOption,
+) (*ssov1.RefreshResponse, error) {
+	a.calls.Add(1)
+
+	if a.err != nil {
+		return nil, FromError(a.err)
+	}
+
+	return &ssov1.RefreshResponse{
+		NewAccessToken:  testAccessToken(a.ttl),
+		NewRefreshToken: "new-refresh",
+	}, nil
+}
+
+func testAccessToken(ttl time.Duration) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid": 42,
+		"exp": time.Now().Add(ttl).Unix(),
+	})
+
+	signed, err := token.SignedString([]byte("test-key"))
+	if err != nil {
+		panic(err)
+	}
+
+	return signed
+}
+
+func TestTokenSource(t *testing.T) {
+	const early = time.Minute
+
+	tests := []struct {
+		name string
+		// ttl is how long the current access token is still valid.
+		ttl        time.Duration
+		refreshErr error
+		wantErr    error
+		wantCalls  int32
+		// wantCurrent means the current token is returned, not a new one.
+		wantCurrent bool
+	}{
+		{name: "fresh token", ttl: time.Hour, wantCurrent: true},
+		{name: "refreshed early", ttl: 30 * time.Second, wantCalls: 1},
+		{name: "refreshed after expiry", ttl: -time.Minute, wantCalls: 1},
+		{
+			name:        "sso unavailable in the early window",
+			ttl:         30 * time.Second,
+			refreshErr:  status.Error(codes.Unavailable, "connection refused"),
+			wantCalls:   1,
+			wantCurrent: true,
+		},
+		{
+			name:       "sso unavailable after expiry",
+			ttl:        -time.Minute,
+			refreshErr: status.Error(codes.Unavailable, "connection refused"),
+			wantErr:    ErrUnavailable,
+			wantCalls:  1,
+		},
+		{
+			name:       "timeout in the early window",
+			ttl:        30 * time.Second,
+			refreshErr: status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			wantErr:    context.DeadlineExceeded,
+			wantCalls:  1,
+		},
+		{
+			name:       "session revoked in the early window",
+			ttl:        30 * time.Second,
+			refreshErr: status.Error(codes.Unauthenticated, "session revoked"),
+			wantErr:    ErrSessionInvalid,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &testAuth{ttl: time.Hour, err: tt.refreshErr}
+			current := Tokens{AccessToken: testAccessToken(tt.ttl), RefreshToken: "refresh"}
+			ts := NewTokenSource(&Client{auth: auth}, current, early, nil)
+
+			got, err := ts.Token(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token() = %v, want %v", err, tt.wantErr)
+			}
+
+			if calls := auth.calls.Load(); calls != tt.wantCalls {
+				t.Errorf("refreshed %d times, want %d", calls, tt.wantCalls)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if (got == current.AccessToken) != tt.wantCurrent {
+				t.Errorf("got the current token: %v, want %v", got == current.AccessToken, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestTokenSourceRetriesAfterTemporaryError(t *testing.T) {
+	tests := []struct {
+		name       string
+		refreshErr error
+		// wantRetry means the next call refreshes again.
+		wantRetry bool
+	}{
+		{name: "unavailable", refreshErr: status.Error(codes.Unavailable, "connection refused"), wantRetry: true},
+		{name: "internal", refreshErr: status.Error(codes.Internal, "boom"), wantRetry: true},
+		{name: "timeout", refreshErr: status.Error(codes.DeadlineExceeded, "context deadline exceeded")},
+		{name: "revoked", refreshErr: status.Error(codes.Unauthenticated, "session revoked")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &testAuth{ttl: time.Hour, err: tt.refreshErr}
+			ts := NewTokenSource(&Client{auth: auth}, Tokens{AccessToken: testAccessToken(30 * time.Second)}, time.Minute, nil)
+
+			ts.Token(context.Background())
+
+			auth.err = nil
+
+			// The call right after the failure waits out the backoff.
+			ts.Token(context.Background())
+			if calls := auth.calls.Load(); calls != 1 {
+				t.Fatalf("refreshed %d times during the backoff, want 1", calls)
+			}
+
+			ts.mu.Lock()
+			ts.retryAt = time.Time{}
+			ts.mu.Unlock()
+
+			_, err := ts.Token(context.Background())
+
+			if retried := auth.calls.Load() == 2; retried != tt.wantRetry {
+				t.Errorf("refreshed again: %v, want %v", retried, tt.wantRetry)
+			}
+
+			if (err == nil) != tt.wantRetry {
+				t.Errorf("second Token() = %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenSourceBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		// ttl is how long the current access token is still valid.
+		ttl     time.Duration
+		wantErr error
+	}{
+		{name: "token still valid", ttl: 30 * time.Second},
+		{name: "token expired", ttl: -time.Minute, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &testAuth{ttl: time.Hour, err: status.Error(codes.Unavailable, "connection refused")}
+			current := Tokens{AccessToken: testAccessToken(tt.ttl)}
+			ts := NewTokenSource(&Client{auth: auth}, current, time.Minute, nil)
+
+			ts.Token(context.Background())
+			first := ts.retryAt
+
+			got, err := ts.Token(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token() during the backoff = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != current.AccessToken {
+				t.Error("Token() during the backoff didn't return the current token")
+			}
+
+			ts.retryAt = time.Time{}
+			ts.Token(context.Background())
+
+			if calls := auth.calls.Load(); calls != 2 {
+				t.Errorf("refreshed %d times, want 2", calls)
+			}
+			if second := ts.retryAt.Sub(time.Now()); second <= first.Sub(time.Now()) {
+				t.Errorf("second backoff %v isn't longer than the first", second)
+			}
+		})
+	}
+}
+
+func TestTokenSourceSharesRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		callers int
+	}{
+		{name: "one caller", callers: 1},
+		{name: "many callers", callers: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &testAuth{ttl: time.Hour}
+
+			var refreshed []Tokens
+			ts := NewTokenSource(&Client{auth: auth}, Tokens{AccessToken: testAccessToken(-time.Minute)}, time.Minute,
+				func(tokens Tokens) { refreshed = append(refreshed, tokens) })
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := ts.Token(context.Background()); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if calls := auth.calls.Load(); calls != 1 {
+				t.Errorf("refreshed %d times, want 1", calls)
+			}
+
+			if len(refreshed) != 1 || ts.Tokens() != refreshed[0] {
+				t.Errorf("onRefresh got %d pairs, want the current one", len(refreshed))
+			}
+		})
+	}
+}
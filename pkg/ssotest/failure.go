@@ -0,0 +1,66 @@
+package ssotest
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+type failure struct {
+	err error
+	// left is how many more calls fail; zero means every call until Reset.
+	left int
+}
+
+// Fail makes the next times calls of the method return err without
+// reaching the service. The method is the full name, such as
+// ssov1.Auth_Login_FullMethodName; times <= 0 fails every call until Reset.
+func (s *Server) Fail(method string, err error, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = &failure{err: err, left: max(times, 0)}
+}
+
+// FailStorage makes every storage call return err until Reset, so the
+// service answers as it does when Postgres is down.
+func (s *Server) FailStorage(err error) {
+	s.storage.Fail(err)
+}
+
+// Reset removes all injected failures.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.failures)
+	s.storage.Fail(nil)
+}
+
+func (s *Server) failureFor(method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.failures[method]
+	if !ok {
+		return nil
+	}
+
+	if f.left > 0 {
+		f.left--
+		if f.left == 0 {
+			delete(s.failures, method)
+		}
+	}
+
+	return f.err
+}
+
+func (s *Server) failureInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := s.failureFor(info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
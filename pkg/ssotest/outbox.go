@@ -0,0 +1,64 @@
+package ssotest
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Message is an SMS or email the server sent. SMS have no Subject.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+type outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (o *outbox) add(msg Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, msg)
+}
+
+func (o *outbox) to(to string) []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var messages []Message
+	for _, msg := range o.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+
+	return slices.Clip(messages)
+}
+
+type smsSender struct {
+	outbox *outbox
+}
+
+func (s smsSender) Send(_ context.Context, phone, text string) error {
+	s.outbox.add(Message{To: phone, Text: text})
+	return nil
+}
+
+type emailSender struct {
+	outbox *outbox
+}
+
+func (s emailSender) Send(_ context.Context, to, subject, body string) error {
+	s.outbox.add(Message{To: to, Subject: subject, Text: body})
+	return nil
+}
+
+// Messages returns what was sent to the phone or email, oldest first.
+// Phones are in the normalized form, like +79991234567.
+func (s *Server) Messages(to string) []Message {
+	return s.outbox.to(to)
+}
@@ -0,0 +1,201 @@
+package ssotest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/KVSH-user/vizapSSO/internal/entity"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/pkg/ssoauth"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+// AccountStatus and SecurityEvent are the service's own types, named here
+// for tests outside the module.
+type (
+	AccountStatus = entity.AccountStatus
+	SecurityEvent = entity.SecurityEvent
+)
+
+const (
+	AccountActive    = entity.AccountActive
+	AccountLocked    = entity.AccountLocked
+	AccountSuspended = entity.AccountSuspended
+)
+
+// App is an app registered in the server.
+type App struct {
+	ID   int32
+	Name string
+}
+
+// User is an active account registered in the server.
+type User struct {
+	ID    int64
+	Phone string
+}
+
+// AddApp registers an app with the password and refresh token grants.
+func (s *Server) AddApp(name string) App {
+	s.tb.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		s.tb.Fatalf("ssotest: %v", err)
+	}
+
+	app, err := s.storage.SaveApp(entity.App{
+		Name:       name,
+		SigningKey: hex.EncodeToString(key),
+		GrantTypes: []string{entity.GrantPassword, entity.GrantRefreshToken},
+	})
+	if err != nil {
+		s.tb.Fatalf("ssotest: add app %q: %v", name, err)
+	}
+
+	return App{ID: app.ID, Name: app.Name}
+}
+
+// DisableApp turns the app off, so its users can't sign in.
+func (s *Server) DisableApp(appID int32) {
+	s.tb.Helper()
+
+	if err := s.storage.DisableApp(appID, true); err != nil {
+		s.tb.Fatalf("ssotest: disable app %d: %v", appID, err)
+	}
+}
+
+// SetAppScopes sets the scopes issued in the app's access tokens.
+func (s *Server) SetAppScopes(appID int32, scopes ...string) {
+	s.tb.Helper()
+
+	if err := s.storage.SetAppScopes(appID, scopes); err != nil {
+		s.tb.Fatalf("ssotest: set scopes of app %d: %v", appID, err)
+	}
+}
+
+// GrantRole gives the user a role in the app. Tokens issued from then on
+// carry it.
+func (s *Server) GrantRole(uid int64, appID int32, role string) {
+	s.tb.Helper()
+
+	if err := s.storage.GrantUserAppRole(uid, appID, role, "ssotest"); err != nil {
+		s.tb.Fatalf("ssotest: grant role %q to user %d: %v", role, uid, err)
+	}
+}
+
+// AddUser registers an active account, skipping the SMS confirmation.
+// The phone can be in any form the service accepts.
+func (s *Server) AddUser(phone, password string) User {
+	s.tb.Helper()
+
+	normalized, err := s.phones.Normalize(phone)
+	if err != nil {
+		s.tb.Fatalf("ssotest: add user %q: %v", phone, err)
+	}
+
+	passHash, err := s.hasher.Generate(context.Background(), []byte(password))
+	if err != nil {
+		s.tb.Fatalf("ssotest: add user %q: %v", phone, err)
+	}
+
+	uid, err := s.storage.SaveUser(normalized, passHash)
+	if err != nil {
+		s.tb.Fatalf("ssotest: add user %q: %v", phone, err)
+	}
+
+	return User{ID: uid, Phone: normalized}
+}
+
+// SetEmail gives the user a verified email, as needed for password reset.
+func (s *Server) SetEmail(uid int64, email string) {
+	s.tb.Helper()
+
+	if err := s.storage.SetVerifiedEmail(uid, email); err != nil {
+		s.tb.Fatalf("ssotest: set email of user %d: %v", uid, err)
+	}
+}
+
+// SetAccountStatus locks, suspends or reactivates the account. A zero
+// until keeps the status until the next change.
+func (s *Server) SetAccountStatus(uid int64, status AccountStatus, until time.Time) {
+	s.tb.Helper()
+
+	user, err := s.storage.UserByID(uid)
+	if err != nil {
+		s.tb.Fatalf("ssotest: set status of user %d: %v", uid, err)
+	}
+
+	err = s.storage.SetAccountState(uid, user.State.Status, entity.AccountState{
+		Status:    status,
+		Reason:    "ssotest",
+		ChangedBy: "ssotest",
+		Until:     until,
+	}, nil)
+	if err != nil {
+		s.tb.Fatalf("ssotest: set status of user %d: %v", uid, err)
+	}
+}
+
+// SecurityEvents returns the security events the service recorded,
+// oldest first.
+func (s *Server) SecurityEvents() []SecurityEvent {
+	return s.storage.AllSecurityEvents()
+}
+
+// Keys are the public keys of the server's access tokens, for offline
+// verification in the test with ssoauth.NewOfflineVerifier.
+func (s *Server) Keys() ssoauth.Keys {
+	public := s.signingKey.Public().(ed25519.PublicKey)
+
+	return ssoauth.Keys{jwt.KeyID(public): public}
+}
+
+// JWKS is the key set the server publishes, for tests that serve it to
+// ssoauth.NewJWKS.
+func (s *Server) JWKS() []byte {
+	return s.keys.JWKS()
+}
+
+// MintToken signs an access token of the app like the service does, then
+// sets the given claims over the defaults, so tests can forge any token.
+// The defaults are uid, app_id, jti, iat, exp = now + ttl and sid = 0,
+// which skips the session check; a negative ttl gives an expired token.
+// A nil claim value removes the claim.
+func (s *Server) MintToken(app App, uid int64, ttl time.Duration, claims map[string]any) string {
+	s.tb.Helper()
+
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		s.tb.Fatalf("ssotest: %v", err)
+	}
+
+	now := time.Now()
+	mapClaims := gojwt.MapClaims{
+		"uid":    uid,
+		"exp":    now.Add(ttl).Unix(),
+		"iat":    now.Unix(),
+		"jti":    hex.EncodeToString(tokenID),
+		"app_id": app.ID,
+		"sid":    int64(0),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(mapClaims, name)
+			continue
+		}
+		mapClaims[name] = value
+	}
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, mapClaims)
+	token.Header["kid"] = jwt.KeyID(s.signingKey.Public().(ed25519.PublicKey))
+
+	signed, err := token.SignedString(s.signingKey)
+	if err != nil {
+		s.tb.Fatalf("ssotest: mint token: %v", err)
+	}
+
+	return signed
+}
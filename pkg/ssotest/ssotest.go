@@ -0,0 +1,170 @@
+// Package ssotest runs the SSO auth API in process for tests of its
+// consumers. The server is the real serverAPI and auth service on an
+// in-memory storage, reached through a bufconn listener, so tests need
+// neither Postgres nor the network.
+//
+//	sso := ssotest.Start(t)
+//	app := sso.AddApp("shop")
+//	user := sso.AddUser("+79991234567", "secret123")
+//	client := sso.Client()
+package ssotest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"github.com/KVSH-user/vizapSSO/internal/config"
+	authgrpc "github.com/KVSH-user/vizapSSO/internal/grpc/auth"
+	"github.com/KVSH-user/vizapSSO/internal/interceptor"
+	"github.com/KVSH-user/vizapSSO/internal/lib/geoip"
+	"github.com/KVSH-user/vizapSSO/internal/lib/hasher"
+	"github.com/KVSH-user/vizapSSO/internal/lib/jwt"
+	"github.com/KVSH-user/vizapSSO/internal/lib/otp"
+	"github.com/KVSH-user/vizapSSO/internal/lib/password"
+	"github.com/KVSH-user/vizapSSO/internal/lib/phone"
+	"github.com/KVSH-user/vizapSSO/internal/lib/siem"
+	"github.com/KVSH-user/vizapSSO/internal/services/auth"
+	"github.com/KVSH-user/vizapSSO/internal/storage/memory"
+	"github.com/KVSH-user/vizapSSO/pkg/ssoclient"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// Target is the address to dial with the Dialer option.
+	Target = "passthrough:///ssotest"
+
+	// AccessTokenTTL and RefreshTokenTTL are the token lifetimes of apps.
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 720 * time.Hour
+	// CodeResendLimit is how many codes a phone or email gets in an hour.
+	CodeResendLimit = 5
+
+	bufSize = 1 << 20
+)
+
+// Server is a running fake SSO. It is stopped when the test ends.
+type Server struct {
+	tb       testing.TB
+	storage  *memory.Storage
+	phones   *phone.Normalizer
+	hasher   *hasher.Hasher
+	listener *bufconn.Listener
+	gRPC     *grpc.Server
+	conn     *grpc.ClientConn
+	client   *ssoclient.Client
+	outbox   *outbox
+	// signingKey is the private key of keys, for MintToken.
+	signingKey ed25519.PrivateKey
+	keys       *jwt.Keys
+
+	mu       sync.Mutex
+	failures map[string]*failure
+}
+
+// Start runs a fake SSO for the test.
+func Start(tb testing.TB) *Server {
+	tb.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	s := &Server{
+		tb:       tb,
+		storage:  memory.New(),
+		hasher:   hasher.New(0, 2*time.Second, bcrypt.MinCost),
+		listener: bufconn.Listen(bufSize),
+		outbox:   &outbox{},
+		failures: make(map[string]*failure),
+	}
+
+	var err error
+	s.phones, err = phone.New("RU", false)
+	if err != nil {
+		tb.Fatalf("ssotest: %v", err)
+	}
+
+	locator, err := geoip.New("")
+	if err != nil {
+		tb.Fatalf("ssotest: %v", err)
+	}
+
+	_, s.signingKey, err = ed25519.GenerateKey(nil)
+	if err != nil {
+		tb.Fatalf("ssotest: %v", err)
+	}
+	s.keys = jwt.NewKeys(s.signingKey)
+
+	recorder := siem.NewRecorder(s.storage, s.storage, nil)
+
+	codeLimiter := otp.NewLimiter(s.storage, CodeResendLimit, time.Hour)
+
+	authService := auth.New(log, s.storage, s.storage, s.storage, s.storage, s.storage, s.hasher, s.storage,
+		smsSender{s.outbox}, codeLimiter, config.RegistrationConfig{CodeTTL: 10 * time.Minute, MaxAttempts: 5}, s.phones,
+		s.storage, password.NewPolicy(8, 72), 5, s.storage,
+		config.LockoutConfig{MaxFailures: 10, Window: 15 * time.Minute}, s.storage,
+		config.PhoneConfig{ChangeCodeTTL: 10 * time.Minute, ChangeMaxAttempts: 5, ReuseCooldown: 720 * time.Hour},
+		s.storage, emailSender{s.outbox}, s.storage,
+		config.PasswordResetConfig{TokenTTL: time.Hour, LinkFormat: "https://sso.test/reset-password?token=%s"},
+		s.storage,
+		config.AccountConfig{DeletionGracePeriod: 720 * time.Hour, CodeTTL: 10 * time.Minute, CodeMaxAttempts: 5},
+		s.storage, locator, config.SessionConfig{SlidingRefresh: true}, recorder, AccessTokenTTL, RefreshTokenTTL,
+		s.keys)
+
+	s.gRPC = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			interceptor.UnaryClientInfoInterceptor(nil),
+			s.failureInterceptor(),
+		),
+	)
+	authgrpc.Register(s.gRPC, authService)
+
+	go func() {
+		_ = s.gRPC.Serve(s.listener)
+	}()
+
+	s.conn, err = grpc.NewClient(Target, s.Dialer(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		tb.Fatalf("ssotest: %v", err)
+	}
+
+	s.client, err = ssoclient.Dial(Target, insecure.NewCredentials(), ssoclient.RetryPolicy{MaxAttempts: 1},
+		s.Dialer())
+	if err != nil {
+		tb.Fatalf("ssotest: %v", err)
+	}
+
+	tb.Cleanup(s.stop)
+
+	return s
+}
+
+func (s *Server) stop() {
+	_ = s.client.Close()
+	_ = s.conn.Close()
+	s.gRPC.Stop()
+}
+
+// Dialer connects a gRPC client to the server; dial Target with it.
+func (s *Server) Dialer() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.listener.DialContext(ctx)
+	})
+}
+
+// Conn is a plain connection to the server.
+func (s *Server) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// Client is an ssoclient connected to the server. It doesn't retry, so
+// injected failures reach the test as they are.
+func (s *Server) Client() *ssoclient.Client {
+	return s.client
+}
@@ -0,0 +1,184 @@
+package ssotest
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/KVSH-user/protos_viz/gen/go/sso"
+	"github.com/KVSH-user/vizapSSO/pkg/ssoauth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMintToken(t *testing.T) {
+	ctx := context.Background()
+
+	sso := Start(t)
+	app := sso.AddApp("shop")
+	user := sso.AddUser("+79991234567", "secret123")
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		claims  map[string]any
+		apps    []int32
+		wantErr error
+		wantUID int64
+	}{
+		{name: "defaults", ttl: time.Minute, wantUID: user.ID},
+		{name: "expired", ttl: -time.Minute, wantErr: ssoauth.ErrInvalidToken},
+		{name: "other user", ttl: time.Minute, claims: map[string]any{"uid": int64(42)}, wantUID: 42},
+		{name: "without exp", ttl: time.Minute, claims: map[string]any{"exp": nil}, wantErr: ssoauth.ErrInvalidToken},
+		{
+			name:    "other app",
+			ttl:     time.Minute,
+			claims:  map[string]any{"app_id": app.ID + 1},
+			apps:    []int32{app.ID},
+			wantErr: ssoauth.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := sso.MintToken(app, user.ID, tt.ttl, tt.claims)
+
+			p, err := ssoauth.NewOfflineVerifier(sso.Keys(), tt.apps).Verify(ctx, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && p.UserID != tt.wantUID {
+				t.Errorf("uid = %d, want %d", p.UserID, tt.wantUID)
+			}
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+	failure := status.Error(codes.Unavailable, "down")
+
+	tests := []struct {
+		name  string
+		times int
+		reset bool
+		want  []error
+	}{
+		{name: "once", times: 1, want: []error{failure, nil, nil}},
+		{name: "twice", times: 2, want: []error{failure, failure, nil}},
+		{name: "until reset", times: 0, want: []error{failure, failure, failure}},
+		{name: "reset", times: 0, reset: true, want: []error{nil, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sso := Start(t)
+
+			sso.Fail(ssov1.Auth_Login_FullMethodName, failure, tt.times)
+			if tt.reset {
+				sso.Reset()
+			}
+
+			for i, want := range tt.want {
+				if err := sso.failureFor(ssov1.Auth_Login_FullMethodName); err != want {
+					t.Fatalf("call %d: err = %v, want %v", i, err, want)
+				}
+			}
+
+			if err := sso.failureFor(ssov1.Auth_Register_FullMethodName); err != nil {
+				t.Errorf("other method: err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestFailStorage(t *testing.T) {
+	sso := Start(t)
+	user := sso.AddUser("+79991234567", "secret123")
+	failure := errors.New("connection refused")
+
+	sso.FailStorage(failure)
+	if _, err := sso.storage.UserByID(user.ID); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	sso.Reset()
+	if _, err := sso.storage.UserByID(user.ID); err != nil {
+		t.Fatalf("after Reset: %v", err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	ctx := context.Background()
+
+	sso := Start(t)
+	_ = smsSender{sso.outbox}.Send(ctx, "+79991234567", "code 123456")
+	_ = emailSender{sso.outbox}.Send(ctx, "user@example.com", "Reset", "link")
+	_ = smsSender{sso.outbox}.Send(ctx, "+79991234567", "code 654321")
+
+	tests := []struct {
+		name string
+		to   string
+		want []Message
+	}{
+		{
+			name: "sms",
+			to:   "+79991234567",
+			want: []Message{
+				{To: "+79991234567", Text: "code 123456"},
+				{To: "+79991234567", Text: "code 654321"},
+			},
+		},
+		{
+			name: "email",
+			to:   "user@example.com",
+			want: []Message{{To: "user@example.com", Subject: "Reset", Text: "link"}},
+		},
+		{name: "nothing sent", to: "+79990000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sso.Messages(tt.to); !slices.Equal(got, tt.want) {
+				t.Errorf("Messages(%q) = %v, want %v", tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+	}{
+		{name: "normalized", phone: "+79991234567", want: "+79991234567"},
+		{name: "national", phone: "89991234567", want: "+79991234567"},
+		{name: "formatted", phone: "+7 (999) 123-45-67", want: "+79991234567"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sso := Start(t)
+
+			user := sso.AddUser(tt.phone, "secret123")
+			if user.Phone != tt.want {
+				t.Errorf("phone = %q, want %q", user.Phone, tt.want)
+			}
+			if user.ID == 0 {
+				t.Error("user id is zero")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	sso := Start(t)
+
+	for kid := range sso.Keys() {
+		if !strings.Contains(string(sso.JWKS()), `"kid":"`+kid+`"`) {
+			t.Errorf("JWKS %s has no key %q", sso.JWKS(), kid)
+		}
+	}
+}